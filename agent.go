@@ -0,0 +1,590 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"chiffremento-cli/pkg"
+)
+
+// Agent de secrets.
+//
+// Déchiffrer quarante fichiers d'affilée, c'était quarante saisies. L'agent
+// garde en mémoire, pour une durée limitée, les mots de passe et les clés
+// symétriques qu'on lui confie, et les rend aux commandes qui les lui
+// demandent sur une socket Unix propre à l'utilisateur :
+//
+//	chiffremento agent &             démarre l'agent
+//	chiffremento agent add [NOM]     lui confie un mot de passe, ou une clé avec -key-file
+//	chiffremento agent list          ce qu'il détient, sans les secrets
+//	chiffremento agent lock          oublie tout
+//	chiffremento agent stop          oublie tout et s'arrête
+//
+// Les secrets ne quittent pas la mémoire de l'agent : aucun fichier, des pages
+// verrouillées pour qu'ils ne partent pas dans le swap, et pas de core dump.
+// La socket n'est ouverte qu'à son propriétaire (0600, dans un dossier en
+// 0700).
+//
+// Chaque fichier a son propre sel : l'agent évite la saisie, pas la
+// dérivation. Quand il détient plusieurs secrets, ils sont essayés du plus
+// récent au plus ancien ; un mauvais secret échoue dès le premier paquet, sans
+// rien écrire.
+
+// agentSockEnv remplace le chemin par défaut de la socket.
+const agentSockEnv = "CHTO_AGENT_SOCK"
+
+const (
+	agentKindPassword = "password"
+	agentKindKey      = "key"
+
+	// agentMaxMessage borne un échange : un secret n'y dépasse pas
+	// maxPasswordSource, même encodé.
+	agentMaxMessage = 4 * maxPasswordSource
+	// agentMaxEntries borne ce qu'un agent détient.
+	agentMaxEntries = 64
+	agentTimeout    = 5 * time.Second
+)
+
+// useAgent dit si les commandes consultent l'agent avant de demander le mot
+// de passe. Faux par défaut : seul run l'active, quand aucune source
+// explicite n'est donnée et que -no-agent est absent.
+var useAgent bool
+
+var errAgentAbsent error = sentinel("aucun agent ne tourne : chiffremento agent pour en démarrer un")
+
+// agentRequest et agentResponse sont les messages échangés, un aller-retour
+// JSON par connexion.
+type agentRequest struct {
+	Op     string        `json:"op"`
+	Name   string        `json:"name,omitempty"`
+	Kind   string        `json:"kind,omitempty"`
+	Secret []byte        `json:"secret,omitempty"`
+	TTL    time.Duration `json:"ttl,omitempty"`
+}
+
+type agentItem struct {
+	Name    string    `json:"name"`
+	Kind    string    `json:"kind"`
+	Expires time.Time `json:"expires"`
+	Secret  []byte    `json:"secret,omitempty"`
+}
+
+type agentResponse struct {
+	Error string      `json:"error,omitempty"`
+	Items []agentItem `json:"items,omitempty"`
+}
+
+func agentKindLabel(kind string) string {
+	if kind == agentKindKey {
+		return tr("clé")
+	}
+	return tr("mot de passe")
+}
+
+// agentSocketPath rend le chemin de la socket, et si c'est l'emplacement par
+// défaut, le dossier privé qui la contient.
+func agentSocketPath() (path, privateDir string) {
+	if p := os.Getenv(agentSockEnv); p != "" {
+		return p, ""
+	}
+	var dir string
+	switch {
+	case runtime.GOOS == "windows":
+		// Le dossier temporaire est déjà propre à l'utilisateur.
+		dir = filepath.Join(os.TempDir(), "chiffremento")
+	case os.Getenv("XDG_RUNTIME_DIR") != "":
+		dir = filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "chiffremento")
+	default:
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("chiffremento-%d", os.Getuid()))
+	}
+	return filepath.Join(dir, "agent.sock"), dir
+}
+
+// --- Côté agent ---------------------------------------------------------
+
+type agentEntry struct {
+	name, kind string
+	secret     *pkg.SecureBuffer
+	expires    time.Time
+	timer      *time.Timer
+}
+
+type agent struct {
+	mu      sync.Mutex
+	entries []*agentEntry // du plus ancien au plus récent
+	ttl     time.Duration
+	seq     int
+	// warned évite de répéter l'avertissement quand le verrouillage des pages
+	// est refusé (RLIMIT_MEMLOCK).
+	warned bool
+	stop   func()
+}
+
+func newAgent(ttl time.Duration) *agent {
+	return &agent{ttl: ttl}
+}
+
+// agentListener est l'agent en cours, que le gestionnaire de signaux ferme
+// pour ne pas laisser de socket derrière lui.
+var agentListener net.Listener
+
+// runAgent démarre l'agent au premier plan, jusqu'à stop ou Ctrl+C.
+func runAgent(ttl time.Duration) error {
+	if ttl <= 0 {
+		return errorf("-agent-ttl doit être positif")
+	}
+	path, dir := agentSocketPath()
+	l, err := listenAgent(path, dir)
+	if err != nil {
+		return err
+	}
+	disableCoreDumps()
+	agentListener = l
+	fmt.Fprintf(os.Stderr, "%s %s\n", styleAccent.Render("✓"), styleText.Render(fmt.Sprintf(tr("agent à l'écoute sur %s"), path)))
+	fmt.Fprintln(os.Stderr, styleDim.Render(fmt.Sprintf(tr(
+		"  secrets oubliés après %s ; chiffremento agent stop ou Ctrl+C pour l'arrêter"), ttl)))
+	return newAgent(ttl).serve(l)
+}
+
+// stopAgent ferme l'agent en cours, s'il y en a un.
+func stopAgent() {
+	if agentListener != nil {
+		agentListener.Close()
+	}
+}
+
+// listenAgent ouvre la socket. Celle d'un agent mort est remplacée ; celle
+// d'un agent vivant, jamais.
+func listenAgent(path, privateDir string) (net.Listener, error) {
+	if privateDir != "" {
+		if err := os.MkdirAll(privateDir, 0o700); err != nil {
+			return nil, errorf("dossier de l'agent: %w", err)
+		}
+		if err := checkPrivateDir(privateDir); err != nil {
+			return nil, err
+		}
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, errorf("un agent tourne déjà sur %s", path)
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := listenPrivate(path)
+	if err != nil {
+		return nil, errorf("socket de l'agent: %w", err)
+	}
+	return l, nil
+}
+
+func (a *agent) serve(l net.Listener) error {
+	a.stop = func() { l.Close() }
+	defer a.lock()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return errorf("agent: %w", err)
+		}
+		go a.handle(conn)
+	}
+}
+
+func (a *agent) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(agentTimeout))
+
+	var req agentRequest
+	err := json.NewDecoder(io.LimitReader(conn, agentMaxMessage)).Decode(&req)
+	defer zero(req.Secret)
+	var resp agentResponse
+	if err != nil {
+		resp.Error = tr("requête illisible")
+	} else {
+		resp = a.do(req)
+	}
+	json.NewEncoder(conn).Encode(resp)
+	for _, it := range resp.Items {
+		zero(it.Secret)
+	}
+}
+
+func (a *agent) do(req agentRequest) agentResponse {
+	switch req.Op {
+	case "add":
+		if err := a.add(req.Name, req.Kind, req.Secret, req.TTL); err != nil {
+			return agentResponse{Error: err.Error()}
+		}
+		return agentResponse{}
+	case "get":
+		return agentResponse{Items: a.items(req.Kind, true)}
+	case "list":
+		return agentResponse{Items: a.items("", false)}
+	case "lock":
+		a.lock()
+		return agentResponse{}
+	case "stop":
+		a.lock()
+		// Après la réponse : la connexion en cours doit pouvoir la recevoir.
+		time.AfterFunc(10*time.Millisecond, a.stop)
+		return agentResponse{}
+	default:
+		return agentResponse{Error: fmt.Sprintf(tr("opération inconnue %q"), req.Op)}
+	}
+}
+
+func (a *agent) add(name, kind string, secret []byte, ttl time.Duration) error {
+	if kind != agentKindPassword && kind != agentKindKey {
+		return errorf("sorte de secret inconnue %q", kind)
+	}
+	if len(secret) == 0 {
+		return errorf("secret vide")
+	}
+	if kind == agentKindKey && len(secret) != pkg.SymmetricKeySize {
+		return errorf("clé de %d octets (attendu %d)", len(secret), pkg.SymmetricKeySize)
+	}
+	if ttl <= 0 {
+		ttl = a.ttl
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if name == "" {
+		a.seq++
+		name = fmt.Sprintf("%s %d", agentKindLabel(kind), a.seq)
+	}
+	for _, e := range a.entries {
+		if e.name == name {
+			a.removeLocked(e)
+			break
+		}
+	}
+	if len(a.entries) >= agentMaxEntries {
+		return errorf("l'agent détient déjà %d secrets : lock pour faire de la place", agentMaxEntries)
+	}
+
+	// Verrouillé avant la copie : le secret ne doit jamais occuper une page
+	// que le noyau pourrait envoyer dans le swap.
+	s, err := pkg.NewSecureBuffer(len(secret))
+	if err != nil {
+		return err
+	}
+	if !s.Locked() && !a.warned {
+		a.warned = true
+		fmt.Fprintln(os.Stderr, styleDim.Render(tr("attention : verrouillage mémoire refusé (RLIMIT_MEMLOCK ?) ; les secrets peuvent partir dans le swap")))
+	}
+	copy(s.Bytes(), secret)
+
+	e := &agentEntry{name: name, kind: kind, secret: s, expires: time.Now().Add(ttl)}
+	e.timer = time.AfterFunc(ttl, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.removeLocked(e)
+	})
+	a.entries = append(a.entries, e)
+	return nil
+}
+
+// items rend les secrets détenus, du plus récent au plus ancien. Les secrets
+// eux-mêmes ne sont copiés que sur demande.
+func (a *agent) items(kind string, withSecret bool) []agentItem {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []agentItem
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		e := a.entries[i]
+		if kind != "" && e.kind != kind {
+			continue
+		}
+		it := agentItem{Name: e.name, Kind: e.kind, Expires: e.expires}
+		if withSecret {
+			it.Secret = append([]byte(nil), e.secret.Bytes()...)
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// lock efface tous les secrets.
+func (a *agent) lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for len(a.entries) > 0 {
+		a.removeLocked(a.entries[0])
+	}
+}
+
+func (a *agent) removeLocked(e *agentEntry) {
+	for i, x := range a.entries {
+		if x == e {
+			a.entries = append(a.entries[:i], a.entries[i+1:]...)
+			e.timer.Stop()
+			e.secret.Destroy()
+			return
+		}
+	}
+}
+
+// --- Côté commandes -----------------------------------------------------
+
+// agentCall envoie une requête à l'agent. errAgentAbsent si aucun n'écoute.
+//
+// Avant d'envoyer quoi que ce soit, la socket doit être prouvée à nous : son
+// dossier, elle-même et, quand le système le dit, le processus qui l'écoute.
+// Sans cela, un autre utilisateur ayant pris /tmp/chiffremento-UID le premier
+// recevrait les mots de passe d'agent add et fournirait à enc le sien. Un
+// échec est un refus (agentTrustError), jamais un agent absent.
+func agentCall(req agentRequest) (agentResponse, error) {
+	var resp agentResponse
+	path, dir := agentSocketPath()
+	if dir != "" {
+		if _, err := os.Lstat(dir); errors.Is(err, fs.ErrNotExist) {
+			return resp, errAgentAbsent
+		}
+		if err := checkPrivateDir(dir); err != nil {
+			return resp, &agentTrustError{err}
+		}
+	}
+	if err := checkAgentSocket(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return resp, errAgentAbsent
+		}
+		return resp, &agentTrustError{err}
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return resp, errAgentAbsent
+	}
+	defer conn.Close()
+	if err := checkAgentPeer(conn); err != nil {
+		return resp, &agentTrustError{err}
+	}
+	conn.SetDeadline(time.Now().Add(agentTimeout))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, errorf("agent: %w", err)
+	}
+	if err := json.NewDecoder(io.LimitReader(conn, agentMaxMessage)).Decode(&resp); err != nil {
+		return resp, errorf("réponse de l'agent illisible: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, errorf("agent : %s", resp.Error)
+	}
+	return resp, nil
+}
+
+// agentTrustError signale une socket d'agent qui n'est pas prouvée à nous.
+// Les commandes s'arrêtent dessus au lieu de passer à la saisie : l'usurpation
+// doit se voir.
+type agentTrustError struct{ err error }
+
+func (e *agentTrustError) Error() string {
+	return fmt.Sprintf(tr("agent refusé, aucun secret échangé : %v"), e.err)
+}
+
+func (e *agentTrustError) Unwrap() error { return e.err }
+
+// agentSecrets rend les secrets de l'agent de la sorte demandée, du plus
+// récent au plus ancien. nil sans agent, ou quand l'appelant a déjà son
+// secret ; une erreur seulement si la socket est refusée. À effacer après
+// usage.
+func agentSecrets(kind string, opts pkg.Options) ([]agentItem, error) {
+	if !useAgent || opts.Key != nil {
+		return nil, nil
+	}
+	resp, err := agentCall(agentRequest{Op: "get", Kind: kind})
+	if err != nil {
+		var trust *agentTrustError
+		if errors.As(err, &trust) {
+			return nil, err
+		}
+		if !errors.Is(err, errAgentAbsent) {
+			fmt.Fprintln(diag, styleDim.Render(fmt.Sprintf(tr("note : %v"), err)))
+		}
+		return nil, nil
+	}
+	return resp.Items, nil
+}
+
+// encryptSecret rend le secret de chiffrement : celui de l'agent s'il n'en
+// détient qu'un, sinon la saisie habituelle. Entre plusieurs, choisir serait
+// deviner.
+func encryptSecret(opts pkg.Options, stdinTaken bool) (*pkg.SecureBuffer, error) {
+	items, err := agentSecrets(agentKindPassword, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 1 {
+		fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("mot de passe ")), fmt.Sprintf(tr("« %s », de l'agent"), items[0].Name))
+		return pkg.SecureCopy(items[0].Secret)
+	}
+	for _, it := range items {
+		zero(it.Secret)
+	}
+	return readSecret(opts, true, stdinTaken)
+}
+
+// unlock lance op avec les secrets de l'agent, puis avec le mot de passe
+// demandé si aucun ne convient. once limite à une seule tentative : sur un
+// flux, l'en-tête consommé ou le clair déjà émis interdisent de recommencer.
+func unlock(kind string, opts pkg.Options, stdinTaken, once bool, op func(password []byte, opts pkg.Options) error) error {
+	// Le code de secours se suffit : ni agent, ni saisie.
+	if opts.RecoveryCode != nil {
+		return op(nil, opts)
+	}
+	items, err := agentSecrets(kind, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, it := range items {
+			zero(it.Secret)
+		}
+	}()
+	for _, it := range items {
+		o, password := opts, it.Secret
+		if it.Kind == agentKindKey {
+			o.Key, password = it.Secret, nil
+		}
+		err := op(password, o)
+		if err == nil {
+			fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("secret       ")),
+				fmt.Sprintf(tr("%s « %s », de l'agent"), agentKindLabel(it.Kind), it.Name))
+			return nil
+		}
+		if once {
+			return err
+		}
+		fmt.Fprintln(diag, styleDim.Render(fmt.Sprintf(tr("agent : « %s » ne convient pas (%v)"), it.Name, err)))
+	}
+
+	// Sans clé, il reste les parts de Shamir, à coller au terminal.
+	if kind == agentKindKey && opts.Key == nil {
+		key, err := keyFromTerminal()
+		if errors.Is(err, errNoShareTerminal) {
+			if len(items) > 0 {
+				return errorf("aucune clé de l'agent n'ouvre ce fichier : il faut -key-file ou -share")
+			}
+			return errorf("ce fichier est chiffré par clé symétrique : il faut -key-file ou -share")
+		}
+		if err != nil {
+			return err
+		}
+		defer zero(key)
+		opts.Key = key
+		return op(nil, opts)
+	}
+	password, err := readSecret(opts, false, stdinTaken)
+	if err != nil {
+		return err
+	}
+	defer password.Destroy()
+	return op(password.Bytes(), opts)
+}
+
+// secretKind est la sorte de secret qu'attend un fichier. Sur un flux,
+// l'en-tête n'est pas relisible d'avance : c'est un mot de passe, sauf
+// -key-file.
+func secretKind(d *pkg.Details, opts pkg.Options) string {
+	if (d != nil && d.KeyBased) || opts.Key != nil {
+		return agentKindKey
+	}
+	return agentKindPassword
+}
+
+// doAgent exécute une action de la commande agent. Sans action, l'agent démarre.
+func doAgent(args []string, keyFile string, ttl time.Duration, ttlSet bool) error {
+	action := "start"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	if action != "add" && len(args) > 0 {
+		return errorf("argument en trop pour agent %s : %q", action, args[0])
+	}
+	if keyFile != "" && action != "add" {
+		fmt.Fprintln(os.Stderr, styleDim.Render(tr("note : -key-file n'a d'effet qu'avec agent add, il est ignoré")))
+	}
+
+	switch action {
+	case "start":
+		return runAgent(ttl)
+	case "add":
+		if len(args) > 1 {
+			return errorf("argument en trop pour agent add : %q", args[1])
+		}
+		req := agentRequest{Op: "add", Kind: agentKindPassword}
+		if len(args) == 1 {
+			req.Name = args[0]
+		}
+		if ttlSet {
+			req.TTL = ttl
+		}
+		// Sonder avant de saisir : taper un mot de passe pour un agent absent
+		// serait pénible.
+		if _, err := agentCall(agentRequest{Op: "list"}); err != nil {
+			return err
+		}
+		if keyFile != "" {
+			key, err := loadKeyFile(keyFile)
+			if err != nil {
+				return err
+			}
+			req.Kind, req.Secret = agentKindKey, key
+			if req.Name == "" {
+				req.Name = filepath.Base(keyFile)
+			}
+		} else {
+			password, err := readPassword(true, false)
+			if err != nil {
+				return err
+			}
+			// Détruit après le zero ci-dessous : les defer passent en ordre
+			// inverse.
+			defer password.Destroy()
+			req.Secret = password.Bytes()
+		}
+		defer zero(req.Secret)
+		if _, err := agentCall(req); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s %s\n", styleAccent.Render("✓"), styleText.Render(fmt.Sprintf(tr("%s confié à l'agent"), agentKindLabel(req.Kind))))
+		return nil
+	case "list":
+		resp, err := agentCall(agentRequest{Op: "list"})
+		if err != nil {
+			return err
+		}
+		if len(resp.Items) == 0 {
+			fmt.Fprintln(os.Stderr, styleDim.Render(tr("l'agent ne détient aucun secret")))
+			return nil
+		}
+		for _, it := range resp.Items {
+			fmt.Printf("%-24s %-13s %s\n", it.Name, agentKindLabel(it.Kind),
+				styleDim.Render(fmt.Sprintf(tr("oublié dans %s"), time.Until(it.Expires).Round(time.Second))))
+		}
+		return nil
+	case "lock", "stop":
+		if _, err := agentCall(agentRequest{Op: action}); err != nil {
+			return err
+		}
+		msg := tr("l'agent a oublié tous ses secrets")
+		if action == "stop" {
+			msg = tr("agent arrêté, secrets oubliés")
+		}
+		fmt.Fprintf(os.Stderr, "%s %s\n", styleAccent.Render("✓"), styleText.Render(msg))
+		return nil
+	default:
+		return errorf("action d'agent inconnue %q (attendu start, add, list, lock ou stop)", action)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build darwin || freebsd
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// peerUID lit l'uid du processus à l'autre bout de la socket (LOCAL_PEERCRED,
+// ce qu'utilise getpeereid).
+func peerUID(fd int) (uid int, known bool, err error) {
+	cred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return 0, false, err
+	}
+	return int(cred.Uid), true, nil
+}
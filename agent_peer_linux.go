@@ -0,0 +1,14 @@
+//go:build linux
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// peerUID lit l'uid du processus à l'autre bout de la socket (SO_PEERCRED).
+func peerUID(fd int) (uid int, known bool, err error) {
+	cred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return 0, false, err
+	}
+	return int(cred.Uid), true, nil
+}
@@ -0,0 +1,9 @@
+//go:build !windows && !linux && !darwin && !freebsd
+
+package main
+
+// peerUID : ce système ne dit pas qui écoute ; restent les vérifications du
+// dossier et de la socket.
+func peerUID(int) (uid int, known bool, err error) {
+	return 0, false, nil
+}
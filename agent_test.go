@@ -0,0 +1,291 @@
+//go:build !windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"chiffremento-cli/pkg"
+)
+
+// avecAgent démarre un agent sur une socket de test et active sa
+// consultation le temps du test.
+func avecAgent(t *testing.T, ttl time.Duration) *agent {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	t.Setenv(agentSockEnv, sock)
+	l, err := listenAgent(sock, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newAgent(ttl)
+	fini := make(chan error, 1)
+	go func() { fini <- a.serve(l) }()
+
+	precedent := useAgent
+	useAgent = true
+	t.Cleanup(func() {
+		useAgent = precedent
+		l.Close()
+		<-fini
+	})
+	return a
+}
+
+func confier(t *testing.T, name, kind string, secret []byte) {
+	t.Helper()
+	if _, err := agentCall(agentRequest{Op: "add", Name: name, Kind: kind, Secret: secret}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentProtocole(t *testing.T) {
+	a := avecAgent(t, time.Hour)
+
+	if info, err := os.Stat(os.Getenv(agentSockEnv)); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket : %v, %v", info.Mode(), err)
+	}
+	if _, err := listenAgent(os.Getenv(agentSockEnv), ""); err == nil {
+		t.Error("un second agent a pris la socket du premier")
+	}
+
+	confier(t, "ancien", agentKindPassword, []byte("un"))
+	confier(t, "", agentKindPassword, []byte("deux"))
+	key, _ := pkg.GenerateKey()
+	confier(t, "service", agentKindKey, key)
+	if _, err := agentCall(agentRequest{Op: "add", Kind: agentKindKey, Secret: []byte("court")}); err == nil {
+		t.Error("clé de mauvaise taille acceptée")
+	}
+
+	// Du plus récent au plus ancien, et seulement la sorte demandée.
+	items, _ := agentSecrets(agentKindPassword, pkg.Options{})
+	if len(items) != 2 || string(items[0].Secret) != "deux" || items[1].Name != "ancien" {
+		t.Fatalf("get : %+v", items)
+	}
+	if items[0].Name != "mot de passe 1" {
+		t.Errorf("nom par défaut %q", items[0].Name)
+	}
+	if items, _ := agentSecrets(agentKindPassword, pkg.Options{Key: key}); items != nil {
+		t.Error("l'agent consulté alors que la clé est déjà donnée")
+	}
+
+	// list ne transporte aucun secret.
+	resp, err := agentCall(agentRequest{Op: "list"})
+	if err != nil || len(resp.Items) != 3 {
+		t.Fatalf("list : %+v, %v", resp, err)
+	}
+	for _, it := range resp.Items {
+		if it.Secret != nil {
+			t.Errorf("list a rendu le secret de %q", it.Name)
+		}
+	}
+
+	// Un même nom remplace, lock efface et met les tampons à zéro.
+	confier(t, "ancien", agentKindPassword, []byte("trois"))
+	a.mu.Lock()
+	gardes := append([]*agentEntry(nil), a.entries...)
+	a.mu.Unlock()
+	if len(gardes) != 3 {
+		t.Fatalf("%d secrets après remplacement", len(gardes))
+	}
+	if _, err := agentCall(agentRequest{Op: "lock"}); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := agentSecrets(agentKindPassword, pkg.Options{}); len(items) != 0 {
+		t.Errorf("secrets après lock : %+v", items)
+	}
+	for _, e := range gardes {
+		if e.secret.Bytes() != nil {
+			t.Errorf("%q non effacé", e.name)
+		}
+	}
+}
+
+func TestAgentExpiration(t *testing.T) {
+	avecAgent(t, 50*time.Millisecond)
+	confier(t, "bref", agentKindPassword, []byte("secret"))
+	if _, err := agentCall(agentRequest{Op: "add", Name: "long", Kind: agentKindPassword, Secret: []byte("x"), TTL: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	items, _ := agentSecrets(agentKindPassword, pkg.Options{})
+	if len(items) != 1 || items[0].Name != "long" {
+		t.Errorf("après expiration : %+v", items)
+	}
+}
+
+func TestAgentAbsent(t *testing.T) {
+	t.Setenv(agentSockEnv, filepath.Join(t.TempDir(), "personne.sock"))
+	if _, err := agentCall(agentRequest{Op: "list"}); err != errAgentAbsent {
+		t.Errorf("sans agent : %v", err)
+	}
+	if err := doAgent([]string{"list"}, "", time.Minute, false); err != errAgentAbsent {
+		t.Errorf("agent list sans agent : %v", err)
+	}
+	if err := doAgent([]string{"purge"}, "", time.Minute, false); err == nil {
+		t.Error("action inconnue acceptée")
+	}
+}
+
+func TestAgentDechiffrementSansSaisie(t *testing.T) {
+	dir := t.TempDir()
+	contenu := []byte("quarante fichiers, une seule saisie\n")
+	in := ecrire(t, filepath.Join(dir, "doc.txt"), contenu)
+	rapide := pkg.Options{Argon: &pkg.ArgonParams{Time: 1, MemoryKiB: 1024, Threads: 1}}
+
+	avecAgent(t, time.Hour)
+	confier(t, "le bon", agentKindPassword, []byte(motDePasseTest))
+
+	// Un seul mot de passe dans l'agent : enc le prend sans rien demander.
+	avecEntree(t, os.DevNull)
+	if err := doEncrypt(in, "", rapide); err != nil {
+		t.Fatalf("chiffrement : %v", err)
+	}
+
+	// Le plus récent est faux : il est écarté, le suivant ouvre le fichier.
+	confier(t, "le mauvais", agentKindPassword, []byte("pas celui-là"))
+	out := filepath.Join(dir, "relu.txt")
+	if err := doDecrypt(in+extension, out, pkg.Options{}); err != nil {
+		t.Fatalf("déchiffrement : %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, contenu) {
+		t.Errorf("contenu relu %q", got)
+	}
+	if err := doVerify(in+extension, pkg.Options{}); err != nil {
+		t.Errorf("vérification : %v", err)
+	}
+
+	// Sans agent consulté, la saisie reprend ses droits.
+	useAgent = false
+	avecMotDePasse(t, "faux")
+	if err := doVerify(in+extension, pkg.Options{}); err == nil {
+		t.Error("vérification réussie avec un mauvais mot de passe saisi")
+	}
+}
+
+func TestAgentCleSymetrique(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := pkg.GenerateKey()
+	in := ecrire(t, filepath.Join(dir, "flux.bin"), []byte("entre services"))
+	if err := doEncrypt(in, "", pkg.Options{Key: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	avecAgent(t, time.Hour)
+	sansTerminal(t)
+	if err := doVerify(in+extension, pkg.Options{}); err == nil || !strings.Contains(err.Error(), "-key-file") {
+		t.Errorf("agent vide : %v", err)
+	}
+	autre, _ := pkg.GenerateKey()
+	confier(t, "autre", agentKindKey, autre)
+	if err := doVerify(in+extension, pkg.Options{}); err == nil || !strings.Contains(err.Error(), "aucune clé") {
+		t.Errorf("mauvaise clé : %v", err)
+	}
+	confier(t, "service", agentKindKey, key)
+	if err := doVerify(in+extension, pkg.Options{}); err != nil {
+		t.Errorf("clé de l'agent : %v", err)
+	}
+}
+
+func TestAgentDossierPrive(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "agent")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenAgent(filepath.Join(dir, "agent.sock"), dir); err == nil {
+		t.Error("dossier ouvert à tous accepté")
+	}
+	os.Chmod(dir, 0o700)
+	l, err := listenAgent(filepath.Join(dir, "agent.sock"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	// La socket d'un agent mort est remplacée.
+	mort, err := net.Listen("unix", filepath.Join(dir, "agent.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mort.(*net.UnixListener).SetUnlinkOnClose(false)
+	mort.Close()
+	l, err = listenAgent(filepath.Join(dir, "agent.sock"), dir)
+	if err != nil {
+		t.Fatalf("socket orpheline : %v", err)
+	}
+	l.Close()
+}
+
+func TestAgentSocketUsurpee(t *testing.T) {
+	// Une fausse socket qui note tout ce qu'on lui envoie.
+	espion := func(t *testing.T, path string) <-chan []byte {
+		t.Helper()
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recu := make(chan []byte, 8)
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				buf := make([]byte, 4096)
+				n, _ := conn.Read(buf)
+				recu <- buf[:n]
+				conn.Close()
+			}
+		}()
+		t.Cleanup(func() { l.Close() })
+		return recu
+	}
+	refuse := func(t *testing.T, quoi string, recu <-chan []byte) {
+		t.Helper()
+		var trust *agentTrustError
+		if _, err := agentCall(agentRequest{Op: "add", Kind: agentKindPassword, Secret: []byte("secret")}); !errors.As(err, &trust) {
+			t.Errorf("%s : %v", quoi, err)
+		}
+		precedent := useAgent
+		useAgent = true
+		defer func() { useAgent = precedent }()
+		if _, err := encryptSecret(pkg.Options{}, false); !errors.As(err, &trust) {
+			t.Errorf("%s : enc n'a pas refusé (%v)", quoi, err)
+		}
+		select {
+		case b := <-recu:
+			t.Errorf("%s : %q envoyé à la fausse socket", quoi, b)
+		default:
+		}
+	}
+
+	// Le dossier par défaut, créé ouvert par quelqu'un d'autre.
+	run := t.TempDir()
+	t.Setenv(agentSockEnv, "")
+	t.Setenv("XDG_RUNTIME_DIR", run)
+	dir := filepath.Join(run, "chiffremento")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	refuse(t, "dossier ouvert", espion(t, filepath.Join(dir, "agent.sock")))
+
+	// Une socket ouverte à tous, même dans un dossier sûr.
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	t.Setenv(agentSockEnv, sock)
+	recu := espion(t, sock)
+	if err := os.Chmod(sock, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	refuse(t, "socket ouverte", recu)
+
+	// Autre chose qu'une socket.
+	t.Setenv(agentSockEnv, ecrire(t, filepath.Join(t.TempDir(), "agent.sock"), nil))
+	refuse(t, "fichier ordinaire", nil)
+}
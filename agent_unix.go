@@ -0,0 +1,92 @@
+//go:build !windows
+
+package main
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// disableCoreDumps empêche qu'un plantage de l'agent écrive ses secrets sur
+// le disque.
+func disableCoreDumps() {
+	unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{})
+}
+
+// listenPrivate crée la socket directement en 0600 : un chmod après coup
+// laisserait une fenêtre où d'autres pourraient s'y connecter.
+func listenPrivate(path string) (net.Listener, error) {
+	old := unix.Umask(0o177)
+	defer unix.Umask(old)
+	return net.Listen("unix", path)
+}
+
+// checkPrivateDir refuse un dossier de socket qui ne serait pas à nous seuls :
+// un autre utilisateur aurait pu y placer sa propre socket.
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return errorf("dossier de l'agent: %w", err)
+	}
+	if !info.IsDir() {
+		return errorf("%s n'est pas un dossier", dir)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return errorf("%s appartient à un autre utilisateur", dir)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return errorf("%s est accessible à d'autres utilisateurs (%04o) ; chmod 700 %s", dir, info.Mode().Perm(), dir)
+	}
+	return nil
+}
+
+// checkAgentSocket refuse une socket qui ne serait pas à nous seuls. Lstat :
+// un lien symbolique posé par un autre utilisateur ne doit pas être suivi.
+func checkAgentSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return errorf("%s n'est pas une socket", path)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return errorf("%s appartient à un autre utilisateur", path)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return errorf("%s est accessible à d'autres utilisateurs (%04o)", path, info.Mode().Perm())
+	}
+	return nil
+}
+
+// checkAgentPeer vérifie que le processus à l'autre bout tourne sous notre
+// uid, là où le noyau sait le dire (peerUID). Les vérifications de fichier
+// ne suffisent pas seules : la socket peut changer entre Lstat et connect.
+func checkAgentPeer(conn net.Conn) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return errorf("socket de l'agent: %w", err)
+	}
+	var (
+		uid   int
+		known bool
+		perr  error
+	)
+	if err := raw.Control(func(fd uintptr) { uid, known, perr = peerUID(int(fd)) }); err != nil {
+		return errorf("socket de l'agent: %w", err)
+	}
+	if perr != nil {
+		return errorf("socket de l'agent: %w", perr)
+	}
+	if known && uid != os.Getuid() {
+		return errorf("l'agent tourne sous un autre utilisateur (uid %d)", uid)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package main
+
+import (
+	"net"
+	"os"
+)
+
+// disableCoreDumps : Windows n'écrit pas de vidage mémoire sans qu'on l'ait
+// configuré.
+func disableCoreDumps() {}
+
+// listenPrivate : le dossier temporaire de l'utilisateur, où vit la socket,
+// n'est déjà lisible que par lui.
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
+
+func checkPrivateDir(string) error { return nil }
+
+func checkAgentSocket(path string) error {
+	_, err := os.Lstat(path)
+	return err
+}
+
+func checkAgentPeer(net.Conn) error { return nil }
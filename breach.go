@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"chiffremento-cli/pkg"
+)
+
+// Liste de fuites.
+//
+// -mode breachdb-build condense une liste Have I Been Pwned téléchargée (les
+// empreintes SHA-1, une par ligne) en un filtre compact ; -breach-db le
+// désigne ensuite au choix d'un mot de passe, en enc et en agent add. La
+// recherche se fait sur le disque, sans réseau, et seul le condensé du mot
+// de passe est calculé — rien n'est affiché ni conservé.
+//
+// Un mot de passe tapé au clavier qui figure dans la liste est refusé : il
+// suffit d'en taper un autre. Celui d'une source (-passfile, -passenv,
+// -passfd, -passcmd, entrée standard) est seulement signalé : le refuser
+// casserait une sauvegarde planifiée le jour où la liste est mise à jour.
+
+// breachDBEnv désigne la liste par défaut, pour la TUI qui ne prend pas
+// d'option.
+const breachDBEnv = "CHTO_BREACH_DB"
+
+// breachDB est le chemin de la liste choisie, vide sans liste.
+var breachDB string
+
+var errBreached error = sentinel("ce mot de passe figure dans la liste de fuites : il faut en choisir un autre")
+
+// breached indique si le mot de passe figure dans la liste, sans liste : non.
+func breached(password []byte) (bool, error) {
+	if breachDB == "" {
+		return false, nil
+	}
+	db, err := pkg.OpenBreachDB(breachDB)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	return db.Contains(password)
+}
+
+// warnIfBreached signale un mot de passe de la liste sans le refuser.
+func warnIfBreached(password []byte) error {
+	found, err := breached(password)
+	if err != nil {
+		return err
+	}
+	if found {
+		fmt.Fprintln(os.Stderr, styleDim.Render(tr("attention : ce mot de passe figure dans la liste de fuites ; il est utilisé quand même")))
+	}
+	return nil
+}
+
+// checkBreachDB ouvre la liste une première fois, pour qu'un chemin erroné
+// soit signalé avant la saisie et non après.
+func checkBreachDB() error {
+	if breachDB == "" {
+		return nil
+	}
+	db, err := pkg.OpenBreachDB(breachDB)
+	if err != nil {
+		return errorf("liste de fuites : %w", err)
+	}
+	return db.Close()
+}
+
+func doBreachBuild(in, out string) error {
+	if in == "" || out == "" || isStream(in) || isStream(out) {
+		return errorf("breachdb-build lit -in (la liste téléchargée) et écrit -out, deux fichiers")
+	}
+	fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render(tr("lecture      ")), in)
+	st, err := pkg.BuildBreachDB(in, out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, tr("%s %d empreintes, %s\n"), styleDim.Render(tr("liste        ")), st.Hashes, humanSize(st.Bytes))
+	fmt.Fprintf(os.Stderr, "%s %s\n", styleAccent.Render("✓"), out)
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+func TestListeDeFuitesCLI(t *testing.T) {
+	dir := t.TempDir()
+	src := ecrire(t, filepath.Join(dir, "pwned.txt"),
+		fmt.Appendf(nil, "%X:9545824\r\n%X:1\r\n", sha1.Sum([]byte("azerty123")), sha1.Sum([]byte("soleil"))))
+	db := filepath.Join(dir, "fuites.db")
+	if err := doBreachBuild(src, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := doBreachBuild(src, "-"); err == nil {
+		t.Error("filtre écrit sur la sortie standard")
+	}
+
+	precedent := breachDB
+	breachDB = db
+	t.Cleanup(func() { breachDB = precedent })
+	if err := checkBreachDB(); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateNewPassword("azerty123"); err != errBreached {
+		t.Errorf("mot de passe de la liste : %v", err)
+	}
+	if err := validateNewPassword(motDePasseTest); err != nil {
+		t.Errorf("mot de passe absent de la liste : %v", err)
+	}
+	// Au déchiffrement, la liste ne joue pas : le mot de passe est déjà choisi.
+	if err := validatePassword("azerty123"); err != nil {
+		t.Error(err)
+	}
+
+	// Lu sur l'entrée standard, il est signalé mais utilisé.
+	in := ecrire(t, filepath.Join(dir, "clair.txt"), []byte("x"))
+	avecMotDePasse(t, "azerty123")
+	if err := doEncrypt(in, "", pkg.Options{Argon: &pkg.ArgonParams{Time: 1, MemoryKiB: 1024, Threads: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(in + extension); err != nil {
+		t.Error(err)
+	}
+
+	breachDB = src
+	if err := checkBreachDB(); err == nil {
+		t.Error("liste brute acceptée comme filtre")
+	}
+}
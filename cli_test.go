@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"chiffremento-cli/pkg"
 )
@@ -84,6 +85,15 @@ func captureSortie(t *testing.T) string {
 	return chemin
 }
 
+// sansTerminal fait échouer toute saisie au terminal : un test qui en
+// demanderait une échoue au lieu d'attendre.
+func sansTerminal(t *testing.T) {
+	t.Helper()
+	precedent := ttyDevice
+	ttyDevice = filepath.Join(t.TempDir(), "pas-un-terminal")
+	t.Cleanup(func() { ttyDevice = precedent })
+}
+
 func ecrire(t *testing.T, chemin string, contenu []byte) string {
 	t.Helper()
 	if err := os.WriteFile(chemin, contenu, 0644); err != nil {
@@ -123,7 +133,7 @@ func TestDoEncryptDecryptFichier(t *testing.T) {
 	// Destination choisie au déchiffrement.
 	out := filepath.Join(dir, "relu.txt")
 	avecMotDePasse(t, motDePasseTest)
-	if err := doDecrypt(in+extension, out); err != nil {
+	if err := doDecrypt(in+extension, out, pkg.Options{}); err != nil {
 		t.Fatalf("déchiffrement: %v", err)
 	}
 	got, err := os.ReadFile(out)
@@ -171,7 +181,7 @@ func TestDoEncryptDecryptDossier(t *testing.T) {
 
 	dst := filepath.Join(t.TempDir(), "restaure")
 	avecMotDePasse(t, motDePasseTest)
-	if err := doDecrypt(chto, dst); err != nil {
+	if err := doDecrypt(chto, dst, pkg.Options{}); err != nil {
 		t.Fatalf("déchiffrement du dossier: %v", err)
 	}
 	for rel, attendu := range map[string]string{
@@ -235,7 +245,7 @@ func TestDoEncryptRemplissage(t *testing.T) {
 
 	avecMotDePasse(t, motDePasseTest)
 	out := filepath.Join(dir, "relu.bin")
-	if err := doDecrypt(chto, out); err != nil {
+	if err := doDecrypt(chto, out, pkg.Options{}); err != nil {
 		t.Fatalf("déchiffrement d'un fichier rempli: %v", err)
 	}
 	got, err := os.ReadFile(out)
@@ -273,7 +283,7 @@ func TestDoEncryptVersSortieStandard(t *testing.T) {
 
 	out := filepath.Join(dir, "relu.txt")
 	avecMotDePasse(t, motDePasseTest)
-	if err := doDecrypt(chto, out); err != nil {
+	if err := doDecrypt(chto, out, pkg.Options{}); err != nil {
 		t.Fatalf("relecture de ce qui est sorti du tube: %v", err)
 	}
 	got, err := os.ReadFile(out)
@@ -298,7 +308,7 @@ func TestDoDecryptDossierVersSortieStandard(t *testing.T) {
 
 	sortie := captureSortie(t)
 	avecMotDePasse(t, motDePasseTest)
-	if err := doDecrypt(chto, "-"); err != nil {
+	if err := doDecrypt(chto, "-", pkg.Options{}); err != nil {
 		t.Fatalf("déchiffrement vers la sortie standard: %v", err)
 	}
 
@@ -345,7 +355,7 @@ func TestDoVerifyDepuisFlux(t *testing.T) {
 	defer func() { ttyDevice = precedent }()
 
 	avecEntree(t, chto)
-	err := doVerify("-")
+	err := doVerify("-", pkg.Options{})
 	if err == nil {
 		t.Fatal("la vérification en flux a réussi sans mot de passe disponible")
 	}
@@ -393,19 +403,19 @@ func TestDoDecryptEtVerifyRefus(t *testing.T) {
 	dir := t.TempDir()
 	sansExtension := ecrire(t, filepath.Join(dir, "doc.txt"), []byte("x"))
 
-	if err := doDecrypt(sansExtension, ""); err == nil {
+	if err := doDecrypt(sansExtension, "", pkg.Options{}); err == nil {
 		t.Error("un fichier sans extension .chto a été accepté au déchiffrement")
 	}
-	if err := doVerify(sansExtension); err == nil {
+	if err := doVerify(sansExtension, pkg.Options{}); err == nil {
 		t.Error("un fichier sans extension .chto a été accepté à la vérification")
 	}
-	if err := doDecrypt("-", ""); err == nil {
+	if err := doDecrypt("-", "", pkg.Options{}); err == nil {
 		t.Error("un flux sans -out a été accepté")
 	}
 	// Un .chto qui n'en est pas un : l'en-tête doit être refusé avant toute
 	// demande de mot de passe, donc sans toucher à l'entrée standard.
 	bidon := ecrire(t, filepath.Join(dir, "bidon.chto"), bytes.Repeat([]byte("X"), 64))
-	if err := doDecrypt(bidon, filepath.Join(dir, "out")); err == nil {
+	if err := doDecrypt(bidon, filepath.Join(dir, "out"), pkg.Options{}); err == nil {
 		t.Error("un fichier au format inconnu a été accepté")
 	}
 	if err := doInfo("-"); err == nil {
@@ -425,7 +435,7 @@ func TestDoDecryptMauvaisMotDePasse(t *testing.T) {
 
 	out := filepath.Join(dir, "relu.txt")
 	avecMotDePasse(t, "mauvais mot de passe")
-	if err := doDecrypt(chto, out); err == nil {
+	if err := doDecrypt(chto, out, pkg.Options{}); err == nil {
 		t.Fatal("un mauvais mot de passe a été accepté")
 	}
 	if _, err := os.Stat(out); err == nil {
@@ -446,12 +456,12 @@ func TestDoVerifyEtDoInfo(t *testing.T) {
 	}
 
 	avecMotDePasse(t, motDePasseTest)
-	if err := doVerify(chto); err != nil {
+	if err := doVerify(chto, pkg.Options{}); err != nil {
 		t.Errorf("vérification d'une archive saine: %v", err)
 	}
 
 	avecMotDePasse(t, "mauvais")
-	if err := doVerify(chto); err == nil {
+	if err := doVerify(chto, pkg.Options{}); err == nil {
 		t.Error("la vérification a réussi avec un mauvais mot de passe")
 	}
 
@@ -464,7 +474,7 @@ func TestDoVerifyEtDoInfo(t *testing.T) {
 		t.Fatal(err)
 	}
 	affiche := string(brut)
-	for _, attendu := range []string{"format", "v3", "cascade", "zstd", "dossier", "remplissage", "argon2id"} {
+	for _, attendu := range []string{"format", "v4", "cascade", "zstd", "dossier", "remplissage", "argon2id", "Mio pour la dérivation"} {
 		if !strings.Contains(affiche, attendu) {
 			t.Errorf("la sortie de info ne mentionne pas %q :\n%s", attendu, affiche)
 		}
@@ -501,6 +511,56 @@ func TestDoInfoAncienFormat(t *testing.T) {
 	}
 }
 
+// TestDoUpgradeRecursif : un dossier mêlant anciens et nouveaux fichiers est
+// mis à niveau en un seul passage, et le bilan nomme chaque fichier.
+func TestDoUpgradeRecursif(t *testing.T) {
+	racine := t.TempDir()
+	sous := filepath.Join(racine, "sous")
+	if err := os.MkdirAll(sous, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, nom := range []string{"v1_aes.chto", "v1_aes_gzip.chto"} {
+		brut, err := os.ReadFile(filepath.Join("pkg", "testdata", nom))
+		if err != nil {
+			t.Skipf("fichier de référence absent: %v", err)
+		}
+		ecrire(t, filepath.Join(sous, nom), brut)
+	}
+	// Sans -r, un dossier est refusé.
+	if err := doUpgrade(racine, false, pkg.Options{}); err == nil {
+		t.Fatal("un dossier a été accepté sans -r")
+	}
+
+	sortie := captureSortie(t)
+	avecMotDePasse(t, "reference-v1-password")
+	if err := doUpgrade(racine+string(os.PathSeparator), true, pkg.Options{Comp: pkg.CompZstd}); err != nil {
+		t.Fatalf("mise à niveau: %v", err)
+	}
+	for _, nom := range []string{"v1_aes.chto", "v1_aes_gzip.chto"} {
+		d, err := pkg.Inspect(filepath.Join(sous, nom))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Version != 4 {
+			t.Errorf("%s encore en v%d", nom, d.Version)
+		}
+	}
+	brut, err := os.ReadFile(sortie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, attendu := range []string{"v1_aes.chto", "v1_aes_gzip.chto", "v1 → v4", "zstd"} {
+		if !strings.Contains(string(brut), attendu) {
+			t.Errorf("le bilan ne mentionne pas %q :\n%s", attendu, brut)
+		}
+	}
+
+	// Un second passage n'a plus rien à faire et ne demande pas de mot de passe.
+	if err := doUpgrade(racine, true, pkg.Options{}); err != nil {
+		t.Errorf("second passage: %v", err)
+	}
+}
+
 // --- Fonctions de décision ----------------------------------------------
 
 // TestChooseComp : -comp signifie zstd, et il n'y a plus d'autre choix. gzip
@@ -728,3 +788,183 @@ func TestZero(t *testing.T) {
 		}
 	}
 }
+
+// --- Réglages de la dérivation ------------------------------------------
+
+func TestParseMemSize(t *testing.T) {
+	for s, attendu := range map[string]uint32{
+		"256MiB": 256 << 10, "256": 256 << 10, "512Mio": 512 << 10,
+		"1GiB": 1 << 20, "2g": 2 << 20, "19456KiB": 19456, " 64 M ": 64 << 10,
+	} {
+		got, err := parseMemSize(s)
+		if err != nil || got != attendu {
+			t.Errorf("parseMemSize(%q) = %d, %v ; attendu %d", s, got, err, attendu)
+		}
+	}
+	for _, s := range []string{"", "MiB", "-1MiB", "1.5GiB", "256MB", "4096GiB"} {
+		if _, err := parseMemSize(s); err == nil {
+			t.Errorf("parseMemSize(%q) accepté à tort", s)
+		}
+	}
+}
+
+func TestKDFFlags(t *testing.T) {
+	drapeaux := func(f kdfFlags, noms ...string) *kdfFlags {
+		f.set = map[string]bool{}
+		for _, n := range noms {
+			f.set[n] = true
+		}
+		return &f
+	}
+
+	// Sans réglage brut, le profil passe tel quel.
+	var opts pkg.Options
+	if err := drapeaux(kdfFlags{profile: "fort"}, "kdf").apply(&opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.KDF != pkg.KDFFort || opts.Argon != nil {
+		t.Errorf("profil seul : %+v", opts)
+	}
+
+	// Un réglage brut part du profil et ne remplace que ce qui est donné.
+	opts = pkg.Options{}
+	f := drapeaux(kdfFlags{profile: "fort", mem: "64MiB", threads: 2}, "kdf", "kdf-mem", "kdf-threads")
+	if err := f.apply(&opts); err != nil {
+		t.Fatal(err)
+	}
+	base := pkg.KDFFort.ArgonParams()
+	if opts.Argon == nil || *opts.Argon != (pkg.ArgonParams{Time: base.Time, MemoryKiB: 64 << 10, Threads: 2}) {
+		t.Errorf("réglage brut : %+v", opts.Argon)
+	}
+	if !strings.Contains(kdfDescription(opts), "m=64MiB") {
+		t.Errorf("description : %q", kdfDescription(opts))
+	}
+
+	// Mêmes bornes qu'à la lecture, et un 0 explicite n'est pas une absence.
+	for nom, f := range map[string]*kdfFlags{
+		"passes nulles":   drapeaux(kdfFlags{time: 0}, "kdf-time"),
+		"trop de passes":  drapeaux(kdfFlags{time: 17}, "kdf-time"),
+		"trop de cœurs":   drapeaux(kdfFlags{threads: 300}, "kdf-threads"),
+		"trop de mémoire": drapeaux(kdfFlags{mem: "3GiB"}, "kdf-mem"),
+		"scrypt brut":     drapeaux(kdfFlags{algo: "scrypt", time: 2}, "kdf-algo", "kdf-time"),
+		"scrypt auto":     drapeaux(kdfFlags{profile: kdfAuto, algo: "scrypt"}, "kdf", "kdf-algo"),
+		"plafond absurde": drapeaux(kdfFlags{profile: kdfAuto, target: time.Second, maxMem: "8MiB"}, "kdf", "kdf-max-mem"),
+	} {
+		if err := f.apply(&pkg.Options{}); err == nil {
+			t.Errorf("%s : accepté", nom)
+		}
+	}
+
+	// -kdf auto calibre et inscrit le résultat. Le résumé passe par diag, que
+	// -json fait taire.
+	var notes strings.Builder
+	diag = &notes
+	t.Cleanup(func() { diag = os.Stderr })
+	opts = pkg.Options{}
+	f = drapeaux(kdfFlags{profile: kdfAuto, target: 50 * time.Millisecond, maxMem: "32MiB"}, "kdf")
+	if err := f.apply(&opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Argon == nil || opts.Argon.MemoryKiB > 32<<10 {
+		t.Errorf("calibration : %+v", opts.Argon)
+	}
+	if !strings.Contains(notes.String(), "calibration") {
+		t.Errorf("résumé de calibration hors de diag : %q", notes.String())
+	}
+}
+
+// TestMaxKDFMem : un fichier trop lourd à dériver est refusé avant même que le
+// mot de passe soit demandé — ici, aucun n'est fourni.
+func TestMaxKDFMem(t *testing.T) {
+	dir := t.TempDir()
+	in := ecrire(t, filepath.Join(dir, "doc.txt"), []byte("x"))
+	avecMotDePasse(t, motDePasseTest)
+	if err := doEncrypt(in, "", pkg.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	plafond := pkg.Options{MaxKDFMemory: 128 << 10}
+	var me *pkg.KDFMemoryError
+	if err := doDecrypt(in+extension, filepath.Join(dir, "relu.txt"), plafond); !errors.As(err, &me) {
+		t.Errorf("déchiffrement : %v", err)
+	}
+	if err := doVerify(in+extension, plafond); !errors.As(err, &me) {
+		t.Errorf("vérification : %v", err)
+	}
+}
+
+// --- Clé symétrique -----------------------------------------------------
+
+func TestKeygenEtKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "service.key")
+	if err := doKeygen(keyPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := doKeygen(keyPath); err == nil {
+		t.Error("keygen a écrasé une clé existante")
+	}
+	if err := doKeygen(""); err == nil {
+		t.Error("keygen sans -out accepté")
+	}
+	key, err := loadKeyFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Aucun mot de passe n'est fourni : s'il en demandait un, le test échouerait.
+	contenu := []byte("flux entre services\n")
+	in := ecrire(t, filepath.Join(dir, "doc.txt"), contenu)
+	if err := doEncrypt(in, "", pkg.Options{Key: key}); err != nil {
+		t.Fatalf("chiffrement par clé : %v", err)
+	}
+	out := filepath.Join(dir, "relu.txt")
+	if err := doDecrypt(in+extension, out, pkg.Options{Key: key}); err != nil {
+		t.Fatalf("déchiffrement par clé : %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, contenu) {
+		t.Errorf("contenu relu %q", got)
+	}
+
+	// Sans la clé, refus net.
+	sansTerminal(t)
+	if err := doVerify(in+extension, pkg.Options{}); err == nil || !strings.Contains(err.Error(), "-key-file") {
+		t.Errorf("vérification sans clé : %v", err)
+	}
+
+	sortie := captureSortie(t)
+	if err := doInfo(in + extension); err != nil {
+		t.Fatal(err)
+	}
+	if brut, _ := os.ReadFile(sortie); !strings.Contains(string(brut), "clé symétrique") {
+		t.Errorf("info ne dit pas que le fichier est chiffré par clé :\n%s", brut)
+	}
+}
+
+func TestGenpass(t *testing.T) {
+	sortie := captureSortie(t)
+	if err := doGenpass(7, "en"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(sortie)
+	// La phrase, seule sur sa ligne : elle doit pouvoir passer dans un tube.
+	phrase := strings.TrimSuffix(string(data), "\n")
+	if strings.Count(phrase, "-") != 6 || strings.ContainsAny(phrase, " \n") {
+		t.Errorf("sortie %q", data)
+	}
+	if err := doGenpass(2, "fr"); err == nil {
+		t.Error("phrase de deux mots acceptée")
+	}
+	// Dans l'interface guidée, la phrase s'affiche jusqu'à Entrée, puis
+	// l'écran est effacé.
+	var ecran bytes.Buffer
+	if err := showPassphrase(strings.NewReader("\n"), &ecran, []byte(phrase)); err != nil {
+		t.Fatal(err)
+	}
+	if got := ecran.String(); !strings.Contains(got, phrase) || !strings.Contains(got, "66 bits") || !strings.HasSuffix(got, clearScreen) {
+		t.Errorf("écran %q", got)
+	}
+	if err := showPassphrase(strings.NewReader(""), io.Discard, []byte(phrase)); err == nil {
+		t.Error("fin de l'entrée prise pour Entrée")
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Sous-commandes.
+//
+// `chiffremento enc -in …` est la forme documentée ; `chiffremento -mode enc
+// -in …` reste acceptée telle quelle, avec toutes les options, pour les
+// scripts écrits avant. Les options ne sont déclarées qu'une fois, sur le jeu
+// global de run() : chaque sous-commande en reprend une partie, par nom, dans
+// son propre jeu. Les deux formes lisent donc les mêmes variables et suivent
+// le même chemin une fois la ligne de commande analysée.
+
+// command décrit une sous-commande pour l'analyse, l'aide et la complétion.
+// summary, synopsis et detail sont traduits à l'affichage ; les exemples, des
+// lignes de commande, ne le sont pas.
+type command struct {
+	name     string
+	summary  string // une ligne, pour l'aide générale et la complétion
+	synopsis string
+	detail   string // paragraphe d'aide, facultatif
+	examples []string
+	flags    []string // options propres, déclarées sur le jeu global
+}
+
+// Les familles d'options partagées entre plusieurs sous-commandes.
+var (
+	passOptions     = []string{"passfile", "passenv", "passfd", "passcmd"}
+	kdfOptions      = []string{"kdf", "kdf-algo", "kdf-target", "kdf-max-mem", "kdf-mem", "kdf-time", "kdf-threads"}
+	policyOptions   = []string{"breach-db", "policy", "min-score", "min-entropy", "min-length", "banned-words"}
+	progressOptions = []string{"progress", "progress-fd"}
+)
+
+func flagList(groups ...[]string) []string {
+	var out []string
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+var commands = []command{
+	{
+		name:     "enc",
+		summary:  "chiffrer un fichier ou un dossier",
+		synopsis: "chiffremento enc -in FICHIER|DOSSIER|- [-out CHEMIN] [options]",
+		detail: "Un dossier est empaqueté en tar au fil du chiffrement. Sans -out, le chiffré\n" +
+			"est écrit à côté de l'entrée, avec l'extension " + extension + ".\n\n" +
+			"-breach-db refuse, hors ligne, un nouveau mot de passe présent dans une liste\n" +
+			"condensée par breachdb-build. -policy, -min-score, -min-entropy, -min-length et\n" +
+			"-banned-words imposent une politique, en plus de celle du système (voir -policy).",
+		examples: []string{
+			"chiffremento enc -in rapport.pdf",
+			"chiffremento enc -in photos -aegis -kdf fort",
+			"tar c docs | chiffremento enc -in - -out docs.tar" + extension + " -passenv CHTO_PASSWORD",
+			"chiffremento enc -in coffre.tar -shares 5 -threshold 3",
+		},
+		flags: flagList([]string{"in", "out", "preset", "comp", "pad", "chacha", "parano", "aegis", "meta",
+			"key-file", "shares", "threshold", "recovery", "qr", "no-agent"},
+			kdfOptions, passOptions, policyOptions, progressOptions),
+	},
+	{
+		name:     "dec",
+		summary:  "déchiffrer un fichier ou restaurer un dossier",
+		synopsis: "chiffremento dec -in FICHIER" + extension + "|- [-out CHEMIN|-] [options]",
+		detail: "Sans -out, la sortie reprend le nom de l'entrée sans " + extension + ". Un dossier\n" +
+			"est recréé à l'identique ; sur -out -, il sort en tar.",
+		examples: []string{
+			"chiffremento dec -in rapport.pdf" + extension,
+			"chiffremento dec -in sauvegarde" + extension + " -out - | tar tf -",
+			"chiffremento dec -in coffre.tar" + extension + " -share part-1 -share part-2",
+		},
+		flags: flagList([]string{"in", "out", "preset", "max-kdf-mem", "key-file", "share", "recovery-code", "no-agent", "json"},
+			passOptions, progressOptions),
+	},
+	{
+		name:     "verify",
+		summary:  "contrôler un chiffré sans rien écrire",
+		synopsis: "chiffremento verify -in FICHIER" + extension + "|- [options]",
+		detail:   "Tout est déchiffré et authentifié, une archive est parcourue comme à l'extraction,\nmais rien n'est écrit sur le disque.",
+		examples: []string{
+			"chiffremento verify -in sauvegarde" + extension,
+			"chiffremento verify -in sauvegarde" + extension + " -passenv CHTO_PW -json",
+		},
+		flags: flagList([]string{"in", "preset", "max-kdf-mem", "key-file", "share", "recovery-code", "no-agent", "json"},
+			passOptions, progressOptions),
+	},
+	{
+		name:     "info",
+		summary:  "afficher l'en-tête d'un chiffré, sans mot de passe",
+		synopsis: "chiffremento info -in FICHIER" + extension + " [-json]",
+		examples: []string{"chiffremento info -in sauvegarde" + extension},
+		flags:    []string{"in", "json"},
+	},
+	{
+		name:     "upgrade",
+		summary:  "réécrire les anciens fichiers au format courant",
+		synopsis: "chiffremento upgrade -in FICHIER" + extension + "|DOSSIER [-r] [options]",
+		detail: "Les fichiers v1 et v2 sont réécrits, comme les v3 compressés en gzip ; les autres\n" +
+			"sont déjà à jour. La réécriture se fait en place, sans que le clair touche le disque,\n" +
+			"et suit les liens symboliques. Un seul mot de passe est demandé pour tout le lot.",
+		examples: []string{"chiffremento upgrade -in archives -r"},
+		flags:    flagList([]string{"in", "preset", "r", "comp", "max-kdf-mem"}, kdfOptions, passOptions, progressOptions),
+	},
+	{
+		name:     "edit",
+		summary:  "modifier un fichier chiffré dans l'éditeur",
+		synopsis: "chiffremento edit -in FICHIER" + extension + " [options]",
+		detail: "Le clair est déchiffré dans un fichier privé, en mémoire quand le système en offre\n" +
+			"une ($XDG_RUNTIME_DIR, /dev/shm), puis ouvert dans $VISUAL ou $EDITOR. Si l'éditeur\n" +
+			"se termine sans erreur et que le contenu a changé, le fichier est rechiffré en place\n" +
+			"avec ses paramètres d'origine. Le clair est supprimé dans tous les cas.",
+		examples: []string{
+			"chiffremento edit -in notes.txt" + extension,
+			"EDITOR=nano chiffremento edit -in secrets.env" + extension + " -key-file service.key",
+		},
+		flags: flagList([]string{"in", "preset", "max-kdf-mem", "key-file", "share", "no-agent"}, passOptions),
+	},
+	{
+		name:     "exec",
+		summary:  "lancer une commande avec les secrets d'un fichier d'environnement chiffré",
+		synopsis: "chiffremento exec -in FICHIER.env" + extension + " [options] -- COMMANDE [ARGUMENTS]",
+		detail: "Le fichier, au format .env (NOM=valeur), est déchiffré en mémoire sans toucher le\n" +
+			"disque ; ses variables s'ajoutent à l'environnement de la commande. -as-fd NOM passe\n" +
+			"une valeur par un descripteur hérité, dont le numéro est dans NOM_FD. Les signaux\n" +
+			"sont relayés à la commande, et son code de sortie devient celui de chiffremento.",
+		examples: []string{
+			"chiffremento exec -in secrets.env" + extension + " -- make deploy",
+			"chiffremento exec -in prod.env" + extension + " -key-file ci.key -as-fd DB_PASSWORD -- ./migrate",
+		},
+		flags: flagList([]string{"in", "preset", "as-fd", "max-kdf-mem", "key-file", "share", "no-agent"}, passOptions),
+	},
+	{
+		name:     "keygen",
+		summary:  "créer un fichier de clé symétrique",
+		synopsis: "chiffremento keygen -symmetric -out FICHIER",
+		examples: []string{"chiffremento keygen -symmetric -out service.key"},
+		flags:    []string{"out", "symmetric"},
+	},
+	{
+		name:     "genpass",
+		summary:  "proposer une phrase de passe",
+		synopsis: "chiffremento genpass [-words 6] [-wordlist fr|en]",
+		examples: []string{"chiffremento genpass -words 7"},
+		flags:    []string{"preset", "words", "wordlist"},
+	},
+	{
+		name:     "breachdb-build",
+		summary:  "condenser une liste de fuites Have I Been Pwned",
+		synopsis: "chiffremento breachdb-build -in pwned.txt -out fuites.db",
+		examples: []string{"chiffremento breachdb-build -in pwned-passwords-sha1.txt -out fuites.db"},
+		flags:    []string{"in", "out"},
+	},
+	{
+		name:     "agent",
+		summary:  "garder les secrets en mémoire",
+		synopsis: "chiffremento agent [add [NOM]|list|lock|stop] [options]",
+		detail:   "Sans action, lance l'agent. dec et verify essaient ses secrets avant de demander\nquoi que ce soit ; enc prend son mot de passe s'il est seul.",
+		examples: []string{
+			"chiffremento agent -agent-ttl 1h &",
+			"chiffremento agent add travail",
+			"chiffremento agent add service -key-file service.key",
+		},
+		flags: flagList([]string{"preset", "agent-ttl", "key-file"}, passOptions, policyOptions),
+	},
+	{
+		name:     "config",
+		summary:  "afficher les réglages en vigueur et leur origine",
+		synopsis: "chiffremento config [-preset NOM] [options]",
+		detail: "Les valeurs par défaut et les préréglages viennent de ~/.config/chiffremento/config.toml\n" +
+			"($XDG_CONFIG_HOME, ou $" + configEnv + " pour un autre fichier). Chaque réglage est suivi de\n" +
+			"son origine : option, préréglage, configuration, variable d'environnement ou défaut.",
+		examples: []string{
+			"chiffremento config",
+			"chiffremento config -preset archive -kdf maximum",
+		},
+		flags: flagList([]string{"preset"}, configKeys),
+	},
+	{
+		name:     "bench",
+		summary:  "mesurer les coûts sur cette machine",
+		synopsis: "chiffremento bench [-json]",
+		examples: []string{"chiffremento bench"},
+		flags:    []string{"json"},
+	},
+}
+
+func commandByName(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// flagSet monte le jeu d'options de c à partir du jeu global. Les valeurs sont
+// partagées : analyser l'un remplit les variables de l'autre.
+func (c command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("chiffremento "+c.name, flag.ExitOnError)
+	// -lang vaut pour toutes les commandes : elle est lue avant elles.
+	for _, name := range append(c.flags, "lang") {
+		f := flag.CommandLine.Lookup(name)
+		if f == nil {
+			panic("option inconnue dans la table des commandes : " + name)
+		}
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	fs.Usage = func() { c.help(fs.Output(), fs) }
+	return fs
+}
+
+// help décrit c : synopsis, options et exemples.
+func (c command) help(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "chiffremento %s — %s\n\n  %s\n", c.name, tr(c.summary), tr(c.synopsis))
+	if c.detail != "" {
+		fmt.Fprintf(w, "\n%s\n", tr(c.detail))
+	}
+	fmt.Fprintln(w, tr("\nOptions :"))
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	if len(c.examples) > 0 {
+		fmt.Fprintln(w, tr("\nExemples :"))
+		for _, e := range c.examples {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+}
+
+// skipLeadingLang écarte les -lang placés avant une sous-commande :
+// « chiffremento -lang en info » vaut « chiffremento info ». detectLanguage les
+// a déjà lus ; laissés là, ils feraient prendre la ligne pour l'ancienne forme.
+func skipLeadingLang(args []string) []string {
+	i := 0
+	for i < len(args) {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || name != "lang" {
+			break
+		}
+		if hasValue {
+			i++
+		} else {
+			i += 2
+		}
+	}
+	if i == 0 || i >= len(args) || strings.HasPrefix(args[i], "-") {
+		return args
+	}
+	return args[i:]
+}
+
+// parseCommandLine analyse args selon leur forme. Une sous-commande en tête
+// choisit son jeu d'options et fixe *mode ; sinon c'est l'ancienne forme,
+// avec le jeu global. help et completion sont traitées par run.
+func parseCommandLine(args []string, mode *string) (*flag.FlagSet, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		flag.CommandLine.Parse(args) // ExitOnError : une erreur sort en 2
+		return flag.CommandLine, nil
+	}
+	c, ok := commandByName(args[0])
+	if !ok {
+		return nil, errorf("commande inconnue %q : chiffremento help liste les commandes", args[0])
+	}
+	fs := c.flagSet()
+	fs.Parse(args[1:])
+	*mode = c.name
+	return fs, nil
+}
+
+// doHelp affiche l'aide générale, ou celle d'une commande.
+func doHelp(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return nil
+	}
+	c, ok := commandByName(args[0])
+	if !ok {
+		return errorf("commande inconnue %q : chiffremento help liste les commandes", args[0])
+	}
+	c.help(os.Stdout, c.flagSet())
+	return nil
+}
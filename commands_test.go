@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lancer exécute run() sur args, avec un jeu d'options neuf : run les déclare
+// à chaque appel. Les messages sont en français, quelle que soit la locale de
+// la machine qui lance les tests.
+func lancer(t *testing.T, args ...string) error {
+	t.Helper()
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	precedent, argv := flag.CommandLine, os.Args
+	source, agent, fuites := passwordFrom, useAgent, breachDB
+	flag.CommandLine = flag.NewFlagSet("chiffremento", flag.ContinueOnError)
+	os.Args = append([]string{"chiffremento"}, args...)
+	t.Cleanup(func() {
+		flag.CommandLine, os.Args = precedent, argv
+		passwordFrom, useAgent, breachDB = source, agent, fuites
+		setLanguage(sourceLanguage)
+	})
+	return run()
+}
+
+func TestSousCommandeEtAncienneForme(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"keygen", "-symmetric", "-out", filepath.Join(dir, "nouvelle.key")},
+		{"-mode", "keygen", "-symmetric", "-out", filepath.Join(dir, "ancienne.key")},
+	} {
+		if err := lancer(t, args...); err != nil {
+			t.Fatalf("%v : %v", args, err)
+		}
+		if _, err := os.Stat(args[len(args)-1]); err != nil {
+			t.Errorf("%v : clé absente (%v)", args, err)
+		}
+	}
+	if err := lancer(t, "chiffre", "-in", "x"); err == nil || !strings.Contains(err.Error(), "commande inconnue") {
+		t.Errorf("commande inconnue acceptée : %v", err)
+	}
+}
+
+// TestArgumentInattendu : un argument hors option arrête flag, et les options
+// qui le suivent seraient perdues sans erreur.
+func TestArgumentInattendu(t *testing.T) {
+	dir := t.TempDir()
+	in := ecrire(t, filepath.Join(dir, "a.txt"), []byte("contenu"))
+	t.Setenv("CHTO_TEST_PW", motDePasseTest)
+	for _, args := range [][]string{
+		{"enc", "-in", in, "-passenv", "CHTO_TEST_PW", "oops", "-out", filepath.Join(dir, "b.chto"), "-pad"},
+		{"enc", in},
+		{"-mode", "info", "-in", in, "oops"},
+	} {
+		if err := lancer(t, args...); err == nil || !strings.Contains(err.Error(), "argument inattendu") {
+			t.Errorf("%v : %v", args, err)
+		}
+	}
+	if _, err := os.Stat(in + extension); err == nil {
+		t.Error("chiffré écrit malgré l'argument en trop")
+	}
+
+	// -lang, seule option globale, peut précéder la sous-commande.
+	cle := filepath.Join(dir, "x.key")
+	if err := lancer(t, "-lang", "en", "keygen", "-symmetric", "-out", cle); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cle); err != nil {
+		t.Errorf("-lang avant keygen : clé absente (%v)", err)
+	}
+}
+
+func TestTableDesCommandes(t *testing.T) {
+	// help déclare les options : la table est ensuite vérifiée sur ce jeu.
+	sortie := captureSortie(t)
+	if err := lancer(t, "help", "enc"); err != nil {
+		t.Fatal(err)
+	}
+	aide, _ := os.ReadFile(sortie)
+	for _, attendu := range []string{"chiffremento enc -in", "-pad", "-passenv", "Exemples :"} {
+		if !strings.Contains(string(aide), attendu) {
+			t.Errorf("aide de enc sans %q", attendu)
+		}
+	}
+	for _, c := range commands {
+		c.flagSet() // panique sur une option absente du jeu global
+	}
+	// Une option étrangère à la commande n'est pas dans son jeu.
+	if dec, _ := commandByName("dec"); dec.flagSet().Lookup("pad") != nil {
+		t.Error("dec accepte -pad")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			sortie := captureSortie(t)
+			if err := lancer(t, "completion", shell); err != nil {
+				t.Fatal(err)
+			}
+			script, _ := os.ReadFile(sortie)
+			for _, attendu := range []string{"breachdb-build", "pad", "recovery-code", "_chiffremento"} {
+				if shell == "fish" && attendu == "_chiffremento" {
+					attendu = "complete -c chiffremento"
+				}
+				if !strings.Contains(string(script), attendu) {
+					t.Errorf("script %s sans %q", shell, attendu)
+				}
+			}
+		})
+	}
+	if err := lancer(t, "completion", "tcsh"); err == nil {
+		t.Error("shell inconnu accepté")
+	}
+}
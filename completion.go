@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Scripts de complétion.
+//
+// Ils sont générés depuis la table des commandes et les jeux d'options : une
+// option ajoutée à une commande y apparaît sans rien toucher ici.
+//
+//	source <(chiffremento completion bash)
+//	chiffremento completion zsh > "${fpath[1]}/_chiffremento"
+//	chiffremento completion fish > ~/.config/fish/completions/chiffremento.fish
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+func doCompletion(args []string) error {
+	if len(args) != 1 {
+		return errorf("completion attend un shell : %s", strings.Join(completionShells, ", "))
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout)
+	case "zsh":
+		writeZshCompletion(os.Stdout)
+	case "fish":
+		writeFishCompletion(os.Stdout)
+	default:
+		return errorf("shell %q non pris en charge (attendu %s)", args[0], strings.Join(completionShells, ", "))
+	}
+	return nil
+}
+
+// commandNames liste ce qui peut suivre chiffremento, help et completion
+// compris.
+func commandNames() []string {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	return append(names, "help", "completion")
+}
+
+// optionNames rend les options de c, chacune précédée d'un tiret.
+func optionNames(c command) []string {
+	var out []string
+	for _, n := range c.flags {
+		out = append(out, "-"+n)
+	}
+	return out
+}
+
+// isBoolFlag dit si l'option name se passe de valeur.
+func isBoolFlag(name string) bool {
+	f := flag.CommandLine.Lookup(name)
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprintf(w, `# complétion bash de chiffremento : source <(chiffremento completion bash)
+_chiffremento() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" opts
+	if [ "$COMP_CWORD" -eq 1 ]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		return
+	fi
+	case "${COMP_WORDS[1]}" in
+`, strings.Join(commandNames(), " "))
+	for _, c := range commands {
+		fmt.Fprintf(w, "\t%s) opts=\"%s\" ;;\n", c.name, strings.Join(optionNames(c), " "))
+	}
+	fmt.Fprintf(w, `	help) [ "$COMP_CWORD" -eq 2 ] && COMPREPLY=($(compgen -W "%s" -- "$cur")); return ;;
+	completion) [ "$COMP_CWORD" -eq 2 ] && COMPREPLY=($(compgen -W "%s" -- "$cur")); return ;;
+	*) return ;;
+	esac
+	case "$cur" in
+	-*) COMPREPLY=($(compgen -W "$opts" -- "$cur")) ;;
+	*) COMPREPLY=($(compgen -f -- "$cur")) ;;
+	esac
+}
+complete -o filenames -F _chiffremento chiffremento
+`, strings.Join(commandNames()[:len(commands)], " "), strings.Join(completionShells, " "))
+}
+
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintln(w, `#compdef chiffremento
+# complétion zsh de chiffremento : source <(chiffremento completion zsh)
+_chiffremento() {
+	local -a cmds opts
+	cmds=(`)
+	for _, c := range commands {
+		fmt.Fprintf(w, "\t\t%s\n", shellQuote(c.name+":"+tr(c.summary)))
+	}
+	fmt.Fprintf(w, "\t\t%s\n\t\t%s\n\t)\n", shellQuote("help:"+tr("options et exemples d'une commande")),
+		shellQuote("completion:"+tr("script de complétion pour le shell")))
+	fmt.Fprintln(w, `	if (( CURRENT == 2 )); then
+		_describe 'commande' cmds
+		return
+	fi
+	case $words[2] in`)
+	for _, c := range commands {
+		fmt.Fprintf(w, "\t%s) opts=(%s) ;;\n", c.name, strings.Join(optionNames(c), " "))
+	}
+	fmt.Fprintf(w, `	help) (( CURRENT == 3 )) && _describe 'commande' cmds; return ;;
+	completion) (( CURRENT == 3 )) && compadd %s; return ;;
+	*) return ;;
+	esac
+	if [[ $PREFIX == -* ]]; then
+		compadd -a opts
+	else
+		_files
+	fi
+}
+compdef _chiffremento chiffremento
+`, strings.Join(completionShells, " "))
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# complétion fish de chiffremento : chiffremento completion fish | source")
+	fmt.Fprintln(w, "complete -c chiffremento -f")
+	for _, c := range commands {
+		fmt.Fprintf(w, "complete -c chiffremento -n __fish_use_subcommand -a %s -d %s\n", c.name, shellQuote(tr(c.summary)))
+	}
+	fmt.Fprintf(w, "complete -c chiffremento -n __fish_use_subcommand -a help -d %s\n", shellQuote(tr("options et exemples d'une commande")))
+	fmt.Fprintf(w, "complete -c chiffremento -n __fish_use_subcommand -a completion -d %s\n", shellQuote(tr("script de complétion pour le shell")))
+	fmt.Fprintf(w, "complete -c chiffremento -n '__fish_seen_subcommand_from help' -a %s\n",
+		shellQuote(strings.Join(commandNames()[:len(commands)], " ")))
+	fmt.Fprintf(w, "complete -c chiffremento -n '__fish_seen_subcommand_from completion' -a %s\n",
+		shellQuote(strings.Join(completionShells, " ")))
+	for _, c := range commands {
+		for _, name := range c.flags {
+			f := flag.CommandLine.Lookup(name)
+			// Une option qui attend une valeur la prend dans les fichiers :
+			// c'est le cas le plus courant (-in, -out, -key-file…).
+			value := " -r -F"
+			if isBoolFlag(name) {
+				value = ""
+			}
+			fmt.Fprintf(w, "complete -c chiffremento -n '__fish_seen_subcommand_from %s' -o %s%s -d %s\n",
+				c.name, name, value, shellQuote(firstClause(f.Usage)))
+		}
+	}
+}
+
+// shellQuote protège s entre apostrophes, pour bash, zsh et fish.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// firstClause garde le début d'une description d'option : les complétions
+// l'affichent sur une ligne.
+func firstClause(usage string) string {
+	for _, sep := range []string{" ; ", " (", ", par exemple"} {
+		if i := strings.Index(usage, sep); i > 0 {
+			usage = usage[:i]
+		}
+	}
+	return usage
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"chiffremento-cli/pkg"
+)
+
+// Fichier de configuration.
+//
+// Les mêmes combinaisons (-parano -kdf fort -meta minimal -pad) revenaient à
+// chaque commande. ~/.config/chiffremento/config.toml (ou
+// $XDG_CONFIG_HOME/chiffremento/config.toml, ou le fichier désigné par
+// $CHTO_CONFIG) fixe des valeurs par défaut et des préréglages nommés :
+//
+//	# valeurs par défaut, pour toutes les commandes
+//	kdf = "fort"
+//	breach-db = "~/.local/share/chiffremento/fuites.db"
+//
+//	[preset.archive]
+//	parano = true
+//	meta = "minimal"
+//	pad = true
+//
+// Les clés sont les noms des options, et chacune ne vaut que pour les
+// commandes qui acceptent l'option. -preset archive choisit un préréglage.
+// Une option de la ligne de commande l'emporte sur le préréglage, qui
+// l'emporte sur les valeurs par défaut. Les options qui s'excluent (-chacha,
+// -parano et -aegis ; -comp et -pad ; les options -kdf*) se règlent en bloc :
+// -aegis sur la ligne de commande écarte le parano = true du préréglage au
+// lieu de le contredire.
+//
+// Seul un sous-ensemble de TOML est lu : des tables [preset.NOM], et des
+// valeurs chaînes, booléennes ou numériques.
+
+// configEnv désigne un fichier de configuration à la place du fichier usuel.
+const configEnv = "CHTO_CONFIG"
+
+// configKeys sont les options qu'un fichier de configuration peut régler :
+// des préférences, pas l'objet d'une commande. -in, -out, les sources de mot
+// de passe et les secrets restent sur la ligne de commande.
+var configKeys = flagList(
+	[]string{"comp", "pad", "chacha", "parano", "aegis", "meta"},
+	kdfOptions,
+	[]string{"max-kdf-mem", "no-agent", "agent-ttl", "words", "wordlist", "progress"},
+	policyOptions,
+)
+
+// configGroups sont les options qui se règlent en bloc.
+var configGroups = [][]string{{"chacha", "parano", "aegis"}, {"comp", "pad"}, kdfOptions}
+
+// configPaths sont les clés qui désignent un fichier : relatives, elles le
+// sont au fichier de configuration.
+var configPaths = []string{"breach-db", "policy", "banned-words"}
+
+// configPath rend le chemin du fichier de configuration, vide si le dossier
+// personnel est introuvable.
+func configPath() string {
+	if p := os.Getenv(configEnv); p != "" {
+		return p
+	}
+	// Un XDG_CONFIG_HOME relatif est à ignorer, selon la spécification.
+	if d := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(d) {
+		return filepath.Join(d, "chiffremento", "config.toml")
+	}
+	if runtime.GOOS == "windows" {
+		if d, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(d, "chiffremento", "config.toml")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "chiffremento", "config.toml")
+}
+
+type configEntry struct {
+	value string
+	line  int
+}
+
+// configLayer associe des options à leurs valeurs.
+type configLayer map[string]configEntry
+
+type userConfig struct {
+	path     string // vide : pas de fichier
+	defaults configLayer
+	presets  map[string]configLayer
+	names    []string // préréglages, dans l'ordre du fichier
+}
+
+// loadConfig lit le fichier de configuration. Le fichier usuel est
+// facultatif ; celui que désigne $CHTO_CONFIG doit exister.
+func loadConfig() (*userConfig, error) {
+	path := configPath()
+	if path == "" {
+		return &userConfig{}, nil
+	}
+	c, err := parseConfigFile(path)
+	if errors.Is(err, os.ErrNotExist) && os.Getenv(configEnv) == "" {
+		return &userConfig{}, nil
+	}
+	return c, err
+}
+
+func parseConfigFile(path string) (*userConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &userConfig{path: path, defaults: configLayer{}, presets: map[string]configLayer{}}
+	layer := c.defaults
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		if strings.HasPrefix(l, "[") {
+			table := stripComment(l)
+			name, ok := strings.CutPrefix(strings.TrimSpace(strings.Trim(table, "[]")), "preset.")
+			if !ok || !strings.HasSuffix(table, "]") || !isBareKey(name) {
+				return nil, errorf("%s, ligne %d : table inconnue %s (attendu [preset.NOM])", path, line, l)
+			}
+			if _, dup := c.presets[name]; dup {
+				return nil, errorf("%s, ligne %d : préréglage %q défini deux fois", path, line, name)
+			}
+			layer = configLayer{}
+			c.presets[name] = layer
+			c.names = append(c.names, name)
+			continue
+		}
+		key, raw, ok := strings.Cut(l, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isBareKey(key) {
+			return nil, errorf("%s, ligne %d : « clé = valeur » attendu", path, line)
+		}
+		if !slices.Contains(configKeys, key) {
+			// Comme pour la politique : une faute de frappe ne doit pas
+			// passer pour un réglage appliqué.
+			return nil, errorf("%s, ligne %d : clé inconnue %q", path, line, key)
+		}
+		if _, dup := layer[key]; dup {
+			return nil, errorf("%s, ligne %d : %s défini deux fois", path, line, key)
+		}
+		value, err := parseConfigValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, errorf("%s, ligne %d : %w", path, line, err)
+		}
+		if slices.Contains(configPaths, key) && value != "" {
+			value = expandHome(value)
+			if !filepath.IsAbs(value) {
+				value = filepath.Join(filepath.Dir(path), value)
+			}
+		}
+		layer[key] = configEntry{value: value, line: line}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errorf("lecture de %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func isBareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// stripComment retire un commentaire de fin de ligne hors chaîne.
+func stripComment(s string) string {
+	if i := strings.Index(s, "#"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// parseConfigValue lit une valeur TOML : chaîne entre guillemets (avec
+// échappements) ou entre apostrophes (littérale), booléen ou nombre.
+func parseConfigValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch raw[i] {
+			case '"':
+				if rest := stripComment(raw[i+1:]); rest != "" {
+					return "", errorf("texte inattendu après la chaîne : %q", rest)
+				}
+				return b.String(), nil
+			case '\\':
+				i++
+				if i == len(raw) {
+					break
+				}
+				switch raw[i] {
+				case '"', '\\':
+					b.WriteByte(raw[i])
+				case 't':
+					b.WriteByte('\t')
+				case 'n':
+					b.WriteByte('\n')
+				default:
+					return "", errorf(`échappement \%c non pris en charge`, raw[i])
+				}
+			default:
+				b.WriteByte(raw[i])
+			}
+		}
+		return "", errorf("chaîne sans guillemet fermant")
+	case strings.HasPrefix(raw, "'"):
+		s, rest, ok := strings.Cut(raw[1:], "'")
+		if !ok {
+			return "", errorf("chaîne sans apostrophe fermante")
+		}
+		if rest = stripComment(rest); rest != "" {
+			return "", errorf("texte inattendu après la chaîne : %q", rest)
+		}
+		return s, nil
+	}
+	v := stripComment(raw)
+	if v == "true" || v == "false" {
+		return v, nil
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(v, "_", ""), 64); err == nil {
+		return strings.ReplaceAll(v, "_", ""), nil
+	}
+	if v == "" {
+		return "", errorf("valeur manquante")
+	}
+	return "", errorf("valeur %q : une chaîne s'écrit entre guillemets", v)
+}
+
+// configSource est une couche de réglages et ce qu'elle affiche comme origine.
+type configSource struct {
+	label string
+	layer configLayer
+	path  string // fichier d'où vient la couche, pour les messages
+}
+
+// sources rend les couches par priorité croissante : valeurs par défaut du
+// fichier, variables d'environnement, préréglage.
+func (c *userConfig) sources(preset string) ([]configSource, error) {
+	out := []configSource{{label: tr("configuration"), layer: c.defaults, path: c.path}}
+	for key, env := range map[string]string{"breach-db": breachDBEnv, "policy": policyEnv} {
+		if v := os.Getenv(env); v != "" {
+			out = append(out, configSource{label: "$" + env, layer: configLayer{key: {value: v}}})
+		}
+	}
+	if preset == "" {
+		return out, nil
+	}
+	layer, ok := c.presets[preset]
+	switch {
+	case c.path == "":
+		return nil, errorf("-preset %s : aucun fichier de configuration (%s)", preset, configPath())
+	case !ok && len(c.names) == 0:
+		return nil, errorf("-preset %s : %s ne définit aucun préréglage", preset, c.path)
+	case !ok:
+		return nil, errorf("-preset %s inconnu (dans %s : %s)", preset, c.path, strings.Join(c.names, ", "))
+	}
+	return append(out, configSource{label: fmt.Sprintf(tr("préréglage %s"), preset), layer: layer, path: c.path}), nil
+}
+
+// resolve choisit, pour chaque clé de keys, la couche qui l'emporte : la plus
+// prioritaire qui règle la clé ou une option de son bloc. Une clé qu'aucune
+// couche retenue ne règle est absente du résultat.
+func resolve(keys []string, sources []configSource) map[string]*configSource {
+	out := map[string]*configSource{}
+	for _, key := range keys {
+		group := []string{key}
+		for _, g := range configGroups {
+			if slices.Contains(g, key) {
+				group = g
+			}
+		}
+		for i := len(sources) - 1; i >= 0; i-- {
+			if !slices.ContainsFunc(group, func(k string) bool { _, ok := sources[i].layer[k]; return ok }) {
+				continue
+			}
+			if _, ok := sources[i].layer[key]; ok {
+				out[key] = &sources[i]
+			}
+			break
+		}
+	}
+	return out
+}
+
+// apply règle les options de cmd que la ligne de commande laisse libres, et
+// les ajoute à set : pour la suite, une valeur venue du fichier compte comme
+// donnée. Le résultat associe chaque option réglable à son origine.
+func (c *userConfig) apply(cmd command, preset string, set map[string]bool) (map[string]string, error) {
+	sources, err := c.sources(preset)
+	if err != nil {
+		return nil, err
+	}
+	// La ligne de commande forme la couche la plus prioritaire.
+	given := configLayer{}
+	for name := range set {
+		given[name] = configEntry{}
+	}
+	// Une clé ou des parts remplacent la dérivation : les options -kdf* du
+	// fichier sont alors sans objet, et non une contradiction.
+	if set["key-file"] || set["shares"] || set["threshold"] {
+		for _, k := range kdfOptions {
+			given[k] = configEntry{}
+		}
+	}
+	sources = append(sources, configSource{label: tr("option"), layer: given})
+
+	var keys []string
+	for _, k := range cmd.flags {
+		if slices.Contains(configKeys, k) {
+			keys = append(keys, k)
+		}
+	}
+	origins := map[string]string{}
+	for _, key := range keys {
+		origins[key] = tr("défaut")
+	}
+	for key, src := range resolve(keys, sources) {
+		if src == &sources[len(sources)-1] {
+			if set[key] {
+				origins[key] = src.label
+			}
+			continue
+		}
+		e := src.layer[key]
+		if err := flag.CommandLine.Set(key, e.value); err != nil {
+			if src.path == "" {
+				return nil, fmt.Errorf("%s : %w", src.label, err)
+			}
+			return nil, errorf("%s, ligne %d : %s : %w", src.path, e.line, key, err)
+		}
+		set[key] = true
+		origins[key] = src.label
+	}
+	return origins, nil
+}
+
+type configFlags struct {
+	preset string
+}
+
+func registerConfigFlags() *configFlags {
+	f := &configFlags{}
+	flag.StringVar(&f.preset, "preset", "", tr("appliquer ce préréglage du fichier de configuration ; les options données l'emportent"))
+	return f
+}
+
+// doConfig affiche les réglages en vigueur pour chaque option réglable, et
+// d'où ils viennent.
+func doConfig(c *userConfig, origins map[string]string) error {
+	switch {
+	case c.path == "":
+		fmt.Printf("%-14s %s\n", tr("fichier"), fmt.Sprintf(tr("aucun (%s n'existe pas)"), configPath()))
+	default:
+		fmt.Printf("%-14s %s\n", tr("fichier"), c.path)
+	}
+	if len(c.names) > 0 {
+		fmt.Printf("%-14s %s\n", tr("préréglages"), strings.Join(c.names, ", "))
+	}
+	fmt.Println()
+	for _, key := range configKeys {
+		fmt.Printf("%-14s %-24s %s\n", key, flag.CommandLine.Lookup(key).Value.String(), styleDim.Render(origins[key]))
+	}
+	return nil
+}
+
+// summary résume un préréglage sur une ligne, dans l'ordre des options.
+func (c *userConfig) summary(preset string) string {
+	var parts []string
+	for _, key := range configKeys {
+		if e, ok := c.presets[preset][key]; ok {
+			parts = append(parts, key+" "+e.value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tuiSettings sont les réglages que l'interface guidée sait montrer.
+type tuiSettings struct {
+	algo     byte
+	kdf      pkg.KDFProfile
+	compress *bool // nil : laissé au choix selon la cible
+	pad      bool
+	keepMeta bool
+}
+
+// tuiSettings traduit les valeurs par défaut et un préréglage en réglages de
+// l'interface guidée. Ce qu'elle ne montre pas (-kdf auto, -kdf-algo…) est
+// laissé de côté : l'écran resterait muet sur ce qui serait appliqué.
+func (c *userConfig) tuiSettings(preset string) (tuiSettings, error) {
+	s := tuiSettings{algo: pkg.AlgoAES, kdf: pkg.KDFStandard}
+	sources := []configSource{{label: tr("configuration"), layer: c.defaults, path: c.path}}
+	if preset != "" {
+		sources = append(sources, configSource{label: fmt.Sprintf(tr("préréglage %s"), preset), layer: c.presets[preset], path: c.path})
+	}
+	boolean := func(key string, e configEntry) (bool, error) {
+		b, err := strconv.ParseBool(e.value)
+		if err != nil {
+			return false, errorf("%s, ligne %d : %s : booléen attendu", c.path, e.line, key)
+		}
+		return b, nil
+	}
+	keys := []string{"chacha", "parano", "aegis", "kdf", "comp", "pad", "meta"}
+	algos := map[string]bool{}
+	for key, src := range resolve(keys, sources) {
+		e := src.layer[key]
+		switch key {
+		case "kdf":
+			if p, err := pkg.ParseKDFProfile(e.value); err == nil {
+				s.kdf = p
+			}
+		case "meta":
+			m, err := pkg.ParseMetadataMode(e.value)
+			if err != nil {
+				return s, errorf("%s, ligne %d : %w", c.path, e.line, err)
+			}
+			s.keepMeta = m != pkg.MetadataNone
+		default:
+			b, err := boolean(key, e)
+			if err != nil {
+				return s, err
+			}
+			switch key {
+			case "chacha", "parano", "aegis":
+				algos[key] = b
+			case "comp":
+				s.compress = &b
+			case "pad":
+				s.pad = b
+			}
+		}
+	}
+	// pad et comp se règlent en bloc : pad = true seul écarte la compression
+	// que l'interface propose d'office pour un dossier.
+	if s.pad && s.compress == nil {
+		s.compress = new(bool)
+	}
+	algo, err := chooseAlgo(algos["chacha"], algos["parano"], algos["aegis"])
+	if err != nil {
+		return s, fmt.Errorf("%s : %w", c.path, err)
+	}
+	s.algo = algo
+	return s, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+// avecConfig écrit un fichier de configuration et le désigne par $CHTO_CONFIG.
+func avecConfig(t *testing.T, contenu string) string {
+	t.Helper()
+	chemin := ecrire(t, filepath.Join(t.TempDir(), "config.toml"), []byte(contenu))
+	t.Setenv(configEnv, chemin)
+	return chemin
+}
+
+func TestParseConfig(t *testing.T) {
+	chemin := avecConfig(t, `# valeurs par défaut
+kdf = "fort"   # commentaire
+breach-db = 'fuites.db'
+min-entropy = 50.5
+
+[preset.archive]
+parano = true
+meta = "minimal"
+banned-words = "/etc/\"mots\".txt"
+
+[preset.partage] # sans réglage
+`)
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(c.names, ","); got != "archive,partage" {
+		t.Errorf("préréglages %s", got)
+	}
+	for cle, attendu := range map[string]string{
+		"kdf":         "fort",
+		"breach-db":   filepath.Join(filepath.Dir(chemin), "fuites.db"),
+		"min-entropy": "50.5",
+	} {
+		if got := c.defaults[cle].value; got != attendu {
+			t.Errorf("%s = %q, attendu %q", cle, got, attendu)
+		}
+	}
+	if got := c.presets["archive"]["banned-words"].value; got != `/etc/"mots".txt` {
+		t.Errorf("banned-words = %q", got)
+	}
+	if got := c.summary("archive"); got != `parano true, meta minimal, banned-words /etc/"mots".txt` {
+		t.Errorf("résumé %q", got)
+	}
+
+	for _, c := range []struct{ contenu, erreur string }{
+		{"kdf = fort", "entre guillemets"},
+		{`kdf = "fort`, "guillemet fermant"},
+		{"passfile = \"pw\"", "clé inconnue"},
+		{"pad = true\npad = false", "défini deux fois"},
+		{"[archive]", "table inconnue"},
+		{"[preset.a]\n[preset.a]", "défini deux fois"},
+		{"pad", "clé = valeur"},
+	} {
+		avecConfig(t, c.contenu)
+		if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), c.erreur) {
+			t.Errorf("%q : erreur %v, attendu %q", c.contenu, err, c.erreur)
+		}
+	}
+
+	// Le fichier usuel est facultatif ; celui de $CHTO_CONFIG, non.
+	t.Setenv(configEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if c, err := loadConfig(); err != nil || c.path != "" {
+		t.Errorf("sans fichier : %+v, %v", c, err)
+	}
+	t.Setenv(configEnv, filepath.Join(t.TempDir(), "absent.toml"))
+	if _, err := loadConfig(); err == nil {
+		t.Error("$CHTO_CONFIG absent accepté")
+	}
+}
+
+func TestConfigPriorite(t *testing.T) {
+	avecConfig(t, `kdf = "fort"
+chacha = true
+pad = true
+
+[preset.archive]
+parano = true
+comp = true
+`)
+	sortie := captureSortie(t)
+	if err := lancer(t, "config", "-preset", "archive", "-aegis"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(sortie)
+	origines := map[string]string{}
+	for _, l := range strings.Split(string(data), "\n") {
+		if f := strings.Fields(l); len(f) >= 3 {
+			origines[f[0]] = f[1] + " " + strings.Join(f[2:], " ")
+		}
+	}
+	for cle, attendu := range map[string]string{
+		"aegis":  "true option",             // la ligne de commande l'emporte…
+		"parano": "false défaut",            // …sur tout le bloc des algorithmes
+		"comp":   "true préréglage archive", // le préréglage écarte pad du fichier
+		"pad":    "false défaut",
+		"kdf":    "fort configuration",
+	} {
+		if origines[cle] != attendu {
+			t.Errorf("%s : %q, attendu %q", cle, origines[cle], attendu)
+		}
+	}
+
+	if err := lancer(t, "config", "-preset", "inconnu"); err == nil || !strings.Contains(err.Error(), "archive") {
+		t.Errorf("préréglage inconnu : %v", err)
+	}
+}
+
+// TestPresetChiffrement chiffre avec un préréglage, de bout en bout.
+func TestPresetChiffrement(t *testing.T) {
+	avecConfig(t, `no-agent = true
+
+[preset.archive]
+parano = true
+pad = true
+meta = "minimal"
+kdf-mem = "8MiB"
+kdf-time = 1
+kdf-threads = 1
+`)
+	dir := t.TempDir()
+	in := ecrire(t, filepath.Join(dir, "rapport.txt"), []byte("contenu du rapport"))
+	avecMotDePasse(t, motDePasseTest)
+	if err := lancer(t, "enc", "-in", in, "-preset", "archive"); err != nil {
+		t.Fatal(err)
+	}
+	d, err := pkg.Inspect(in + extension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Algo != pkg.AlgoName(pkg.AlgoCascade) || !d.Padded || !d.Metadata || d.KDFMemoryKiB != 8<<10 {
+		t.Errorf("en-tête %+v", d)
+	}
+}
+
+func TestTUISettings(t *testing.T) {
+	avecConfig(t, `kdf = "maximum"
+comp = true
+
+[preset.archive]
+aegis = true
+pad = true
+meta = "minimal"
+`)
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.tuiSettings("")
+	if err != nil || s.algo != pkg.AlgoAES || s.kdf != pkg.KDFMaximum || s.compress == nil || !*s.compress {
+		t.Errorf("sans préréglage : %+v, %v", s, err)
+	}
+	s, err = c.tuiSettings("archive")
+	if err != nil || s.algo != pkg.AlgoAEGIS || !s.pad || s.compress == nil || *s.compress || !s.keepMeta {
+		t.Errorf("archive : %+v, %v", s, err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"unicode"
+)
+
+// Fichiers d'environnement.
+//
+// La syntaxe est celle, répandue, des fichiers .env :
+//
+//	# commentaire
+//	export NOM=valeur        # export est facultatif
+//	JETON='littéral, $ compris'
+//	CLE="ligne 1\nligne 2"   # échappements \n \r \t \" \\ \$
+//	PEM="-----BEGIN…
+//	…-----END…"              # une chaîne entre guillemets peut courir sur plusieurs lignes
+//
+// Une valeur sans guillemets s'arrête à un # précédé d'une espace. Aucune
+// substitution : $AUTRE reste tel quel, un fichier de secrets ne doit pas
+// dépendre de l'environnement de la machine qui le lit.
+
+// envVar est une variable lue dans un fichier d'environnement. value pointe
+// dans le contenu passé à parseDotenv : aucune copie n'en est faite.
+type envVar struct {
+	name  string
+	value []byte
+}
+
+// parseDotenv lit le contenu d'un fichier d'environnement ; path ne sert
+// qu'aux messages d'erreur. data est réécrit sur place (fins de ligne \r\n,
+// échappements) : les valeurs restent dans le tampon de l'appelant, protégé
+// chez exec, plutôt que d'être recopiées en strings sur le tas.
+func parseDotenv(path string, data []byte) ([]envVar, error) {
+	text := dropCR(data)
+	var vars []envVar
+	seen := map[string]bool{}
+	for pos, line := 0, 1; pos < len(text); line++ {
+		start := pos
+		end := bytes.IndexByte(text[pos:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += pos
+		}
+		pos = end + 1
+		l := bytes.TrimSpace(text[start:end])
+		if len(l) == 0 || l[0] == '#' {
+			continue
+		}
+		eq := bytes.IndexByte(text[start:end], '=')
+		var name string
+		if eq >= 0 {
+			n := bytes.TrimSpace(text[start : start+eq])
+			if rest, ok := bytes.CutPrefix(n, []byte("export ")); ok {
+				n = bytes.TrimSpace(rest)
+			}
+			name = string(n)
+		}
+		if eq < 0 || !isEnvName(name) {
+			return nil, errorf("%s, ligne %d : « NOM=valeur » attendu", path, line)
+		}
+		lead := bytes.TrimLeftFunc(text[start+eq+1:end], unicode.IsSpace)
+		rawStart := end - len(lead)
+		raw := bytes.TrimRightFunc(lead, unicode.IsSpace)
+
+		value, lines := raw, 0
+		if len(raw) > 0 && (raw[0] == '"' || raw[0] == '\'') {
+			// Une chaîne entre guillemets peut courir sur les lignes
+			// suivantes : elle se lit dans la suite du texte.
+			v, n, crossed, err := dotenvQuoted(text[rawStart:])
+			if err != nil {
+				return nil, errorf("%s, ligne %d : %w", path, line, err)
+			}
+			value, lines = v, crossed
+			end = rawStart + n
+			if i := bytes.IndexByte(text[end:], '\n'); i >= 0 {
+				end += i
+			} else {
+				end = len(text)
+			}
+			if rest := stripComment(string(text[rawStart+n : end])); rest != "" {
+				return nil, errorf("%s, ligne %d : %w", path, line, errorf("texte inattendu après la chaîne : %q", rest))
+			}
+			pos = end + 1
+		} else if i := bytes.Index(raw, []byte(" #")); i >= 0 {
+			value = bytes.TrimSpace(raw[:i])
+		}
+
+		if seen[name] {
+			return nil, errorf("%s, ligne %d : %s défini deux fois", path, line, name)
+		}
+		seen[name] = true
+		vars = append(vars, envVar{name: name, value: value})
+		line += lines
+	}
+	return vars, nil
+}
+
+// dropCR remplace sur place les fins de ligne \r\n par \n.
+func dropCR(data []byte) []byte {
+	n := 0
+	for i, c := range data {
+		if c == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			continue
+		}
+		data[n] = c
+		n++
+	}
+	return data[:n]
+}
+
+// dotenvQuoted lit la valeur entre guillemets (avec échappements) ou entre
+// apostrophes (littérale) qui ouvre raw. La valeur est décodée sur place, au
+// début de raw : elle n'est jamais plus longue que sa forme écrite. n est le
+// nombre d'octets lus, guillemet fermant compris, et lines celui des sauts de
+// ligne traversés.
+func dotenvQuoted(raw []byte) (value []byte, n, lines int, err error) {
+	quote := raw[0]
+	w := 0
+	for i := 1; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == quote:
+			return raw[:w], i + 1, lines, nil
+		case c == '\\' && quote == '"' && i+1 < len(raw):
+			i++
+			switch raw[i] {
+			case '"', '\\', '$':
+				c = raw[i]
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			default:
+				return nil, 0, 0, errorf(`échappement \%c non pris en charge`, raw[i])
+			}
+		case c == '\n':
+			lines++
+		}
+		raw[w] = c
+		w++
+	}
+	if quote == '\'' {
+		return nil, 0, 0, errorf("chaîne sans apostrophe fermante")
+	}
+	return nil, 0, 0, errorf("chaîne sans guillemet fermant")
+}
+
+// isEnvName accepte les noms de variable portables : lettres, chiffres et _,
+// sans chiffre en tête.
+func isEnvName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
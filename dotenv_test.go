@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	contenu := []byte(`# base de données
+export DB_USER=admin
+DB_PASSWORD = s3cr#t   # le # collé fait partie de la valeur
+JETON='littéral $HOME \n'
+MULTI="ligne 1\nligne 2\t\"fin\""
+PEM="-----BEGIN-----
+abc
+-----END-----" # commentaire
+VIDE=
+`)
+	vars, err := parseDotenv("secrets.env", contenu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attendu := []struct{ name, value string }{
+		{"DB_USER", "admin"},
+		{"DB_PASSWORD", "s3cr#t"},
+		{"JETON", `littéral $HOME \n`},
+		{"MULTI", "ligne 1\nligne 2\t\"fin\""},
+		{"PEM", "-----BEGIN-----\nabc\n-----END-----"},
+		{"VIDE", ""},
+	}
+	if len(vars) != len(attendu) {
+		t.Fatalf("%d variables : %+v", len(vars), vars)
+	}
+	for i, v := range attendu {
+		if vars[i].name != v.name || string(vars[i].value) != v.value {
+			t.Errorf("variable %d : %s=%q, attendu %+v", i, vars[i].name, vars[i].value, v)
+		}
+	}
+	// Les valeurs restent dans le tampon lu, décodées sur place : aucune
+	// copie sur le tas.
+	for _, v := range vars[:5] {
+		if !dansTampon(v.value, contenu) {
+			t.Errorf("%s recopié hors du tampon", v.name)
+		}
+	}
+
+	// Fins de ligne Windows, et numéro de ligne après une chaîne multiligne.
+	vars, err = parseDotenv("secrets.env", []byte("A=\"x\r\ny\"\r\nB=2\r\n"))
+	if err != nil || len(vars) != 2 || string(vars[0].value) != "x\ny" || string(vars[1].value) != "2" {
+		t.Errorf("\\r\\n : %+v, %v", vars, err)
+	}
+	if _, err := parseDotenv("secrets.env", []byte("A=\"x\ny\"\nA=2")); err == nil || !strings.Contains(err.Error(), "ligne 3 :") {
+		t.Errorf("ligne après une chaîne multiligne : %v", err)
+	}
+
+	for _, c := range []struct{ contenu, erreur string }{
+		{"pas de signe égal", "ligne 1 : « NOM=valeur » attendu"},
+		{"1A=x", "« NOM=valeur » attendu"},
+		{"A=1\n\nA=2", "ligne 3 : A défini deux fois"},
+		{"A=\"ouvert\nB=2", "ligne 1 : chaîne sans guillemet fermant"},
+		{"A='ouvert", "apostrophe fermante"},
+		{`A="x" y`, "texte inattendu"},
+		{`A="\q"`, `échappement \q`},
+	} {
+		_, err := parseDotenv("secrets.env", []byte(c.contenu))
+		if err == nil || !strings.Contains(err.Error(), c.erreur) {
+			t.Errorf("%q : %v, attendu %q", c.contenu, err, c.erreur)
+		}
+	}
+}
+
+// dansTampon dit si v est une tranche de buf : elles finissent sur le même
+// octet du tableau sous-jacent.
+func dansTampon(v, buf []byte) bool {
+	return cap(v) > 0 && &v[:cap(v)][cap(v)-1] == &buf[:cap(buf)][cap(buf)-1]
+}
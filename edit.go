@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"chiffremento-cli/pkg"
+)
+
+// doEdit ouvre un .chto dans l'éditeur, puis le rechiffre en place s'il a
+// changé. Le clair est supprimé quoi qu'il arrive.
+func doEdit(in string, opts pkg.Options) error {
+	if isStream(in) {
+		return errorf("edit a besoin d'un fichier : un flux ne peut pas être réécrit en place")
+	}
+	if !strings.HasSuffix(in, extension) {
+		return errorf("un fichier à modifier doit porter l'extension %s", extension)
+	}
+	d, err := pkg.Inspect(in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(diag, tr("%s format v%d · %s · %s%s\n"), styleDim.Render(tr("fichier      ")),
+		d.Version, d.Algo, d.KDF, detailsSuffix(d))
+	// Tout refus passe avant le secret : inutile de le demander pour rien.
+	if err := d.Editable(); err != nil {
+		return err
+	}
+	if err := checkSecretKind(d, opts); err != nil {
+		return err
+	}
+	if err := pkg.CheckKDFMemory(d.KDFMemoryKiB, opts.MaxKDFMemory); err != nil {
+		return err
+	}
+
+	// L'éditeur ne tourne pas dans op : unlock réessaie op avec le secret
+	// suivant de l'agent, et rouvrirait l'éditeur après un échec.
+	var s *pkg.EditSession
+	err = unlock(secretKind(&d, opts), opts, false, false, func(password []byte, opts pkg.Options) error {
+		var err error
+		s, err = pkg.OpenEdit(in, password, opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if !s.MemoryBacked() {
+		fmt.Fprintln(diag, styleDim.Render(fmt.Sprintf(tr(
+			"note : pas de système de fichiers en mémoire ici, le clair passe par %s le temps de l'édition"), filepath.Dir(s.Path()))))
+	}
+
+	if err := runEditor(s.Path()); err != nil {
+		return err
+	}
+	changed, err := s.Save()
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Fprintln(diag, styleDim.Render(tr("aucun changement : le fichier est laissé tel quel")))
+		return nil
+	}
+	fmt.Fprintf(diag, "%s %s\n", styleAccent.Render("✓"), styleText.Render(fmt.Sprintf(tr("%s rechiffré"), in)))
+	return nil
+}
+
+// editorCommand suit l'usage : $VISUAL, puis $EDITOR, puis l'éditeur que le
+// système garantit. La valeur peut porter des options (« code --wait »).
+func editorCommand() []string {
+	for _, v := range []string{"VISUAL", "EDITOR"} {
+		if f := strings.Fields(os.Getenv(v)); len(f) > 0 {
+			return f
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// runEditor ouvre path dans l'éditeur, sur notre terminal, et attend qu'il se
+// termine. Un éditeur qui sort en erreur ne vaut pas validation : rien n'est
+// alors rechiffré.
+func runEditor(path string) error {
+	ed := editorCommand()
+	cmd := exec.Command(ed[0], append(ed[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// Le terminal envoie Ctrl+C à tout le groupe de processus, donc à nous
+	// aussi : l'éditeur s'en sert, et nous interrompre perdrait la
+	// modification en cours.
+	sigs := []os.Signal{os.Interrupt}
+	foreground.Store(&sigs)
+	defer foreground.Store(nil)
+	if err := cmd.Run(); err != nil {
+		return errorf("l'éditeur %s a échoué, rien n'a été rechiffré : %w", ed[0], err)
+	}
+	return nil
+}
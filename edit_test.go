@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+// avecEditeur fait de script, un shell qui reçoit le clair en $1, l'éditeur
+// de doEdit.
+func avecEditeur(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("éditeur de test en shell")
+	}
+	p := ecrire(t, filepath.Join(t.TempDir(), "editeur"), []byte("#!/bin/sh\n"+script+"\n"))
+	if err := os.Chmod(p, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", p)
+}
+
+func TestDoEdit(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{3}, pkg.SymmetricKeySize)
+	in := ecrire(t, filepath.Join(dir, "notes.txt"), []byte("première ligne\n"))
+	if err := doEncrypt(in, "", pkg.Options{Key: key, Comp: pkg.CompZstd}); err != nil {
+		t.Fatal(err)
+	}
+	enc := in + extension
+	relire := func() string {
+		t.Helper()
+		out := filepath.Join(t.TempDir(), "relu")
+		if err := doDecrypt(enc, out, pkg.Options{Key: key}); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := os.ReadFile(out)
+		return string(b)
+	}
+
+	// L'éditeur ajoute une ligne et note où se trouvait le clair. Chaque
+	// session efface la clé reçue : elle en reçoit une copie.
+	chemin := filepath.Join(dir, "chemin")
+	avecEditeur(t, `echo "$1" > `+chemin+` && echo seconde ligne >> "$1"`)
+	if err := doEdit(enc, pkg.Options{Key: bytes.Clone(key)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := relire(); got != "première ligne\nseconde ligne\n" {
+		t.Errorf("contenu après édition %q", got)
+	}
+	if d, _ := pkg.Inspect(enc); d.Comp != "zstd" {
+		t.Errorf("compression perdue : %+v", d)
+	}
+	clair, _ := os.ReadFile(chemin)
+	if filepath.Base(strings.TrimSpace(string(clair))) != "notes.txt" {
+		t.Errorf("clair nommé %q", clair)
+	}
+	if _, err := os.Stat(filepath.Dir(strings.TrimSpace(string(clair)))); !os.IsNotExist(err) {
+		t.Errorf("clair laissé derrière : %v", err)
+	}
+
+	// Sans changement, ou si l'éditeur échoue, le chiffré n'est pas touché.
+	avant, _ := os.ReadFile(enc)
+	avecEditeur(t, "true")
+	if err := doEdit(enc, pkg.Options{Key: bytes.Clone(key)}); err != nil {
+		t.Fatal(err)
+	}
+	avecEditeur(t, `echo perdu >> "$1"; exit 1`)
+	if err := doEdit(enc, pkg.Options{Key: bytes.Clone(key)}); err == nil || !strings.Contains(err.Error(), "rien n'a été rechiffré") {
+		t.Errorf("échec de l'éditeur : %v", err)
+	}
+	if apres, _ := os.ReadFile(enc); !bytes.Equal(apres, avant) {
+		t.Error("chiffré réécrit sans modification validée")
+	}
+
+	// Les refus passent avant l'éditeur et le secret.
+	avecEditeur(t, "exit 3")
+	if err := doEdit(in, pkg.Options{Key: key}); err == nil || !strings.Contains(err.Error(), extension) {
+		t.Errorf("fichier sans extension : %v", err)
+	}
+	dossier := filepath.Join(dir, "d")
+	if err := os.Mkdir(dossier, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ecrire(t, filepath.Join(dossier, "f"), []byte("x"))
+	if err := doEncrypt(dossier, "", pkg.Options{Key: key}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doEdit(dossier+extension, pkg.Options{Key: key}); err == nil || !strings.Contains(err.Error(), "dossier") {
+		t.Errorf("dossier : %v", err)
+	}
+}
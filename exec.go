@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"chiffremento-cli/pkg"
+)
+
+// Lancement d'une commande avec des secrets.
+//
+// `chiffremento exec -in secrets.env.chto -- make deploy` déchiffre le fichier
+// d'environnement en mémoire, dans un tampon protégé, et lance la commande avec
+// ses variables en plus des nôtres. Le clair ne touche jamais le disque : ni
+// fichier temporaire, ni tube nommé. Le tampon ne protège pas tout : une
+// variable passée par l'environnement y est recopiée en string au lancement,
+// hors de portée de l'effacement. Les valeurs passées avec -as-fd restent dans
+// le tampon, et ne sont même pas dans l'environnement, que d'autres processus
+// du même utilisateur peuvent lire (/proc/PID/environ) : elles arrivent par un
+// tube hérité.
+//
+// chiffremento reste le parent jusqu'au bout : il relaie les signaux et
+// reprend le code de sortie de la commande à son compte, si bien qu'un script
+// ou un agent de build ne voit pas la différence.
+
+// maxEnvFile borne le clair d'un fichier d'environnement : il est déchiffré en
+// mémoire, et un fichier de cette taille n'est sûrement pas le bon.
+const maxEnvFile = 1 << 20
+
+// childExit porte le code de sortie de la commande lancée par exec.
+type childExit struct {
+	code int
+	// err est nil quand la commande s'est terminée d'elle-même : elle a déjà
+	// dit ce qu'elle avait à dire, il n'y a rien à ajouter.
+	err error
+}
+
+func (e childExit) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return fmt.Sprintf(tr("la commande s'est terminée avec le code %d"), e.code)
+}
+
+func (e childExit) Unwrap() error { return e.err }
+
+// fdNames est la liste des variables passées par -as-fd.
+type fdNames []string
+
+func (s *fdNames) String() string { return strings.Join(*s, ",") }
+
+func (s *fdNames) Set(v string) error {
+	if !isEnvName(v) {
+		return errorf("%q n'est pas un nom de variable", v)
+	}
+	if slices.Contains(*s, v) {
+		return errorf("%s donné deux fois", v)
+	}
+	*s = append(*s, v)
+	return nil
+}
+
+// doExec lance args avec les variables du fichier in.
+func doExec(in string, args []string, asFD []string, opts pkg.Options) error {
+	if len(args) == 0 {
+		return errorf("exec attend une commande après -- : chiffremento exec -in secrets.env%s -- COMMANDE [ARGUMENTS]", extension)
+	}
+	if isStream(in) {
+		return errorf("exec lit ses secrets dans un fichier : l'entrée standard reste celle de la commande")
+	}
+	if !strings.HasSuffix(in, extension) {
+		return errorf("un fichier d'environnement chiffré doit porter l'extension %s", extension)
+	}
+	if len(asFD) > 0 && !inheritFDs {
+		return errorf("-as-fd n'est pas disponible sur ce système, qui ne transmet pas de descripteurs aux processus lancés")
+	}
+	d, err := pkg.Inspect(in)
+	if err != nil {
+		return err
+	}
+	if d.Archive {
+		return errorf("c'est un dossier chiffré : exec attend un fichier d'environnement")
+	}
+	if err := checkSecretKind(d, opts); err != nil {
+		return err
+	}
+	if err := pkg.CheckKDFMemory(d.KDFMemoryKiB, opts.MaxKDFMemory); err != nil {
+		return err
+	}
+
+	buf, err := pkg.NewSecureBuffer(maxEnvFile)
+	if err != nil {
+		return err
+	}
+	defer buf.Destroy()
+	var plain []byte
+	err = unlock(secretKind(&d, opts), opts, false, false, func(password []byte, opts pkg.Options) error {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w := &boundedWriter{buf: buf.Bytes()}
+		if err := pkg.DecryptStream(w, f, password, opts); err != nil {
+			// Le clair d'un essai raté n'est pas authentifié : il ne sert
+			// à rien, et n'a pas à traîner jusqu'au suivant.
+			zero(w.buf[:w.n])
+			return err
+		}
+		plain = w.buf[:w.n]
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	vars, err := parseDotenv(in, plain)
+	if err != nil {
+		return err
+	}
+	return runChild(args, vars, asFD, in)
+}
+
+// boundedWriter écrit dans un tampon de taille fixe, et refuse ce qui déborde.
+type boundedWriter struct {
+	buf []byte
+	n   int
+}
+
+func (w *boundedWriter) Write(p []byte) (int, error) {
+	if len(p) > len(w.buf)-w.n {
+		return 0, errorf("plus de %d Kio : ce n'est pas un fichier d'environnement", maxEnvFile>>10)
+	}
+	w.n += copy(w.buf[w.n:], p)
+	return len(p), nil
+}
+
+// runChild lance la commande et attend sa fin, en relayant les signaux.
+func runChild(args []string, vars []envVar, asFD []string, in string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// Un tube par variable : le descripteur 3 pour la première, et ainsi de
+	// suite, annoncé dans NOM_FD.
+	var writers []*os.File
+	var values [][]byte
+	var fdEnv []string
+	defer func() {
+		for _, w := range writers {
+			w.Close()
+		}
+	}()
+	for i, name := range asFD {
+		j := slices.IndexFunc(vars, func(v envVar) bool { return v.name == name })
+		if j < 0 {
+			return errorf("-as-fd %s : %s ne définit pas cette variable", name, in)
+		}
+		if slices.ContainsFunc(vars, func(v envVar) bool { return v.name == name+"_FD" }) {
+			return errorf("-as-fd %s : %s définit déjà %s_FD", name, in, name)
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			return errorf("-as-fd %s : %w", name, err)
+		}
+		defer r.Close()
+		cmd.ExtraFiles = append(cmd.ExtraFiles, r)
+		writers = append(writers, w)
+		values = append(values, vars[j].value)
+		fdEnv = append(fdEnv, fmt.Sprintf("%s_FD=%d", name, 3+i))
+	}
+
+	// Les signaux relayés sont pris avant le lancement : un SIGTERM reçu entre
+	// les deux doit atteindre la commande, pas nous arrêter seuls.
+	relay := make(chan os.Signal, 8)
+	if len(forwardedSignals) > 0 { // sans argument, Notify prendrait tout
+		signal.Notify(relay, forwardedSignals...)
+		defer signal.Stop(relay)
+	}
+	sigs := append([]os.Signal{os.Interrupt}, forwardedSignals...)
+	foreground.Store(&sigs)
+	defer foreground.Store(nil)
+
+	// Les variables du fichier l'emportent sur celles déjà présentes : os/exec
+	// ne garde que la dernière valeur d'un nom. L'environnement d'un processus
+	// est fait de strings : ces valeurs-là sortent du tampon protégé au dernier
+	// moment, en copies qu'on ne peut pas effacer. Celles de -as-fd, écrites
+	// depuis le tampon, y échappent.
+	cmd.Env = os.Environ()
+	for _, v := range vars {
+		if !slices.Contains(asFD, v.name) {
+			cmd.Env = append(cmd.Env, v.name+"="+string(v.value))
+		}
+	}
+	cmd.Env = append(cmd.Env, fdEnv...)
+	err := cmd.Start()
+	cmd.Env = nil // la commande a les siennes : que le GC reprenne les nôtres
+	if err != nil {
+		// Les codes du shell : 127 introuvable, 126 impossible à lancer.
+		code := 126
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			code = 127
+		}
+		return childExit{code: code, err: errorf("lancement de %s : %w", args[0], err)}
+	}
+	// La commande a ses copies des lectures ; les nôtres fermées, elle verra
+	// la fin de chaque tube dès que la valeur y est écrite.
+	for i, w := range writers {
+		cmd.ExtraFiles[i].Close()
+		go func(w *os.File, v []byte) {
+			w.Write(v)
+			w.Close()
+		}(w, values[i])
+	}
+	writers = nil
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	for {
+		select {
+		case sig := <-relay:
+			if !fromKeyboard(sig) {
+				cmd.Process.Signal(sig)
+			}
+		case err := <-done:
+			return childResult(err)
+		}
+	}
+}
+
+// childResult traduit la fin de la commande. Tuée par un signal, elle sort
+// comme dans un shell : 128 plus le numéro du signal.
+func childResult(err error) error {
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		if err != nil {
+			return errorf("attente de la commande : %w", err)
+		}
+		return nil
+	}
+	code := ee.ExitCode()
+	if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		code = 128 + int(ws.Signal())
+	}
+	return reportedError{childExit{code: code}}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+func TestDoExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commandes de test en shell")
+	}
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{5}, pkg.SymmetricKeySize)
+	in := ecrire(t, filepath.Join(dir, "secrets.env"), []byte("JETON=abc\nDB_PASSWORD='mot de passe'\n"))
+	if err := doEncrypt(in, "", pkg.Options{Key: key}); err != nil {
+		t.Fatal(err)
+	}
+	enc := in + extension
+	opts := pkg.Options{Key: key}
+	sortie := filepath.Join(dir, "sortie")
+	lire := func() string {
+		t.Helper()
+		b, err := os.ReadFile(sortie)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	// Les variables du fichier s'ajoutent à l'environnement, et l'emportent.
+	t.Setenv("JETON", "ancien")
+	t.Setenv("CHTO_TEST_GARDE", "oui")
+	if err := doExec(enc, []string{"sh", "-c", `echo "$JETON|$DB_PASSWORD|$CHTO_TEST_GARDE" > ` + sortie}, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := lire(); got != "abc|mot de passe|oui\n" {
+		t.Errorf("environnement %q", got)
+	}
+
+	// -as-fd : la valeur arrive par le descripteur annoncé, pas par l'environnement.
+	script := `printf '%s|' "${DB_PASSWORD-absent}" > ` + sortie + ` && cat /dev/fd/$DB_PASSWORD_FD >> ` + sortie
+	if err := doExec(enc, []string{"sh", "-c", script}, []string{"DB_PASSWORD"}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := lire(); got != "absent|mot de passe" {
+		t.Errorf("descripteur %q", got)
+	}
+	if err := doExec(enc, []string{"true"}, []string{"AUTRE"}, opts); err == nil || !strings.Contains(err.Error(), "ne définit pas") {
+		t.Errorf("-as-fd inconnue : %v", err)
+	}
+
+	// Le code de sortie de la commande devient le nôtre.
+	err := doExec(enc, []string{"sh", "-c", "exit 7"}, nil, opts)
+	if code := exitCode(err); code != 7 {
+		t.Errorf("code %d (%v), attendu 7", code, err)
+	}
+	if _, ok := err.(reportedError); !ok {
+		t.Errorf("la sortie de la commande ne doit pas être réaffichée : %T", err)
+	}
+	err = doExec(enc, []string{"sh", "-c", "kill -TERM $$"}, nil, opts)
+	if code := exitCode(err); code != 128+15 {
+		t.Errorf("commande tuée : code %d (%v)", code, err)
+	}
+	err = doExec(enc, []string{filepath.Join(dir, "introuvable")}, nil, opts)
+	if code := exitCode(err); code != 127 {
+		t.Errorf("commande introuvable : code %d (%v)", code, err)
+	}
+
+	// Les refus passent avant le secret.
+	for _, c := range []struct {
+		in     string
+		args   []string
+		erreur string
+	}{
+		{enc, nil, "attend une commande"},
+		{"-", []string{"true"}, "entrée standard"},
+		{in, []string{"true"}, extension},
+	} {
+		if err := doExec(c.in, c.args, nil, opts); err == nil || !strings.Contains(err.Error(), c.erreur) {
+			t.Errorf("%s %v : %v, attendu %q", c.in, c.args, err, c.erreur)
+		}
+	}
+	trop := ecrire(t, filepath.Join(dir, "gros.env"), bytes.Repeat([]byte("A=1\n"), maxEnvFile/4+1))
+	if err := doEncrypt(trop, "", opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := doExec(trop+extension, []string{"true"}, nil, opts); err == nil || !strings.Contains(err.Error(), "Kio") {
+		t.Errorf("fichier trop gros : %v", err)
+	}
+}
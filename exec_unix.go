@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// inheritFDs : -as-fd passe par les descripteurs hérités (ExtraFiles).
+const inheritFDs = true
+
+// forwardedSignals sont relayés à la commande lancée par exec.
+var forwardedSignals = []os.Signal{syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2}
+
+// fromKeyboard dit si sig a déjà atteint la commande. Au terminal, Ctrl+C et
+// Ctrl+\ vont à tout le groupe de processus au premier plan, dont elle fait
+// partie : les relayer les lui ferait recevoir deux fois, et bien des
+// programmes prennent un second Ctrl+C pour un arrêt forcé.
+func fromKeyboard(sig os.Signal) bool {
+	return (sig == syscall.SIGINT || sig == syscall.SIGQUIT) && term.IsTerminal(int(os.Stdin.Fd()))
+}
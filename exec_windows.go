@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// inheritFDs : Windows ne transmet pas de descripteurs au-delà des trois
+// flux standard, -as-fd y est donc refusé.
+const inheritFDs = false
+
+// forwardedSignals : Windows ne sait envoyer à un processus que Kill. Ctrl+C,
+// lui, atteint déjà tous les processus de la console.
+var forwardedSignals []os.Signal
+
+func fromKeyboard(os.Signal) bool { return true }
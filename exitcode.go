@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"chiffremento-cli/pkg"
+)
+
+// Codes de sortie.
+//
+// Un script doit pouvoir distinguer un mauvais mot de passe d'un fichier
+// abîmé sans lire le message, qui est fait pour un humain. Ces valeurs sont
+// documentées dans le README : les changer casse les scripts des autres.
+const (
+	exitFailure = 1 // toute autre erreur
+	// 2 : option inconnue ou mal formée, posé par le paquet flag lui-même.
+	exitNotFound    = 3   // fichier ou dossier introuvable
+	exitFormat      = 4   // pas un .chto, ou version non supportée
+	exitTruncated   = 5   // fichier tronqué
+	exitAuth        = 6   // échec d'authentification : mauvais secret ou fichier modifié
+	exitArchive     = 7   // archive refusée à l'extraction ou à la vérification
+	exitInterrupted = 130 // interrompu par Ctrl+C ou SIGTERM
+)
+
+// exitCode choisit le code de sortie de err. L'ordre compte : une erreur
+// d'authentification peut envelopper une erreur de lecture, et c'est
+// l'authentification qui intéresse le script ; une commande introuvable pour
+// exec est un os.ErrNotExist, mais son code est celui du shell.
+func exitCode(err error) int {
+	var child childExit
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &child):
+		// exec reprend le code de la commande qu'il a lancée, tel quel.
+		return child.code
+	case errors.Is(err, pkg.ErrCanceled):
+		return exitInterrupted
+	case errors.Is(err, pkg.ErrAuthentication):
+		return exitAuth
+	case errors.Is(err, pkg.ErrTruncated):
+		return exitTruncated
+	case errors.Is(err, pkg.ErrBadMagic), errors.Is(err, pkg.ErrUnsupportedVersion):
+		return exitFormat
+	case errors.Is(err, pkg.ErrUnsafeArchivePath), errors.Is(err, pkg.ErrArchiveRefused):
+		return exitArchive
+	case errors.Is(err, os.ErrNotExist):
+		return exitNotFound
+	}
+	return exitFailure
+}
+
+// exitKind nomme un code de sortie pour le champ error.kind de -json : un
+// identifiant stable, plus lisible qu'un nombre dans un script.
+func exitKind(code int) string {
+	switch code {
+	case exitNotFound:
+		return "not_found"
+	case exitFormat:
+		return "format"
+	case exitTruncated:
+		return "truncated"
+	case exitAuth:
+		return "authentication"
+	case exitArchive:
+		return "archive"
+	case exitInterrupted:
+		return "interrupted"
+	}
+	return "failure"
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+func TestExitCode(t *testing.T) {
+	dir := t.TempDir()
+	in := ecrire(t, filepath.Join(dir, "doc.txt"), bytes.Repeat([]byte("contenu "), 20000))
+	chto := filepath.Join(dir, "doc.chto")
+	avecMotDePasse(t, motDePasseTest)
+	if err := doEncrypt(in, chto, pkg.Options{Algo: pkg.AlgoAES}); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(chto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tronque := ecrire(t, filepath.Join(dir, "tronque.chto"), raw[:len(raw)/2])
+	bidon := ecrire(t, filepath.Join(dir, "bidon.chto"), bytes.Repeat([]byte("X"), 64))
+
+	cases := []struct {
+		name, chto, pw string
+		want           int
+	}{
+		{"mauvais mot de passe", chto, "mauvais mot de passe", exitAuth},
+		{"fichier tronqué", tronque, motDePasseTest, exitTruncated},
+		{"pas un .chto", bidon, motDePasseTest, exitFormat},
+		{"fichier absent", filepath.Join(dir, "absent.chto"), motDePasseTest, exitNotFound},
+	}
+	for _, c := range cases {
+		avecMotDePasse(t, c.pw)
+		err := doDecrypt(c.chto, filepath.Join(dir, "relu.txt"), pkg.Options{})
+		if got := exitCode(err); got != c.want {
+			t.Errorf("%s : code %d, attendu %d (%v)", c.name, got, c.want, err)
+		}
+	}
+
+	// Le reste sans passer par un fichier.
+	for err, want := range map[error]int{
+		nil:                 0,
+		errors.New("autre"): exitFailure,
+		fmt.Errorf("extraction: %w", pkg.ErrUnsafeArchivePath): exitArchive,
+		fmt.Errorf("x: %w", pkg.ErrUnsupportedVersion):         exitFormat,
+		fmt.Errorf("x: %w", pkg.ErrCanceled):                   exitInterrupted,
+	} {
+		if got := exitCode(err); got != want {
+			t.Errorf("%v : code %d, attendu %d", err, got, want)
+		}
+	}
+}
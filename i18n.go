@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"chiffremento-cli/pkg"
+)
+
+// Langue des messages.
+//
+// Les messages sont écrits en français dans le code, à l'endroit où ils
+// servent : ce texte est aussi la clé de sa traduction, comme avec gettext.
+// Une autre langue est un catalogue qui associe chaque texte français à sa
+// traduction, et chaque code d'erreur de pkg (pkg.Error) à la sienne : la
+// bibliothèque ne traduit rien elle-même, elle porte des codes stables.
+//
+// La langue vient de -lang, sinon de LC_ALL, LC_MESSAGES puis LANG, dans
+// l'ordre de POSIX. Une langue sans catalogue, comme la locale C, garde le
+// français.
+//
+// Ajouter une langue : un fichier i18n_xx.go qui déclare son catalogue, et
+// une entrée dans catalogs. TestCatalogues vérifie qu'il ne manque aucune clé.
+
+// sourceLanguage est la langue du code, qui n'a pas besoin de catalogue.
+const sourceLanguage = "fr"
+
+// catalog traduit une langue.
+type catalog struct {
+	messages map[string]string // texte français → traduction
+	errors   map[string]string // code d'erreur de pkg → format traduit
+}
+
+var catalogs = map[string]*catalog{
+	"en": &catalogEN,
+}
+
+// languages liste les langues proposées, celle du code en tête.
+var languages = []string{sourceLanguage, "en"}
+
+// language est la langue en vigueur, fixée par setLanguage avant tout message.
+var language = sourceLanguage
+
+// tr traduit un texte. Un texte absent du catalogue reste en français : un
+// oubli se voit, mais ne casse rien.
+func tr(s string) string {
+	if c := catalogs[language]; c != nil {
+		if t, ok := c.messages[s]; ok {
+			return t
+		}
+	}
+	return s
+}
+
+// errorf est fmt.Errorf sur un format traduit.
+func errorf(format string, args ...any) error {
+	return fmt.Errorf(tr(format), args...)
+}
+
+// sentinel est une erreur de paquet, reconnue par errors.Is. Son texte est
+// traduit à chaque lecture : errorf traduirait à l'initialisation du paquet,
+// avant que la langue ne soit choisie, et le message resterait en français.
+type sentinel string
+
+func (s sentinel) Error() string { return tr(string(s)) }
+
+// setLanguage fixe la langue des messages, ceux de pkg compris.
+func setLanguage(l string) {
+	language = l
+	c := catalogs[l]
+	if c == nil {
+		pkg.SetTranslator(nil)
+		return
+	}
+	pkg.SetTranslator(func(code string) (string, bool) {
+		f, ok := c.errors[code]
+		return f, ok
+	})
+}
+
+// detectLanguage choisit la langue avant l'analyse de la ligne de commande :
+// les descriptions des options en dépendent déjà. -lang est donc repéré à la
+// main dans args. Une valeur inconnue de -lang est rendue dans unknown, pour
+// que l'erreur soit rédigée dans la langue de l'environnement ; une locale
+// inconnue, elle, garde le français sans erreur.
+func detectLanguage(args []string) (l, unknown string) {
+	l = localeLanguage()
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if name != "lang" {
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				break // le parseur signalera la valeur manquante
+			}
+			value = args[i+1]
+		}
+		if !slices.Contains(languages, value) {
+			return l, value
+		}
+		return value, ""
+	}
+	return l, ""
+}
+
+// localeLanguage lit la langue de la locale : fr_FR.UTF-8, en_GB@euro ou en
+// donnent leur premier segment.
+func localeLanguage() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(env)
+		if locale == "" {
+			continue
+		}
+		if i := strings.IndexAny(locale, "_.@"); i >= 0 {
+			locale = locale[:i]
+		}
+		if l := strings.ToLower(locale); slices.Contains(languages, l) {
+			return l
+		}
+		return sourceLanguage
+	}
+	return sourceLanguage
+}
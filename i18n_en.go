@@ -0,0 +1,782 @@
+package main
+
+// catalogEN traduit les messages en anglais.
+var catalogEN = catalog{
+	messages: map[string]string{
+		// main.go
+		"\ninterrompu":                   "\ninterrupted",
+		"    %-10s %-34s %6d Mio  %8s\n": "    %-10s %-34s %6d MiB  %8s\n",
+		`  %-16s %s
+  %-16s %s
+
+-in - lit l'entrée standard, -out - écrit sur la sortie standard : l'outil est
+donc composable. Sur un flux, l'écriture atomique n'existe pas et le clair sort
+avant que la fin du fichier soit authentifiée — à réserver aux tubes.
+
+Le mot de passe n'est jamais passé en argument : il est demandé de façon
+masquée, ou lu sur l'entrée standard si celle-ci n'est pas un terminal. Pour
+l'automatisation, -passfile, -passenv, -passfd et -passcmd le lisent
+ailleurs — indispensables avec -in - sans terminal.
+
+L'ancienne forme, chiffremento -mode COMMANDE [options], reste acceptée avec
+toutes les options ; chiffremento -version affiche la version.
+`: `  %-16s %s
+  %-16s %s
+
+-in - reads standard input, -out - writes to standard output: the tool is
+therefore composable. On a stream, atomic writes do not exist and the plaintext
+comes out before the end of the file is authenticated — keep this for pipes.
+
+The password is never passed as an argument: it is asked for without echo,
+or read from standard input when that is not a terminal. For automation,
+-passfile, -passenv, -passfd and -passcmd read it from elsewhere — required
+with -in - without a terminal.
+
+The old form, chiffremento -mode COMMAND [options], is still accepted with
+all options; chiffremento -version prints the version.
+`,
+		"  %s%-10s %-24s %6d Mio  %8s\n": "  %s%-10s %-24s %6d MiB  %8s\n",
+		"  -kdf auto -kdf-target 500ms calibre argon2id sur cette machine plutôt que de choisir un profil": "  -kdf auto -kdf-target 500ms calibrates argon2id on this machine instead of picking a profile",
+		"  alternatives (-kdf-algo), pour les environnements contraints ou FIPS":                           "  alternatives (-kdf-algo), for constrained or FIPS environments",
+		"  débit de chiffrement":                                                                           "  encryption throughput",
+		"  dérivation de clé (argon2id)":                                                                   "  key derivation (argon2id)",
+		"  la mémoire annoncée sera aussi exigée au déchiffrement":                                         "  the announced memory will also be required for decryption",
+		"  produit par un format v%d : lecture seule, les nouveaux fichiers sont en v%d":                   "  produced by a v%d format: read-only, new files are v%d",
+		"  qui a ce fichier peut tout déchiffrer, et sans lui rien ne se déchiffre : à sauvegarder à part": "  whoever has this file can decrypt everything, and without it nothing decrypts: back it up separately",
+		"%.0f bits, %d mots tirés au hasard · %s hors ligne":                                               "%.0f bits, %d randomly drawn words · %s offline",
+		"%d Mio pour la dérivation":                                                                        "%d MiB for derivation",
+		"%d cœurs logiques":                                                                                "%d logical cores",
+		"%d fichier(s) sur %d n'ont pas pu être mis à niveau":                                              "%d of %d file(s) could not be upgraded",
+		"%d octets": "%d bytes",
+		"%s %d fichier(s), %d à mettre à niveau\n":                                                                         "%s %d file(s), %d to upgrade\n",
+		"%s (%d Mio disponibles ici)":                                                                                      "%s (%d MiB available here)",
+		"%s (dossier, doit ne pas exister)":                                                                                "%s (directory, must not exist)",
+		"%s est un dossier : -r met à niveau tous les %s qu'il contient":                                                   "%s is a directory: add -r to upgrade every %s it contains",
+		"%s format v%d · %s · %s%s\n":                                                                                      "%s format v%d · %s · %s%s\n",
+		"%s ne donne aucun nom de sortie exploitable":                                                                      "%s gives no usable output name",
+		"%s porte déjà l'extension %s : il semble déjà chiffré":                                                            "%s already has the %s extension: it seems to be encrypted already",
+		"%s — %d Mio disponibles ici : indéchiffrable sur cette machine":                                                   "%s — %d MiB available here: cannot be decrypted on this machine",
+		"-aegis s'exclut avec -chacha et -parano : un seul algorithme par fichier":                                         "-aegis excludes -chacha and -parano: one algorithm per file",
+		"-chacha et -parano s'excluent : le mode parano utilise déjà chacha20 en couche externe":                           "-chacha and -parano are exclusive: parano mode already uses chacha20 as the outer layer",
+		"-in est obligatoire":                                                                                              "-in is required",
+		"-json ne vaut qu'en modes info, verify, dec et bench":                                                             "-json only applies to the info, verify, dec and bench modes",
+		"-json occupe la sortie standard : -out - est impossible avec":                                                     "-json takes standard output: -out - is impossible with it",
+		"-key-file remplace la dérivation de mot de passe : les options -kdf* sont sans objet":                             "-key-file replaces password derivation: the -kdf* options do not apply",
+		"-key-file remplace le mot de passe : il s'exclut avec -passfile, -passenv, -passfd et -passcmd":                   "-key-file replaces the password: it excludes -passfile, -passenv, -passfd and -passcmd",
+		"-lang %q inconnue (attendu %s)":                                                                                   "-lang %q unknown (expected %s)",
+		"-max-kdf-mem : %w":                                                                                                "-max-kdf-mem: %w",
+		"-out est obligatoire : où écrire la clé ? (- pour la sortie standard)":                                            "-out is required: where should the key be written? (- for standard output)",
+		"-out est obligatoire quand l'entrée est l'entrée standard":                                                        "-out is required when the input is standard input",
+		"-recovery double un mot de passe : il s'exclut avec -key-file et -shares":                                         "-recovery backs up a password: it excludes -key-file and -shares",
+		"-recovery-code remplace le mot de passe : il s'exclut avec -key-file, -share et les sources de mot de passe":      "-recovery-code replaces the password: it excludes -key-file, -share and the password sources",
+		"-share reconstitue la clé du fichier : il s'exclut avec -key-file et les sources de mot de passe":                 "-share rebuilds the file key: it excludes -key-file and the password sources",
+		"-shares chiffre avec une clé neuve : -key-file, les sources de mot de passe et les options -kdf* sont sans objet": "-shares encrypts with a fresh key: -key-file, the password sources and the -kdf* options do not apply",
+		"-shares et -threshold vont ensemble : combien de parts, et combien pour déchiffrer ?":                             "-shares and -threshold go together: how many shares, and how many to decrypt?",
+		"[COMMANDE]":          "[COMMAND]",
+		"afficher la version": "print the version",
+		"argument inattendu %q : les options qui le suivent ne seraient pas lues (le fichier se donne par -in)": "unexpected argument %q: the options after it would not be read (the file is given with -in)",
+		"aucun argument fourni (l'interface guidée nécessite un terminal)":                                      "no argument given (the guided interface needs a terminal)",
+		"aucun code de secours":                                                           "no recovery code",
+		"aucun fichier %s trouvé dans %s":                                                 "no %s file found in %s",
+		"avec -recovery, afficher aussi le code de secours en QR code":                    "with -recovery, also show the recovery code as a QR code",
+		"avec -shares, nombre de parts nécessaires pour déchiffrer":                       "with -shares, number of shares needed to decrypt",
+		"avec, aegis-256 est en général le plus rapide : c'est là que -aegis se justifie": "with it, aegis-256 is usually the fastest: that is where -aegis pays off",
+		"chiffrement  ": "encryption   ",
+		`chiffremento %s — chiffrement de fichiers et de dossiers
+
+  chiffremento                       interface guidée
+  chiffremento COMMANDE [options]
+
+Commandes :
+`: `chiffremento %s — file and directory encryption
+
+  chiffremento                       guided interface
+  chiffremento COMMAND [options]
+
+Commands:
+`,
+		"chiffrer ou déchiffrer avec ce fichier de clé symétrique plutôt qu'un mot de passe (enc, dec, verify, edit et exec)": "encrypt or decrypt with this symmetric key file instead of a password (enc, dec, verify, edit and exec)",
+		"clé symétrique (-key-file), pas de mot de passe":                                                                     "symmetric key (-key-file), no password",
+		"clé symétrique de 256 bits écrite dans %s (lisible par son seul propriétaire)":                                       "256-bit symmetric key written to %s (readable by you only)",
+		"code de secours (-recovery-code)":                                                                                    "recovery code (-recovery-code)",
+		"compresser les données en zstd avant chiffrement ; en upgrade, recompresser en zstd les anciens fichiers gzip":       "compress data with zstd before encryption; with upgrade, recompress old gzip files with zstd",
+		"compression":        "compression",
+		"compression  ":      "compression  ",
+		"contenu":            "content",
+		"contenu      ":      "content      ",
+		"création de %s: %w": "creating %s: %w",
+		"destination (défaut : entrée + %s en enc, entrée sans l'extension en dec) ; - pour la sortie standard": "destination (default: input + %s with enc, input without the extension with dec); - for standard output",
+		"diffère du nom de sortie ; à renommer si besoin":                                                       "differs from the output name; rename it if needed",
+		"dossier":               "directory",
+		"dossier (archive tar)": "directory (tar archive)",
+		"dossier, empaqueté en tar au fil du chiffrement":                                                                "directory, packed as tar while encrypting",
+		"durée pendant laquelle l'agent garde un secret (agent et agent add)":                                            "how long the agent keeps a secret (agent and agent add)",
+		"déchiffrer avec le code de secours plutôt que le mot de passe, ou - pour le saisir au terminal (dec et verify)": "decrypt with the recovery code instead of the password, or - to type it at the terminal (dec and verify)",
+		"déjà à jour": "already up to date",
+		"en enc, découper la clé du fichier en N parts de Shamir, écrites à côté du chiffré (avec -threshold)":                      "with enc, split the file key into N Shamir shares, written next to the encrypted file (with -threshold)",
+		"en enc, produire aussi un code de secours qui ouvre le fichier sans le mot de passe":                                       "with enc, also produce a recovery code that opens the file without the password",
+		"en exec, passer cette variable par un descripteur hérité, annoncé dans NOM_FD, plutôt que par l'environnement (à répéter)": "with exec, pass this variable through an inherited descriptor, announced in NAME_FD, rather than through the environment (repeatable)",
+		"en genpass, langue des mots : %s":                          "with genpass, language of the words: %s",
+		"en genpass, nombre de mots de la phrase de passe":          "with genpass, number of words in the passphrase",
+		"en keygen, créer une clé symétrique de 256 bits":           "with keygen, create a 256-bit symmetric key",
+		"en upgrade, parcourir le dossier -in et ses sous-dossiers": "with upgrade, walk the -in directory and its subdirectories",
+		"enc (chiffrer), dec (déchiffrer), verify (contrôler), info (inspecter), upgrade (mettre à niveau), edit (modifier), exec (lancer une commande avec des secrets), keygen (créer une clé), genpass (proposer une phrase de passe), breachdb-build (condenser une liste de fuites), agent (garder les secrets en mémoire) ou bench (mesurer)": "enc (encrypt), dec (decrypt), verify (check), info (inspect), upgrade (upgrade), edit (edit), exec (run a command with secrets), keygen (create a key), genpass (suggest a passphrase), breachdb-build (condense a breach list), agent (keep secrets in memory) or bench (measure)",
+		"entropie     ": "entropy      ",
+		"erreur :":      "error:",
+		"fichier      ": "file         ",
+		"fichier d'une part de Shamir (dec, verify, edit et exec, à répéter) ; celles qui manquent sont demandées au terminal": "file holding a Shamir share (dec, verify, edit and exec, repeatable); missing ones are asked for at the terminal",
+		"fichier ou dossier d'entrée, ou - pour l'entrée standard (dossier en mode enc uniquement)":                            "input file or directory, or - for standard input (directory with enc only)",
+		"flux tar sur la sortie standard (à passer à tar)":                                                                     "tar stream on standard output (pipe it to tar)",
+		"format": "format",
+		"info a besoin d'un fichier : l'en-tête d'un flux ne peut pas être relu sans le consommer": "info needs a file: the header of a stream cannot be read again without consuming it",
+		"keygen ne produit que des clés symétriques : -symmetric est requis":                       "keygen only produces symmetric keys: add -symmetric",
+		"langue des messages : %s (défaut : d'après LC_ALL, LC_MESSAGES ou LANG)":                  "message language: %s (default: from LC_ALL, LC_MESSAGES or LANG)",
+		"le fichier d'entrée et le fichier de sortie sont identiques":                              "the input file and the output file are the same",
+		"lecture: %w": "reading: %w",
+		"machine":     "machine",
+		"masquer la taille réelle en ajoutant du remplissage ; s'exclut avec -comp": "hide the real size by adding padding; excludes -comp",
+		"mesure impossible : %v": "measurement impossible: %v",
+		"mode inconnu %q (attendu enc, dec, verify, info, upgrade, edit, exec, keygen, genpass, breachdb-build, agent ou bench)": "unknown mode %q (expected enc, dec, verify, info, upgrade, edit, exec, keygen, genpass, breachdb-build, agent or bench)",
+		"mode parano : double chiffrement en cascade (chacha20 + aes), plus lent":                                                "parano mode: double cascade encryption (chacha20 + aes), slower",
+		"mémoire":       "memory",
+		"métadonnées":   "metadata",
+		"métadonnées  ": "metadata     ",
+		"métadonnées conservées dans le chiffré : none (défaut) ou minimal (nom et date)": "metadata kept in the encrypted file: none (default) or minimal (name and date)",
+		"ne pas consulter l'agent, toujours demander le mot de passe":                     "do not ask the agent, always prompt for the password",
+		"nom d'origine": "original name",
+		"nom d'origine et date conservés dans le chiffré": "original name and date kept in the encrypted file",
+		"non": "no",
+		"note : -as-fd n'a d'effet qu'en mode exec, il est ignoré":                                                                       "note: -as-fd only applies to exec, it is ignored",
+		"note : -breach-db, -policy, -min-* et -banned-words n'ont d'effet qu'en mode enc et agent add, ils sont ignorés":                "note: -breach-db, -policy, -min-* and -banned-words only apply to enc and agent add, they are ignored",
+		"note : -comp, -pad, -chacha, -parano, -aegis, -meta et les options -kdf* n'ont d'effet qu'en mode enc, elles sont ignorées ici": "note: -comp, -pad, -chacha, -parano, -aegis, -meta and the -kdf* options only apply to enc, they are ignored here",
+		"note : -key-file n'a d'effet qu'en modes enc, dec, verify, edit et exec, il est ignoré":                                         "note: -key-file only applies to enc, dec, verify, edit and exec, it is ignored",
+		"note : -max-kdf-mem n'a d'effet qu'en modes dec, verify, upgrade, edit et exec, il est ignoré":                                  "note: -max-kdf-mem only applies to dec, verify, upgrade, edit and exec, it is ignored",
+		"note : -out n'a pas d'effet en mode %s, il est ignoré":                                                                          "note: -out has no effect with %s, it is ignored",
+		"note : -pad, -chacha, -parano, -aegis et -meta n'ont pas d'effet en mode upgrade : l'algorithme et le contenu sont conservés":   "note: -pad, -chacha, -parano, -aegis and -meta have no effect with upgrade: the algorithm and the content are kept",
+		"note : -preset n'a d'effet qu'en modes enc, dec, verify, upgrade, edit, exec, agent, genpass et config, il est ignoré":          "note: -preset only applies to enc, dec, verify, upgrade, edit, exec, agent, genpass and config, it is ignored",
+		"note : -qr n'a d'effet qu'avec -recovery, il est ignoré":                                                                        "note: -qr only applies with -recovery, it is ignored",
+		"note : -r n'a d'effet qu'en mode upgrade, il est ignoré":                                                                        "note: -r only applies to upgrade, it is ignored",
+		"note : -recovery n'a d'effet qu'en mode enc, il est ignoré":                                                                     "note: -recovery only applies to enc, it is ignored",
+		"note : -recovery-code n'a d'effet qu'en modes dec et verify, il est ignoré":                                                     "note: -recovery-code only applies to dec and verify, it is ignored",
+		"note : -share n'a d'effet qu'en modes dec, verify, edit et exec, il est ignoré":                                                 "note: -share only applies to dec, verify, edit and exec, it is ignored",
+		"note : -shares et -threshold n'ont d'effet qu'en mode enc, ils sont ignorés":                                                    "note: -shares and -threshold only apply to enc, they are ignored",
+		"note : -symmetric n'a d'effet qu'en mode keygen, il est ignoré":                                                                 "note: -symmetric only applies to keygen, it is ignored",
+		"note : -words et -wordlist n'ont d'effet qu'en mode genpass, ils sont ignorés":                                                  "note: -words and -wordlist only apply to genpass, they are ignored",
+		"oui, nom et date à l'intérieur du chiffré":                                                                                      "yes, name and date inside the encrypted file",
+		"oui, taille réelle masquée": "yes, real size hidden",
+		"parcours de %s: %w":         "walking %s: %w",
+		"refuser les fichiers dont la dérivation exige plus que cette mémoire, par exemple 512MiB (dec, verify, upgrade, edit et exec)":       "refuse files whose derivation needs more than this memory, for example 512MiB (dec, verify, upgrade, edit and exec)",
+		"refuser un nouveau mot de passe présent dans cette liste de fuites, construite par breachdb-build (enc et agent add ; défaut : $%s)": "refuse a new password found in this breach list, built by breachdb-build (enc and agent add; default: $%s)",
+		"remplissage":   "padding",
+		"remplissage  ": "padding      ",
+		"rendre compte en JSON sur la sortie standard, sans rien d'autre (info, verify, dec et bench)": "report as JSON on standard output, and nothing else (info, verify, dec and bench)",
+		"sans accélération AES matérielle, chacha20 passe devant : c'est là que -chacha se justifie ;": "without AES hardware acceleration, chacha20 comes first: that is where -chacha pays off;",
+		"script de complétion pour bash, zsh ou fish":                                                  "completion script for bash, zsh or fish",
+		"secours":                             "recovery",
+		"secret":                              "secret",
+		"sortie       ":                       "output       ",
+		"taille":                              "size",
+		"taille arrondie au palier supérieur": "size rounded up to the next step",
+		"taille masquée":                      "size hidden",
+		"un fichier à déchiffrer doit porter l'extension %s":         "a file to decrypt must have the %s extension",
+		"un fichier à mettre à niveau doit porter l'extension %s":    "a file to upgrade must have the %s extension",
+		"un fichier à vérifier doit porter l'extension %s":           "a file to verify must have the %s extension",
+		"une commande est obligatoire : chiffremento help les liste": "a command is required: chiffremento help lists them",
+		"upgrade      ": "upgrade      ",
+		"upgrade réécrit les fichiers en place : il a besoin d'un chemin, pas d'un flux": "upgrade rewrites files in place: it needs a path, not a stream",
+		"utiliser AEGIS-256 : plus rapide qu'AES-GCM avec AES-NI, clé engagée":           "use AEGIS-256: faster than AES-GCM with AES-NI, key-committing",
+		"utiliser ChaCha20-Poly1305 au lieu d'AES-GCM":                                   "use ChaCha20-Poly1305 instead of AES-GCM",
+		"échec":                        "failed",
+		"écrit sur la sortie standard": "written to standard output",
+
+		// commands.go
+		"\nExemples :": "\nExamples:",
+		"\nOptions :":  "\nOptions:",
+		`Le clair est déchiffré dans un fichier privé, en mémoire quand le système en offre
+une ($XDG_RUNTIME_DIR, /dev/shm), puis ouvert dans $VISUAL ou $EDITOR. Si l'éditeur
+se termine sans erreur et que le contenu a changé, le fichier est rechiffré en place
+avec ses paramètres d'origine. Le clair est supprimé dans tous les cas.`: `The plaintext is decrypted into a private file, in memory when the system offers
+a place for it ($XDG_RUNTIME_DIR, /dev/shm), then opened in $VISUAL or $EDITOR. If the
+editor exits without error and the content changed, the file is re-encrypted in place
+with its original parameters. The plaintext is removed in every case.`,
+		`Le fichier, au format .env (NOM=valeur), est déchiffré en mémoire sans toucher le
+disque ; ses variables s'ajoutent à l'environnement de la commande. -as-fd NOM passe
+une valeur par un descripteur hérité, dont le numéro est dans NOM_FD. Les signaux
+sont relayés à la commande, et son code de sortie devient celui de chiffremento.`: `The file, in .env format (NAME=value), is decrypted in memory without touching the
+disk; its variables are added to the command's environment. -as-fd NAME passes a
+value through an inherited descriptor, whose number is in NAME_FD. Signals are
+relayed to the command, and its exit code becomes chiffremento's.`,
+		`Les fichiers v1 et v2 sont réécrits, comme les v3 compressés en gzip ; les autres
+sont déjà à jour. La réécriture se fait en place, sans que le clair touche le disque,
+et suit les liens symboliques. Un seul mot de passe est demandé pour tout le lot.`: `v1 and v2 files are rewritten, as are v3 files compressed with gzip; the others
+are already up to date. Files are rewritten in place, without the plaintext touching
+the disk, following symbolic links. A single password is asked for the whole batch.`,
+		`Les valeurs par défaut et les préréglages viennent de ~/.config/chiffremento/config.toml
+($XDG_CONFIG_HOME, ou $CHTO_CONFIG pour un autre fichier). Chaque réglage est suivi de
+son origine : option, préréglage, configuration, variable d'environnement ou défaut.`: `Defaults and presets come from ~/.config/chiffremento/config.toml
+($XDG_CONFIG_HOME, or $CHTO_CONFIG for another file). Each setting is followed by
+its origin: option, preset, configuration, environment variable or default.`,
+		`Sans -out, la sortie reprend le nom de l'entrée sans .chto. Un dossier
+est recréé à l'identique ; sur -out -, il sort en tar.`: `Without -out, the output takes the input name without .chto. A directory
+is recreated as it was; with -out -, it comes out as tar.`,
+		`Sans action, lance l'agent. dec et verify essaient ses secrets avant de demander
+quoi que ce soit ; enc prend son mot de passe s'il est seul.`: `Without an action, starts the agent. dec and verify try its secrets before asking
+for anything; enc takes its password if it is the only one.`,
+		`Tout est déchiffré et authentifié, une archive est parcourue comme à l'extraction,
+mais rien n'est écrit sur le disque.`: `Everything is decrypted and authenticated, an archive is walked as if extracted,
+but nothing is written to disk.`,
+		`Un dossier est empaqueté en tar au fil du chiffrement. Sans -out, le chiffré
+est écrit à côté de l'entrée, avec l'extension .chto.
+
+-breach-db refuse, hors ligne, un nouveau mot de passe présent dans une liste
+condensée par breachdb-build. -policy, -min-score, -min-entropy, -min-length et
+-banned-words imposent une politique, en plus de celle du système (voir -policy).`: `A directory is packed as tar while it is encrypted. Without -out, the encrypted
+file is written next to the input, with the .chto extension.
+
+-breach-db refuses, offline, a new password found in a list condensed by
+breachdb-build. -policy, -min-score, -min-entropy, -min-length and
+-banned-words enforce a policy, on top of the system one (see -policy).`,
+		"afficher l'en-tête d'un chiffré, sans mot de passe":                       "show the header of an encrypted file, without a password",
+		"afficher les réglages en vigueur et leur origine":                         "show the settings in effect and where they come from",
+		"chiffremento agent [add [NOM]|list|lock|stop] [options]":                  "chiffremento agent [add [NAME]|list|lock|stop] [options]",
+		"chiffremento bench [-json]":                                               "chiffremento bench [-json]",
+		"chiffremento breachdb-build -in pwned.txt -out fuites.db":                 "chiffremento breachdb-build -in pwned.txt -out breaches.db",
+		"chiffremento config [-preset NOM] [options]":                              "chiffremento config [-preset NAME] [options]",
+		"chiffremento dec -in FICHIER.chto|- [-out CHEMIN|-] [options]":            "chiffremento dec -in FILE.chto|- [-out PATH|-] [options]",
+		"chiffremento edit -in FICHIER.chto [options]":                             "chiffremento edit -in FILE.chto [options]",
+		"chiffremento enc -in FICHIER|DOSSIER|- [-out CHEMIN] [options]":           "chiffremento enc -in FILE|DIRECTORY|- [-out PATH] [options]",
+		"chiffremento exec -in FICHIER.env.chto [options] -- COMMANDE [ARGUMENTS]": "chiffremento exec -in FILE.env.chto [options] -- COMMAND [ARGUMENTS]",
+		"chiffremento genpass [-words 6] [-wordlist fr|en]":                        "chiffremento genpass [-words 6] [-wordlist fr|en]",
+		"chiffremento info -in FICHIER.chto [-json]":                               "chiffremento info -in FILE.chto [-json]",
+		"chiffremento keygen -symmetric -out FICHIER":                              "chiffremento keygen -symmetric -out FILE",
+		"chiffremento upgrade -in FICHIER.chto|DOSSIER [-r] [options]":             "chiffremento upgrade -in FILE.chto|DIRECTORY [-r] [options]",
+		"chiffremento verify -in FICHIER.chto|- [options]":                         "chiffremento verify -in FILE.chto|- [options]",
+		"commande inconnue %q : chiffremento help liste les commandes":             "unknown command %q: chiffremento help lists the commands",
+		"condenser une liste de fuites Have I Been Pwned":                          "condense a Have I Been Pwned breach list",
+		"contrôler un chiffré sans rien écrire":                                    "check an encrypted file without writing anything",
+		"créer un fichier de clé symétrique":                                       "create a symmetric key file",
+		"déchiffrer un fichier ou restaurer un dossier":                            "decrypt a file or restore a directory",
+		"dérivation":                    "derivation",
+		"extraction":                    "extraction",
+		"garder les secrets en mémoire": "keep secrets in memory",
+		"lancer une commande avec les secrets d'un fichier d'environnement chiffré": "run a command with the secrets of an encrypted environment file",
+		"mesurer les coûts sur cette machine":                                       "measure costs on this machine",
+		"modifier un fichier chiffré dans l'éditeur":                                "edit an encrypted file in the editor",
+		"proposer une phrase de passe":                                              "suggest a passphrase",
+		"réécrire les anciens fichiers au format courant":                           "rewrite old files in the current format",
+		"écriture": "writing",
+
+		// completion.go
+		"completion attend un shell : %s":          "completion expects a shell: %s",
+		"options et exemples d'une commande":       "options and examples for a command",
+		"script de complétion pour le shell":       "completion script for the shell",
+		"shell %q non pris en charge (attendu %s)": "shell %q not supported (expected %s)",
+
+		// config.go
+		"%s, ligne %d : %s : %w":                                                                "%s, line %d: %s: %w",
+		"%s, ligne %d : %s : booléen attendu":                                                   "%s, line %d: %s: boolean expected",
+		"%s, ligne %d : %s défini deux fois":                                                    "%s, line %d: %s defined twice",
+		"%s, ligne %d : %w":                                                                     "%s, line %d: %w",
+		"%s, ligne %d : clé inconnue %q":                                                        "%s, line %d: unknown key %q",
+		"%s, ligne %d : préréglage %q défini deux fois":                                         "%s, line %d: preset %q defined twice",
+		"%s, ligne %d : table inconnue %s (attendu [preset.NOM])":                               "%s, line %d: unknown table %s (expected [preset.NAME])",
+		"%s, ligne %d : « clé = valeur » attendu":                                               "%s, line %d: “key = value” expected",
+		"-preset %s : %s ne définit aucun préréglage":                                           "-preset %s: %s defines no preset",
+		"-preset %s : aucun fichier de configuration (%s)":                                      "-preset %s: no configuration file (%s)",
+		"-preset %s inconnu (dans %s : %s)":                                                     "-preset %s unknown (in %s: %s)",
+		"appliquer ce préréglage du fichier de configuration ; les options données l'emportent": "apply this preset from the configuration file; options given on the command line take precedence",
+		"aucun (%s n'existe pas)":                                                               "none (%s does not exist)",
+		"chaîne sans apostrophe fermante":                                                       "string without closing apostrophe",
+		"chaîne sans guillemet fermant":                                                         "string without closing quote",
+		"configuration":                                                                         "configuration",
+		"défaut":                                                                                "default",
+		"fichier":                                                                               "file",
+		"lecture de %s: %w":                                                                     "reading %s: %w",
+		"option":                                                                                "option",
+		"préréglage %s":                                                                         "preset %s",
+		"préréglages":                                                                           "presets",
+		"texte inattendu après la chaîne : %q":                                                  "unexpected text after the string: %q",
+		"valeur %q : une chaîne s'écrit entre guillemets":                                       "value %q: a string is written between quotes",
+		"valeur manquante":                                                                      "missing value",
+		"échappement \\%c non pris en charge":                                                   "escape \\%c not supported",
+
+		// agent.go
+		"  secrets oubliés après %s ; chiffremento agent stop ou Ctrl+C pour l'arrêter": "  secrets forgotten after %s; chiffremento agent stop or Ctrl+C to stop it",
+		"%s confié à l'agent":          "%s handed to the agent",
+		"%s « %s », de l'agent":        "%s “%s”, from the agent",
+		"-agent-ttl doit être positif": "-agent-ttl must be positive",
+		"action d'agent inconnue %q (attendu start, add, list, lock ou stop)": "unknown agent action %q (expected start, add, list, lock or stop)",
+		"agent : %s": "agent: %s",
+		"agent refusé, aucun secret échangé : %v": "agent refused, no secret exchanged: %v",
+		"agent : « %s » ne convient pas (%v)":     "agent: “%s” does not fit (%v)",
+		"agent arrêté, secrets oubliés":           "agent stopped, secrets forgotten",
+		"agent à l'écoute sur %s":                 "agent listening on %s",
+		"agent: %w":                               "agent: %w",
+		"argument en trop pour agent %s : %q":     "extra argument for agent %s: %q",
+		"argument en trop pour agent add : %q":    "extra argument for agent add: %q",
+		"attention : verrouillage mémoire refusé (RLIMIT_MEMLOCK ?) ; les secrets peuvent partir dans le swap": "warning: memory locking refused (RLIMIT_MEMLOCK?); secrets may end up in swap",
+		"aucun agent ne tourne : chiffremento agent pour en démarrer un":                                       "no agent is running: chiffremento agent starts one",
+		"aucune clé de l'agent n'ouvre ce fichier : il faut -key-file ou -share":                               "no agent key opens this file: use -key-file or -share",
+		"ce fichier est chiffré par clé symétrique : il faut -key-file ou -share":                              "this file is encrypted with a symmetric key: use -key-file or -share",
+		"clé":                               "key",
+		"clé de %d octets (attendu %d)":     "key of %d bytes (expected %d)",
+		"dossier de l'agent: %w":            "agent directory: %w",
+		"l'agent a oublié tous ses secrets": "the agent forgot all its secrets",
+		"l'agent détient déjà %d secrets : lock pour faire de la place": "the agent already holds %d secrets: lock to make room",
+		"l'agent ne détient aucun secret":                               "the agent holds no secret",
+		"mot de passe":                                                  "password",
+		"mot de passe ":                                                 "password     ",
+		"note : %v":                                                     "note: %v",
+		"note : -key-file n'a d'effet qu'avec agent add, il est ignoré": "note: -key-file only applies to agent add, it is ignored",
+		"opération inconnue %q":            "unknown operation %q",
+		"oublié dans %s":                   "forgotten in %s",
+		"requête illisible":                "unreadable request",
+		"réponse de l'agent illisible: %w": "unreadable agent response: %w",
+		"secret       ":                    "secret       ",
+		"secret vide":                      "empty secret",
+		"socket de l'agent: %w":            "agent socket: %w",
+		"sorte de secret inconnue %q":      "unknown kind of secret %q",
+		"un agent tourne déjà sur %s":      "an agent is already running on %s",
+		"« %s », de l'agent":               "“%s”, from the agent",
+
+		// agent_unix.go
+		"%s appartient à un autre utilisateur":                            "%s belongs to another user",
+		"%s est accessible à d'autres utilisateurs (%04o) ; chmod 700 %s": "%s is accessible to other users (%04o); chmod 700 %s",
+		"%s n'est pas un dossier":                                         "%s is not a directory",
+		"%s n'est pas une socket":                                         "%s is not a socket",
+		"%s est accessible à d'autres utilisateurs (%04o)":                "%s is accessible to other users (%04o)",
+		"l'agent tourne sous un autre utilisateur (uid %d)":               "the agent runs as another user (uid %d)",
+
+		// kdfflags.go
+		"%s %s, ~%s sur cette machine (visé : %s)\n": "%s %s, ~%s on this machine (target: %s)\n",
+		"%s (sur mesure)": "%s (custom)",
+		"-kdf auto, -kdf-mem, -kdf-time et -kdf-threads règlent Argon2id, pas %s": "-kdf auto, -kdf-mem, -kdf-time and -kdf-threads tune Argon2id, not %s",
+		"-kdf-max-mem : %w":                               "-kdf-max-mem: %w",
+		"-kdf-mem : %w":                                   "-kdf-mem: %w",
+		"-kdf-threads hors bornes : %d":                   "-kdf-threads out of range: %d",
+		"-kdf-time hors bornes : %d":                      "-kdf-time out of range: %d",
+		"avec -kdf auto, durée visée pour une dérivation": "with -kdf auto, target duration of one derivation",
+		"avec -kdf auto, mémoire maximale de la dérivation (exigée aussi au déchiffrement)": "with -kdf auto, maximum memory of the derivation (also required for decryption)",
+		"calibration  ": "calibration  ",
+		"clé symétrique, sans dérivation de mot de passe":                                                                           "symmetric key, no password derivation",
+		"coût de la dérivation de clé : standard (défaut), fort, maximum, ou auto pour calibrer sur cette machine (enc et upgrade)": "key derivation cost: standard (default), fort, maximum, or auto to calibrate on this machine (enc and upgrade)",
+		"fonction de dérivation : argon2id (défaut), scrypt ou pbkdf2 (enc et upgrade)":                                             "derivation function: argon2id (default), scrypt or pbkdf2 (enc and upgrade)",
+		"mémoire d'Argon2id, par exemple 512MiB ; remplace celle du profil":                                                         "Argon2id memory, for example 512MiB; overrides the profile's",
+		"nombre de passes d'Argon2id ; remplace celui du profil":                                                                    "number of Argon2id passes; overrides the profile's",
+		"note : -kdf-target et -kdf-max-mem n'ont d'effet qu'avec -kdf auto, ils sont ignorés":                                      "note: -kdf-target and -kdf-max-mem only apply with -kdf auto, they are ignored",
+		"parallélisme d'Argon2id ; remplace celui du profil":                                                                        "Argon2id parallelism; overrides the profile's",
+		"taille mémoire invalide %q (exemples : 256MiB, 1GiB)":                                                                      "invalid memory size %q (examples: 256MiB, 1GiB)",
+		"taille mémoire trop grande":                                                                                                "memory size too large",
+		"unité de mémoire inconnue %q (attendu KiB, MiB ou GiB)":                                                                    "unknown memory unit %q (expected KiB, MiB or GiB)",
+
+		// passsource.go
+		"attention : %s est lisible par d'autres utilisateurs (%04o) ; chmod 600 %s":                 "warning: %s is readable by other users (%04o); chmod 600 %s",
+		"-passfd 0 est l'entrée standard, qui porte déjà les données : il faut un autre descripteur": "-passfd 0 is standard input, which already carries the data: use another descriptor",
+		"-passfile, -passenv, -passfd et -passcmd s'excluent : une seule source de mot de passe":     "-passfile, -passenv, -passfd and -passcmd are exclusive: a single password source",
+		"ce fichier est protégé par mot de passe : ni -key-file ni -share ne l'ouvriront":            "this file is protected by a password: neither -key-file nor -share will open it",
+		"ce fichier n'a pas de code de secours : il s'ouvre avec son mot de passe":                   "this file has no recovery code: it opens with its password",
+		"descripteur %d":                                                "descriptor %d",
+		"descripteur %d invalide":                                       "invalid descriptor %d",
+		"la commande a échoué : %w":                                     "the command failed: %w",
+		"la variable d'environnement %s n'est pas définie":              "the environment variable %s is not set",
+		"lire le mot de passe dans cette variable d'environnement":      "read the password from this environment variable",
+		"lire le mot de passe dans la première ligne de ce fichier":     "read the password from the first line of this file",
+		"lire le mot de passe sur ce descripteur de fichier hérité":     "read the password from this inherited file descriptor",
+		"lire le mot de passe sur la sortie standard de cette commande": "read the password from the standard output of this command",
+		"mot de passe (%s) : %w":                                        "password (%s): %w",
+		"mot de passe vide (%s)":                                        "empty password (%s)",
+		"plus de %d Kio : ce n'est pas un mot de passe":                 "more than %d KiB: this is not a password",
+
+		// secureinput.go
+		"lecture du mot de passe: %w":       "reading the password: %w",
+		"mot de passe de plus de %d Kio":    "password longer than %d KiB",
+		"saisie du mot de passe abandonnée": "password entry abandoned",
+		"saisie interrompue":                "input interrupted",
+
+		// policy.go
+		"%.0f bits":                        "%.0f bits",
+		"%d caractères":                    "%d characters",
+		"%d mots interdits":                "%d banned words",
+		"%s, ligne %d : règle inconnue %q": "%s, line %d: unknown rule %q",
+		"au moins %s":                      "at least %s",
+		"entropie minimale invalide : %v":  "invalid minimum entropy: %v",
+		"fichier de politique de mots de passe, en plus de %s (enc et agent add ; défaut : $%s)": "password policy file, on top of %s (enc and agent add; default: $%s)",
+		"longueur minimale invalide : %d":                                                  "invalid minimum length: %d",
+		"politique de mots de passe : au moins %.0f bits d'entropie estimée (~%.0f ici)":   "password policy: at least %.0f bits of estimated entropy (~%.0f here)",
+		"politique de mots de passe : au moins %d caractères (%d ici)":                     "password policy: at least %d characters (%d here)",
+		"politique de mots de passe : score zxcvbn d'au moins %d sur %d (%d ici)":          "password policy: zxcvbn score of at least %d out of %d (%d here)",
+		"politique de mots de passe : « %s » est interdit dans un mot de passe":            "password policy: “%s” is banned in a password",
+		"refuser un nouveau mot de passe plus court que ce nombre de caractères":           "refuse a new password shorter than this number of characters",
+		"refuser un nouveau mot de passe qui contient un mot de ce fichier (un par ligne)": "refuse a new password containing a word from this file (one per line)",
+		"refuser un nouveau mot de passe sous ce score zxcvbn, de 0 à 4":                   "refuse a new password below this zxcvbn score, from 0 to 4",
+		"refuser un nouveau mot de passe sous cette entropie estimée, en bits":             "refuse a new password below this estimated entropy, in bits",
+		"score %d/%d": "score %d/%d",
+		"score zxcvbn minimal hors bornes : %d (attendu 0..%d)": "minimum zxcvbn score out of range: %d (expected 0..%d)",
+
+		// breach.go
+		"%s %d empreintes, %s\n": "%s %d hashes, %s\n",
+		"attention : ce mot de passe figure dans la liste de fuites ; il est utilisé quand même": "warning: this password is in the breach list; it is used anyway",
+		"breachdb-build lit -in (la liste téléchargée) et écrit -out, deux fichiers":             "breachdb-build reads -in (the downloaded list) and writes -out, two files",
+		"ce mot de passe figure dans la liste de fuites : il faut en choisir un autre":           "this password is in the breach list: choose another one",
+		"lecture      ":        "reading      ",
+		"liste        ":        "list         ",
+		"liste de fuites : %w": "breach list: %w",
+
+		// shares.go
+		"  chaque part est à remettre à une personne différente, puis à effacer d'ici": "  give each share to a different person, then delete them from here",
+		`# chiffremento — part %d sur %d de %s ; %d parts réunies la déchiffrent.
+# Seule, cette part ne révèle rien. À remettre à une seule personne.
+%s
+`: `# chiffremento — share %d of %d of %s; %d shares together decrypt it.
+# On its own, this share reveals nothing. Give it to a single person.
+%s
+`,
+		"%d part(s) sur %d : les autres sont à coller une à une":                                          "%d of %d share(s): paste the others one at a time",
+		"%d parts écrites (%s.share-1 à -%d), %d suffisent à déchiffrer":                                  "%d shares written (%s.share-1 to -%d), %d are enough to decrypt",
+		"%s existe déjà : une part n'est jamais écrasée":                                                  "%s already exists: a share is never overwritten",
+		"%w (%v) : les parts se donnent par -share":                                                       "%w (%v): give the shares with -share",
+		"%w : les parts se donnent par -share":                                                            "%w: give the shares with -share",
+		"-shares écrit les parts à côté du chiffré : il faut un -out fichier":                             "-shares writes the shares next to the encrypted file: a file -out is required",
+		"aucun terminal pour saisir les parts":                                                            "no terminal to type the shares",
+		"ce fichier est chiffré par clé : ses parts sont à coller une à une (ligne vide pour abandonner)": "this file is encrypted with a key: paste its shares one at a time (empty line to give up)",
+		"création de la part: %w":                                                                         "creating the share: %w",
+		"lecture de la part: %w":                                                                          "reading the share: %w",
+		"part %d :":                                                                                       "share %d:",
+		"saisie des parts abandonnée":                                                                     "share entry abandoned",
+		"écriture de la part: %w":                                                                         "writing the share: %w",
+
+		// recovery.go
+		"aucun terminal pour saisir le code de secours":      "no terminal to type the recovery code",
+		"aucun terminal pour saisir le code de secours (%v)": "no terminal to type the recovery code (%v)",
+		"code de secours :": "recovery code:",
+		"code de secours — à imprimer ou recopier, puis à ranger loin du fichier": "recovery code — print or copy it, then store it away from the file",
+		"forme compacte": "compact form",
+		"il ouvre le fichier sans mot de passe (-recovery-code) ; il ne sera plus affiché": "it opens the file without a password (-recovery-code); it will not be shown again",
+		"lecture du code de secours: %w":                                                 "reading the recovery code: %w",
+		"saisie du code de secours abandonnée":                                           "recovery code entry abandoned",
+		"saisie des dix-huit mots, ou de la forme compacte (ligne vide pour abandonner)": "type the eighteen words, or the compact form (empty line to give up)",
+
+		// qrcode.go
+		"trop long pour un QR code de version 3": "too long for a version 3 QR code",
+
+		// progress.go
+		"%.1f %co": "%.1f %cB",
+		"%d o":     "%d B",
+		"entrée":   "input",
+		"sel":      "salt",
+		"sortie":   "output",
+
+		// progressevents.go
+		"%s trouvés": "%s found",
+		"-progress %q inconnu (attendu ndjson ou plain)":                                             "-progress %q unknown (expected ndjson or plain)",
+		"-progress-fd 0 est l'entrée standard : il faut un descripteur ouvert en écriture":           "-progress-fd 0 is standard input: choose a descriptor open for writing",
+		"-progress-fd 1 : la sortie standard porte déjà les données ou le JSON":                      "-progress-fd 1: standard output already carries the data or the JSON",
+		"-progress-fd : descripteur %d invalide":                                                     "-progress-fd: invalid descriptor %d",
+		"-progress-fd : descripteur %d invalide (%w)":                                                "-progress-fd: invalid descriptor %d (%w)",
+		"descripteur hérité qui reçoit les événements de -progress (défaut : 2, la sortie d'erreur)": "inherited descriptor that receives the -progress events (default: 2, standard error)",
+		"inventaire": "scan",
+		"note : -progress n'a d'effet qu'en modes enc, dec, verify et upgrade, il est ignoré":                                                                  "note: -progress only applies to enc, dec, verify and upgrade, it is ignored",
+		"note : -progress-fd n'a d'effet qu'avec -progress, il est ignoré":                                                                                     "note: -progress-fd only applies with -progress, it is ignored",
+		"suivre l'opération par des événements périodiques : ndjson (une ligne JSON par événement) ou plain (une ligne lisible) ; enc, dec, verify et upgrade": "follow the operation through periodic events: ndjson (one JSON line per event) or plain (one readable line); enc, dec, verify and upgrade",
+
+		// edit.go
+		"%s rechiffré": "%s re-encrypted",
+		"aucun changement : le fichier est laissé tel quel":                                             "no change: the file is left as it was",
+		"edit a besoin d'un fichier : un flux ne peut pas être réécrit en place":                        "edit needs a file: a stream cannot be rewritten in place",
+		"l'éditeur %s a échoué, rien n'a été rechiffré : %w":                                            "the editor %s failed, nothing was re-encrypted: %w",
+		"note : pas de système de fichiers en mémoire ici, le clair passe par %s le temps de l'édition": "note: no in-memory file system here, the plaintext goes through %s while editing",
+		"un fichier à modifier doit porter l'extension %s":                                              "a file to edit must have the %s extension",
+
+		// dotenv.go
+		"%s, ligne %d : « NOM=valeur » attendu": "%s, line %d: “NAME=value” expected",
+
+		// exec.go
+		"%q n'est pas un nom de variable":              "%q is not a variable name",
+		"%s donné deux fois":                           "%s given twice",
+		"-as-fd %s : %s définit déjà %s_FD":            "-as-fd %s: %s already defines %s_FD",
+		"-as-fd %s : %s ne définit pas cette variable": "-as-fd %s: %s does not define this variable",
+		"-as-fd %s : %w":                               "-as-fd %s: %w",
+		"-as-fd n'est pas disponible sur ce système, qui ne transmet pas de descripteurs aux processus lancés": "-as-fd is not available on this system, which does not pass descriptors to the processes it starts",
+		"attente de la commande : %w":                                                                     "waiting for the command: %w",
+		"c'est un dossier chiffré : exec attend un fichier d'environnement":                               "this is an encrypted directory: exec expects an environment file",
+		"exec attend une commande après -- : chiffremento exec -in secrets.env%s -- COMMANDE [ARGUMENTS]": "exec expects a command after --: chiffremento exec -in secrets.env%s -- COMMAND [ARGUMENTS]",
+		"exec lit ses secrets dans un fichier : l'entrée standard reste celle de la commande":             "exec reads its secrets from a file: standard input stays the command's",
+		"la commande s'est terminée avec le code %d":                                                      "the command exited with code %d",
+		"lancement de %s : %w": "starting %s: %w",
+		"plus de %d Kio : ce n'est pas un fichier d'environnement":      "more than %d KiB: this is not an environment file",
+		"un fichier d'environnement chiffré doit porter l'extension %s": "an encrypted environment file must have the %s extension",
+
+		// tui.go
+		"\ncontient un dossier : il sera extrait dans %s, qui ne doit pas déjà exister":                  "\ncontains a directory: it will be extracted to %s, which must not already exist",
+		"\nformat v%d, plus ancien que celui produit aujourd'hui : lecture seule, il sera relu tel quel": "\nformat v%d, older than the one produced today: read-only, it will be read as is",
+		"%s · %d Mio de mémoire, exigés aussi au déchiffrement":                                          "%s · %d MiB of memory, also required for decryption",
+		"(rien, contrôle seul)":                                                    "(nothing, check only)",
+		"16 o aléatoires · dossier empaqueté en tar":                               "16 random bytes · directory packed as tar",
+		"16 o aléatoires · dossier tar · taille masquée":                           "16 random bytes · tar directory · size hidden",
+		"16 o aléatoires · en-tête lié à la clé":                                   "16 random bytes · header bound to the key",
+		"16 o aléatoires · taille réelle masquée":                                  "16 random bytes · real size hidden",
+		"aegis-256  (rapide avec AES-NI)":                                          "aegis-256  (fast with AES-NI)",
+		"aes-256-gcm  (défaut)":                                                    "aes-256-gcm  (default)",
+		"algorithme":                                                               "algorithm",
+		"archive intacte, extractible, rien écrit sur le disque":                   "archive intact, extractable, nothing written to disk",
+		"arrondit la taille au palier supérieur (jusqu'à ~12 % de disque en plus)": "rounds the size up to the next step (up to ~12 % more disk)",
+		"aucun  (réglages par défaut)":                                             "none  (default settings)",
+		"aucun mot de passe reçu sur l'entrée standard":                            "no password received on standard input",
+		"c'est un dossier : seul un fichier %s peut être déchiffré ou vérifié":     "this is a directory: only a %s file can be decrypted or verified",
+		"cascade  chacha20 + aes  (parano)":                                        "cascade  chacha20 + aes  (paranoid)",
+		"cassé en quelques heures":                                                 "cracked in a few hours",
+		"cassé en quelques jours":                                                  "cracked in a few days",
+		"cassé en quelques minutes":                                                "cracked in a few minutes",
+		"cassé en quelques mois":                                                   "cracked in a few months",
+		"cassé en quelques secondes":                                               "cracked in a few seconds",
+		"ce fichier doit porter l'extension %s":                                    "this file must have the %s extension",
+		"ce fichier est déjà chiffré":                                              "this file is already encrypted",
+		"ce n'est ni un fichier régulier ni un dossier":                            "this is neither a regular file nor a directory",
+		"ce n'est pas la phrase affichée":                                          "this is not the phrase shown",
+		"chemin du fichier %s, ou glisser-déposer":                                 "path of the %s file, or drag and drop",
+		"chemin introuvable":                                                       "path not found",
+		"chemin, ou glisser-déposer le fichier ou le dossier":                      "path, or drag and drop the file or directory",
+		"chiffrement":                            "encryption",
+		"chiffrer un fichier ou un dossier":      "encrypt a file or a directory",
+		"compresser avant chiffrement  (zstd)":   "compress before encryption  (zstd)",
+		"compressé":                              "compressed",
+		"confirmation :":                         "confirmation:",
+		"conserver le nom et la date d'origine":  "keep the original name and date",
+		"correct":                                "fair",
+		"coût de la dérivation de clé":           "key derivation cost",
+		"des millénaires":                        "millennia",
+		"déchiffrement":                          "decryption",
+		"déchiffrer un %s  (fichier ou dossier)": "decrypt a %s  (file or directory)",
+		"désigner la cible":                      "choose the target",
+		"elle n'est plus affichée : sans elle, le fichier est définitivement perdu": "it is no longer shown: without it, the file is lost for good",
+		"en générer un pour moi  (phrase de passe)":                                 "generate one for me  (passphrase)",
+		"faible, une phrase de passe serait bien plus sûre":                         "weak, a passphrase would be much safer",
+		"fichier %s à lire": "%s file to read",
+		"fichier intact, déchiffrable, rien écrit sur le disque": "file intact, decryptable, nothing written to disk",
+		"fichier ou dossier à chiffrer":                          "file or directory to encrypt",
+		"format v%d · %s · %s":                                   "format v%d · %s · %s",
+		"format v%d · lu dans l'en-tête":                         "format v%d · read from the header",
+		"fort":                                                   "strong",
+		"un fichier ou un dossier est attendu":                   "give a file or a directory",
+		"instantané":                                             "instant",
+		"jamais affiché, jamais visible dans ps ni dans l'historique": "never shown, never visible in ps or in the history",
+		"l'entrée standard porte les données à chiffrer, le mot de passe doit donc être saisi au terminal — introuvable ici (%w). Il faut -in FICHIER plutôt que -in -": "standard input carries the data to encrypt, so the password must be typed at the terminal — not found here (%w). Use -in FILE instead of -in -",
+		"le choisir moi-même":                   "choose it myself",
+		"le mot de passe ne peut pas être vide": "the password cannot be empty",
+		"le terminal de contrôle n'est pas utilisable pour une saisie masquée : il faut -in FICHIER plutôt que -in -": "the controlling terminal cannot be used for hidden input: use -in FILE instead of -in -",
+		"les deux saisies diffèrent": "the two entries differ",
+		"masquer la taille réelle":   "hide the real size",
+		"maximum":                    "maximum",
+		"mot de passe :":             "password:",
+		"à noter maintenant, puis Entrée : elle ne sera plus affichée": "write it down now, then Enter: it will not be shown again",
+		"mot de passe vide":      "empty password",
+		"opération":              "operation",
+		"oui":                    "yes",
+		"parcourir les fichiers": "browse files",
+		"phrase de passe":        "passphrase",
+		"politique : %s":         "policy: %s",
+		"proposée active sur un dossier · laisse fuiter la compressibilité du contenu": "suggested on for a directory · leaks how compressible the content is",
+		"préréglage":                       "preset",
+		"recopie de la phrase de passe :":  "type the passphrase again:",
+		"rien ne sera écrit sur le disque": "nothing will be written to disk",
+		"réduit la taille, mais laisse fuiter la compressibilité du contenu": "reduces the size, but leaks how compressible the content is",
+		"saisir un chemin  (ou glisser-déposer)":                             "type a path  (or drag and drop)",
+		"solide":             "strong",
+		"standard  (défaut)": "standard  (default)",
+		"stockés à l'intérieur du chiffré, donc restituables même sous un nom neutre": "stored inside the encrypted file, so they can be restored even under a neutral name",
+		"tiré de %s":                         "from %s",
+		"vérification":                       "verification",
+		"vérifier un %s  (sans rien écrire)": "verify a %s  (without writing anything)",
+		"~%.0f ans":                          "~%.0f years",
+		"~%.0f bits — %s · %s hors ligne":    "~%.0f bits — %s · %s offline",
+		"↑↓ se déplacer · → entrer dans un dossier · entrée choisir": "↑↓ move · → enter a directory · enter select",
+	},
+	errors: map[string]string{
+		"aegis.key_mismatch":         "%w: aegis-256, the key does not match this file",
+		"aegis.last_packet_missing":  "%w: aegis-256, the last packet is missing",
+		"aegis.packet_auth":          "%w: aegis-256, packet %d is not authentic (file modified, reordered or wrong key)",
+		"aegis.packet_size":          "aegis-256: packet %d has an invalid size (%d bytes)",
+		"aegis.trailing_data":        "%w: aegis-256, data after the last packet",
+		"aegis.write_after_close":    "aegis-256: write after Close",
+		"algo.unknown":               "unknown algorithm: %d",
+		"archive.copy":               "archiving %s: %w",
+		"archive.extract":            "extracting %s: %w",
+		"archive.finish":             "finishing the archive: %w",
+		"archive.grew":               "%s grew while being archived",
+		"archive.mkdir_parent":       "creating the parent directory of %s: %w",
+		"archive.read":               "reading the archive: %w",
+		"archive.refused":            "archive refused",
+		"archive.refused_too_many":   "%w: more than %d entries",
+		"archive.refused_type":       "%w: %s, entry of unsupported type (%c)",
+		"archive.size":               "computing the size of %s: %w",
+		"archive.size_changed":       "%s changed size while being archived (%d bytes expected, %d read)",
+		"archive.too_deep":           "directory tree too deep (more than %d levels): %s",
+		"archive.too_many_entries":   "directory too large: more than %d entries",
+		"archive.unnamed":            "%w: entry without a name",
+		"archive.unsafe_char":        "%w: forbidden character in %q",
+		"archive.unsafe_outside":     "%w: %q leaves the destination",
+		"archive.unsafe_path":        "path refused in the archive",
+		"archive.unsafe_too_deep":    "%w: directory tree too deep (more than %d levels): %q",
+		"archive.unsafe_volume":      "%w: %q points to another volume",
+		"archive.unsupported_type":   "%s: unsupported type (%s) — symbolic links, sockets and devices are refused",
+		"archive.walk":               "walking the directory: %w",
+		"archive.write_entry":        "writing entry %s: %w",
+		"argon.memory":               "Argon2 parameter out of range: memory=%d KiB (expected 1..%d)",
+		"argon.parallelism":          "Argon2 parameter out of range: parallelism=%d (expected 1..%d)",
+		"argon.time":                 "Argon2 parameter out of range: time=%d (expected 1..%d)",
+		"auth.failed":                "authentication failed",
+		"auth.wrong_password":        "%w: wrong password",
+		"auth.wrong_recovery_code":   "%w: wrong recovery code",
+		"auth.wrong_secret":          "%w: wrong password, wrong key or modified file (%w)",
+		"breach.corrupt":             "%s: breach list truncated or corrupted",
+		"breach.empty":               "%s: no SHA-1 hash",
+		"breach.hash_invalid":        "%s, line %d: invalid SHA-1 hash (%v)",
+		"breach.hash_length":         "%s, line %d: SHA-1 hash expected (40 hexadecimal characters), got %d",
+		"breach.not_a_list":          "%s is not a breach list (built by chiffremento breachdb-build)",
+		"breach.read":                "reading the breach list: %w",
+		"calibrate.max_memory":       "memory cap out of range: %d MiB (expected %d..%d)",
+		"calibrate.target":           "target duration out of range: %v (expected between 0 and %v)",
+		"canceled":                   "operation canceled",
+		"canceled.context":           "%w: %w",
+		"cascade.inner":              "creating the inner stream: %w",
+		"cascade.outer":              "creating the outer stream: %w",
+		"cascade.outer_decrypt":      "initialising the outer decryption: %w",
+		"comp.finish":                "finishing the compression: %w",
+		"comp.gzip":                  "creating the gzip stream: %w",
+		"comp.gzip_write":            "gzip is no longer produced: zstd replaces it, about eight times faster at a comparable size (old gzip files remain decryptable)",
+		"comp.unknown":               "unknown compression algorithm: %d",
+		"comp.zstd":                  "creating the zstd stream: %w",
+		"crypto.decrypt":             "decrypting: %w",
+		"crypto.encrypt":             "encrypting: %w",
+		"crypto.finish":              "finishing the encryption: %w",
+		"crypto.nonce":               "generating the nonce: %w",
+		"crypto.salt":                "generating the salt: %w",
+		"crypto.verify":              "verifying: %w",
+		"edit.archive":               "this is an encrypted directory: only a file can be edited in place",
+		"edit.changed_on_disk":       "%s changed since it was opened: edit abandoned so as not to overwrite it",
+		"edit.old_format":            "old v%d format: it must be upgraded first",
+		"edit.recovery":              "a file with a recovery code cannot be edited in place: decrypt it, then encrypt it again",
+		"extract.exists":             "%s already exists: move or rename it before extracting",
+		"format.bad_magic":           "unknown format: this file was not produced by chiffremento",
+		"format.truncated":           "truncated file",
+		"format.unsupported_version": "unsupported format version",
+		"format.version_range":       "%w: %d (this binary reads versions %d to %d)",
+		"header.kdf_with_key":        "inconsistent header: KDF parameters on a key-encrypted file",
+		"header.legacy_comp_flag":    "inconsistent header: v1/v2 compression flag on a v3 file",
+		"header.pad_flag":            "inconsistent header: padding flag on a v%d file",
+		"header.read":                "reading the header: %w",
+		"header.recovery_flag":       "inconsistent header: recovery code on a file that cannot carry one",
+		"header.unknown_algo":        "unknown algorithm in the header: %d",
+		"header.unknown_flags":       "unknown flags in the header: 0x%02x",
+		"header.unknown_kdf":         "unknown KDF in the header: %d",
+		"header.write":               "writing the header: %w",
+		"input.not_regular":          "%s is neither a regular file nor a directory",
+		"input.size":                 "size of the input file: %w",
+		"io.chmod_path":              "permissions of %s: %w",
+		"io.close_path":              "closing %s: %w",
+		"io.create_path":             "creating %s: %w",
+		"io.read":                    "reading: %w",
+		"io.read_path":               "reading %s: %w",
+		"io.remove_path":             "removing %s: %w",
+		"io.rename":                  "renaming to %s: %w",
+		"io.sync":                    "syncing to disk: %w",
+		"io.temp_chmod":              "permissions of the temporary file: %w",
+		"io.temp_close":              "closing the temporary file: %w",
+		"io.temp_create":             "creating the temporary file: %w",
+		"io.tempdir_chmod":           "permissions of the temporary directory: %w",
+		"io.tempdir_create":          "creating the temporary directory: %w",
+		"io.write_path":              "writing %s: %w",
+		"kdf.argon_params":           "Argon2id parameters do not apply to %s",
+		"kdf.memory_available":       "not enough memory: deriving this file needs %d MiB, only %d MiB are available here (decrypt it on a better-equipped machine)",
+		"kdf.memory_cap":             "file refused: its derivation needs %d MiB, beyond the cap of %d MiB",
+		"kdf.salt_size":              "invalid salt: %d bytes (expected %d)",
+		"kdf.unknown_id":             "unknown KDF: %d",
+		"kdf.unknown_name":           "unknown KDF %q (expected argon2id, scrypt or pbkdf2)",
+		"kdf.unknown_profile":        "unknown KDF profile %q (expected standard, fort or maximum)",
+		"kdf.with_key":               "a symmetric key replaces the derivation: profile, KDF and Argon2id parameters do not apply",
+		"key.size":                   "symmetric key of %d bytes (expected %d)",
+		"keyfile.checksum":           "corrupted key file: the checksum does not match (key truncated or modified)",
+		"keyfile.create":             "creating the key file: %w",
+		"keyfile.exists":             "%s already exists: a key is never overwritten",
+		"keyfile.generate":           "generating the key: %w",
+		"keyfile.invalid":            "%s: %w",
+		"keyfile.length":             "key file of invalid length: %w",
+		"keyfile.not_a_key":          "this is not a chiffremento key file (expected a line %q…)",
+		"keyfile.read":               "reading the key: %w",
+		"keyfile.unreadable":         "unreadable key file: %w",
+		"keyfile.write":              "writing the key: %w",
+		"keys.derive":                "deriving the key: %w",
+		"keys.subkeys":               "deriving the subkeys: %w",
+		"keys.v1_inner":              "inner key (v1): %w",
+		"keys.v1_outer":              "outer key (v1): %w",
+		"keys.v1_subpasswords":       "deriving the sub-passwords (v1): %w",
+		"metadata.corrupt":           "metadata missing or corrupted",
+		"metadata.empty_name":        "metadata: empty file name",
+		"metadata.name_length":       "metadata: invalid name length (%d)",
+		"metadata.name_too_long":     "metadata: name of %d bytes, maximum %d",
+		"metadata.read":              "reading the metadata: %w",
+		"metadata.read_name":         "reading the original name: %w",
+		"metadata.unknown_mode":      "unknown metadata mode %q (expected none or minimal)",
+		"metadata.unusable_name":     "metadata: unusable original name",
+		"metadata.write":             "writing the metadata: %w",
+		"options.pad_with_comp":      "padding and compression are mutually exclusive: the size of a compressed file depends on how compressible the content is, which padding does not hide",
+		"pad.needs_size_stream":      "padding needs a known input size: impossible on a stream",
+		"pad.needs_size_writer":      "padding needs a known input size: impossible on a writer",
+		"pad.out_of_range":           "padding out of range: %d bytes",
+		"pad.read":                   "reading the padding: %w",
+		"pad.read_header":            "reading the padding header: %w",
+		"pad.write":                  "writing the padding: %w",
+		"pad.write_header":           "writing the padding header: %w",
+		"passphrase.draw":            "drawing the passphrase: %w",
+		"passphrase.unknown_list":    "unknown word list: %q (expected %s)",
+		"passphrase.words":           "number of words out of range: %d (expected %d..%d)",
+		"pbkdf2.iterations":          "PBKDF2 parameter out of range: %d iterations (expected 1..%d)",
+		"pbkdf2.reserved":            "inconsistent PBKDF2 parameters: reserved bytes are not zero",
+		"recovery.checksum":          "recovery code mistyped: the checksum does not match",
+		"recovery.compact":           "unreadable compact recovery code",
+		"recovery.file_key":          "generating the file key: %w",
+		"recovery.generate":          "generating the recovery code: %w",
+		"recovery.size":              "recovery code of %d bytes (expected %d)",
+		"recovery.unknown_word":      "unknown word %d: %q",
+		"recovery.word_count":        "recovery code of %d words (expected %d)",
+		"recovery.wrap_key":          "wrapping key: %w",
+		"scrypt.memory":              "scrypt parameters out of range: %d MiB of memory (maximum %d)",
+		"scrypt.n":                   "scrypt parameter out of range: N=2^%d (expected 2^1..2^%d)",
+		"scrypt.n_header":            "scrypt parameter out of range: N=2^%d",
+		"scrypt.p":                   "scrypt parameter out of range: p=%d (expected 1..%d)",
+		"scrypt.r":                   "scrypt parameter out of range: r=%d (expected 1..%d)",
+		"secret.needs_key":           "this file is encrypted with a symmetric key: it needs the key, not a password",
+		"secret.needs_password":      "this file is protected by a password, not by a symmetric key",
+		"secret.no_recovery":         "this file has no recovery code",
+		"secret.recovery_with_key":   "a recovery code backs up a password: it makes no sense with a symmetric key",
+		"secure.alloc":               "allocating a protected buffer: %w",
+		"secure.guard_pages":         "guard pages: %w",
+		"secure.negative_size":       "negative protected buffer size",
+		"shamir.checksum":            "corrupted share: the checksum does not match (share mistyped?)",
+		"shamir.draw":                "drawing the shares: %w",
+		"shamir.duplicate":           "share %d is given twice",
+		"shamir.inconsistent":        "inconsistent share: invalid threshold or index",
+		"shamir.length":              "share of invalid length: mistyped?",
+		"shamir.mixed":               "these shares do not come from the same file",
+		"shamir.none":                "no share given",
+		"shamir.not_a_share":         "this is not a chiffremento share (expected a line %q…)",
+		"shamir.not_enough":          "%d share(s) out of the %d needed",
+		"shamir.one_per_file":        "only one share per file",
+		"shamir.shares":              "number of shares out of range: %d (expected 2..%d)",
+		"shamir.threshold":           "threshold out of range: %d (expected 2..%d)",
+		"shamir.unreadable":          "unreadable share: %w",
+		"shamir.wrong":               "the shares do not rebuild the key: one of them is wrong",
+		"stream.read_after_close":    "read after the decryption was closed",
+		"stream.write_after_close":   "write after the encryption was closed",
+		"suite.key_count":            "suite %s: %d keys requested (expected 1 or 2)",
+		"suite.key_size":             "suite %s: key of %d bytes (expected 16 to 64)",
+		"suite.reserved_id":          "suite identifier %d is reserved: private suites start at %d",
+		"suite.taken":                "suite identifier %d already taken by %s",
+		"suite.unnamed":              "suite %d has no name",
+		"suite.zero":                 "suite identifier 0 is reserved: it means “default” in Options",
+		"truncated.header":           "%w: incomplete header",
+		"truncated.last_packet":      "%w: the last packet is missing (%w)",
+		"truncated.no_payload":       "%w: the payload is missing",
+		"upgrade.reread_header":      "re-reading the header: %w",
+	},
+}
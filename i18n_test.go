@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+// litteral rend la valeur d'une chaîne littérale, éventuellement coupée en
+// plusieurs morceaux par des +.
+func litteral(e ast.Expr) (string, bool) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.BinaryExpr:
+		a, ok := litteral(e.X)
+		b, ok2 := litteral(e.Y)
+		return a + b, ok && ok2 && e.Op == token.ADD
+	case *ast.ParenExpr:
+		return litteral(e.X)
+	}
+	return "", false
+}
+
+// appel est un appel relevé dans le code : sa position, et ses premiers
+// arguments quand ce sont des chaînes littérales ("" sinon).
+type appel struct {
+	ou   string
+	args []string
+}
+
+// appels relève, dans les fichiers Go de dir hors tests, les appels aux
+// fonctions nommées.
+func appels(t *testing.T, dir string, fonctions ...string) []appel {
+	t.Helper()
+	fichiers, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var out []appel
+	for _, chemin := range fichiers {
+		if strings.HasSuffix(chemin, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, chemin, nil, parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			c, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if id, ok := c.Fun.(*ast.Ident); ok && slices.Contains(fonctions, id.Name) {
+				a := appel{ou: fset.Position(c.Pos()).String()}
+				for _, arg := range c.Args[:min(2, len(c.Args))] {
+					s, _ := litteral(arg)
+					a.args = append(a.args, s)
+				}
+				out = append(out, a)
+			}
+			return true
+		})
+	}
+	return out
+}
+
+var verbe = regexp.MustCompile(`%[-+#0]*(\[\d+\])?(\d+|\*)?(\.(\d+|\*))?[a-zA-Z%]`)
+
+// verbes rend la suite des verbes de format de s : une traduction doit
+// consommer les mêmes arguments, dans le même ordre.
+func verbes(s string) string {
+	return strings.Join(verbe.FindAllString(s, -1), " ")
+}
+
+// TestCatalogues vérifie que chaque langue traduit tous les messages et tous
+// les codes d'erreur de pkg, sans rien de plus, et avec les mêmes verbes.
+func TestCatalogues(t *testing.T) {
+	// Les messages sont en français dans le code : la clé est le texte.
+	messages := map[string]string{}
+	for _, a := range appels(t, ".", "tr", "errorf", "sentinel") {
+		if a.args[0] != "" {
+			messages[a.args[0]] = a.ou
+		}
+	}
+	// Textes des tables, traduits à l'affichage par tr(variable).
+	for _, c := range commands {
+		for _, s := range []string{c.summary, c.synopsis, c.detail} {
+			if s != "" {
+				messages[s] = "commande " + c.name
+			}
+		}
+	}
+	for _, s := range phaseLabels {
+		messages[s] = "phaseLabels"
+	}
+	// Les erreurs de pkg portent un code, et leur format français.
+	codes := map[string]appel{}
+	for _, a := range appels(t, "pkg", "errorf") {
+		if a.args[0] == "" || len(a.args) < 2 {
+			t.Fatalf("%s : code d'erreur non littéral", a.ou)
+		}
+		codes[a.args[0]] = a
+	}
+	if len(messages) < 100 || len(codes) < 100 {
+		t.Fatalf("relevé suspect : %d messages, %d codes", len(messages), len(codes))
+	}
+
+	for _, l := range languages {
+		if l == sourceLanguage {
+			continue
+		}
+		c := catalogs[l]
+		if c == nil {
+			t.Fatalf("langue %s sans catalogue", l)
+		}
+		for cle, ou := range messages {
+			traduit, ok := c.messages[cle]
+			switch {
+			case !ok:
+				t.Errorf("%s : message sans traduction (%s) : %q", l, ou, cle)
+			case verbes(traduit) != verbes(cle):
+				t.Errorf("%s : verbes %q au lieu de %q dans %q", l, verbes(traduit), verbes(cle), traduit)
+			}
+		}
+		for cle := range c.messages {
+			if _, ok := messages[cle]; !ok {
+				t.Errorf("%s : traduction d'un message disparu : %q", l, cle)
+			}
+		}
+		for code, a := range codes {
+			traduit, ok := c.errors[code]
+			switch {
+			case !ok:
+				t.Errorf("%s : code d'erreur sans traduction (%s) : %s", l, a.ou, code)
+			case verbes(traduit) != verbes(a.args[1]):
+				t.Errorf("%s : verbes %q au lieu de %q pour %s", l, verbes(traduit), verbes(a.args[1]), code)
+			}
+		}
+		for code := range c.errors {
+			if _, ok := codes[code]; !ok {
+				t.Errorf("%s : traduction d'un code disparu : %s", l, code)
+			}
+		}
+	}
+}
+
+func TestLangue(t *testing.T) {
+	for _, c := range []struct {
+		lcAll, lcMessages, lang string
+		args                    []string
+		attendu, inconnue       string
+	}{
+		{"", "", "", nil, "fr", ""},
+		{"", "", "en_GB.UTF-8", nil, "en", ""},
+		{"", "en_US", "fr_FR.UTF-8", nil, "en", ""},
+		{"fr_CA", "en_US", "en_US", nil, "fr", ""},
+		{"C", "", "en_US", nil, "fr", ""}, // la locale C n'a pas de catalogue
+		{"", "", "de_DE", nil, "fr", ""},
+		{"", "", "fr_FR", []string{"enc", "-lang", "en"}, "en", ""},
+		{"", "", "en", []string{"-lang=fr", "info"}, "fr", ""},
+		{"", "", "en", []string{"enc", "--", "-lang", "fr"}, "en", ""},
+		{"", "", "en", []string{"-lang", "de"}, "en", "de"},
+	} {
+		t.Setenv("LC_ALL", c.lcAll)
+		t.Setenv("LC_MESSAGES", c.lcMessages)
+		t.Setenv("LANG", c.lang)
+		l, inconnue := detectLanguage(c.args)
+		if l != c.attendu || inconnue != c.inconnue {
+			t.Errorf("%+v : %s, %q", c, l, inconnue)
+		}
+	}
+
+	// De bout en bout : les messages du programme et ceux de pkg.
+	if err := lancer(t, "chiffre", "-lang", "en"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("commande inconnue en anglais : %v", err)
+	}
+	if err := lancer(t, "info", "-in", ecrire(t, filepath.Join(t.TempDir(), "x.chto"), []byte("CHTO")), "-lang", "en"); err == nil ||
+		!strings.Contains(err.Error(), "incomplete header") {
+		t.Errorf("erreur de pkg en anglais : %v", err)
+	}
+	if err := lancer(t, "help", "-lang", "de"); err == nil || !strings.Contains(err.Error(), "inconnue") {
+		t.Errorf("-lang de accepté : %v", err)
+	}
+}
+
+// TestErreursTraduites vérifie qu'aucune erreur destinée à l'utilisateur
+// n'échappe au catalogue : pas de texte passé à fmt.Errorf ou errors.New sans
+// code ni tr, pas d'errorf évalué à l'initialisation du paquet, avant le choix
+// de la langue. Puis, sous -lang en, les messages qui y échappaient.
+func TestErreursTraduites(t *testing.T) {
+	lettre := regexp.MustCompile(`\pL`)
+	fset := token.NewFileSet()
+	for _, dir := range []string{".", "pkg"} {
+		fichiers, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, chemin := range fichiers {
+			if strings.HasSuffix(chemin, "_test.go") {
+				continue
+			}
+			f, err := parser.ParseFile(fset, chemin, nil, parser.SkipObjectResolution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ast.Inspect(f, func(n ast.Node) bool {
+				c, ok := n.(*ast.CallExpr)
+				if !ok || len(c.Args) == 0 {
+					return true
+				}
+				sel, ok := c.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				paquet, _ := sel.X.(*ast.Ident)
+				if paquet == nil || !(paquet.Name == "fmt" && sel.Sel.Name == "Errorf" || paquet.Name == "errors" && sel.Sel.Name == "New") {
+					return true
+				}
+				if s, ok := litteral(c.Args[0]); ok && lettre.MatchString(verbe.ReplaceAllString(s, "")) {
+					t.Errorf("%s : texte non traduit %q", fset.Position(c.Pos()), s)
+				}
+				return true
+			})
+			if dir != "." {
+				continue
+			}
+			for _, decl := range f.Decls {
+				g, ok := decl.(*ast.GenDecl)
+				if !ok || g.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range g.Specs {
+					for _, v := range spec.(*ast.ValueSpec).Values {
+						if c, ok := v.(*ast.CallExpr); ok {
+							if id, ok := c.Fun.(*ast.Ident); ok && id.Name == "errorf" {
+								t.Errorf("%s : errorf à l'initialisation, traduit avant le choix de la langue (sentinel)", fset.Position(c.Pos()))
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	setLanguage("en")
+	t.Cleanup(func() { setLanguage(sourceLanguage) })
+	for _, c := range []struct {
+		err     error
+		attendu string
+	}{
+		{pkg.CheckKDFMemory(512<<10, 256<<10), "file refused: its derivation needs 512 MiB"},
+		{errAgentAbsent, "no agent is running"},
+		{errInputAborted, "input interrupted"},
+	} {
+		if c.err == nil || !strings.HasPrefix(c.err.Error(), c.attendu) {
+			t.Errorf("%v : attendu %q", c.err, c.attendu)
+		}
+	}
+	var coded *pkg.Error
+	if err := pkg.CheckKDFMemory(512<<10, 256<<10); !errors.As(err, &coded) || coded.Code != "kdf.memory_cap" {
+		t.Errorf("plafond mémoire sans code : %v", err)
+	}
+
+	dir := t.TempDir()
+	in := ecrire(t, filepath.Join(dir, "doc.txt"), []byte("contenu"))
+	code, _ := pkg.GenerateRecoveryCode()
+	rapide := &pkg.ArgonParams{Time: 1, MemoryKiB: 1024, Threads: 1}
+	if err := pkg.Encrypt(in, in+extension, []byte(motDePasseTest), pkg.Options{Argon: rapide, RecoveryCode: code}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.Verify(in+extension, []byte("faux"), pkg.Options{}); err == nil || err.Error() != "authentication failed: wrong password" {
+		t.Errorf("mauvais mot de passe avec code de secours : %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"chiffremento-cli/pkg"
+)
+
+// Sortie JSON (-json).
+//
+// Un script lit un seul objet sur la sortie standard, et rien d'autre : les
+// messages pour un humain partent dans diag, qui ne mène nulle part en JSON.
+// Seule une erreur d'usage, avant que l'opération commence, reste en texte sur
+// la sortie d'erreur.
+//
+// jsonSchemaVersion change dès qu'un champ change de sens ou disparaît ;
+// ajouter un champ ne le change pas. Le README décrit le schéma.
+const jsonSchemaVersion = 1
+
+var (
+	jsonOutput bool
+	// diag reçoit ce qui s'adresse à un humain : notes, récapitulatifs,
+	// confirmations.
+	diag io.Writer = os.Stderr
+)
+
+// opReport est l'objet émis par info, verify, dec et bench.
+type opReport struct {
+	Schema int      `json:"schema"`
+	Mode   string   `json:"mode"`
+	Status string   `json:"status"` // "ok" ou "error"
+	Error  *opError `json:"error,omitempty"`
+	Input  string   `json:"input,omitempty"`
+	Output string   `json:"output,omitempty"`
+	// Archive est absent tant qu'on ne sait pas : un flux n'en dit rien avant
+	// d'avoir été déchiffré.
+	Archive  *bool                `json:"archive,omitempty"`
+	Metadata *opMetadata          `json:"metadata,omitempty"`
+	BytesIn  *int64               `json:"bytes_in,omitempty"`
+	BytesOut *int64               `json:"bytes_out,omitempty"`
+	Details  *pkg.Details         `json:"details,omitempty"`
+	Bench    *pkg.BenchmarkReport `json:"bench,omitempty"`
+
+	// counted compte l'entrée quand sa taille n'est pas connue d'avance.
+	counted *countingReader
+}
+
+type opError struct {
+	Code    int    `json:"code"` // le code de sortie du processus
+	Kind    string `json:"kind"` // voir exitKind
+	Message string `json:"message"`
+}
+
+type opMetadata struct {
+	Name    string    `json:"name"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func newReport(mode, in string) *opReport {
+	return &opReport{Schema: jsonSchemaVersion, Mode: mode, Input: in}
+}
+
+// countInput compte ce qui est lu de r, pour bytes_in.
+func (r *opReport) countInput(src io.Reader) io.Reader {
+	r.counted = &countingReader{r: src}
+	return r.counted
+}
+
+func (r *opReport) setInputSize(n int64)  { r.BytesIn = &n }
+func (r *opReport) setOutputSize(n int64) { r.BytesOut = &n }
+func (r *opReport) setArchive(a bool)     { r.Archive = &a }
+
+func (r *opReport) setResult(res pkg.DecryptResult) {
+	r.setArchive(res.Archive)
+	r.setOutputSize(res.Bytes)
+	if m := res.Metadata; m != nil {
+		r.Metadata = &opMetadata{Name: m.Name, ModTime: m.ModTime.UTC()}
+	}
+}
+
+// finish émet le rapport en JSON et renvoie err, marquée comme déjà rapportée
+// pour que main ne la répète pas. Hors JSON, il renvoie err telle quelle.
+func (r *opReport) finish(err error) error {
+	if !jsonOutput {
+		return err
+	}
+	if r.BytesIn == nil && r.counted != nil {
+		r.setInputSize(r.counted.n)
+	}
+	r.Status = "ok"
+	if err != nil {
+		code := exitCode(err)
+		r.Status = "error"
+		r.Error = &opError{Code: code, Kind: exitKind(code), Message: err.Error()}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	if werr := enc.Encode(r); werr != nil && err == nil {
+		return werr
+	}
+	if err != nil {
+		return reportedError{err}
+	}
+	return nil
+}
+
+// reportedError est une erreur déjà décrite dans le rapport JSON.
+type reportedError struct{ err error }
+
+func (e reportedError) Error() string { return e.err.Error() }
+func (e reportedError) Unwrap() error { return e.err }
+
+func alreadyReported(err error) bool {
+	var r reportedError
+	return errors.As(err, &r)
+}
+
+// countingReader et countingWriter comptent les octets d'un flux.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
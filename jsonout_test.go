@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+// enJSON active -json le temps du test.
+func enJSON(t *testing.T) {
+	t.Helper()
+	jsonOutput, diag = true, io.Discard
+	t.Cleanup(func() { jsonOutput, diag = false, os.Stderr })
+}
+
+// rapports relit les objets émis sur la sortie standard, un par ligne.
+func rapports(t *testing.T, chemin string) []map[string]any {
+	t.Helper()
+	raw, err := os.ReadFile(chemin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []map[string]any
+	for _, l := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(l), &m); err != nil {
+			t.Fatalf("ligne qui n'est pas du JSON : %q (%v)", l, err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestSortieJSON(t *testing.T) {
+	dir := t.TempDir()
+	contenu := bytes.Repeat([]byte("contenu "), 5000)
+	in := ecrire(t, filepath.Join(dir, "doc.txt"), contenu)
+	chto := in + extension
+	avecMotDePasse(t, motDePasseTest)
+	if err := doEncrypt(in, chto, pkg.Options{Metadata: pkg.MetadataMinimal}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(chto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sansTerminal(t)
+	enJSON(t)
+	sortie := captureSortie(t)
+	if err := doInfo(chto); err != nil {
+		t.Fatal(err)
+	}
+	avecMotDePasse(t, motDePasseTest)
+	if err := doVerify(chto, pkg.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	avecMotDePasse(t, motDePasseTest)
+	relu := filepath.Join(dir, "relu.txt")
+	if err := doDecrypt(chto, relu, pkg.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	avecMotDePasse(t, "mauvais mot de passe")
+	err = doVerify(chto, pkg.Options{})
+	if !alreadyReported(err) || exitCode(err) != exitAuth {
+		t.Errorf("erreur %v : déjà rapportée %v, code %d", err, alreadyReported(err), exitCode(err))
+	}
+
+	r := rapports(t, sortie)
+	if len(r) != 4 {
+		t.Fatalf("%d objets émis, attendu 4", len(r))
+	}
+	info, verify, dec, echec := r[0], r[1], r[2], r[3]
+	for i, m := range r {
+		if m["schema"] != float64(jsonSchemaVersion) {
+			t.Errorf("objet %d : schema %v", i, m["schema"])
+		}
+	}
+	if info["mode"] != "info" || info["status"] != "ok" || info["bytes_in"] != float64(st.Size()) || info["archive"] != false {
+		t.Errorf("info : %v", info)
+	}
+	if d, _ := info["details"].(map[string]any); d == nil || d["metadata"] != true || d["key_based"] != false {
+		t.Errorf("info.details : %v", info["details"])
+	}
+	if verify["status"] != "ok" || verify["bytes_out"] != float64(len(contenu)) || verify["bytes_in"] != float64(st.Size()) {
+		t.Errorf("verify : %v", verify)
+	}
+	if m, _ := verify["metadata"].(map[string]any); m == nil || m["name"] != "doc.txt" || m["mod_time"] == "" {
+		t.Errorf("verify.metadata : %v", verify["metadata"])
+	}
+	if dec["output"] != relu || dec["bytes_out"] != float64(len(contenu)) {
+		t.Errorf("dec : %v", dec)
+	}
+	if got, _ := os.ReadFile(relu); !bytes.Equal(got, contenu) {
+		t.Error("le fichier déchiffré diffère")
+	}
+	e, _ := echec["error"].(map[string]any)
+	if echec["status"] != "error" || e == nil || e["kind"] != "authentication" || e["code"] != float64(exitAuth) {
+		t.Errorf("échec : %v", echec)
+	}
+	if _, ok := echec["bytes_out"]; ok {
+		t.Error("échec : bytes_out annoncé sans rien avoir déchiffré")
+	}
+}
+
+func TestSortieJSONFlux(t *testing.T) {
+	dir := t.TempDir()
+	contenu := bytes.Repeat([]byte("flux "), 3000)
+	in := ecrire(t, filepath.Join(dir, "flux.txt"), contenu)
+	chto := in + extension
+	avecMotDePasse(t, motDePasseTest)
+	if err := doEncrypt(in, chto, pkg.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(chto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enJSON(t)
+	sortie := captureSortie(t)
+	avecEntree(t, chto)
+	// L'entrée standard porte les données : le mot de passe vient d'ailleurs.
+	t.Setenv("CHTO_TEST_PW", motDePasseTest)
+	precedent := passwordFrom
+	passwordFrom = passwordSource{env: "CHTO_TEST_PW", fd: -1}
+	t.Cleanup(func() { passwordFrom = precedent })
+	if err := doVerify("-", pkg.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	r := rapports(t, sortie)[0]
+	if r["bytes_in"] != float64(st.Size()) || r["bytes_out"] != float64(len(contenu)) || r["archive"] != false {
+		t.Errorf("verify sur un flux : %v", r)
+	}
+}
+
+func TestJSONMesureEnEchec(t *testing.T) {
+	raw, err := json.Marshal(pkg.AEADMeasure{Name: "x", Err: errors.New("pas ici")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"error":"pas ici"`) {
+		t.Errorf("mesure en échec : %s", raw)
+	}
+}
+
+// TestJSONIdentifiantsStables : algo, kdf et comp sont des identifiants, pas les
+// libellés affichés, et ne suivent donc pas la langue.
+func TestJSONIdentifiantsStables(t *testing.T) {
+	dir := t.TempDir()
+	in := ecrire(t, filepath.Join(dir, "doc.txt"), []byte("contenu"))
+	pbkdf2 := filepath.Join(dir, "pbkdf2.chto")
+	if err := pkg.Encrypt(in, pbkdf2, []byte(motDePasseTest), pkg.Options{KDFAlgo: pkg.KDFPBKDF2, Algo: pkg.AlgoCascade}); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := pkg.GenerateKey()
+	cle := filepath.Join(dir, "cle.chto")
+	if err := pkg.Encrypt(in, cle, nil, pkg.Options{Key: key, Comp: pkg.CompZstd}); err != nil {
+		t.Fatal(err)
+	}
+
+	sortie := captureSortie(t)
+	t.Cleanup(func() { jsonOutput, diag = false, os.Stderr })
+	for _, chto := range []string{pbkdf2, cle} {
+		if err := lancer(t, "info", "-in", chto, "-json", "-lang", "en"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := rapports(t, sortie)
+	if len(r) != 2 {
+		t.Fatalf("%d objets émis, attendu 2", len(r))
+	}
+	d, _ := r[0]["details"].(map[string]any)
+	p, _ := d["kdf_params"].(map[string]any)
+	if d["algo"] != "cascade" || d["kdf"] != "pbkdf2-sha256" || d["comp"] != "none" || p == nil || p["iterations"] != float64(600000) {
+		t.Errorf("pbkdf2 : %v", d)
+	}
+	d, _ = r[1]["details"].(map[string]any)
+	if d["algo"] != "aes-256-gcm" || d["kdf"] != "key" || d["comp"] != "zstd" || len(d["kdf_params"].(map[string]any)) != 0 {
+		t.Errorf("clé : %v", d)
+	}
+}
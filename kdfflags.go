@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"chiffremento-cli/pkg"
+)
+
+// kdfFlags rassemble les drapeaux qui règlent la dérivation de clé : le profil
+// (-kdf), la fonction (-kdf-algo), la calibration (-kdf auto, -kdf-target,
+// -kdf-max-mem) et les réglages bruts d'Argon2id (-kdf-mem, -kdf-time,
+// -kdf-threads).
+type kdfFlags struct {
+	profile, algo string
+	target        time.Duration
+	maxMem        string
+	mem           string
+	time, threads uint
+	// set liste les drapeaux présents sur la ligne de commande : un -kdf-time 0
+	// explicite doit être refusé, pas confondu avec l'absence du drapeau.
+	set map[string]bool
+}
+
+// kdfAuto est la valeur de -kdf qui déclenche la calibration.
+const kdfAuto = "auto"
+
+func registerKDFFlags() *kdfFlags {
+	f := &kdfFlags{}
+	flag.StringVar(&f.profile, "kdf", "", tr("coût de la dérivation de clé : standard (défaut), fort, maximum, ou auto pour calibrer sur cette machine (enc et upgrade)"))
+	flag.StringVar(&f.algo, "kdf-algo", "", tr("fonction de dérivation : argon2id (défaut), scrypt ou pbkdf2 (enc et upgrade)"))
+	flag.DurationVar(&f.target, "kdf-target", 500*time.Millisecond, tr("avec -kdf auto, durée visée pour une dérivation"))
+	flag.StringVar(&f.maxMem, "kdf-max-mem", "256MiB", tr("avec -kdf auto, mémoire maximale de la dérivation (exigée aussi au déchiffrement)"))
+	flag.StringVar(&f.mem, "kdf-mem", "", tr("mémoire d'Argon2id, par exemple 512MiB ; remplace celle du profil"))
+	flag.UintVar(&f.time, "kdf-time", 0, tr("nombre de passes d'Argon2id ; remplace celui du profil"))
+	flag.UintVar(&f.threads, "kdf-threads", 0, tr("parallélisme d'Argon2id ; remplace celui du profil"))
+	return f
+}
+
+// any indique si un drapeau de dérivation a été donné.
+func (f *kdfFlags) any() bool {
+	for _, n := range []string{"kdf", "kdf-algo", "kdf-target", "kdf-max-mem", "kdf-mem", "kdf-time", "kdf-threads"} {
+		if f.set[n] {
+			return true
+		}
+	}
+	return false
+}
+
+// raw indique si un réglage brut d'Argon2id a été donné.
+func (f *kdfFlags) raw() bool {
+	return f.set["kdf-mem"] || f.set["kdf-time"] || f.set["kdf-threads"]
+}
+
+// apply traduit les drapeaux en options de dérivation. La calibration, quand
+// elle est demandée, tourne ici : elle annonce son résultat avant que le mot
+// de passe soit demandé.
+func (f *kdfFlags) apply(opts *pkg.Options) error {
+	kdfID, err := pkg.ParseKDFAlgo(f.algo)
+	if err != nil {
+		return err
+	}
+	opts.KDFAlgo = kdfID
+
+	auto := f.profile == kdfAuto
+	if (f.set["kdf-target"] || f.set["kdf-max-mem"]) && !auto {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -kdf-target et -kdf-max-mem n'ont d'effet qu'avec -kdf auto, ils sont ignorés")))
+	}
+	if (auto || f.raw()) && kdfID != pkg.KDFArgon2id {
+		return errorf("-kdf auto, -kdf-mem, -kdf-time et -kdf-threads règlent Argon2id, pas %s", pkg.KDFAlgoName(kdfID))
+	}
+
+	var params pkg.ArgonParams
+	switch {
+	case auto:
+		maxMem, err := parseMemSize(f.maxMem)
+		if err != nil {
+			return errorf("-kdf-max-mem : %w", err)
+		}
+		cal, err := pkg.CalibrateKDF(f.target, maxMem)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(diag, tr("%s %s, ~%s sur cette machine (visé : %s)\n"), styleDim.Render(tr("calibration  ")),
+			cal.Params, cal.Estimated.Round(time.Millisecond), f.target)
+		params = cal.Params
+	case f.raw():
+		profile, err := pkg.ParseKDFProfile(f.profile)
+		if err != nil {
+			return err
+		}
+		params = profile.ArgonParams()
+	default:
+		opts.KDF, err = pkg.ParseKDFProfile(f.profile)
+		return err
+	}
+
+	if f.set["kdf-mem"] {
+		if params.MemoryKiB, err = parseMemSize(f.mem); err != nil {
+			return errorf("-kdf-mem : %w", err)
+		}
+	}
+	if f.set["kdf-time"] {
+		if f.time > math.MaxUint32 {
+			return errorf("-kdf-time hors bornes : %d", f.time)
+		}
+		params.Time = uint32(f.time)
+	}
+	if f.set["kdf-threads"] {
+		if f.threads > math.MaxUint8 {
+			return errorf("-kdf-threads hors bornes : %d", f.threads)
+		}
+		params.Threads = uint8(f.threads)
+	}
+	// Mêmes bornes qu'à la lecture : un fichier que son propre déchiffrement
+	// refuserait ne doit pas être écrit.
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	opts.Argon = &params
+	return nil
+}
+
+// kdfDescription décrit la dérivation des options pour l'affichage.
+func kdfDescription(opts pkg.Options) string {
+	if opts.Key != nil {
+		return tr("clé symétrique, sans dérivation de mot de passe")
+	}
+	if opts.Argon != nil {
+		return fmt.Sprintf(tr("%s (sur mesure)"), opts.Argon)
+	}
+	kdf := opts.KDF
+	if kdf == "" {
+		kdf = pkg.KDFStandard
+	}
+	return fmt.Sprintf("%s (%s)", kdf.KDFLabelFor(opts.KDFAlgo), kdf)
+}
+
+// parseMemSize lit une taille mémoire (« 256MiB », « 1GiB », « 512Mio ») et la
+// rend en KiB, l'unité de l'en-tête. Un nombre sans unité est en Mio.
+func parseMemSize(s string) (uint32, error) {
+	t := strings.TrimSpace(s)
+	num := strings.TrimRightFunc(t, unicode.IsLetter)
+	unit := strings.ToLower(t[len(num):])
+	n, err := strconv.ParseUint(strings.TrimSpace(num), 10, 32)
+	if err != nil {
+		return 0, errorf("taille mémoire invalide %q (exemples : 256MiB, 1GiB)", s)
+	}
+	var kib uint64
+	switch unit {
+	case "k", "kib", "kio":
+		kib = n
+	case "", "m", "mib", "mio":
+		kib = n << 10
+	case "g", "gib", "gio":
+		kib = n << 20
+	default:
+		return 0, errorf("unité de mémoire inconnue %q (attendu KiB, MiB ou GiB)", unit)
+	}
+	if kib > math.MaxUint32 {
+		return 0, errorf("taille mémoire trop grande")
+	}
+	return uint32(kib), nil
+}
@@ -1,14 +1,15 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,22 +23,55 @@ const extension = ".chto"
 func main() {
 	installSignalHandler()
 	if err := run(); err != nil {
-		fmt.Fprintln(os.Stderr, styleError.Render("erreur :"), err)
-		os.Exit(1)
+		if !alreadyReported(err) {
+			fmt.Fprintln(os.Stderr, styleError.Render(tr("erreur :")), err)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
 func run() error {
-	showVersion := flag.Bool("version", false, "afficher la version")
-	mode := flag.String("mode", "", "enc (chiffrer), dec (déchiffrer), verify (contrôler), info (inspecter) ou bench (mesurer)")
-	fileIn := flag.String("in", "", "fichier ou dossier d'entrée, ou - pour l'entrée standard (dossier en mode enc uniquement)")
-	fileOut := flag.String("out", "", "destination (défaut : entrée + "+extension+" en enc, entrée sans l'extension en dec) ; - pour la sortie standard")
-	compress := flag.Bool("comp", false, "compresser les données en zstd avant chiffrement")
-	pad := flag.Bool("pad", false, "masquer la taille réelle en ajoutant du remplissage ; s'exclut avec -comp")
-	chacha := flag.Bool("chacha", false, "utiliser ChaCha20-Poly1305 au lieu d'AES-GCM")
-	parano := flag.Bool("parano", false, "mode parano : double chiffrement en cascade (chacha20 + aes), plus lent")
-	kdf := flag.String("kdf", "", "coût de la dérivation de clé : standard (défaut), fort ou maximum")
-	meta := flag.String("meta", "", "métadonnées conservées dans le chiffré : none (défaut) ou minimal (nom et date)")
+	// La langue d'abord : les descriptions des options en dépendent.
+	l, unknown := detectLanguage(os.Args[1:])
+	setLanguage(l)
+	if unknown != "" {
+		return errorf("-lang %q inconnue (attendu %s)", unknown, strings.Join(languages, ", "))
+	}
+	flag.String("lang", language, fmt.Sprintf(tr("langue des messages : %s (défaut : d'après LC_ALL, LC_MESSAGES ou LANG)"), strings.Join(languages, ", ")))
+	showVersion := flag.Bool("version", false, tr("afficher la version"))
+	mode := flag.String("mode", "", tr("enc (chiffrer), dec (déchiffrer), verify (contrôler), info (inspecter), upgrade (mettre à niveau), edit (modifier), exec (lancer une commande avec des secrets), keygen (créer une clé), genpass (proposer une phrase de passe), breachdb-build (condenser une liste de fuites), agent (garder les secrets en mémoire) ou bench (mesurer)"))
+	fileIn := flag.String("in", "", tr("fichier ou dossier d'entrée, ou - pour l'entrée standard (dossier en mode enc uniquement)"))
+	fileOut := flag.String("out", "", fmt.Sprintf(tr("destination (défaut : entrée + %s en enc, entrée sans l'extension en dec) ; - pour la sortie standard"), extension))
+	compress := flag.Bool("comp", false, tr("compresser les données en zstd avant chiffrement ; en upgrade, recompresser en zstd les anciens fichiers gzip"))
+	pad := flag.Bool("pad", false, tr("masquer la taille réelle en ajoutant du remplissage ; s'exclut avec -comp"))
+	chacha := flag.Bool("chacha", false, tr("utiliser ChaCha20-Poly1305 au lieu d'AES-GCM"))
+	parano := flag.Bool("parano", false, tr("mode parano : double chiffrement en cascade (chacha20 + aes), plus lent"))
+	aegis := flag.Bool("aegis", false, tr("utiliser AEGIS-256 : plus rapide qu'AES-GCM avec AES-NI, clé engagée"))
+	kdf := registerKDFFlags()
+	meta := flag.String("meta", "", tr("métadonnées conservées dans le chiffré : none (défaut) ou minimal (nom et date)"))
+	maxKDFMem := flag.String("max-kdf-mem", "", tr("refuser les fichiers dont la dérivation exige plus que cette mémoire, par exemple 512MiB (dec, verify, upgrade, edit et exec)"))
+	passSrc := registerPasswordFlags()
+	pol := registerPolicyFlags()
+	prog := registerProgressFlags()
+	cfgFlags := registerConfigFlags()
+	keyFile := flag.String("key-file", "", tr("chiffrer ou déchiffrer avec ce fichier de clé symétrique plutôt qu'un mot de passe (enc, dec, verify, edit et exec)"))
+	symmetric := flag.Bool("symmetric", false, tr("en keygen, créer une clé symétrique de 256 bits"))
+	nShares := flag.Int("shares", 0, tr("en enc, découper la clé du fichier en N parts de Shamir, écrites à côté du chiffré (avec -threshold)"))
+	threshold := flag.Int("threshold", 0, tr("avec -shares, nombre de parts nécessaires pour déchiffrer"))
+	var shareList shareFiles
+	flag.Var(&shareList, "share", tr("fichier d'une part de Shamir (dec, verify, edit et exec, à répéter) ; celles qui manquent sont demandées au terminal"))
+	recovery := flag.Bool("recovery", false, tr("en enc, produire aussi un code de secours qui ouvre le fichier sans le mot de passe"))
+	qr := flag.Bool("qr", false, tr("avec -recovery, afficher aussi le code de secours en QR code"))
+	recoveryCode := flag.String("recovery-code", "", tr("déchiffrer avec le code de secours plutôt que le mot de passe, ou - pour le saisir au terminal (dec et verify)"))
+	nWords := flag.Int("words", pkg.DefaultPassphraseWords, tr("en genpass, nombre de mots de la phrase de passe"))
+	wordlistLang := flag.String("wordlist", pkg.PassphraseLanguages[0], fmt.Sprintf(tr("en genpass, langue des mots : %s"), strings.Join(pkg.PassphraseLanguages, ", ")))
+	breachPath := flag.String("breach-db", os.Getenv(breachDBEnv), fmt.Sprintf(tr("refuser un nouveau mot de passe présent dans cette liste de fuites, construite par breachdb-build (enc et agent add ; défaut : $%s)"), breachDBEnv))
+	recursive := flag.Bool("r", false, tr("en upgrade, parcourir le dossier -in et ses sous-dossiers"))
+	agentTTL := flag.Duration("agent-ttl", 15*time.Minute, tr("durée pendant laquelle l'agent garde un secret (agent et agent add)"))
+	noAgent := flag.Bool("no-agent", false, tr("ne pas consulter l'agent, toujours demander le mot de passe"))
+	var asFD fdNames
+	flag.Var(&asFD, "as-fd", tr("en exec, passer cette variable par un descripteur hérité, annoncé dans NOM_FD, plutôt que par l'environnement (à répéter)"))
+	asJSON := flag.Bool("json", false, tr("rendre compte en JSON sur la sortie standard, sans rien d'autre (info, verify, dec et bench)"))
 	flag.Usage = usage
 
 	// Sans le moindre argument, dans un vrai terminal : interface guidée.
@@ -45,13 +79,80 @@ func run() error {
 	// sur une entrée qui n'arrivera jamais.
 	if len(os.Args) == 1 {
 		if isInteractive() {
+			breachDB = os.Getenv(breachDBEnv)
+			if err := checkBreachDB(); err != nil {
+				return err
+			}
 			return runTUI()
 		}
 		usage()
-		return errors.New("aucun argument fourni (l'interface guidée nécessite un terminal)")
+		return errorf("aucun argument fourni (l'interface guidée nécessite un terminal)")
 	}
 
-	flag.Parse()
+	args := skipLeadingLang(os.Args[1:])
+	switch args[0] {
+	case "help":
+		return doHelp(args[1:])
+	case "completion":
+		return doCompletion(args[1:])
+	}
+	fs, err := parseCommandLine(args, mode)
+	if err != nil {
+		return err
+	}
+	// Les options de l'agent peuvent suivre son action : « agent add
+	// -key-file service.key » ne doit pas laisser -key-file dans les arguments.
+	var agentArgs []string
+	if *mode == "agent" && fs.NArg() > 0 {
+		action := fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+		agentArgs = append([]string{action}, fs.Args()...)
+	} else if fs.NArg() > 0 && *mode != "exec" {
+		// flag s'arrête au premier argument qui n'est pas une option : tout ce
+		// qui le suit serait ignoré sans un mot.
+		return errorf("argument inattendu %q : les options qui le suivent ne seraient pas lues (le fichier se donne par -in)", fs.Arg(0))
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	kdf.set = set
+	pol.set = set
+	if err := passSrc.validate(); err != nil {
+		return err
+	}
+	passwordFrom = *passSrc
+	if *asJSON {
+		switch *mode {
+		case "info", "verify", "dec", "bench":
+		default:
+			return errorf("-json ne vaut qu'en modes info, verify, dec et bench")
+		}
+		if *mode == "dec" && isStream(*fileOut) {
+			return errorf("-json occupe la sortie standard : -out - est impossible avec")
+		}
+		jsonOutput = true
+		diag = io.Discard
+	}
+	// Le fichier de configuration ne règle que ce que la ligne de commande
+	// laisse libre, et seulement pour les commandes qui prennent -preset.
+	var cfg *userConfig
+	var origins map[string]string
+	if c, ok := commandByName(*mode); ok && slices.Contains(c.flags, "preset") {
+		if cfg, err = loadConfig(); err != nil {
+			return err
+		}
+		if origins, err = cfg.apply(c, cfgFlags.preset, set); err != nil {
+			return err
+		}
+	} else if set["preset"] {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -preset n'a d'effet qu'en modes enc, dec, verify, upgrade, edit, exec, agent, genpass et config, il est ignoré")))
+	}
+	if *mode == "config" {
+		return doConfig(cfg, origins)
+	}
+	breachDB = *breachPath
+	useAgent = !*noAgent && !passSrc.set()
 
 	if *showVersion {
 		fmt.Printf("chiffremento %s\n", version)
@@ -62,68 +163,255 @@ func run() error {
 	if *mode == "bench" {
 		return doBench()
 	}
+	// keygen non plus : il n'écrit que -out.
+	if *mode == "keygen" {
+		if !*symmetric {
+			return errorf("keygen ne produit que des clés symétriques : -symmetric est requis")
+		}
+		return doKeygen(*fileOut)
+	}
+	// genpass non plus : il n'écrit que sur la sortie standard.
+	if *mode == "genpass" {
+		return doGenpass(*nWords, *wordlistLang)
+	}
+	// breachdb-build ne touche pas non plus à un chiffré : il lit la liste
+	// téléchargée et écrit le filtre.
+	if *mode == "breachdb-build" {
+		return doBreachBuild(*fileIn, *fileOut)
+	}
+	// La politique et la liste de fuites ne jouent qu'au choix d'un mot de
+	// passe : ailleurs, elles ne sont même pas lues.
+	if *mode == "enc" || (*mode == "agent" && len(agentArgs) > 0 && agentArgs[0] == "add") {
+		if err := checkBreachDB(); err != nil {
+			return err
+		}
+		p, err := pol.load()
+		if err != nil {
+			return err
+		}
+		policy = p
+	} else if set["breach-db"] || pol.any() {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -breach-db, -policy, -min-* et -banned-words n'ont d'effet qu'en mode enc et agent add, ils sont ignorés")))
+	}
+	if set["words"] || set["wordlist"] {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -words et -wordlist n'ont d'effet qu'en mode genpass, ils sont ignorés")))
+	}
+	// agent non plus : il ne sert que des secrets.
+	if *mode == "agent" {
+		return doAgent(agentArgs, *keyFile, *agentTTL, set["agent-ttl"])
+	}
+	if *symmetric {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -symmetric n'a d'effet qu'en mode keygen, il est ignoré")))
+	}
 
-	if *mode == "" || *fileIn == "" {
+	if *mode == "" {
 		usage()
-		return errors.New("-mode et -in sont obligatoires")
+		return errorf("une commande est obligatoire : chiffremento help les liste")
+	}
+	if *fileIn == "" {
+		fs.Usage()
+		return errorf("-in est obligatoire")
+	}
+	progressOut, err = prog.open(*mode, set, jsonOutput || isStream(*fileOut))
+	if err != nil {
+		return err
+	}
+
+	if *mode == "upgrade" {
+		if *chacha || *parano || *aegis || *pad || *meta != "" {
+			fmt.Fprintln(diag, styleDim.Render(tr(
+				"note : -pad, -chacha, -parano, -aegis et -meta n'ont pas d'effet en mode upgrade : l'algorithme et le contenu sont conservés")))
+		}
+	} else if *mode != "enc" && (*compress || *chacha || *parano || *aegis || *pad || kdf.any() || *meta != "") {
+		fmt.Fprintln(diag, styleDim.Render(tr(
+			"note : -comp, -pad, -chacha, -parano, -aegis, -meta et les options -kdf* n'ont d'effet qu'en mode enc, elles sont ignorées ici")))
+	}
+	if (*mode == "info" || *mode == "upgrade" || *mode == "edit" || *mode == "exec") && *fileOut != "" {
+		fmt.Fprintf(diag, "%s\n", styleDim.Render(fmt.Sprintf(tr("note : -out n'a pas d'effet en mode %s, il est ignoré"), *mode)))
+	}
+	var maxMem uint32
+	if *maxKDFMem != "" {
+		if *mode != "dec" && *mode != "verify" && *mode != "upgrade" && *mode != "edit" && *mode != "exec" {
+			fmt.Fprintln(diag, styleDim.Render(tr("note : -max-kdf-mem n'a d'effet qu'en modes dec, verify, upgrade, edit et exec, il est ignoré")))
+		}
+		m, err := parseMemSize(*maxKDFMem)
+		if err != nil {
+			return errorf("-max-kdf-mem : %w", err)
+		}
+		maxMem = m
+	}
+	if len(asFD) > 0 && *mode != "exec" {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -as-fd n'a d'effet qu'en mode exec, il est ignoré")))
+	}
+	if *recursive && *mode != "upgrade" {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -r n'a d'effet qu'en mode upgrade, il est ignoré")))
+	}
+	splitting := *nShares != 0 || *threshold != 0
+	if splitting && *mode != "enc" {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -shares et -threshold n'ont d'effet qu'en mode enc, ils sont ignorés")))
+		splitting = false
+	}
+	if splitting {
+		if *nShares == 0 || *threshold == 0 {
+			return errorf("-shares et -threshold vont ensemble : combien de parts, et combien pour déchiffrer ?")
+		}
+		if *keyFile != "" || passSrc.set() || kdf.any() {
+			return errorf("-shares chiffre avec une clé neuve : -key-file, les sources de mot de passe et les options -kdf* sont sans objet")
+		}
+	}
+	if len(shareList) > 0 && *mode != "dec" && *mode != "verify" && *mode != "edit" && *mode != "exec" {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -share n'a d'effet qu'en modes dec, verify, edit et exec, il est ignoré")))
+		shareList = nil
+	}
+	if len(shareList) > 0 && (*keyFile != "" || passSrc.set()) {
+		return errorf("-share reconstitue la clé du fichier : il s'exclut avec -key-file et les sources de mot de passe")
 	}
 
-	if *mode != "enc" && (*compress || *chacha || *parano || *pad || *kdf != "" || *meta != "") {
-		fmt.Fprintln(os.Stderr, styleDim.Render(
-			"note : -comp, -pad, -chacha, -parano, -kdf et -meta n'ont d'effet qu'en mode enc, ils sont ignorés ici"))
+	if *recovery && *mode != "enc" {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -recovery n'a d'effet qu'en mode enc, il est ignoré")))
+		*recovery = false
+	}
+	if *recovery && (*keyFile != "" || splitting) {
+		return errorf("-recovery double un mot de passe : il s'exclut avec -key-file et -shares")
+	}
+	if *qr && !*recovery {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -qr n'a d'effet qu'avec -recovery, il est ignoré")))
+	}
+	if *recoveryCode != "" && *mode != "dec" && *mode != "verify" {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -recovery-code n'a d'effet qu'en modes dec et verify, il est ignoré")))
+		*recoveryCode = ""
 	}
-	if *mode == "info" && *fileOut != "" {
-		fmt.Fprintln(os.Stderr, styleDim.Render("note : -out n'a pas d'effet en mode info, il est ignoré"))
+	var rescue []byte
+	if *recoveryCode != "" {
+		if *keyFile != "" || len(shareList) > 0 || passSrc.set() {
+			return errorf("-recovery-code remplace le mot de passe : il s'exclut avec -key-file, -share et les sources de mot de passe")
+		}
+		c, err := readRecoveryCode(*recoveryCode)
+		if err != nil {
+			return err
+		}
+		rescue = c
+		defer zero(rescue)
+	}
+
+	var key []byte
+	if len(shareList) > 0 {
+		k, err := loadShares(shareList)
+		if err != nil {
+			return err
+		}
+		key = k
+		defer zero(key)
+	} else if *keyFile != "" && (*mode == "enc" || *mode == "dec" || *mode == "verify" || *mode == "edit" || *mode == "exec") {
+		if passSrc.set() {
+			return errorf("-key-file remplace le mot de passe : il s'exclut avec -passfile, -passenv, -passfd et -passcmd")
+		}
+		if *mode == "enc" && kdf.any() {
+			return errorf("-key-file remplace la dérivation de mot de passe : les options -kdf* sont sans objet")
+		}
+		k, err := loadKeyFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		key = k
+		defer zero(key)
+	} else if *keyFile != "" {
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -key-file n'a d'effet qu'en modes enc, dec, verify, edit et exec, il est ignoré")))
 	}
 
 	switch *mode {
 	case "enc":
-		algo, err := chooseAlgo(*chacha, *parano)
+		algo, err := chooseAlgo(*chacha, *parano, *aegis)
 		if err != nil {
 			return err
 		}
-		profile, err := pkg.ParseKDFProfile(*kdf)
+		metaMode, err := pkg.ParseMetadataMode(*meta)
 		if err != nil {
 			return err
 		}
-		metaMode, err := pkg.ParseMetadataMode(*meta)
+		opts := pkg.Options{Algo: algo, Comp: chooseComp(*compress), Pad: *pad, Metadata: metaMode, Key: key}
+		progressOut.attach(&opts)
+		if splitting {
+			return encryptWithShares(*fileIn, *fileOut, *nShares, *threshold, opts)
+		}
+		if key == nil {
+			if err := kdf.apply(&opts); err != nil {
+				return err
+			}
+		}
+		if !*recovery {
+			return doEncrypt(*fileIn, *fileOut, opts)
+		}
+		code, err := pkg.GenerateRecoveryCode()
 		if err != nil {
 			return err
 		}
-		return doEncrypt(*fileIn, *fileOut, pkg.Options{
-			Algo: algo, Comp: chooseComp(*compress), Pad: *pad,
-			KDF: profile, Metadata: metaMode,
-		})
+		defer zero(code)
+		opts.RecoveryCode = code
+		if err := doEncrypt(*fileIn, *fileOut, opts); err != nil {
+			return err
+		}
+		return printRecoveryCode(code, *qr)
 	case "dec":
-		return doDecrypt(*fileIn, *fileOut)
+		opts := pkg.Options{MaxKDFMemory: maxMem, Key: key, RecoveryCode: rescue}
+		progressOut.attach(&opts)
+		return doDecrypt(*fileIn, *fileOut, opts)
 	case "verify":
-		return doVerify(*fileIn)
+		opts := pkg.Options{MaxKDFMemory: maxMem, Key: key, RecoveryCode: rescue}
+		progressOut.attach(&opts)
+		return doVerify(*fileIn, opts)
 	case "info":
 		return doInfo(*fileIn)
+	case "upgrade":
+		opts := pkg.Options{Comp: chooseComp(*compress), MaxKDFMemory: maxMem}
+		progressOut.attach(&opts)
+		if err := kdf.apply(&opts); err != nil {
+			return err
+		}
+		return doUpgrade(*fileIn, *recursive, opts)
+	case "edit":
+		return doEdit(*fileIn, pkg.Options{MaxKDFMemory: maxMem, Key: key})
+	case "exec":
+		return doExec(*fileIn, fs.Args(), asFD, pkg.Options{MaxKDFMemory: maxMem, Key: key})
 	default:
-		return fmt.Errorf("mode inconnu %q (attendu enc, dec, verify, info ou bench)", *mode)
+		return errorf("mode inconnu %q (attendu enc, dec, verify, info, upgrade, edit, exec, keygen, genpass, breachdb-build, agent ou bench)", *mode)
 	}
 }
 
+// foreground liste, pendant qu'un processus enfant tourne (l'éditeur de edit,
+// la commande de exec), les signaux qui lui reviennent : le gestionnaire les
+// laisse passer au lieu d'interrompre. nil le reste du temps.
+var foreground atomic.Pointer[[]os.Signal]
+
 // installSignalHandler évite qu'un Ctrl+C laisse un .chto-tmp-* orphelin :
 // les defer ne s'exécutent pas quand le processus est interrompu.
 func installSignalHandler() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-c
+		for sig := range c {
+			if f := foreground.Load(); f != nil && slices.Contains(*f, sig) {
+				continue
+			}
+			break
+		}
 		pkg.CleanupTemporaries()
-		fmt.Fprintln(os.Stderr, "\ninterrompu")
-		os.Exit(130)
+		stopAgent()
+		fmt.Fprintln(os.Stderr, tr("\ninterrompu"))
+		os.Exit(exitInterrupted)
 	}()
 }
 
 // chooseAlgo refuse les combinaisons contradictoires. La v1 laissait -parano
 // écraser -chacha en silence.
-func chooseAlgo(chacha, parano bool) (byte, error) {
+func chooseAlgo(chacha, parano, aegis bool) (byte, error) {
 	switch {
 	case chacha && parano:
-		return 0, errors.New("-chacha et -parano s'excluent : le mode parano utilise déjà chacha20 en couche externe")
+		return 0, errorf("-chacha et -parano s'excluent : le mode parano utilise déjà chacha20 en couche externe")
+	case aegis && (chacha || parano):
+		return 0, errorf("-aegis s'exclut avec -chacha et -parano : un seul algorithme par fichier")
+	case aegis:
+		return pkg.AlgoAEGIS, nil
 	case parano:
 		return pkg.AlgoCascade, nil
 	case chacha:
@@ -150,13 +438,10 @@ func chooseComp(compress bool) byte {
 func isStream(p string) bool { return p == "-" }
 
 func doEncrypt(in, out string, opts pkg.Options) error {
-	algo, comp, pad, kdf, meta := opts.Algo, opts.Comp, opts.Pad, opts.KDF, opts.Metadata
+	algo, comp, pad, meta := opts.Algo, opts.Comp, opts.Pad, opts.Metadata
 	if algo == 0 {
 		algo = pkg.AlgoAES
 	}
-	if kdf == "" {
-		kdf = pkg.KDFStandard
-	}
 
 	// Un dossier glissé dans le terminal ou complété par le shell arrive
 	// souvent avec un séparateur final : sans ce nettoyage, la sortie
@@ -164,12 +449,12 @@ func doEncrypt(in, out string, opts pkg.Options) error {
 	if !isStream(in) {
 		in = trimTrailingSeparator(in)
 		if strings.HasSuffix(in, extension) {
-			return fmt.Errorf("%s porte déjà l'extension %s : il semble déjà chiffré", in, extension)
+			return errorf("%s porte déjà l'extension %s : il semble déjà chiffré", in, extension)
 		}
 	}
 	if out == "" {
 		if isStream(in) {
-			return errors.New("-out est obligatoire quand l'entrée est l'entrée standard")
+			return errorf("-out est obligatoire quand l'entrée est l'entrée standard")
 		}
 		out = in + extension
 	}
@@ -179,35 +464,37 @@ func doEncrypt(in, out string, opts pkg.Options) error {
 		}
 	}
 
-	password, err := readPassword(true, isStream(in))
+	password, err := encryptSecret(opts, isStream(in))
 	if err != nil {
 		return err
 	}
-	defer zero(password)
+	defer password.Destroy()
 
-	fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("chiffrement  "), pkg.AlgoName(algo))
-	fmt.Fprintf(os.Stderr, "%s %s (%s)\n", styleDim.Render("kdf          "), kdf.KDFLabel(), kdf)
+	fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("chiffrement  ")), pkg.AlgoName(algo))
+	fmt.Fprintf(diag, "%s %s\n", styleDim.Render("kdf          "), kdfDescription(opts))
 	if comp != pkg.CompNone {
-		fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("compression  "), pkg.CompName(comp))
+		fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("compression  ")), pkg.CompName(comp))
 	}
 	if pad {
-		fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("remplissage  "), "taille arrondie au palier supérieur")
+		fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("remplissage  ")), tr("taille arrondie au palier supérieur"))
 	}
 	if meta == pkg.MetadataMinimal {
-		fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("métadonnées  "),
-			"nom d'origine et date conservés dans le chiffré")
+		fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("métadonnées  ")),
+			tr("nom d'origine et date conservés dans le chiffré"))
 	}
 	if !isStream(in) {
 		if st, err := os.Stat(in); err == nil && st.IsDir() {
-			fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("contenu      "),
-				"dossier, empaqueté en tar au fil du chiffrement")
+			fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("contenu      ")),
+				tr("dossier, empaqueté en tar au fil du chiffrement"))
 		}
 	}
 
-	if err := encryptTo(in, out, password, opts); err != nil {
+	err = encryptTo(in, out, password.Bytes(), opts)
+	progressOut.flush()
+	if err != nil {
 		return err
 	}
-	fmt.Fprintf(os.Stderr, "%s %s\n", styleAccent.Render("✓"), describeDest(out))
+	fmt.Fprintf(diag, "%s %s\n", styleAccent.Render("✓"), describeDest(out))
 	return nil
 }
 
@@ -237,19 +524,25 @@ func encryptTo(in, out string, password []byte, opts pkg.Options) error {
 	return closeDst()
 }
 
-func doDecrypt(in, out string) error {
+func doDecrypt(in, out string, opts pkg.Options) error {
+	r := newReport("dec", in)
+	return r.finish(decryptFile(r, in, out, opts))
+}
+
+func decryptFile(r *opReport, in, out string, opts pkg.Options) error {
 	if !isStream(in) && !strings.HasSuffix(in, extension) {
-		return fmt.Errorf("un fichier à déchiffrer doit porter l'extension %s", extension)
+		return errorf("un fichier à déchiffrer doit porter l'extension %s", extension)
 	}
 	if out == "" {
 		if isStream(in) {
-			return errors.New("-out est obligatoire quand l'entrée est l'entrée standard")
+			return errorf("-out est obligatoire quand l'entrée est l'entrée standard")
 		}
 		out = strings.TrimSuffix(in, extension)
 		if filepath.Base(out) == "" || filepath.Base(in) == extension {
-			return fmt.Errorf("%s ne donne aucun nom de sortie exploitable", in)
+			return errorf("%s ne donne aucun nom de sortie exploitable", in)
 		}
 	}
+	r.Output = out
 	if !isStream(in) && !isStream(out) {
 		if err := checkPaths(in, out); err != nil {
 			return err
@@ -259,112 +552,161 @@ func doDecrypt(in, out string) error {
 	// L'en-tête est lisible sans mot de passe : autant annoncer les vrais
 	// paramètres du fichier avant de demander quoi que ce soit. Sur un flux,
 	// c'est impossible sans consommer les octets, donc on s'en passe.
+	var details *pkg.Details
 	if !isStream(in) {
 		d, err := pkg.Inspect(in)
 		if err != nil {
 			return err
 		}
-		fmt.Fprintf(os.Stderr, "%s format v%d · %s · %s%s\n", styleDim.Render("fichier      "),
+		details = &d
+		r.Details = &d
+		r.setArchive(d.Archive)
+		fmt.Fprintf(diag, tr("%s format v%d · %s · %s%s\n"), styleDim.Render(tr("fichier      ")),
 			d.Version, d.Algo, d.KDF, detailsSuffix(d))
+		// Refuser avant le mot de passe : le taper pour rien serait pénible,
+		// et la dérivation échouerait de toute façon.
+		if err := checkSecretKind(d, opts); err != nil {
+			return err
+		}
+		if err := pkg.CheckKDFMemory(d.KDFMemoryKiB, opts.MaxKDFMemory); err != nil {
+			return err
+		}
 		if d.Archive {
 			if isStream(out) {
-				fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("sortie       "),
-					"flux tar sur la sortie standard (à passer à tar)")
+				fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("sortie       ")),
+					tr("flux tar sur la sortie standard (à passer à tar)"))
 			} else {
-				fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("sortie       "),
-					out+string(os.PathSeparator)+" (dossier, doit ne pas exister)")
+				fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("sortie       ")),
+					fmt.Sprintf(tr("%s (dossier, doit ne pas exister)"), out+string(os.PathSeparator)))
 			}
 		}
 	}
 
-	password, err := readPassword(false, isStream(in))
-	if err != nil {
-		return err
-	}
-	defer zero(password)
-
-	meta, err := decryptTo(in, out, password)
+	// Sur un flux, un second essai est impossible : l'en-tête est consommé,
+	// et le clair d'un secret juste sur un fichier abîmé déjà émis.
+	err := unlock(secretKind(details, opts), opts, isStream(in), isStream(in) || isStream(out),
+		func(password []byte, opts pkg.Options) error {
+			res, err := decryptTo(r, in, out, password, opts)
+			progressOut.flush()
+			if err == nil {
+				r.setResult(res)
+			}
+			return err
+		})
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(os.Stderr, "%s %s\n", styleAccent.Render("✓"), describeDest(out))
+	fmt.Fprintf(diag, "%s %s\n", styleAccent.Render("✓"), describeDest(out))
 
 	// Le nom d'origine n'est lisible qu'après authentification : impossible de
 	// l'annoncer plus tôt, et impossible de nommer la sortie avec avant d'avoir
 	// vérifié le fichier. On le signale donc, sans renommer d'autorité.
-	if meta != nil {
-		fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("nom d'origine"), meta.Name)
+	if meta := r.Metadata; meta != nil {
+		fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("nom d'origine")), meta.Name)
 		if !isStream(out) && filepath.Base(out) != meta.Name {
-			fmt.Fprintf(os.Stderr, "%s %s\n", styleDim.Render("             "),
-				"diffère du nom de sortie ; à renommer si besoin")
+			fmt.Fprintf(diag, "%s %s\n", styleDim.Render("             "),
+				tr("diffère du nom de sortie ; à renommer si besoin"))
 		}
 	}
 	return nil
 }
 
 // decryptTo aiguille comme encryptTo. Sur la sortie standard, une archive sort
-// telle quelle, en tar : il n'y a rien à extraire dans un tube.
-func decryptTo(in, out string, password []byte) (*pkg.FileMetadata, error) {
+// telle quelle, en tar : il n'y a rien à extraire dans un tube. r reçoit la
+// taille de l'entrée.
+func decryptTo(r *opReport, in, out string, password []byte, opts pkg.Options) (pkg.DecryptResult, error) {
 	if !isStream(in) && !isStream(out) {
-		res, err := pkg.DecryptTo(in, out, password, pkg.Options{})
-		return res.Metadata, err
+		if st, err := os.Stat(in); err == nil {
+			r.setInputSize(st.Size())
+		}
+		return pkg.DecryptTo(in, out, password, opts)
 	}
 
-	src, _, closeSrc, err := openSource(in)
+	src, size, closeSrc, err := openSource(in)
 	if err != nil {
-		return nil, err
+		return pkg.DecryptResult{}, err
 	}
 	defer closeSrc()
+	if size >= 0 {
+		r.setInputSize(size)
+	} else {
+		src = r.countInput(src)
+	}
 
 	dst, closeDst, err := openDest(out)
 	if err != nil {
-		return nil, err
+		return pkg.DecryptResult{}, err
 	}
 	defer closeDst()
 
 	// Sur un flux, les métadonnées ne sont pas remontées : il n'y a pas de
 	// fichier de sortie à qui appliquer une date, et l'appelant a déjà choisi
 	// où vont les octets.
-	if err := pkg.DecryptStream(dst, src, password, pkg.Options{}); err != nil {
-		return nil, err
+	written := &countingWriter{w: dst}
+	if err := pkg.DecryptStream(written, src, password, opts); err != nil {
+		return pkg.DecryptResult{}, err
 	}
-	return nil, closeDst()
+	return pkg.DecryptResult{Bytes: written.n}, closeDst()
 }
 
 // doVerify contrôle qu'un fichier est intact et déchiffrable sans rien écrire
 // sur le disque. Pratique pour vérifier une sauvegarde sans l'extraire.
-func doVerify(in string) error {
+func doVerify(in string, opts pkg.Options) error {
+	r := newReport("verify", in)
+	return r.finish(verifyFile(r, in, opts))
+}
+
+func verifyFile(r *opReport, in string, opts pkg.Options) error {
 	if !isStream(in) && !strings.HasSuffix(in, extension) {
-		return fmt.Errorf("un fichier à vérifier doit porter l'extension %s", extension)
+		return errorf("un fichier à vérifier doit porter l'extension %s", extension)
 	}
 
 	// Sur un flux, l'en-tête n'est pas relisible d'avance : on ne peut donc pas
 	// savoir s'il s'agit d'une archive avant de l'avoir déchiffrée.
 	archive := false
+	var details *pkg.Details
 	if !isStream(in) {
 		d, err := pkg.Inspect(in)
 		if err != nil {
 			return err
 		}
+		details = &d
+		r.Details = &d
+		r.setArchive(d.Archive)
 		archive = d.Archive
-		fmt.Fprintf(os.Stderr, "%s format v%d · %s · %s%s\n", styleDim.Render("fichier      "),
+		fmt.Fprintf(diag, tr("%s format v%d · %s · %s%s\n"), styleDim.Render(tr("fichier      ")),
 			d.Version, d.Algo, d.KDF, detailsSuffix(d))
+		if err := checkSecretKind(d, opts); err != nil {
+			return err
+		}
+		if err := pkg.CheckKDFMemory(d.KDFMemoryKiB, opts.MaxKDFMemory); err != nil {
+			return err
+		}
 	}
 
-	password, err := readPassword(false, isStream(in))
-	if err != nil {
-		return err
-	}
-	defer zero(password)
-
-	if isStream(in) {
-		if err := pkg.VerifyStream(os.Stdin, password, pkg.Options{}); err != nil {
+	err := unlock(secretKind(details, opts), opts, isStream(in), isStream(in),
+		func(password []byte, opts pkg.Options) error {
+			var res pkg.DecryptResult
+			var err error
+			if isStream(in) {
+				res, err = pkg.VerifyStreamReport(r.countInput(os.Stdin), password, opts)
+			} else {
+				if st, err := os.Stat(in); err == nil {
+					r.setInputSize(st.Size())
+				}
+				res, err = pkg.VerifyReport(in, password, opts)
+			}
+			progressOut.flush()
+			if err == nil {
+				r.setResult(res)
+				archive = res.Archive
+			}
 			return err
-		}
-	} else if err := pkg.Verify(in, password, pkg.Options{}); err != nil {
+		})
+	if err != nil {
 		return err
 	}
-	fmt.Fprintf(os.Stderr, "%s %s\n", styleAccent.Render("✓"),
+	fmt.Fprintf(diag, "%s %s\n", styleAccent.Render("✓"),
 		styleText.Render(verifySucces(archive)))
 	return nil
 }
@@ -372,8 +714,13 @@ func doVerify(in string) error {
 // doInfo affiche l'en-tête d'un .chto sans le déchiffrer : ni mot de passe, ni
 // écriture sur le disque.
 func doInfo(in string) error {
+	r := newReport("info", in)
+	return r.finish(inspectFile(r, in))
+}
+
+func inspectFile(r *opReport, in string) error {
 	if isStream(in) {
-		return errors.New("info a besoin d'un fichier : l'en-tête d'un flux ne peut pas être relu sans le consommer")
+		return errorf("info a besoin d'un fichier : l'en-tête d'un flux ne peut pas être relu sans le consommer")
 	}
 	d, err := pkg.Inspect(in)
 	if err != nil {
@@ -383,29 +730,172 @@ func doInfo(in string) error {
 	if err != nil {
 		return err
 	}
+	r.Details = &d
+	r.setInputSize(st.Size())
+	r.setArchive(d.Archive)
+	if jsonOutput {
+		return nil
+	}
 
 	line := func(label, value string) {
 		fmt.Printf("%s%s\n", styleInfoLabel.Render(label), styleText.Render(value))
 	}
-	line("fichier", in)
-	line("taille", fmt.Sprintf("%d octets", st.Size()))
-	line("format", fmt.Sprintf("v%d", d.Version))
+	line(tr("fichier"), in)
+	line(tr("taille"), fmt.Sprintf(tr("%d octets"), st.Size()))
+	line(tr("format"), fmt.Sprintf("v%d", d.Version))
 	line("aead", d.Algo)
 	line("kdf", d.KDF)
-	line("compression", d.Comp)
-	line("contenu", map[bool]string{true: "dossier (archive tar)", false: "fichier"}[d.Archive])
-	line("remplissage", map[bool]string{true: "oui, taille réelle masquée", false: "non"}[d.Padded])
-	line("métadonnées", map[bool]string{
-		true:  "oui, nom et date à l'intérieur du chiffré",
-		false: "non",
+	if d.KeyBased {
+		line(tr("secret"), tr("clé symétrique (-key-file), pas de mot de passe"))
+	} else {
+		line(tr("secret"), tr("mot de passe"))
+		line(tr("mémoire"), memoryRequirement(d.KDFMemoryKiB))
+		line(tr("secours"), map[bool]string{true: tr("code de secours (-recovery-code)"), false: tr("aucun code de secours")}[d.Recovery])
+	}
+	line(tr("compression"), d.Comp)
+	line(tr("contenu"), map[bool]string{true: tr("dossier (archive tar)"), false: tr("fichier")}[d.Archive])
+	line(tr("remplissage"), map[bool]string{true: tr("oui, taille réelle masquée"), false: tr("non")}[d.Padded])
+	line(tr("métadonnées"), map[bool]string{
+		true:  tr("oui, nom et date à l'intérieur du chiffré"),
+		false: tr("non"),
 	}[d.Metadata])
 	if d.Version < 3 {
-		fmt.Println(styleDim.Render(fmt.Sprintf(
-			"  produit par un format v%d : lecture seule, les nouveaux fichiers sont en v3", d.Version)))
+		fmt.Println(styleDim.Render(fmt.Sprintf(tr(
+			"  produit par un format v%d : lecture seule, les nouveaux fichiers sont en v%d"), d.Version, pkg.FormatVersion)))
 	}
 	return nil
 }
 
+// memoryRequirement décrit la mémoire qu'exigera la dérivation, comparée à ce
+// qui est disponible ici quand le système sait le dire.
+func memoryRequirement(needKiB uint64) string {
+	s := fmt.Sprintf(tr("%d Mio pour la dérivation"), needKiB/1024)
+	avail, ok := pkg.AvailableMemoryKiB()
+	switch {
+	case !ok:
+		return s
+	case needKiB > avail:
+		return fmt.Sprintf(tr("%s — %d Mio disponibles ici : indéchiffrable sur cette machine"), s, avail/1024)
+	default:
+		return fmt.Sprintf(tr("%s (%d Mio disponibles ici)"), s, avail/1024)
+	}
+}
+
+// doUpgrade réécrit au format courant un .chto, ou tous ceux d'un dossier avec
+// -r. Un seul mot de passe est demandé pour le lot : un fichier qui ne
+// l'accepte pas est signalé dans le bilan, pas traité comme une erreur fatale,
+// pour qu'un fichier récalcitrant n'empêche pas de mettre à niveau les autres.
+func doUpgrade(in string, recursive bool, opts pkg.Options) error {
+	if isStream(in) {
+		return errorf("upgrade réécrit les fichiers en place : il a besoin d'un chemin, pas d'un flux")
+	}
+	in = trimTrailingSeparator(in)
+	targets, err := upgradeTargets(in, recursive)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Fprintf(diag, "%s\n", styleDim.Render(fmt.Sprintf(tr("aucun fichier %s trouvé dans %s"), extension, in)))
+		return nil
+	}
+
+	// Les fichiers déjà à jour sont écartés avant de demander quoi que ce
+	// soit : un lot sans rien à faire ne doit pas exiger de mot de passe.
+	var todo []string
+	for _, t := range targets {
+		d, err := pkg.Inspect(t)
+		if err == nil && !d.Outdated() {
+			continue
+		}
+		todo = append(todo, t)
+	}
+	fmt.Fprintf(diag, tr("%s %d fichier(s), %d à mettre à niveau\n"),
+		styleDim.Render(tr("upgrade      ")), len(targets), len(todo))
+	fmt.Fprintf(diag, "%s %s\n", styleDim.Render("kdf          "), kdfDescription(opts))
+
+	var password *pkg.SecureBuffer
+	if len(todo) > 0 {
+		password, err = readPassword(false, false)
+		if err != nil {
+			return err
+		}
+		defer password.Destroy()
+	}
+
+	echecs := 0
+	for _, t := range targets {
+		res, err := pkg.Upgrade(t, password.Bytes(), opts)
+		progressOut.flush()
+		printUpgradeLine(t, res, err)
+		if err != nil {
+			echecs++
+		}
+	}
+	if echecs > 0 {
+		return errorf("%d fichier(s) sur %d n'ont pas pu être mis à niveau", echecs, len(targets))
+	}
+	return nil
+}
+
+// upgradeTargets liste les .chto à traiter. Un dossier sans -r est refusé :
+// réécrire tout un arbre doit être un choix explicite.
+func upgradeTargets(in string, recursive bool) ([]string, error) {
+	st, err := os.Stat(in)
+	if err != nil {
+		return nil, errorf("lecture: %w", err)
+	}
+	if !st.IsDir() {
+		if !strings.HasSuffix(in, extension) {
+			return nil, errorf("un fichier à mettre à niveau doit porter l'extension %s", extension)
+		}
+		return []string{in}, nil
+	}
+	if !recursive {
+		return nil, errorf("%s est un dossier : -r met à niveau tous les %s qu'il contient", in, extension)
+	}
+
+	var targets []string
+	err = filepath.WalkDir(in, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Les temporaires d'une opération interrompue portent un nom en
+		// .chto-tmp-* : ce ne sont pas des fichiers à réécrire.
+		if d.Type().IsRegular() && strings.HasSuffix(p, extension) &&
+			!strings.HasPrefix(d.Name(), ".chto-tmp-") {
+			targets = append(targets, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errorf("parcours de %s: %w", in, err)
+	}
+	return targets, nil
+}
+
+// printUpgradeLine ajoute une ligne au bilan, sur la sortie standard : c'est le
+// résultat de la commande, les notes restent sur la sortie d'erreur.
+func printUpgradeLine(path string, res pkg.UpgradeResult, err error) {
+	var etat string
+	switch {
+	case err != nil:
+		etat = styleError.Render(tr("échec")) + "  " + styleDim.Render(err.Error())
+	case !res.Upgraded:
+		etat = styleDim.Render(tr("déjà à jour"))
+	default:
+		etat = styleAccent.Render("✓") + " " + styleText.Render(fmt.Sprintf("v%d → v%d", res.From, pkg.FormatVersion))
+		if res.CompFrom != res.CompTo {
+			etat += styleDim.Render(fmt.Sprintf(" · %s → %s",
+				strings.Fields(pkg.CompName(res.CompFrom))[0], pkg.CompName(res.CompTo)))
+		}
+	}
+	version := "   "
+	if res.From != 0 {
+		version = fmt.Sprintf("v%d ", res.From)
+	}
+	fmt.Printf("  %s %s  %s\n", styleDim.Render(version), styleText.Render(path), etat)
+}
+
 // openSource ouvre l'entrée et renvoie sa taille, ou -1 si elle est inconnue.
 func openSource(in string) (io.Reader, int64, func(), error) {
 	if isStream(in) {
@@ -413,7 +903,7 @@ func openSource(in string) (io.Reader, int64, func(), error) {
 	}
 	f, err := os.Open(in)
 	if err != nil {
-		return nil, 0, nil, fmt.Errorf("lecture: %w", err)
+		return nil, 0, nil, errorf("lecture: %w", err)
 	}
 	size := int64(-1)
 	if st, err := f.Stat(); err == nil && st.Mode().IsRegular() {
@@ -431,7 +921,7 @@ func openDest(out string) (io.Writer, func() error, error) {
 	}
 	f, err := os.Create(out)
 	if err != nil {
-		return nil, nil, fmt.Errorf("création de %s: %w", out, err)
+		return nil, nil, errorf("création de %s: %w", out, err)
 	}
 	closed := false
 	return f, func() error {
@@ -445,7 +935,7 @@ func openDest(out string) (io.Writer, func() error, error) {
 
 func describeDest(out string) string {
 	if isStream(out) {
-		return "écrit sur la sortie standard"
+		return tr("écrit sur la sortie standard")
 	}
 	return out
 }
@@ -456,10 +946,10 @@ func detailsSuffix(d pkg.Details) string {
 		s += " · " + d.Comp
 	}
 	if d.Archive {
-		s += " · dossier"
+		s += " · " + tr("dossier")
 	}
 	if d.Padded {
-		s += " · taille masquée"
+		s += " · " + tr("taille masquée")
 	}
 	return s
 }
@@ -476,12 +966,12 @@ func trimTrailingSeparator(p string) string {
 // checkPaths attrape les cas où l'on écraserait la source par la sortie.
 func checkPaths(in, out string) error {
 	if in == out {
-		return errors.New("le fichier d'entrée et le fichier de sortie sont identiques")
+		return errorf("le fichier d'entrée et le fichier de sortie sont identiques")
 	}
 	absIn, err1 := filepath.Abs(in)
 	absOut, err2 := filepath.Abs(out)
 	if err1 == nil && err2 == nil && absIn == absOut {
-		return errors.New("le fichier d'entrée et le fichier de sortie sont identiques")
+		return errorf("le fichier d'entrée et le fichier de sortie sont identiques")
 	}
 	return nil
 }
@@ -493,60 +983,132 @@ func zero(b []byte) {
 }
 
 func usage() {
-	fmt.Fprintf(os.Stderr, `chiffremento %s — chiffrement de fichiers et de dossiers
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, tr(`chiffremento %s — chiffrement de fichiers et de dossiers
 
-  chiffremento                        interface guidée
-  chiffremento -mode enc    -in FICHIER|DOSSIER [-out CHEMIN] [options]
-  chiffremento -mode dec    -in FICHIER%s      [-out CHEMIN]
-  chiffremento -mode verify -in FICHIER%s      contrôle sans rien écrire
-  chiffremento -mode info   -in FICHIER%s      en-tête, sans mot de passe
+  chiffremento                       interface guidée
+  chiffremento COMMANDE [options]
 
-Un dossier est empaqueté en tar au fil du chiffrement, et recréé à l'identique
-au déchiffrement.
+Commandes :
+`), version)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, tr(c.summary))
+	}
+	fmt.Fprintf(w, tr(`  %-16s %s
+  %-16s %s
 
 -in - lit l'entrée standard, -out - écrit sur la sortie standard : l'outil est
 donc composable. Sur un flux, l'écriture atomique n'existe pas et le clair sort
 avant que la fin du fichier soit authentifiée — à réserver aux tubes.
 
-  chiffremento -mode dec -in sauvegarde%s -out - | tar tf -
-
 Le mot de passe n'est jamais passé en argument : il est demandé de façon
-masquée, ou lu sur l'entrée standard si celle-ci n'est pas un terminal.
+masquée, ou lu sur l'entrée standard si celle-ci n'est pas un terminal. Pour
+l'automatisation, -passfile, -passenv, -passfd et -passcmd le lisent
+ailleurs — indispensables avec -in - sans terminal.
+
+L'ancienne forme, chiffremento -mode COMMANDE [options], reste acceptée avec
+toutes les options ; chiffremento -version affiche la version.
+`), "help "+tr("[COMMANDE]"), tr("options et exemples d'une commande"),
+		"completion SHELL", tr("script de complétion pour bash, zsh ou fish"))
+}
+
+// doKeygen crée une clé symétrique dans out, ou l'écrit sur la sortie standard
+// avec -out -. Un fichier existant n'est jamais écrasé.
+func doKeygen(out string) error {
+	if out == "" {
+		return errorf("-out est obligatoire : où écrire la clé ? (- pour la sortie standard)")
+	}
+	key, err := pkg.GenerateKey()
+	if err != nil {
+		return err
+	}
+	defer zero(key)
+
+	if isStream(out) {
+		data, err := pkg.EncodeKey(key)
+		if err != nil {
+			return err
+		}
+		defer zero(data)
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := pkg.WriteKeyFile(out, key); err != nil {
+		return err
+	}
+	fmt.Fprintf(diag, "%s %s\n", styleAccent.Render("✓"),
+		styleText.Render(fmt.Sprintf(tr("clé symétrique de 256 bits écrite dans %s (lisible par son seul propriétaire)"), out)))
+	fmt.Fprintln(diag, styleDim.Render(tr("  qui a ce fichier peut tout déchiffrer, et sans lui rien ne se déchiffre : à sauvegarder à part")))
+	return nil
+}
 
-Options :
-`, version, extension, extension, extension, extension)
-	flag.PrintDefaults()
+// doGenpass tire une phrase de passe et l'écrit seule sur la sortie standard,
+// pour qu'elle puisse passer dans un tube ; son entropie va sur la sortie
+// d'erreur, à côté de l'estimation de zxcvbn. Les deux diffèrent : l'une
+// compte les tirages possibles, l'autre ce qu'un attaquant sans la liste
+// essaierait.
+func doGenpass(n int, lang string) error {
+	phrase, err := pkg.GeneratePassphrase(n, lang)
+	if err != nil {
+		return err
+	}
+	defer zero(phrase)
+	bits, err := pkg.PassphraseBits(n, lang)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Printf("%s\n", phrase); err != nil {
+		return err
+	}
+	fmt.Fprintf(diag, "%s %s\n", styleDim.Render(tr("entropie     ")),
+		fmt.Sprintf(tr("%.0f bits, %d mots tirés au hasard · %s hors ligne"), bits, n, crackTime(bits)))
+	fmt.Fprintf(diag, "%s %s\n", styleDim.Render("zxcvbn       "), strengthHint(string(phrase)))
+	return nil
 }
 
 // doBench mesure les coûts sur cette machine. Ni lecture ni écriture de
 // fichier, ni mot de passe : c'est de l'information, pas une opération.
 func doBench() error {
 	rep := pkg.Benchmark()
+	if jsonOutput {
+		r := newReport("bench", "")
+		r.Bench = &rep
+		return r.finish(nil)
+	}
 
-	fmt.Printf("%s%s\n\n", styleLabel.Render("machine"),
-		styleText.Render(fmt.Sprintf("%d cœurs logiques", rep.CPUs)))
+	fmt.Printf("%s%s\n\n", styleLabel.Render(tr("machine")),
+		styleText.Render(fmt.Sprintf(tr("%d cœurs logiques"), rep.CPUs)))
 
-	fmt.Println(styleDim.Render("  dérivation de clé (argon2id)"))
+	fmt.Println(styleDim.Render(tr("  dérivation de clé (argon2id)")))
 	for _, m := range rep.KDF {
 		marque := "  "
 		if m.Profile == rep.Advised {
 			marque = styleAccent.Render("→ ")
 		}
-		fmt.Printf("  %s%-10s %-24s %6d Mio  %8s\n", marque, m.Profile, m.Label,
+		fmt.Printf(tr("  %s%-10s %-24s %6d Mio  %8s\n"), marque, m.Profile, m.Label,
 			m.MemoryMiB, m.Duration.Round(time.Millisecond))
 	}
 	fmt.Printf("\n  %s\n", styleAccent.Render(rep.Advisory))
-	fmt.Println(styleDim.Render("  la mémoire annoncée sera aussi exigée au déchiffrement"))
+	fmt.Println(styleDim.Render(tr("  la mémoire annoncée sera aussi exigée au déchiffrement")))
+	fmt.Println(styleDim.Render(tr("  -kdf auto -kdf-target 500ms calibre argon2id sur cette machine plutôt que de choisir un profil")))
+
+	fmt.Printf("\n%s\n", styleDim.Render(tr("  alternatives (-kdf-algo), pour les environnements contraints ou FIPS")))
+	for _, m := range rep.KDFAlt {
+		fmt.Printf(tr("    %-10s %-34s %6d Mio  %8s\n"), m.Profile, m.Label,
+			m.MemoryMiB, m.Duration.Round(time.Millisecond))
+	}
 
-	fmt.Printf("\n%s\n", styleDim.Render("  débit de chiffrement"))
+	fmt.Printf("\n%s\n", styleDim.Render(tr("  débit de chiffrement")))
 	for _, m := range rep.AEAD {
 		if m.Err != nil {
-			fmt.Printf("    %-34s %s\n", m.Name, styleDim.Render("mesure impossible : "+m.Err.Error()))
+			fmt.Printf("    %-34s %s\n", m.Name, styleDim.Render(fmt.Sprintf(tr("mesure impossible : %v"), m.Err)))
 			continue
 		}
 		fmt.Printf("    %-34s %10s/s\n", m.Name, humanSize(m.BytesPerSec))
 	}
-	fmt.Printf("\n  %s\n", styleDim.Render(
-		"sans accélération AES matérielle, chacha20 passe devant : c'est là que -chacha se justifie"))
+	fmt.Printf("\n  %s\n", styleDim.Render(tr(
+		"sans accélération AES matérielle, chacha20 passe devant : c'est là que -chacha se justifie ;")))
+	fmt.Printf("  %s\n", styleDim.Render(tr(
+		"avec, aegis-256 est en général le plus rapide : c'est là que -aegis se justifie")))
 	return nil
 }
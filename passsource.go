@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"chiffremento-cli/pkg"
+)
+
+// Sources explicites du mot de passe, pour l'automatisation.
+//
+// readPassword ne connaît que le terminal et la première ligne de l'entrée
+// standard. En CI, avec -in -, il n'y a ni l'un ni l'autre de disponible : les
+// données occupent l'entrée standard et aucun terminal n'est attaché. Ces
+// quatre sources comblent le manque, sans jamais faire passer le mot de passe
+// lui-même dans les arguments :
+//
+//   - -passfile CHEMIN : la première ligne d'un fichier ;
+//   - -passenv NOM : une variable d'environnement, retirée de l'environnement
+//     une fois lue pour que les processus lancés ensuite n'en héritent pas ;
+//   - -passfd N : la première ligne d'un descripteur hérité (`-passfd 3
+//     3<secret`) ;
+//   - -passcmd COMMANDE : la sortie standard d'une commande, lancée par le
+//     shell (`-passcmd "pass show sauvegarde"`).
+//
+// Une seule à la fois. Aucune confirmation n'est demandée : il n'y a pas de
+// faute de frappe à rattraper dans un secret qu'on n'a pas tapé.
+
+// maxPasswordSource borne ce qui est lu depuis une source : un mot de passe
+// n'a rien à faire au-delà, et un -passfile pointé par erreur sur une image
+// disque ne doit pas être chargé en mémoire.
+const maxPasswordSource = 64 << 10
+
+type passwordSource struct {
+	file, env, cmd string
+	fd             int
+}
+
+// passwordFrom est la source choisie sur la ligne de commande. Sans source,
+// les chemins interactifs de readPassword s'appliquent. fd vaut -1 et non 0 :
+// 0 est un descripteur valide, l'entrée standard.
+var passwordFrom = passwordSource{fd: -1}
+
+func registerPasswordFlags() *passwordSource {
+	s := &passwordSource{fd: -1}
+	flag.StringVar(&s.file, "passfile", "", tr("lire le mot de passe dans la première ligne de ce fichier"))
+	flag.StringVar(&s.env, "passenv", "", tr("lire le mot de passe dans cette variable d'environnement"))
+	flag.IntVar(&s.fd, "passfd", -1, tr("lire le mot de passe sur ce descripteur de fichier hérité"))
+	flag.StringVar(&s.cmd, "passcmd", "", tr("lire le mot de passe sur la sortie standard de cette commande"))
+	return s
+}
+
+// set indique si une source explicite a été choisie.
+func (s passwordSource) set() bool {
+	return s.file != "" || s.env != "" || s.cmd != "" || s.fd >= 0
+}
+
+// validate refuse les combinaisons ambiguës, avant toute lecture.
+func (s passwordSource) validate() error {
+	n := 0
+	for _, b := range []bool{s.file != "", s.env != "", s.cmd != "", s.fd >= 0} {
+		if b {
+			n++
+		}
+	}
+	if n > 1 {
+		return errorf("-passfile, -passenv, -passfd et -passcmd s'excluent : une seule source de mot de passe")
+	}
+	return nil
+}
+
+// read lit le mot de passe à la source choisie. stdinTaken signale que
+// l'entrée standard porte les données : -passfd 0 la volerait.
+func (s passwordSource) read(stdinTaken bool) ([]byte, error) {
+	var (
+		raw  []byte
+		from string
+		err  error
+	)
+	switch {
+	case s.file != "":
+		from = s.file
+		raw, err = readPasswordFile(s.file)
+	case s.env != "":
+		from = "$" + s.env
+		v, ok := os.LookupEnv(s.env)
+		if !ok {
+			return nil, errorf("la variable d'environnement %s n'est pas définie", s.env)
+		}
+		raw = []byte(v)
+		os.Unsetenv(s.env)
+	case s.fd >= 0:
+		from = fmt.Sprintf(tr("descripteur %d"), s.fd)
+		if s.fd == 0 && stdinTaken {
+			return nil, errorf("-passfd 0 est l'entrée standard, qui porte déjà les données : il faut un autre descripteur")
+		}
+		f := os.NewFile(uintptr(s.fd), from)
+		if f == nil {
+			return nil, errorf("descripteur %d invalide", s.fd)
+		}
+		raw, err = readLimited(f)
+		f.Close()
+	case s.cmd != "":
+		from = "-passcmd"
+		raw, err = runPasswordCommand(s.cmd)
+	}
+	if err != nil {
+		zero(raw)
+		return nil, errorf("mot de passe (%s) : %w", from, err)
+	}
+	defer zero(raw)
+
+	line := raw
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) == 0 {
+		return nil, errorf("mot de passe vide (%s)", from)
+	}
+	return bytes.Clone(line), nil
+}
+
+// readPasswordFile lit un fichier de mot de passe, en prévenant s'il est
+// lisible par d'autres que son propriétaire.
+func readPasswordFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		warnIfShared(path, info)
+	}
+	return readLimited(f)
+}
+
+// warnIfShared prévient quand un fichier de secret est lisible par d'autres
+// que son propriétaire. Sous Windows, les bits de permission ne disent rien
+// des ACL : pas d'avertissement.
+func warnIfShared(path string, info os.FileInfo) {
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o044 != 0 {
+		fmt.Fprintln(diag, styleDim.Render(fmt.Sprintf(tr(
+			"attention : %s est lisible par d'autres utilisateurs (%04o) ; chmod 600 %s"), path, info.Mode().Perm(), path)))
+	}
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, maxPasswordSource+1))
+	if err != nil {
+		return raw, err
+	}
+	if len(raw) > maxPasswordSource {
+		return raw, errorf("plus de %d Kio : ce n'est pas un mot de passe", maxPasswordSource>>10)
+	}
+	return raw, nil
+}
+
+// runPasswordCommand lance la commande par le shell et rend sa sortie
+// standard. Sa sortie d'erreur reste celle de l'utilisateur : un gestionnaire
+// de secrets peut avoir à y demander quelque chose.
+func runPasswordCommand(command string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = diag
+	err := cmd.Run()
+	raw := out.Bytes()
+	if err != nil {
+		return raw, errorf("la commande a échoué : %w", err)
+	}
+	if len(raw) > maxPasswordSource {
+		return raw, errorf("plus de %d Kio : ce n'est pas un mot de passe", maxPasswordSource>>10)
+	}
+	return raw, nil
+}
+
+// readSecret demande le mot de passe, sauf quand une clé symétrique le
+// remplace : il n'y a alors rien à demander, et le résultat est nil — Bytes et
+// Destroy l'acceptent tel quel.
+func readSecret(opts pkg.Options, confirm, stdinTaken bool) (*pkg.SecureBuffer, error) {
+	if opts.Key != nil {
+		return nil, nil
+	}
+	return readPassword(confirm, stdinTaken)
+}
+
+// checkSecretKind refuse, avant toute saisie, une clé sur un fichier protégé
+// par mot de passe. L'inverse se règle plus tard : sans -key-file, la clé peut
+// encore venir de l'agent ou de parts saisies au terminal.
+func checkSecretKind(d pkg.Details, opts pkg.Options) error {
+	if !d.KeyBased && opts.Key != nil {
+		return errorf("ce fichier est protégé par mot de passe : ni -key-file ni -share ne l'ouvriront")
+	}
+	if !d.Recovery && opts.RecoveryCode != nil {
+		return errorf("ce fichier n'a pas de code de secours : il s'ouvre avec son mot de passe")
+	}
+	return nil
+}
+
+// loadKeyFile lit un fichier de clé, avec le même avertissement qu'un
+// -passfile lisible par d'autres.
+func loadKeyFile(path string) ([]byte, error) {
+	if info, err := os.Stat(path); err == nil {
+		warnIfShared(path, info)
+	}
+	return pkg.ReadKeyFile(path)
+}
@@ -0,0 +1,150 @@
+//go:build !windows
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+// avecSource choisit une source de mot de passe le temps d'un test.
+func avecSource(t *testing.T, s passwordSource) {
+	t.Helper()
+	precedent := passwordFrom
+	passwordFrom = s
+	t.Cleanup(func() { passwordFrom = precedent })
+}
+
+func TestPasswordSourceFichier(t *testing.T) {
+	dir := t.TempDir()
+	for contenu, attendu := range map[string]string{
+		"secret\n":              "secret",
+		"secret\r\nautre ligne": "secret",
+		"sans fin de ligne":     "sans fin de ligne",
+	} {
+		path := filepath.Join(dir, "pw")
+		if err := os.WriteFile(path, []byte(contenu), 0600); err != nil {
+			t.Fatal(err)
+		}
+		pw, err := passwordSource{file: path, fd: -1}.read(false)
+		if err != nil || string(pw) != attendu {
+			t.Errorf("%q : lu %q, %v", contenu, pw, err)
+		}
+	}
+
+	for nom, contenu := range map[string][]byte{
+		"vide":       []byte("\n"),
+		"démesuré":   bytes.Repeat([]byte("x"), maxPasswordSource+1),
+		"inexistant": nil,
+	} {
+		path := filepath.Join(dir, "refus")
+		os.Remove(path)
+		if contenu != nil {
+			if err := os.WriteFile(path, contenu, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := (passwordSource{file: path, fd: -1}).read(false); err == nil {
+			t.Errorf("%s : accepté", nom)
+		}
+	}
+}
+
+func TestPasswordSourceEnvironnement(t *testing.T) {
+	t.Setenv("CHTO_TEST_PW", "depuis-env")
+	pw, err := passwordSource{env: "CHTO_TEST_PW", fd: -1}.read(false)
+	if err != nil || string(pw) != "depuis-env" {
+		t.Fatalf("lu %q, %v", pw, err)
+	}
+	// Retirée après lecture : un processus lancé ensuite n'en hérite pas.
+	if _, ok := os.LookupEnv("CHTO_TEST_PW"); ok {
+		t.Error("la variable est restée dans l'environnement")
+	}
+	if _, err := (passwordSource{env: "CHTO_TEST_ABSENTE", fd: -1}).read(false); err == nil {
+		t.Error("variable absente acceptée")
+	}
+}
+
+func TestPasswordSourceDescripteur(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	w.WriteString("depuis-fd\n")
+	w.Close()
+	// Un double du descripteur, comme le shell en passe un avec 3<secret :
+	// read le ferme après lecture, r garde le sien.
+	fd, err := syscall.Dup(int(r.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw, err := passwordSource{fd: fd}.read(true)
+	if err != nil || string(pw) != "depuis-fd" {
+		t.Errorf("lu %q, %v", pw, err)
+	}
+
+	// L'entrée standard porte les données : -passfd 0 les volerait.
+	if _, err := (passwordSource{fd: 0}).read(true); err == nil {
+		t.Error("-passfd 0 accepté alors que l'entrée standard est occupée")
+	}
+}
+
+func TestPasswordSourceCommande(t *testing.T) {
+	pw, err := passwordSource{cmd: "printf 'depuis-cmd\\n'", fd: -1}.read(false)
+	if err != nil || string(pw) != "depuis-cmd" {
+		t.Errorf("lu %q, %v", pw, err)
+	}
+	if _, err := (passwordSource{cmd: "echo fuite; exit 3", fd: -1}).read(false); err == nil {
+		t.Error("commande en échec acceptée")
+	} else if strings.Contains(err.Error(), "fuite") {
+		t.Errorf("l'erreur recopie la sortie de la commande : %v", err)
+	}
+}
+
+func TestPasswordSourceExclusives(t *testing.T) {
+	if err := (passwordSource{file: "a", env: "B", fd: -1}).validate(); err == nil {
+		t.Error("deux sources acceptées")
+	}
+	if err := (passwordSource{fd: 3}).validate(); err != nil {
+		t.Errorf("une seule source refusée : %v", err)
+	}
+	if (passwordSource{fd: -1}).set() {
+		t.Error("aucune source ne doit valoir « choisie »")
+	}
+}
+
+// TestPasswordSourceSansTerminal : le cas de la CI — données sur l'entrée
+// standard, aucun terminal. Le chiffrement passe par -passenv, le
+// déchiffrement par -passfile.
+func TestPasswordSourceSansTerminal(t *testing.T) {
+	dir := t.TempDir()
+	contenu := []byte("données de CI\n")
+	avecEntree(t, ecrire(t, filepath.Join(dir, "donnees"), contenu))
+	precedent := ttyDevice
+	ttyDevice = filepathJoinInexistant(t)
+	t.Cleanup(func() { ttyDevice = precedent })
+
+	t.Setenv("CHTO_TEST_PW", motDePasseTest)
+	avecSource(t, passwordSource{env: "CHTO_TEST_PW", fd: -1})
+	chto := filepath.Join(dir, "ci.chto")
+	if err := doEncrypt("-", chto, pkg.Options{}); err != nil {
+		t.Fatalf("chiffrement depuis un tube : %v", err)
+	}
+
+	pwFile := ecrire(t, filepath.Join(dir, "pw"), []byte(motDePasseTest+"\n"))
+	avecSource(t, passwordSource{file: pwFile, fd: -1})
+	out := filepath.Join(dir, "relu")
+	if err := doDecrypt(chto, out, pkg.Options{}); err != nil {
+		t.Fatalf("déchiffrement : %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, contenu) {
+		t.Errorf("contenu relu %q", got)
+	}
+}
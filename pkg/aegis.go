@@ -0,0 +1,275 @@
+package pkg
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"math/bits"
+)
+
+// AEGIS-256, d'après draft-irtf-cfrg-aegis-aead.
+//
+// Ni la bibliothèque standard ni x/crypto ne le fournissent, d'où cette
+// implémentation. L'état fait six blocs de 16 octets, et chaque bloc de
+// message coûte six tours d'AES — un seul tour par bloc d'état, sans
+// expansion de clé. Avec AES-NI, c'est sensiblement plus rapide qu'AES-GCM.
+//
+// Sans instructions AES, le tour est calculé par tables : correct, mais plus
+// lent et pas à temps constant face à un attaquant qui partage le cache du
+// processeur. Sur ces machines, chacha20-poly1305 reste le bon choix ; la
+// commande bench le montre.
+//
+// Seule la variante à tag de 256 bits est utilisée.
+
+const (
+	aegisKeySize   = 32
+	aegisNonceSize = 32
+	aegisTagSize   = 32
+)
+
+// aegisState est l'état S0..S5. La disposition (six blocs contigus) est
+// aussi celle qu'attend la version assembleur.
+type aegisState [6][16]byte
+
+var (
+	aegisC0 = [16]byte{0x00, 0x01, 0x01, 0x02, 0x03, 0x05, 0x08, 0x0d, 0x15, 0x22, 0x37, 0x59, 0x90, 0xe9, 0x79, 0x62}
+	aegisC1 = [16]byte{0xdb, 0x3d, 0x18, 0x55, 0x6d, 0xc2, 0x2f, 0xf1, 0x20, 0x11, 0x31, 0x42, 0x73, 0xb5, 0x28, 0xdd}
+)
+
+// newAegisState initialise l'état pour une clé et un nonce de 32 octets.
+func newAegisState(key, nonce []byte) *aegisState {
+	var k0, k1, n0, n1, kn0, kn1 [16]byte
+	copy(k0[:], key[:16])
+	copy(k1[:], key[16:])
+	copy(n0[:], nonce[:16])
+	copy(n1[:], nonce[16:])
+	subtle.XORBytes(kn0[:], k0[:], n0[:])
+	subtle.XORBytes(kn1[:], k1[:], n1[:])
+
+	s := new(aegisState)
+	s[0] = kn0
+	s[1] = kn1
+	s[2] = aegisC1
+	s[3] = aegisC0
+	subtle.XORBytes(s[4][:], k0[:], aegisC0[:])
+	subtle.XORBytes(s[5][:], k1[:], aegisC1[:])
+
+	var init [64]byte
+	copy(init[0:], k0[:])
+	copy(init[16:], k1[:])
+	copy(init[32:], kn0[:])
+	copy(init[48:], kn1[:])
+	for range 4 {
+		aegisAbsorbBlocks(s, init[:])
+	}
+	return s
+}
+
+// absorb intègre les données associées, complétées par des zéros.
+func (s *aegisState) absorb(ad []byte) {
+	full := len(ad) &^ 15
+	aegisAbsorbBlocks(s, ad[:full])
+	if full < len(ad) {
+		var last [16]byte
+		copy(last[:], ad[full:])
+		aegisAbsorbBlocks(s, last[:])
+	}
+}
+
+// encrypt chiffre src dans dst (même longueur, recouvrement exact permis).
+func (s *aegisState) encrypt(dst, src []byte) {
+	full := len(src) &^ 15
+	aegisEncryptBlocks(s, dst[:full], src[:full])
+	if full < len(src) {
+		// Le dernier bloc est chiffré complété de zéros, puis tronqué : c'est
+		// exactement la définition de l'Enc partiel.
+		var in, out [16]byte
+		copy(in[:], src[full:])
+		aegisEncryptBlocks(s, out[:], in[:])
+		copy(dst[full:], out[:])
+	}
+}
+
+// decrypt déchiffre src dans dst (même longueur, recouvrement exact permis).
+func (s *aegisState) decrypt(dst, src []byte) {
+	full := len(src) &^ 15
+	aegisDecryptBlocks(s, dst[:full], src[:full])
+	if full < len(src) {
+		// Le bloc partiel ne passe pas par aegisDecryptBlocks : l'état doit être
+		// mis à jour avec le clair complété de zéros, pas avec le keystream qui
+		// déborde du chiffré.
+		n := len(src) - full
+		z := s.keystream()
+		var m [16]byte
+		subtle.XORBytes(m[:n], src[full:], z[:n])
+		copy(dst[full:], m[:n])
+		aegisAbsorbBlocks(s, m[:])
+	}
+}
+
+// finalize renvoie le tag de 256 bits. L'état n'est plus utilisable ensuite.
+func (s *aegisState) finalize(adLen, msgLen int) [aegisTagSize]byte {
+	var t [16]byte
+	binary.LittleEndian.PutUint64(t[0:], uint64(adLen)*8)
+	binary.LittleEndian.PutUint64(t[8:], uint64(msgLen)*8)
+	subtle.XORBytes(t[:], t[:], s[3][:])
+	var rounds [7 * 16]byte
+	for i := range 7 {
+		copy(rounds[i*16:], t[:])
+	}
+	aegisAbsorbBlocks(s, rounds[:])
+
+	var tag [aegisTagSize]byte
+	subtle.XORBytes(tag[:16], s[0][:], s[1][:])
+	subtle.XORBytes(tag[:16], tag[:16], s[2][:])
+	subtle.XORBytes(tag[16:], s[3][:], s[4][:])
+	subtle.XORBytes(tag[16:], tag[16:], s[5][:])
+	return tag
+}
+
+// keystream renvoie z = S1 ^ S4 ^ S5 ^ (S2 & S3), sans toucher l'état.
+func (s *aegisState) keystream() [16]byte {
+	var z [16]byte
+	for i := range z {
+		z[i] = s[1][i] ^ s[4][i] ^ s[5][i] ^ (s[2][i] & s[3][i])
+	}
+	return z
+}
+
+// aegisSeal chiffre msg et renvoie chiffré ‖ tag, ajoutés à dst.
+func aegisSeal(dst, key, nonce, ad, msg []byte) []byte {
+	s := newAegisState(key, nonce)
+	s.absorb(ad)
+	out, ct := sliceForAppend(dst, len(msg)+aegisTagSize)
+	s.encrypt(ct[:len(msg)], msg)
+	tag := s.finalize(len(ad), len(msg))
+	copy(ct[len(msg):], tag[:])
+	return out
+}
+
+// aegisOpen vérifie et déchiffre chiffré ‖ tag, en ajoutant le clair à dst.
+// Rien n'est renvoyé si le tag ne correspond pas : le clair calculé est
+// effacé avant de rendre la main.
+func aegisOpen(dst, key, nonce, ad, sealed []byte) ([]byte, bool) {
+	if len(sealed) < aegisTagSize {
+		return nil, false
+	}
+	ct, want := sealed[:len(sealed)-aegisTagSize], sealed[len(sealed)-aegisTagSize:]
+	s := newAegisState(key, nonce)
+	s.absorb(ad)
+	out, m := sliceForAppend(dst, len(ct))
+	s.decrypt(m, ct)
+	tag := s.finalize(len(ad), len(ct))
+	if subtle.ConstantTimeCompare(tag[:], want) != 1 {
+		wipe(m)
+		return nil, false
+	}
+	return out, true
+}
+
+// sliceForAppend étend in de n octets et renvoie la tranche complète et la
+// partie ajoutée, comme le font les AEAD de la bibliothèque standard.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+// --- Implémentation portable -------------------------------------------
+
+// aegisUpdate applique Update(m) : S'0 = R(S5) ^ S0 ^ m, S'i = R(S(i-1)) ^ Si.
+func aegisUpdate(s *aegisState, m *[16]byte) {
+	s5 := s[5]
+	var k [16]byte
+	s[5] = aesRound(&s[4], &s5)
+	s[4] = aesRound(&s[3], &s[4])
+	s[3] = aesRound(&s[2], &s[3])
+	s[2] = aesRound(&s[1], &s[2])
+	s[1] = aesRound(&s[0], &s[1])
+	subtle.XORBytes(k[:], s[0][:], m[:])
+	s[0] = aesRound(&s5, &k)
+}
+
+func aegisAbsorbGeneric(s *aegisState, src []byte) {
+	var m [16]byte
+	for len(src) >= 16 {
+		copy(m[:], src)
+		aegisUpdate(s, &m)
+		src = src[16:]
+	}
+}
+
+func aegisEncryptGeneric(s *aegisState, dst, src []byte) {
+	var m [16]byte
+	for len(src) >= 16 {
+		copy(m[:], src)
+		z := s.keystream()
+		subtle.XORBytes(dst[:16], m[:], z[:])
+		aegisUpdate(s, &m)
+		src, dst = src[16:], dst[16:]
+	}
+}
+
+func aegisDecryptGeneric(s *aegisState, dst, src []byte) {
+	var m [16]byte
+	for len(src) >= 16 {
+		z := s.keystream()
+		subtle.XORBytes(m[:], src[:16], z[:])
+		copy(dst, m[:])
+		aegisUpdate(s, &m)
+		src, dst = src[16:], dst[16:]
+	}
+}
+
+// aesRound calcule un tour d'AES (SubBytes, ShiftRows, MixColumns puis
+// AddRoundKey avec rk), soit l'instruction AESENC.
+func aesRound(in, rk *[16]byte) [16]byte {
+	var out [16]byte
+	for c := 0; c < 16; c += 4 {
+		w := aesTe0[in[c]] ^
+			bits.RotateLeft32(aesTe0[in[(c+5)&15]], -8) ^
+			bits.RotateLeft32(aesTe0[in[(c+10)&15]], -16) ^
+			bits.RotateLeft32(aesTe0[in[(c+15)&15]], -24)
+		binary.BigEndian.PutUint32(out[c:], w^binary.BigEndian.Uint32(rk[c:]))
+	}
+	return out
+}
+
+// aesTe0[x] regroupe la contribution d'un octet x de la ligne 0 à sa
+// colonne : (2·S(x), S(x), S(x), 3·S(x)). Les trois autres lignes en sont
+// des rotations.
+var aesTe0 = func() (te [256]uint32) {
+	// S-box calculée plutôt que recopiée : inverse dans GF(2^8), puis
+	// transformation affine. On parcourt le groupe multiplicatif par
+	// puissances de 3, p et q restant inverses l'un de l'autre.
+	var sbox [256]byte
+	sbox[0] = 0x63
+	p, q := byte(1), byte(1)
+	for {
+		p ^= xtime(p) // p·3
+		q ^= q << 1   // q/3, soit q·0xf6
+		q ^= q << 2
+		q ^= q << 4
+		q ^= (q >> 7) * 0x09
+		sbox[p] = 0x63 ^ q ^ bits.RotateLeft8(q, 1) ^ bits.RotateLeft8(q, 2) ^
+			bits.RotateLeft8(q, 3) ^ bits.RotateLeft8(q, 4)
+		if p == 1 {
+			break
+		}
+	}
+	for x := range te {
+		s := uint32(sbox[x])
+		s2 := uint32(xtime(sbox[x]))
+		te[x] = s2<<24 | s<<16 | s<<8 | (s2 ^ s)
+	}
+	return te
+}()
+
+// xtime multiplie par 2 dans GF(2^8).
+func xtime(b byte) byte {
+	return b<<1 ^ (b>>7)*0x1b
+}
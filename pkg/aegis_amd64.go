@@ -0,0 +1,46 @@
+//go:build amd64 && !purego
+
+package pkg
+
+import "golang.org/x/sys/cpu"
+
+// aegisAccelerated indique que les tours d'AES passent par AES-NI. Variable
+// plutôt que constante pour que les tests comparent les deux chemins.
+var aegisAccelerated = cpu.X86.HasAES
+
+//go:noescape
+func aegisAbsorbAsm(s *aegisState, src []byte)
+
+//go:noescape
+func aegisEncryptAsm(s *aegisState, dst, src []byte)
+
+//go:noescape
+func aegisDecryptAsm(s *aegisState, dst, src []byte)
+
+// aegisAbsorbBlocks applique Update à chaque bloc de src, dont la longueur est
+// un multiple de 16.
+func aegisAbsorbBlocks(s *aegisState, src []byte) {
+	if aegisAccelerated {
+		aegisAbsorbAsm(s, src)
+		return
+	}
+	aegisAbsorbGeneric(s, src)
+}
+
+// aegisEncryptBlocks chiffre des blocs complets de 16 octets.
+func aegisEncryptBlocks(s *aegisState, dst, src []byte) {
+	if aegisAccelerated {
+		aegisEncryptAsm(s, dst, src)
+		return
+	}
+	aegisEncryptGeneric(s, dst, src)
+}
+
+// aegisDecryptBlocks déchiffre des blocs complets de 16 octets.
+func aegisDecryptBlocks(s *aegisState, dst, src []byte) {
+	if aegisAccelerated {
+		aegisDecryptAsm(s, dst, src)
+		return
+	}
+	aegisDecryptGeneric(s, dst, src)
+}
@@ -0,0 +1,122 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// État S0..S5 dans X0..X5, bloc de message dans X6.
+
+#define LOAD_STATE \
+	MOVOU 0(AX), X0;  \
+	MOVOU 16(AX), X1; \
+	MOVOU 32(AX), X2; \
+	MOVOU 48(AX), X3; \
+	MOVOU 64(AX), X4; \
+	MOVOU 80(AX), X5
+
+#define STORE_STATE \
+	MOVOU X0, 0(AX);  \
+	MOVOU X1, 16(AX); \
+	MOVOU X2, 32(AX); \
+	MOVOU X3, 48(AX); \
+	MOVOU X4, 64(AX); \
+	MOVOU X5, 80(AX)
+
+// Update(X6) : S'i = AESENC(S(i-1), Si), S'0 = AESENC(S5, S0 ^ m).
+// Écrase X7 et X8.
+#define UPDATE \
+	MOVOU  X5, X7; \
+	MOVOU  X4, X8; \
+	AESENC X5, X8; \
+	MOVOU  X8, X5; \
+	MOVOU  X3, X8; \
+	AESENC X4, X8; \
+	MOVOU  X8, X4; \
+	MOVOU  X2, X8; \
+	AESENC X3, X8; \
+	MOVOU  X8, X3; \
+	MOVOU  X1, X8; \
+	AESENC X2, X8; \
+	MOVOU  X8, X2; \
+	MOVOU  X0, X8; \
+	AESENC X1, X8; \
+	MOVOU  X8, X1; \
+	PXOR   X6, X0; \
+	AESENC X0, X7; \
+	MOVOU  X7, X0
+
+// Keystream dans X9 : S1 ^ S4 ^ S5 ^ (S2 & S3).
+#define KEYSTREAM \
+	MOVOU X2, X9; \
+	PAND  X3, X9; \
+	PXOR  X1, X9; \
+	PXOR  X4, X9; \
+	PXOR  X5, X9
+
+// func aegisAbsorbAsm(s *aegisState, src []byte)
+TEXT ·aegisAbsorbAsm(SB), NOSPLIT, $0-32
+	MOVQ s+0(FP), AX
+	MOVQ src_base+8(FP), SI
+	MOVQ src_len+16(FP), CX
+	SHRQ $4, CX
+	JZ   absorb_done
+	LOAD_STATE
+
+absorb_loop:
+	MOVOU (SI), X6
+	UPDATE
+	ADDQ  $16, SI
+	DECQ  CX
+	JNZ   absorb_loop
+	STORE_STATE
+
+absorb_done:
+	RET
+
+// func aegisEncryptAsm(s *aegisState, dst, src []byte)
+TEXT ·aegisEncryptAsm(SB), NOSPLIT, $0-56
+	MOVQ s+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+	SHRQ $4, CX
+	JZ   enc_done
+	LOAD_STATE
+
+enc_loop:
+	MOVOU (SI), X6
+	KEYSTREAM
+	PXOR  X6, X9
+	MOVOU X9, (DI)
+	UPDATE
+	ADDQ  $16, SI
+	ADDQ  $16, DI
+	DECQ  CX
+	JNZ   enc_loop
+	STORE_STATE
+
+enc_done:
+	RET
+
+// func aegisDecryptAsm(s *aegisState, dst, src []byte)
+TEXT ·aegisDecryptAsm(SB), NOSPLIT, $0-56
+	MOVQ s+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+	SHRQ $4, CX
+	JZ   dec_done
+	LOAD_STATE
+
+dec_loop:
+	MOVOU (SI), X6
+	KEYSTREAM
+	PXOR  X9, X6
+	MOVOU X6, (DI)
+	UPDATE
+	ADDQ  $16, SI
+	ADDQ  $16, DI
+	DECQ  CX
+	JNZ   dec_loop
+	STORE_STATE
+
+dec_done:
+	RET
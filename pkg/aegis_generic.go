@@ -0,0 +1,17 @@
+//go:build !amd64 || purego
+
+package pkg
+
+// aegisAccelerated vaut toujours false hors amd64 : le tour d'AES est calculé
+// par tables.
+var aegisAccelerated = false
+
+// aegisAbsorbBlocks applique Update à chaque bloc de src, dont la longueur est
+// un multiple de 16.
+func aegisAbsorbBlocks(s *aegisState, src []byte) { aegisAbsorbGeneric(s, src) }
+
+// aegisEncryptBlocks chiffre des blocs complets de 16 octets.
+func aegisEncryptBlocks(s *aegisState, dst, src []byte) { aegisEncryptGeneric(s, dst, src) }
+
+// aegisDecryptBlocks déchiffre des blocs complets de 16 octets.
+func aegisDecryptBlocks(s *aegisState, dst, src []byte) { aegisDecryptGeneric(s, dst, src) }
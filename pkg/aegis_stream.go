@@ -0,0 +1,274 @@
+package pkg
+
+import (
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"io"
+)
+
+// Flux AEGIS-256.
+//
+// minio/sio ne connaît que ses deux AEAD, AEGIS a donc son propre découpage.
+// Il reprend les garanties de DARE, par les mêmes moyens :
+//
+//	préfixe (24) ‖ engagement (32) ‖ paquet 0 ‖ paquet 1 ‖ … ‖ paquet final
+//	paquet = en-tête (4) ‖ chiffré (≤ 64 Kio) ‖ tag (32)
+//
+// L'en-tête d'un paquet porte la longueur du clair et, sur le bit de poids
+// fort, la marque « dernier paquet ». Il est authentifié comme donnée
+// associée. Le nonce de chaque paquet est le préfixe aléatoire suivi de son
+// numéro d'ordre sur 64 bits :
+//
+//   - un paquet déplacé est déchiffré avec le mauvais nonce et refusé ;
+//   - un flux coupé entre deux paquets n'a pas de paquet final et est refusé ;
+//   - des octets ajoutés après le paquet final sont refusés.
+//
+// Tous les paquets sauf le dernier sont pleins : il n'existe qu'un découpage
+// valide pour un clair donné.
+//
+// L'engagement est HKDF-Expand(SHA-256, clé, étiquette ‖ préfixe, 32). AEGIS
+// ne garantit pas à lui seul qu'un chiffré ne s'ouvre que sous une seule clé ;
+// l'engagement, vérifié en temps constant avant tout déchiffrement, le
+// garantit — et fait d'un mauvais mot de passe une erreur nette dès les
+// premiers octets.
+
+const (
+	aegisChunkSize    = 64 << 10
+	aegisPrefixSize   = 24
+	aegisCommitSize   = 32
+	aegisPacketHeader = 4
+	aegisFinalBit     = uint32(1) << 31
+	infoAegisCommit   = "chiffremento-aegis256-commit"
+)
+
+var (
+	errAegisCommit    = errorf("aegis.key_mismatch", "%w : aegis-256, la clé ne correspond pas à ce fichier", ErrAuthentication)
+	errAegisTruncated = errorf("aegis.last_packet_missing", "%w : aegis-256, le dernier paquet manque", ErrTruncated)
+	errAegisTrailing  = errorf("aegis.trailing_data", "%w : aegis-256, données après le dernier paquet", ErrAuthentication)
+)
+
+// aegisSuite est l'AEGIS-256 découpé en paquets décrit ci-dessus.
+type aegisSuite struct{}
+
+func (aegisSuite) ID() byte        { return AlgoAEGIS }
+func (aegisSuite) Name() string    { return "aegis-256" }
+func (aegisSuite) KeySizes() []int { return []int{aegisKeySize} }
+
+func (aegisSuite) NewWriter(dst io.Writer, keys [][]byte) (io.WriteCloser, error) {
+	w := &aegisWriter{dst: dst, key: keys[0]}
+	if _, err := rand.Read(w.prefix[:]); err != nil {
+		return nil, errorf("crypto.nonce", "génération du nonce: %w", err)
+	}
+	return w, nil
+}
+
+func (aegisSuite) NewReader(src io.Reader, keys [][]byte) (io.Reader, error) {
+	return &aegisReader{src: src, key: keys[0]}, nil
+}
+
+// aegisCommitment calcule l'engagement de clé d'un flux.
+func aegisCommitment(key, prefix []byte) ([]byte, error) {
+	return hkdf.Expand(sha256.New, key, infoAegisCommit+string(prefix), aegisCommitSize)
+}
+
+// aegisNonce construit le nonce du paquet seq.
+func aegisNonce(prefix *[aegisPrefixSize]byte, seq uint64) []byte {
+	nonce := make([]byte, aegisNonceSize)
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint64(nonce[aegisPrefixSize:], seq)
+	return nonce
+}
+
+type aegisWriter struct {
+	dst     io.Writer
+	key     []byte
+	prefix  [aegisPrefixSize]byte
+	seq     uint64
+	started bool
+	closed  bool
+	buf     []byte
+	out     []byte
+	err     error
+}
+
+func (w *aegisWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errorf("aegis.write_after_close", "aegis-256 : écriture après Close")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.buf == nil {
+		w.buf = make([]byte, 0, aegisChunkSize)
+	}
+	n := 0
+	for len(p) > 0 {
+		// Un paquet plein n'est scellé qu'à l'arrivée de l'octet suivant :
+		// jusque-là, on ne sait pas s'il est le dernier.
+		if len(w.buf) == aegisChunkSize {
+			if err := w.seal(w.buf, false); err != nil {
+				return n, err
+			}
+			w.buf = w.buf[:0]
+		}
+		// Les paquets entiers de p sont scellés sans passer par le tampon.
+		if len(w.buf) == 0 && len(p) > aegisChunkSize {
+			if err := w.seal(p[:aegisChunkSize], false); err != nil {
+				return n, err
+			}
+			p = p[aegisChunkSize:]
+			n += aegisChunkSize
+			continue
+		}
+		c := copy(w.buf[len(w.buf):aegisChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// Close scelle le paquet final, vide si besoin. dst n'est pas fermé.
+func (w *aegisWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	err := w.seal(w.buf, true)
+	wipe(w.buf[:cap(w.buf)])
+	return err
+}
+
+// seal chiffre data en un paquet et l'écrit.
+func (w *aegisWriter) seal(data []byte, final bool) error {
+	if !w.started {
+		commit, err := aegisCommitment(w.key, w.prefix[:])
+		if err != nil {
+			w.err = err
+			return err
+		}
+		if _, err := w.dst.Write(append(w.prefix[:], commit...)); err != nil {
+			w.err = err
+			return err
+		}
+		w.started = true
+	}
+
+	hdr := uint32(len(data))
+	if final {
+		hdr |= aegisFinalBit
+	}
+	w.out = binary.BigEndian.AppendUint32(w.out[:0], hdr)
+	w.out = aegisSeal(w.out, w.key, aegisNonce(&w.prefix, w.seq), w.out[:aegisPacketHeader], data)
+	if _, err := w.dst.Write(w.out); err != nil {
+		w.err = err
+		return err
+	}
+	w.seq++
+	return nil
+}
+
+type aegisReader struct {
+	src     io.Reader
+	key     []byte
+	prefix  [aegisPrefixSize]byte
+	seq     uint64
+	started bool
+	final   bool
+	in      []byte
+	plain   []byte
+	pending []byte
+	err     error
+}
+
+func (r *aegisReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.final {
+			r.err = io.EOF
+			continue
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// next lit, vérifie et déchiffre le paquet suivant.
+func (r *aegisReader) next() error {
+	if !r.started {
+		var head [aegisPrefixSize + aegisCommitSize]byte
+		if _, err := io.ReadFull(r.src, head[:]); err != nil {
+			return eofAsTruncated(err)
+		}
+		copy(r.prefix[:], head[:aegisPrefixSize])
+		want, err := aegisCommitment(r.key, r.prefix[:])
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare(want, head[aegisPrefixSize:]) != 1 {
+			return errAegisCommit
+		}
+		r.started = true
+	}
+
+	var hdr [aegisPacketHeader]byte
+	if _, err := io.ReadFull(r.src, hdr[:]); err != nil {
+		return eofAsTruncated(err)
+	}
+	v := binary.BigEndian.Uint32(hdr[:])
+	final, size := v&aegisFinalBit != 0, int(v&^aegisFinalBit)
+	if size > aegisChunkSize || (!final && size != aegisChunkSize) {
+		return errorf("aegis.packet_size", "aegis-256 : paquet %d de taille invalide (%d octets)", r.seq, size)
+	}
+
+	if cap(r.in) < size+aegisTagSize {
+		r.in = make([]byte, size+aegisTagSize)
+	}
+	r.in = r.in[:size+aegisTagSize]
+	if _, err := io.ReadFull(r.src, r.in); err != nil {
+		return eofAsTruncated(err)
+	}
+	plain, ok := aegisOpen(r.plain[:0], r.key, aegisNonce(&r.prefix, r.seq), hdr[:], r.in)
+	if !ok {
+		return errorf("aegis.packet_auth", "%w : aegis-256, paquet %d non authentique (fichier modifié, réordonné ou mauvaise clé)", ErrAuthentication, r.seq)
+	}
+	r.plain = plain
+	r.seq++
+
+	if final {
+		// Rien ne doit suivre : seule une fin de flux propre le prouve. Une
+		// erreur de lecture, ou une annulation, n'est pas une fin.
+		var one [1]byte
+		n, err := io.ReadFull(r.src, one[:])
+		switch {
+		case n != 0:
+			wipe(plain)
+			return errAegisTrailing
+		case err != io.EOF:
+			wipe(plain)
+			return err
+		}
+		r.final = true
+	}
+	r.pending = plain
+	return nil
+}
+
+// eofAsTruncated traduit une fin de flux prématurée : avant le paquet final,
+// toute fin est une troncature.
+func eofAsTruncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errAegisTruncated
+	}
+	return err
+}
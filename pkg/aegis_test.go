@@ -0,0 +1,245 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+// aegisVecteurs reprend les vecteurs de test d'AEGIS-256 de
+// draft-irtf-cfrg-aegis-aead (annexe A.3), variante à tag de 256 bits.
+var aegisVecteurs = []struct {
+	name            string
+	key, nonce, ad  string
+	msg, ct, tag256 string
+}{
+	{
+		name:   "bloc de zéros",
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		msg:    "00000000000000000000000000000000",
+		ct:     "754fc3d8c973246dcc6d741412a4b236",
+		tag256: "1181a1d18091082bf0266f66297d167d2e68b845f61a3b0527d31fc7b7b89f13",
+	},
+	{
+		name:   "message vide",
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		tag256: "6a348c930adbd654896e1666aad67de989ea75ebaa2b82fb588977b1ffec864a",
+	},
+	{
+		name:   "avec données associées",
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		ad:     "0001020304050607",
+		msg:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		ct:     "f373079ed84b2709faee373584585d60accd191db310ef5d8b11833df9dec711",
+		tag256: "b7d28d0c3c0ebd409fd22b44160503073a547412da0854bfb9723020dab8da1a",
+	},
+	{
+		name:   "bloc partiel",
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		ad:     "0001020304050607",
+		msg:    "000102030405060708090a0b0c0d",
+		ct:     "f373079ed84b2709faee37358458",
+		tag256: "8c1cc703c81281bee3f6d9966e14948b4a175b2efbdc31e61a98b4465235c2d9",
+	},
+}
+
+// avecChemins exécute f avec l'implémentation portable puis, si la machine
+// le permet, avec AES-NI : les deux doivent donner les mêmes octets.
+func avecChemins(t *testing.T, f func(t *testing.T)) {
+	t.Helper()
+	materiel := aegisAccelerated
+	t.Cleanup(func() { aegisAccelerated = materiel })
+
+	aegisAccelerated = false
+	t.Run("portable", f)
+	if materiel {
+		aegisAccelerated = true
+		t.Run("aes-ni", f)
+	}
+}
+
+func TestAEGIS256Vecteurs(t *testing.T) {
+	avecChemins(t, func(t *testing.T) {
+		for _, v := range aegisVecteurs {
+			h := func(s string) []byte {
+				b, err := hex.DecodeString(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return b
+			}
+			key, nonce, ad, msg := h(v.key), h(v.nonce), h(v.ad), h(v.msg)
+			want := append(h(v.ct), h(v.tag256)...)
+
+			got := aegisSeal(nil, key, nonce, ad, msg)
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s : scellé %x, attendu %x", v.name, got, want)
+				continue
+			}
+			clair, ok := aegisOpen(nil, key, nonce, ad, got)
+			if !ok || !bytes.Equal(clair, msg) {
+				t.Errorf("%s : ouverture refusée ou clair différent", v.name)
+			}
+			got[0] ^= 1
+			if _, ok := aegisOpen(nil, key, nonce, ad, got); ok {
+				t.Errorf("%s : chiffré modifié accepté", v.name)
+			}
+		}
+	})
+}
+
+// scelleAEGIS chiffre msg avec le découpage en paquets d'AEGIS-256, par
+// écritures de tailles irrégulières, ou d'un seul Write si dUnCoup.
+func scelleAEGIS(t *testing.T, key, msg []byte, dUnCoup bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := aegisSuite{}.NewWriter(&buf, [][]byte{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Écritures de tailles irrégulières : le découpage ne doit pas dépendre
+	// de la façon dont l'appelant fournit les octets.
+	for rest := msg; len(rest) > 0; {
+		n := min(len(rest), 1+len(rest)%7919)
+		if dUnCoup {
+			n = len(rest)
+		}
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func ouvreAEGIS(key, sealed []byte) ([]byte, error) {
+	r, err := aegisSuite{}.NewReader(bytes.NewReader(sealed), [][]byte{key})
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestAEGISFluxAllerRetour(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, aegisKeySize)
+	avecChemins(t, func(t *testing.T) {
+		for _, n := range []int{0, 1, 15, 17, aegisChunkSize - 1, aegisChunkSize, aegisChunkSize + 1, 3*aegisChunkSize + 100} {
+			msg := make([]byte, n)
+			rand.Read(msg)
+			sealed := scelleAEGIS(t, key, msg, false)
+			if d := scelleAEGIS(t, key, msg, true); len(d) != len(sealed) {
+				t.Errorf("%d octets : le découpage dépend de la taille des écritures", n)
+			} else if got, err := ouvreAEGIS(key, d); err != nil || !bytes.Equal(got, msg) {
+				t.Errorf("%d octets : aller-retour raté en un seul Write (%v)", n, err)
+			}
+
+			paquets := max(1, (n+aegisChunkSize-1)/aegisChunkSize)
+			attendu := aegisPrefixSize + aegisCommitSize + n + paquets*(aegisPacketHeader+aegisTagSize)
+			if len(sealed) != attendu {
+				t.Errorf("%d octets : flux de %d octets, attendu %d", n, len(sealed), attendu)
+			}
+			got, err := ouvreAEGIS(key, sealed)
+			if err != nil || !bytes.Equal(got, msg) {
+				t.Errorf("%d octets : aller-retour raté (%v)", n, err)
+			}
+		}
+	})
+}
+
+func TestAEGISFluxAttaques(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, aegisKeySize)
+	msg := make([]byte, 3*aegisChunkSize+100)
+	rand.Read(msg)
+	sealed := scelleAEGIS(t, key, msg, false)
+
+	debut := aegisPrefixSize + aegisCommitSize
+	paquet := aegisPacketHeader + aegisChunkSize + aegisTagSize
+
+	cases := map[string][]byte{
+		// Coupé pile entre deux paquets : chaque paquet restant est
+		// authentique, seul le paquet final manque.
+		"troncature entre paquets": sealed[:debut+2*paquet],
+		"troncature en cours":      sealed[:len(sealed)-1],
+		"flux vide":                nil,
+		"données ajoutées":         append(bytes.Clone(sealed), 0),
+		"paquets échangés": func() []byte {
+			b := bytes.Clone(sealed)
+			p0 := bytes.Clone(b[debut : debut+paquet])
+			copy(b[debut:], b[debut+paquet:debut+2*paquet])
+			copy(b[debut+paquet:], p0)
+			return b
+		}(),
+		"chiffré modifié": func() []byte {
+			b := bytes.Clone(sealed)
+			b[debut+paquet+100] ^= 1
+			return b
+		}(),
+		// Le dernier paquet plein se fait passer pour le final.
+		"marque finale déplacée": func() []byte {
+			b := bytes.Clone(sealed[:debut+3*paquet])
+			b[debut+2*paquet] |= 0x80
+			return b
+		}(),
+	}
+	for name, b := range cases {
+		if _, err := ouvreAEGIS(key, b); err == nil {
+			t.Errorf("%s : flux accepté", name)
+		}
+	}
+
+	// Une erreur de lecture après le paquet final n'est pas une fin de flux.
+	panne := errors.New("panne de lecture")
+	r, err := aegisSuite{}.NewReader(io.MultiReader(bytes.NewReader(sealed), iotest.ErrReader(panne)), [][]byte{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, panne) {
+		t.Errorf("erreur après le paquet final : %v, attendu %v", err, panne)
+	}
+
+	autre := bytes.Repeat([]byte{0x43}, aegisKeySize)
+	if _, err := ouvreAEGIS(autre, sealed); !errors.Is(err, errAegisCommit) {
+		t.Errorf("mauvaise clé : %v, attendu l'échec de l'engagement", err)
+	}
+}
+
+func TestEncryptAEGIS(t *testing.T) {
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", bytes.Repeat([]byte("sauvegarde "), 20000))
+	enc := filepath.Join(dir, "clair.txt.chto")
+	if err := Encrypt(in, enc, []byte("pw"), Options{Algo: AlgoAEGIS, Comp: CompZstd}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Inspect(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Algo != "aegis-256" {
+		t.Errorf("algorithme %q dans l'en-tête", d.Algo)
+	}
+
+	out := filepath.Join(dir, "rendu.txt")
+	if err := Decrypt(enc, out, []byte("pw"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.ReadFile(in)
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, want) {
+		t.Error("contenu différent après aller-retour")
+	}
+	if err := Decrypt(enc, filepath.Join(dir, "faux.txt"), []byte("faux"), Options{}); err == nil {
+		t.Error("mauvais mot de passe accepté")
+	}
+}
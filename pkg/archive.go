@@ -2,8 +2,8 @@ package pkg
 
 import (
 	"archive/tar"
+	"context"
 	"errors"
-	"fmt"
 	"io"
 	"io/fs"
 	"os"
@@ -56,7 +56,7 @@ func InputSize(path string) (int64, error) {
 	if !info.IsDir() {
 		return info.Size(), nil
 	}
-	plan, err := scanDirectory(path)
+	plan, err := scanDirectory(path, nil)
 	if err != nil {
 		return 0, err
 	}
@@ -82,8 +82,9 @@ type archivePlan struct {
 // Faire ce parcours d'abord coûte un aller-retour sur les métadonnées, mais il
 // donne deux choses qu'on ne peut pas obtenir en streaming : le total exact
 // pour la barre de progression, et un échec *avant* que le moindre octet ne
-// soit écrit quand l'arborescence contient quelque chose qu'on refuse.
-func scanDirectory(root string) (*archivePlan, error) {
+// soit écrit quand l'arborescence contient quelque chose qu'on refuse. t voit
+// passer chaque entrée retenue.
+func scanDirectory(root string, t *tracker) (*archivePlan, error) {
 	plan := &archivePlan{}
 	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -99,10 +100,10 @@ func scanDirectory(root string) (*archivePlan, error) {
 		rel = filepath.ToSlash(rel)
 
 		if pathDepth(rel) > maxRecursionDepth {
-			return fmt.Errorf("arborescence trop profonde (plus de %d niveaux) : %s", maxRecursionDepth, rel)
+			return errorf("archive.too_deep", "arborescence trop profonde (plus de %d niveaux) : %s", maxRecursionDepth, rel)
 		}
 		if len(plan.entries) >= maxArchiveEntries {
-			return fmt.Errorf("dossier trop volumineux : plus de %d entrées", maxArchiveEntries)
+			return errorf("archive.too_many_entries", "dossier trop volumineux : plus de %d entrées", maxArchiveEntries)
 		}
 
 		info, err := d.Info()
@@ -114,15 +115,16 @@ func scanDirectory(root string) (*archivePlan, error) {
 		case info.Mode().IsRegular():
 			plan.total += info.Size()
 		default:
-			return fmt.Errorf("%s : type non supporté (%s) — liens symboliques, sockets et périphériques sont refusés",
+			return errorf("archive.unsupported_type", "%s : type non supporté (%s) — liens symboliques, sockets et périphériques sont refusés",
 				rel, info.Mode().Type())
 		}
 
 		plan.entries = append(plan.entries, archiveEntry{abs: p, rel: rel, info: info})
+		t.scanned(plan.total, rel)
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("parcours du dossier: %w", err)
+		return nil, errorf("archive.walk", "parcours du dossier: %w", err)
 	}
 	return plan, nil
 }
@@ -185,7 +187,7 @@ func tarSize(plan *archivePlan) (int64, error) {
 		// Un tar.Writer neuf par entrée : on ne veut que les octets d'en-tête,
 		// et il n'est jamais refermé, donc aucun bloc de fin n'est émis ici.
 		if err := tar.NewWriter(c).WriteHeader(hdr); err != nil {
-			return 0, fmt.Errorf("calcul de taille de %s: %w", e.rel, err)
+			return 0, errorf("archive.size", "calcul de taille de %s: %w", e.rel, err)
 		}
 		total += c.n
 		if hdr.Typeflag == tar.TypeReg {
@@ -205,33 +207,36 @@ func roundUpBlock(n int64) int64 {
 	return n
 }
 
-// writeArchive sérialise le plan en tar dans w.
-func writeArchive(w io.Writer, plan *archivePlan, progress func(done, total int64)) error {
+// writeArchive sérialise le plan en tar dans w. ctx est revu avant chaque
+// entrée et au fil de la copie de chaque fichier.
+func writeArchive(ctx context.Context, w io.Writer, plan *archivePlan, t *tracker) error {
 	tw := tar.NewWriter(w)
 
 	var done int64
 	count := func(n int64) {
 		done += n
-		if progress != nil {
-			progress(done, plan.total)
-		}
+		t.advance(done, plan.total)
 	}
 
 	for _, e := range plan.entries {
+		if err := canceled(ctx); err != nil {
+			return err
+		}
+		t.entry(e.rel)
 		hdr := tarHeaderFor(e)
 		if err := tw.WriteHeader(hdr); err != nil {
-			return fmt.Errorf("écriture de l'entrée %s: %w", e.rel, err)
+			return errorf("archive.write_entry", "écriture de l'entrée %s: %w", e.rel, err)
 		}
 		if hdr.Typeflag != tar.TypeReg {
 			continue
 		}
-		if err := copyEntry(tw, e, count); err != nil {
+		if err := copyEntry(ctx, tw, e, count); err != nil {
 			return err
 		}
 	}
 
 	if err := tw.Close(); err != nil {
-		return fmt.Errorf("finalisation de l'archive: %w", err)
+		return errorf("archive.finish", "finalisation de l'archive: %w", err)
 	}
 	return nil
 }
@@ -239,22 +244,25 @@ func writeArchive(w io.Writer, plan *archivePlan, progress func(done, total int6
 // copyEntry recopie un fichier dans le tar. La taille annoncée dans l'en-tête
 // tar a été lue au scan : si le fichier a changé entre-temps, l'archive serait
 // silencieusement incohérente. On préfère échouer.
-func copyEntry(tw *tar.Writer, e archiveEntry, count func(int64)) error {
+func copyEntry(ctx context.Context, tw *tar.Writer, e archiveEntry, count func(int64)) error {
 	f, err := os.Open(e.abs)
 	if err != nil {
-		return fmt.Errorf("lecture de %s: %w", e.rel, err)
+		return errorf("io.read_path", "lecture de %s: %w", e.rel, err)
 	}
 	defer f.Close()
 
-	n, err := io.Copy(&progressWriter{w: tw, fn: count}, f)
+	n, err := io.Copy(&progressWriter{w: tw, fn: count}, withContext(ctx, f))
 	if err != nil {
+		if errors.Is(err, ErrCanceled) {
+			return err
+		}
 		if errors.Is(err, tar.ErrWriteTooLong) {
-			return fmt.Errorf("%s a grossi pendant l'archivage", e.rel)
+			return errorf("archive.grew", "%s a grossi pendant l'archivage", e.rel)
 		}
-		return fmt.Errorf("archivage de %s: %w", e.rel, err)
+		return errorf("archive.copy", "archivage de %s: %w", e.rel, err)
 	}
 	if n != e.info.Size() {
-		return fmt.Errorf("%s a changé de taille pendant l'archivage (%d octets attendus, %d lus)",
+		return errorf("archive.size_changed", "%s a changé de taille pendant l'archivage (%d octets attendus, %d lus)",
 			e.rel, e.info.Size(), n)
 	}
 	return nil
@@ -263,19 +271,26 @@ func copyEntry(tw *tar.Writer, e archiveEntry, count func(int64)) error {
 // walkArchive parcourt un tar en validant chaque entrée, et délègue le
 // traitement à handle. Toute la validation vit ici, pour que l'extraction et la
 // vérification ne puissent pas diverger sur ce qu'elles acceptent.
-func walkArchive(r io.Reader, handle func(hdr *tar.Header, rel string, body io.Reader) error) error {
+//
+// ctx est revu avant chaque entrée : un tar de milliers de dossiers vides
+// tient dans quelques lectures, que la vérification de r ne verrait pas. t
+// voit passer chaque entrée acceptée.
+func walkArchive(ctx context.Context, r io.Reader, t *tracker, handle func(hdr *tar.Header, rel string, body io.Reader) error) error {
 	tr := tar.NewReader(r)
 
 	for count := 0; ; count++ {
+		if err := canceled(ctx); err != nil {
+			return err
+		}
 		hdr, err := tr.Next()
 		if errors.Is(err, io.EOF) {
 			return nil
 		}
 		if err != nil {
-			return fmt.Errorf("lecture de l'archive: %w", err)
+			return errorf("archive.read", "lecture de l'archive: %w", err)
 		}
 		if count >= maxArchiveEntries {
-			return fmt.Errorf("archive trop volumineuse : plus de %d entrées", maxArchiveEntries)
+			return errorf("archive.refused_too_many", "%w : plus de %d entrées", ErrArchiveRefused, maxArchiveEntries)
 		}
 
 		rel, err := safeArchivePath(hdr.Name)
@@ -283,8 +298,9 @@ func walkArchive(r io.Reader, handle func(hdr *tar.Header, rel string, body io.R
 			return err
 		}
 		if hdr.Typeflag != tar.TypeDir && hdr.Typeflag != tar.TypeReg {
-			return fmt.Errorf("%s : entrée de type non supporté dans l'archive (%c)", rel, hdr.Typeflag)
+			return errorf("archive.refused_type", "%w : %s, entrée de type non supporté (%c)", ErrArchiveRefused, rel, hdr.Typeflag)
 		}
+		t.entry(rel)
 		if err := handle(hdr, rel, tr); err != nil {
 			return err
 		}
@@ -295,17 +311,17 @@ func walkArchive(r io.Reader, handle func(hdr *tar.Header, rel string, body io.R
 //
 // Chaque nom est validé avant d'être joint à dest : une archive ne peut donc
 // rien écrire ailleurs que sous dest, même si elle a été fabriquée pour ça.
-func extractArchive(r io.Reader, dest string) error {
-	return walkArchive(r, func(hdr *tar.Header, rel string, body io.Reader) error {
+func extractArchive(ctx context.Context, r io.Reader, dest string, t *tracker) error {
+	return walkArchive(ctx, r, t, func(hdr *tar.Header, rel string, body io.Reader) error {
 		target := filepath.Join(dest, filepath.FromSlash(rel))
 		if hdr.Typeflag == tar.TypeDir {
 			if err := os.MkdirAll(target, dirPerm(hdr.FileInfo().Mode())); err != nil {
-				return fmt.Errorf("création de %s: %w", rel, err)
+				return errorf("io.create_path", "création de %s: %w", rel, err)
 			}
 			return nil
 		}
 		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
-			return fmt.Errorf("création du dossier parent de %s: %w", rel, err)
+			return errorf("archive.mkdir_parent", "création du dossier parent de %s: %w", rel, err)
 		}
 		return extractFile(body, target, rel, hdr)
 	})
@@ -314,8 +330,8 @@ func extractArchive(r io.Reader, dest string) error {
 // checkArchive contrôle qu'un tar est lisible de bout en bout et qu'il ne
 // contient rien que l'extraction refuserait, sans écrire un octet sur le
 // disque.
-func checkArchive(r io.Reader) error {
-	return walkArchive(r, func(_ *tar.Header, _ string, body io.Reader) error {
+func checkArchive(ctx context.Context, r io.Reader, t *tracker) error {
+	return walkArchive(ctx, r, t, func(_ *tar.Header, _ string, body io.Reader) error {
 		_, err := io.Copy(io.Discard, body)
 		return err
 	})
@@ -326,14 +342,14 @@ func extractFile(tr io.Reader, target, rel string, hdr *tar.Header) error {
 	// écraser en silence ce que la première entrée a écrit.
 	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, filePerm(hdr.FileInfo().Mode()))
 	if err != nil {
-		return fmt.Errorf("création de %s: %w", rel, err)
+		return errorf("io.create_path", "création de %s: %w", rel, err)
 	}
 	if _, err := io.Copy(f, tr); err != nil {
 		f.Close()
-		return fmt.Errorf("extraction de %s: %w", rel, err)
+		return errorf("archive.extract", "extraction de %s: %w", rel, err)
 	}
 	if err := f.Close(); err != nil {
-		return fmt.Errorf("fermeture de %s: %w", rel, err)
+		return errorf("io.close_path", "fermeture de %s: %w", rel, err)
 	}
 	// Best effort : une date non restaurée n'invalide pas le contenu.
 	os.Chtimes(target, hdr.ModTime, hdr.ModTime)
@@ -343,24 +359,24 @@ func extractFile(tr io.Reader, target, rel string, hdr *tar.Header) error {
 // safeArchivePath valide un nom lu dans une archive et le renvoie nettoyé.
 func safeArchivePath(name string) (string, error) {
 	if name == "" {
-		return "", errors.New("entrée sans nom dans l'archive")
+		return "", errorf("archive.unnamed", "%w : entrée sans nom", ErrUnsafeArchivePath)
 	}
 	if strings.ContainsRune(name, 0) || strings.ContainsRune(name, '\\') {
-		return "", fmt.Errorf("chemin refusé dans l'archive : %q", name)
+		return "", errorf("archive.unsafe_char", "%w : caractère interdit dans %q", ErrUnsafeArchivePath, name)
 	}
 
 	clean := path.Clean(name)
 	if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
-		return "", fmt.Errorf("chemin refusé dans l'archive : %q", name)
+		return "", errorf("archive.unsafe_outside", "%w : %q sort de la destination", ErrUnsafeArchivePath, name)
 	}
 	// Sur Windows, "C:evil" n'est ni absolu ni préfixé de ".." mais désigne
 	// quand même un autre volume.
 	if filepath.VolumeName(filepath.FromSlash(clean)) != "" {
-		return "", fmt.Errorf("chemin refusé dans l'archive : %q", name)
+		return "", errorf("archive.unsafe_volume", "%w : %q désigne un autre volume", ErrUnsafeArchivePath, name)
 	}
 	if pathDepth(clean) > maxRecursionDepth {
-		return "", fmt.Errorf("arborescence trop profonde dans l'archive (plus de %d niveaux) : %q",
-			maxRecursionDepth, name)
+		return "", errorf("archive.unsafe_too_deep", "%w : arborescence trop profonde (plus de %d niveaux) : %q",
+			ErrUnsafeArchivePath, maxRecursionDepth, name)
 	}
 	return clean, nil
 }
@@ -3,6 +3,7 @@ package pkg
 import (
 	"archive/tar"
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"path/filepath"
@@ -301,7 +302,7 @@ func TestExtractionRefuseLesCheminsHostiles(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			dst := t.TempDir()
 			r := tarHostile(t, []*tar.Header{c.hdr})
-			if err := extractArchive(r, dst); err == nil {
+			if err := extractArchive(context.Background(), r, dst, nil); err == nil {
 				t.Fatal("archive hostile acceptée")
 			}
 			// Rien n'a pu sortir du dossier de destination : le parent du
@@ -319,14 +320,14 @@ func TestExtractionRefuseUnDoublon(t *testing.T) {
 		{Name: "f.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
 		{Name: "f.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644},
 	})
-	if err := extractArchive(r, dst); err == nil {
+	if err := extractArchive(context.Background(), r, dst, nil); err == nil {
 		t.Fatal("une archive décrivant deux fois le même chemin a été acceptée")
 	}
 }
 
 func TestVerifyDetecteUneArchiveHostile(t *testing.T) {
 	r := tarHostile(t, []*tar.Header{{Name: "../evasion.txt", Typeflag: tar.TypeReg, Size: 1, Mode: 0644}})
-	if err := checkArchive(r); err == nil {
+	if err := checkArchive(context.Background(), r, nil); err == nil {
 		t.Fatal("la vérification a validé une archive qu'on refuserait d'extraire")
 	}
 }
@@ -408,7 +409,7 @@ func TestFichierModifiePendantArchivage(t *testing.T) {
 				}},
 				total: c.annonce,
 			}
-			err := writeArchive(io.Discard, plan, nil)
+			err := writeArchive(context.Background(), io.Discard, plan, nil)
 			if err == nil {
 				t.Fatal("archive acceptée alors que la taille ne correspond pas")
 			}
@@ -434,7 +435,7 @@ func TestTarSizeExact(t *testing.T) {
 	write(t, root, "vide.txt", nil)
 	write(t, root, "aligné-512.bin", bytes.Repeat([]byte("a"), 512))
 
-	plan, err := scanDirectory(root)
+	plan, err := scanDirectory(root, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -444,7 +445,7 @@ func TestTarSizeExact(t *testing.T) {
 	}
 
 	var buf bytes.Buffer
-	if err := writeArchive(&buf, plan, nil); err != nil {
+	if err := writeArchive(context.Background(), &buf, plan, nil); err != nil {
 		t.Fatal(err)
 	}
 	if int64(buf.Len()) != annonce {
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"math/rand"
 	"os"
@@ -71,6 +72,7 @@ func BenchmarkPipeline(b *testing.B) {
 		{"aes", AlgoAES, CompNone},
 		{"chacha", AlgoChaCha, CompNone},
 		{"cascade", AlgoCascade, CompNone},
+		{"aegis", AlgoAEGIS, CompNone},
 		{"aes+zstd", AlgoAES, CompZstd},
 	}
 
@@ -117,6 +119,7 @@ func BenchmarkEncryptStream(b *testing.B) {
 		{"aes", Options{Algo: AlgoAES}},
 		{"chacha", Options{Algo: AlgoChaCha}},
 		{"cascade", Options{Algo: AlgoCascade}},
+		{"aegis", Options{Algo: AlgoAEGIS}},
 		{"aes+zstd", Options{Algo: AlgoAES, Comp: CompZstd}},
 		{"aes+remplissage", Options{Algo: AlgoAES, Pad: true}},
 	}
@@ -177,13 +180,13 @@ func BenchmarkArchive(b *testing.B) {
 		}
 	}
 
-	plan, err := scanDirectory(root)
+	plan, err := scanDirectory(root, nil)
 	if err != nil {
 		b.Fatal(err)
 	}
 	b.SetBytes(plan.total)
 	for i := 0; i < b.N; i++ {
-		if err := writeArchive(io.Discard, plan, nil); err != nil {
+		if err := writeArchive(context.Background(), io.Discard, plan, nil); err != nil {
 			b.Fatal(err)
 		}
 	}
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"io"
 	"runtime"
@@ -11,35 +12,66 @@ import (
 // Mesure des coûts sur la machine hôte.
 //
 // Deux questions auxquelles on ne peut pas répondre depuis le code : combien
-// coûte chaque profil KDF ici, et lequel d'AES ou de ChaCha est le plus rapide.
-// La seconde n'est pas rhétorique — sans accélération AES matérielle, ChaCha20
-// est nettement devant, et ça ne se devine pas.
+// coûte chaque profil KDF ici, et quel algorithme est le plus rapide. La
+// seconde n'est pas rhétorique — sans accélération AES matérielle, ChaCha20
+// est nettement devant, avec elle c'est AEGIS-256, et ça ne se devine pas.
+
+// Les étiquettes JSON sont celles de -json : les renommer casse les scripts
+// qui lisent le rapport.
 
 // KDFMeasure est le coût d'un profil sur cette machine.
 type KDFMeasure struct {
-	Profile   KDFProfile
-	Label     string
-	MemoryMiB uint32
-	Duration  time.Duration
+	// KDF est la fonction de dérivation mesurée (KDFArgon2id, KDFScrypt…), et
+	// KDFID son identifiant, le même que Details.KDFID.
+	KDF     byte       `json:"-"`
+	KDFID   string     `json:"kdf"`
+	Profile KDFProfile `json:"profile"`
+	// Label est le libellé affiché, hors du JSON : KDF, Profile et
+	// MemoryMiB en sont la forme stable.
+	Label     string        `json:"-"`
+	MemoryMiB uint32        `json:"memory_mib"`
+	Duration  time.Duration `json:"duration_ns"`
 }
 
 // AEADMeasure est le débit d'un algorithme de chiffrement.
 type AEADMeasure struct {
-	Algo  byte
-	Name  string
-	Bytes int64
+	// Algo est la suite mesurée, et AlgoID son identifiant, le même que
+	// Details.AlgoID.
+	Algo   byte   `json:"-"`
+	AlgoID string `json:"algo"`
+	Name   string `json:"name"`
+	Bytes  int64  `json:"bytes"`
 	// BytesPerSec vaut 0 si la mesure a échoué.
-	BytesPerSec int64
-	Err         error
+	BytesPerSec int64 `json:"bytes_per_sec"`
+	Err         error `json:"-"`
+}
+
+// MarshalJSON rend Err en texte : une interface error se sérialiserait en
+// objet vide.
+func (m AEADMeasure) MarshalJSON() ([]byte, error) {
+	type plain AEADMeasure
+	var msg string
+	if m.Err != nil {
+		msg = m.Err.Error()
+	}
+	return json.Marshal(struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{plain(m), msg})
 }
 
 // BenchmarkReport rassemble tout ce que la commande affiche.
 type BenchmarkReport struct {
-	CPUs     int
-	KDF      []KDFMeasure
-	AEAD     []AEADMeasure
-	Advised  KDFProfile
-	Advisory string
+	CPUs int          `json:"cpus"`
+	KDF  []KDFMeasure `json:"kdf"`
+	// KDFAlt mesure les mêmes profils en scrypt et PBKDF2, pour comparaison :
+	// la recommandation ne porte que sur Argon2id, le seul à conseiller hors
+	// contrainte particulière.
+	KDFAlt  []KDFMeasure  `json:"kdf_alt"`
+	AEAD    []AEADMeasure `json:"aead"`
+	Advised KDFProfile    `json:"advised"`
+	// Advisory est la recommandation rédigée, hors du JSON comme Label.
+	Advisory string `json:"-"`
 }
 
 // benchTargetMax est la durée au-delà de laquelle un profil devient pénible à
@@ -82,27 +114,25 @@ func Benchmark() BenchmarkReport {
 	var trouve bool
 
 	for _, p := range AllKDFProfiles() {
-		params := p.argonParams()
-		start := time.Now()
-		key, err := deriveKey(password, salt, params)
-		d := time.Since(start)
-		wipe(key)
+		m, err := measureKDF(KDFArgon2id, p, password, salt)
 		if err != nil {
 			// Un profil hors bornes ne devrait pas exister, mais s'il apparaît on
 			// ne le recommande pas et on continue.
 			continue
 		}
-		rep.KDF = append(rep.KDF, KDFMeasure{
-			Profile:   p,
-			Label:     "argon2id  " + params.String(),
-			MemoryMiB: params.Memory / 1024,
-			Duration:  d,
-		})
-		if d <= benchTargetMax && params.Memory/1024 <= benchAdviseMaxMemMiB {
+		rep.KDF = append(rep.KDF, m)
+		if m.Duration <= benchTargetMax && m.MemoryMiB <= benchAdviseMaxMemMiB {
 			advised = p
 			trouve = true
 		}
 	}
+	for _, kdf := range []byte{KDFScrypt, KDFPBKDF2} {
+		for _, p := range AllKDFProfiles() {
+			if m, err := measureKDF(kdf, p, password, salt); err == nil {
+				rep.KDFAlt = append(rep.KDFAlt, m)
+			}
+		}
+	}
 
 	rep.Advised = advised
 	switch {
@@ -118,30 +148,50 @@ func Benchmark() BenchmarkReport {
 			advised, benchAdviseMaxMemMiB)
 	}
 
-	for _, algo := range []byte{AlgoAES, AlgoChaCha, AlgoCascade} {
-		rep.AEAD = append(rep.AEAD, measureAEAD(algo))
+	// Toutes les suites enregistrées, y compris celles d'une application qui
+	// embarque le paquet : c'est précisément à elle que la comparaison sert.
+	for _, s := range Suites() {
+		rep.AEAD = append(rep.AEAD, measureAEAD(s))
 	}
 	return rep
 }
 
+// measureKDF chronomètre une dérivation d'un profil appliqué à une KDF.
+func measureKDF(kdf byte, p KDFProfile, password, salt []byte) (KDFMeasure, error) {
+	h := &header{Salt: salt}
+	p.applyTo(h, kdf)
+	start := time.Now()
+	key, err := deriveMaster(password, h)
+	d := time.Since(start)
+	wipe(key)
+	if err != nil {
+		return KDFMeasure{}, err
+	}
+	return KDFMeasure{
+		KDF:       kdf,
+		KDFID:     kdfIdent(kdf),
+		Profile:   p,
+		Label:     h.kdfLabel(),
+		MemoryMiB: uint32(h.kdfMemoryKiB() / 1024),
+		Duration:  d,
+	}, nil
+}
+
 // measureAEAD chiffre un bloc vers io.Discard et en déduit un débit.
-func measureAEAD(algo byte) AEADMeasure {
-	m := AEADMeasure{Algo: algo, Name: AlgoName(algo), Bytes: benchPayload}
+func measureAEAD(s CipherSuite) AEADMeasure {
+	m := AEADMeasure{Algo: s.ID(), AlgoID: algoIdent(s.ID()), Name: s.Name(), Bytes: benchPayload}
 
 	// Des clés fixes : on mesure le chiffrement, pas la dérivation. Elles ne
 	// protègent rien, le ciphertext part dans io.Discard.
-	keys := &keySet{
-		Key:   make([]byte, 32),
-		Inner: make([]byte, 32),
-		Outer: make([]byte, 32),
-	}
-	for i := range keys.Key {
-		keys.Key[i] = byte(i)
-		keys.Inner[i] = byte(i + 1)
-		keys.Outer[i] = byte(i + 2)
+	keys := make([][]byte, len(s.KeySizes()))
+	for k, n := range s.KeySizes() {
+		keys[k] = make([]byte, n)
+		for i := range keys[k] {
+			keys[k][i] = byte(i + k)
+		}
 	}
 
-	w, err := initCipherWriter(writerOnly{io.Discard}, algo, keys)
+	w, err := s.NewWriter(writerOnly{io.Discard}, keys)
 	if err != nil {
 		m.Err = err
 		return m
@@ -0,0 +1,230 @@
+package pkg
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math"
+	"os"
+)
+
+// Liste de mots de passe ayant fuité.
+//
+// zxcvbn repère les motifs (mots de dictionnaire, dates, suites de touches),
+// pas les mots de passe réellement sortis d'une fuite : un mot de passe
+// d'apparence aléatoire, réutilisé puis publié, passe pour robuste alors qu'il
+// figure dans toutes les listes d'attaque. Have I Been Pwned publie les
+// empreintes SHA-1 de ces mots de passe, une par ligne
+// (« EMPREINTE:OCCURRENCES »), mais le fichier brut pèse des dizaines de Go.
+//
+// BuildBreachDB le condense en un filtre de Bloom : chaque empreinte allume k
+// bits d'un tableau de m bits. Une recherche relit ces k bits sur le disque,
+// sans charger le fichier ni rien demander au réseau. Un mot de passe de la
+// liste est toujours reconnu ; un mot de passe absent l'est à tort avec une
+// probabilité de breachFalsePositive — un sur mille, qui coûte au pire de
+// devoir en choisir un autre.
+//
+// Format du fichier :
+//
+//	magic  "CHTOBRF1"  8 octets
+//	k      nombre de bits par empreinte, 1 octet
+//	m      taille du filtre en bits, multiple de 8, uint64 big-endian
+//	n      nombre d'empreintes insérées, uint64 big-endian
+//	bits   m/8 octets
+//
+// Les k positions se déduisent de l'empreinte par double hachage : h1 et h2
+// sont ses deux premiers mots de 64 bits, la i-ème position vaut
+// (h1 + i·h2) mod m. SHA-1 est uniforme, inutile de rehacher.
+
+const (
+	breachMagic         = "CHTOBRF1"
+	breachHeaderSize    = 8 + 1 + 8 + 8
+	breachFalsePositive = 1e-3
+	breachMaxHashes     = 32
+)
+
+// BreachStats décrit une liste construite par BuildBreachDB.
+type BreachStats struct {
+	Hashes      int64 // empreintes insérées
+	Bytes       int64 // taille du filtre sur le disque
+	BitsPerHash int   // bits allumés par empreinte
+}
+
+// BuildBreachDB lit une liste d'empreintes SHA-1 au format Have I Been Pwned
+// (une par ligne, « EMPREINTE » ou « EMPREINTE:OCCURRENCES », casse
+// indifférente) et écrit le filtre correspondant dans out.
+//
+// Le filtre est dimensionné d'après la taille de l'entrée, à raison d'une
+// empreinte par 41 octets au plus : une seule lecture suffit, et le taux de
+// faux positifs réel reste sous la cible. Il est construit en mémoire — un
+// peu moins de 2 Go pour la liste complète, quelques Mo pour les cent mille
+// mots de passe les plus courants.
+func BuildBreachDB(in, out string) (BreachStats, error) {
+	f, err := os.Open(in)
+	if err != nil {
+		return BreachStats{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return BreachStats{}, err
+	}
+	// Chaque empreinte prend au moins 40 caractères et un saut de ligne ; la
+	// dernière peut s'en passer.
+	maxHashes := (st.Size() + 1) / (2*sha1.Size + 1)
+	if maxHashes == 0 {
+		return BreachStats{}, errorf("breach.empty", "%s : aucune empreinte SHA-1", in)
+	}
+
+	k, m := breachSize(maxHashes)
+	bits := make([]byte, m/8)
+	var n int64
+	sc := bufio.NewScanner(f)
+	var sum [sha1.Size]byte
+	pos := make([]uint64, k)
+	for line := 1; sc.Scan(); line++ {
+		raw := sc.Bytes()
+		for i, c := range raw {
+			if c == ':' {
+				raw = raw[:i]
+				break
+			}
+		}
+		if len(raw) > 0 && raw[len(raw)-1] == '\r' {
+			raw = raw[:len(raw)-1]
+		}
+		if len(raw) == 0 {
+			continue
+		}
+		if len(raw) != 2*sha1.Size {
+			return BreachStats{}, errorf("breach.hash_length", "%s, ligne %d : empreinte SHA-1 attendue (40 caractères hexadécimaux), %d reçus", in, line, len(raw))
+		}
+		if _, err := hex.Decode(sum[:], raw); err != nil {
+			return BreachStats{}, errorf("breach.hash_invalid", "%s, ligne %d : empreinte SHA-1 invalide (%v)", in, line, err)
+		}
+		breachPositions(&sum, m, pos)
+		for _, p := range pos {
+			bits[p/8] |= 1 << (p % 8)
+		}
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return BreachStats{}, errorf("io.read_path", "lecture de %s: %w", in, err)
+	}
+	if n == 0 {
+		return BreachStats{}, errorf("breach.empty", "%s : aucune empreinte SHA-1", in)
+	}
+
+	dst, err := newAtomicFile(out)
+	if err != nil {
+		return BreachStats{}, err
+	}
+	defer dst.cleanup()
+	var hdr [breachHeaderSize]byte
+	copy(hdr[:], breachMagic)
+	hdr[8] = byte(k)
+	binary.BigEndian.PutUint64(hdr[9:], m)
+	binary.BigEndian.PutUint64(hdr[17:], uint64(n))
+	if _, err := dst.f.Write(hdr[:]); err != nil {
+		return BreachStats{}, errorf("io.write_path", "écriture de %s: %w", out, err)
+	}
+	if _, err := dst.f.Write(bits); err != nil {
+		return BreachStats{}, errorf("io.write_path", "écriture de %s: %w", out, err)
+	}
+	// Le fichier ne contient que des empreintes publiques : lisible par tous,
+	// comme n'importe quelle liste de référence.
+	if err := dst.f.Chmod(0644); err != nil {
+		return BreachStats{}, errorf("io.chmod_path", "permissions de %s: %w", out, err)
+	}
+	if err := dst.commit(); err != nil {
+		return BreachStats{}, err
+	}
+	return BreachStats{Hashes: n, Bytes: int64(breachHeaderSize + len(bits)), BitsPerHash: k}, nil
+}
+
+// breachSize rend le nombre de bits par empreinte et la taille du filtre pour
+// n empreintes, au taux de faux positifs visé : m = −n·ln p / (ln 2)², arrondi
+// à l'octet, et k = (m/n)·ln 2.
+func breachSize(n int64) (k int, m uint64) {
+	bitsPer := -math.Log(breachFalsePositive) / (math.Ln2 * math.Ln2)
+	m = uint64(math.Ceil(float64(n)*bitsPer/8)) * 8
+	k = int(math.Round(bitsPer * math.Ln2))
+	return max(1, min(k, breachMaxHashes)), m
+}
+
+// breachPositions remplit pos avec les positions de l'empreinte dans un
+// filtre de m bits.
+func breachPositions(sum *[sha1.Size]byte, m uint64, pos []uint64) {
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+	for i := range pos {
+		pos[i] = (h1 + uint64(i)*h2) % m
+	}
+}
+
+// BreachDB est une liste ouverte par OpenBreachDB.
+type BreachDB struct {
+	f *os.File
+	k int
+	m uint64
+	n int64
+}
+
+// OpenBreachDB ouvre une liste construite par BuildBreachDB, après en avoir
+// contrôlé l'en-tête et la taille.
+func OpenBreachDB(path string) (*BreachDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var hdr [breachHeaderSize]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil || string(hdr[:8]) != breachMagic {
+		f.Close()
+		return nil, errorf("breach.not_a_list", "%s n'est pas une liste de fuites (construite par chiffremento breachdb-build)", path)
+	}
+	db := &BreachDB{
+		f: f,
+		k: int(hdr[8]),
+		m: binary.BigEndian.Uint64(hdr[9:]),
+		n: int64(binary.BigEndian.Uint64(hdr[17:])),
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if db.k < 1 || db.k > breachMaxHashes || db.m == 0 || db.m%8 != 0 ||
+		uint64(st.Size()) != breachHeaderSize+db.m/8 {
+		f.Close()
+		return nil, errorf("breach.corrupt", "%s : liste de fuites tronquée ou corrompue", path)
+	}
+	return db, nil
+}
+
+// Hashes rend le nombre d'empreintes de la liste.
+func (db *BreachDB) Hashes() int64 { return db.n }
+
+// Contains indique si le mot de passe figure dans la liste. Seule son
+// empreinte est calculée, en mémoire, puis effacée ; rien n'est écrit.
+func (db *BreachDB) Contains(password []byte) (bool, error) {
+	sum := sha1.Sum(password)
+	defer clear(sum[:])
+	pos := make([]uint64, db.k)
+	defer clear(pos)
+	breachPositions(&sum, db.m, pos)
+	var b [1]byte
+	for _, p := range pos {
+		if _, err := db.f.ReadAt(b[:], breachHeaderSize+int64(p/8)); err != nil {
+			return false, errorf("breach.read", "lecture de la liste de fuites: %w", err)
+		}
+		if b[0]&(1<<(p%8)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Close ferme la liste.
+func (db *BreachDB) Close() error { return db.f.Close() }
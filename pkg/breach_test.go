@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListeDeFuites(t *testing.T) {
+	dir := t.TempDir()
+
+	// Le format de Have I Been Pwned : majuscules, nombre d'occurrences, fins
+	// de ligne Windows. Une ligne vide et une empreinte en minuscules en plus.
+	var b strings.Builder
+	const fuites = 2000
+	for i := range fuites {
+		fmt.Fprintf(&b, "%X:%d\r\n", sha1.Sum(fmt.Appendf(nil, "fuite-%d", i)), i+1)
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "%x\n", sha1.Sum([]byte("azerty123")))
+	src := write(t, dir, "pwned.txt", []byte(b.String()))
+
+	path := filepath.Join(dir, "fuites.db")
+	st, err := BuildBreachDB(src, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Hashes != fuites+1 {
+		t.Errorf("%d empreintes, attendu %d", st.Hashes, fuites+1)
+	}
+	if info, _ := os.Stat(path); info.Size() != st.Bytes {
+		t.Errorf("taille %d, annoncée %d", info.Size(), st.Bytes)
+	}
+
+	db, err := OpenBreachDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for i := range fuites {
+		if ok, err := db.Contains(fmt.Appendf(nil, "fuite-%d", i)); err != nil || !ok {
+			t.Fatalf("fuite-%d non reconnu (%v)", i, err)
+		}
+	}
+	if ok, _ := db.Contains([]byte("azerty123")); !ok {
+		t.Error("empreinte en minuscules non reconnue")
+	}
+
+	// Les faux positifs restent de l'ordre de la cible.
+	faux := 0
+	const essais = 20000
+	for i := range essais {
+		if ok, _ := db.Contains(fmt.Appendf(nil, "inédit-%d", i)); ok {
+			faux++
+		}
+	}
+	if faux > essais/200 {
+		t.Errorf("%d faux positifs sur %d", faux, essais)
+	}
+}
+
+func TestListeDeFuitesInvalide(t *testing.T) {
+	dir := t.TempDir()
+	for nom, contenu := range map[string]string{
+		"ntlm":   "8846F7EAEE8FB117AD06BDD830B7586C:42\n",
+		"hexa":   strings.Repeat("Z", 40) + "\n",
+		"vide":   "",
+		"lignes": "\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n",
+	} {
+		src := write(t, dir, nom+".txt", []byte(contenu))
+		if _, err := BuildBreachDB(src, filepath.Join(dir, nom+".db")); err == nil {
+			t.Errorf("%s : liste acceptée", nom)
+		}
+		if _, err := os.Stat(filepath.Join(dir, nom+".db")); err == nil {
+			t.Errorf("%s : filtre écrit malgré l'erreur", nom)
+		}
+	}
+
+	src := write(t, dir, "ok.txt", fmt.Appendf(nil, "%X:1\n", sha1.Sum([]byte("x"))))
+	path := filepath.Join(dir, "ok.db")
+	if _, err := BuildBreachDB(src, path); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+
+	// Une seule empreinte, sans saut de ligne final : 40 octets.
+	seule := write(t, dir, "seule.txt", fmt.Appendf(nil, "%X", sha1.Sum([]byte("x"))))
+	if st, err := BuildBreachDB(seule, filepath.Join(dir, "seule.db")); err != nil || st.Hashes != 1 {
+		t.Errorf("empreinte sans saut de ligne final : %+v, %v", st, err)
+	}
+
+	for nom, r := range map[string][]byte{
+		"tronquée": raw[:len(raw)-1],
+		"magic":    append([]byte("CHTOBRF0"), raw[8:]...),
+		"k nul":    append(append(raw[:8:8], 0), raw[9:]...),
+	} {
+		if _, err := OpenBreachDB(write(t, dir, "abimee.db", r)); err == nil {
+			t.Errorf("%s : liste ouverte", nom)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+// Calibration de la dérivation.
+//
+// Les profils sont réglés pour une machine de bureau récente. Sur une carte
+// ARM, « standard » prend plusieurs secondes ; sur un gros serveur, « maximum »
+// reste en dessous de ce que l'on accepterait d'attendre. La calibration
+// mesure plutôt que de deviner : elle cherche les paramètres Argon2id qui
+// coûtent à peu près la durée visée ici, sans dépasser un plafond de mémoire.
+//
+// La mémoire est le levier qui gêne le plus un attaquant : on la prend aussi
+// haute que le plafond le permet, et on ne la réduit que si une seule passe
+// dépasse déjà la durée visée. Le nombre de passes comble ensuite l'écart.
+// Le parallélisme reste celui des profils, pour que le coût ne dépende pas
+// du nombre de cœurs de la machine qui déchiffrera.
+
+// calibrateMinMemory est le plancher de la calibration : 19 Mio, le minimum
+// que l'OWASP recommande pour Argon2id. En dessous, mieux vaut attendre un
+// peu plus que d'affaiblir la dérivation.
+const calibrateMinMemory = uint32(19 * 1024)
+
+// calibrateMaxTarget borne la durée visée. Au-delà, maxArgonTime plafonnerait
+// de toute façon le nombre de passes sur la plupart des machines.
+const calibrateMaxTarget = 30 * time.Second
+
+// KDFCalibration est le résultat d'une calibration.
+type KDFCalibration struct {
+	Params ArgonParams
+	// Estimated est la durée mesurée (une passe) ou extrapolée (plusieurs)
+	// d'une dérivation avec Params sur cette machine.
+	Estimated time.Duration
+}
+
+// CalibrateKDF cherche les paramètres Argon2id qui coûtent environ target sur
+// cette machine, sans exiger plus de maxMemKiB. maxMemKiB nul vaut la mémoire
+// du profil standard.
+//
+// La mesure dure de l'ordre de target : quelques dérivations à une passe, la
+// mémoire divisée par deux à chaque fois qu'elle est trop lente.
+func CalibrateKDF(target time.Duration, maxMemKiB uint32) (KDFCalibration, error) {
+	password := []byte("mesure-de-reference")
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return KDFCalibration{}, errorf("crypto.salt", "génération du sel: %w", err)
+	}
+	return calibrate(target, maxMemKiB, func(p argonParams) (time.Duration, error) {
+		start := time.Now()
+		key, err := deriveKey(password, salt, p)
+		d := time.Since(start)
+		wipe(key)
+		return d, err
+	})
+}
+
+// calibrate est CalibrateKDF avec une mesure injectable, pour les tests.
+func calibrate(target time.Duration, maxMemKiB uint32, measure func(argonParams) (time.Duration, error)) (KDFCalibration, error) {
+	if target <= 0 || target > calibrateMaxTarget {
+		return KDFCalibration{}, errorf("calibrate.target", "durée visée hors bornes : %v (attendu entre 0 et %v)", target, calibrateMaxTarget)
+	}
+	if maxMemKiB == 0 {
+		maxMemKiB = defaultArgonMemory
+	}
+	if maxMemKiB < calibrateMinMemory || maxMemKiB > maxArgonMemory {
+		return KDFCalibration{}, errorf("calibrate.max_memory", "plafond de mémoire hors bornes : %d Mio (attendu %d..%d)",
+			maxMemKiB/1024, calibrateMinMemory/1024, maxArgonMemory/1024)
+	}
+
+	p := argonParams{Time: 1, Memory: maxMemKiB, Threads: defaultArgonThreads}
+	once, err := measure(p)
+	if err != nil {
+		return KDFCalibration{}, err
+	}
+	for once > target && p.Memory > calibrateMinMemory {
+		p.Memory = max(p.Memory/2, calibrateMinMemory)
+		if once, err = measure(p); err != nil {
+			return KDFCalibration{}, err
+		}
+	}
+
+	// Le coût d'Argon2 croît linéairement avec le nombre de passes : une
+	// mesure à une passe suffit pour extrapoler.
+	if once > 0 {
+		p.Time = uint32(min(int64(target/once), int64(maxArgonTime)))
+	} else {
+		p.Time = maxArgonTime
+	}
+	p.Time = max(p.Time, 1)
+	if err := p.validate(); err != nil {
+		return KDFCalibration{}, err
+	}
+	return KDFCalibration{
+		Params:    ArgonParams{Time: p.Time, MemoryKiB: p.Memory, Threads: p.Threads},
+		Estimated: once * time.Duration(p.Time),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// machineFictive modélise une dérivation dont le coût est proportionnel à la
+// mémoire et au nombre de passes : parMio par Mio et par passe.
+func machineFictive(parMio time.Duration) func(argonParams) (time.Duration, error) {
+	return func(p argonParams) (time.Duration, error) {
+		return parMio * time.Duration(p.Memory/1024) * time.Duration(p.Time), nil
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	cas := []struct {
+		nom     string
+		parMio  time.Duration
+		target  time.Duration
+		maxMem  uint32
+		attendu ArgonParams
+	}{
+		// 256 Mio en 128 ms : la mémoire tient, les passes comblent l'écart.
+		{"bureau", 500 * time.Microsecond, 500 * time.Millisecond, 0,
+			ArgonParams{Time: 3, MemoryKiB: 256 << 10, Threads: defaultArgonThreads}},
+		// Une passe à 256 Mio dure 2 s : la mémoire est divisée jusqu'à tenir.
+		{"carte ARM", 8 * time.Millisecond, 500 * time.Millisecond, 0,
+			ArgonParams{Time: 1, MemoryKiB: 32 << 10, Threads: defaultArgonThreads}},
+		// Même au plancher ça ne tient pas : on garde le plancher, une passe.
+		{"trop lente", time.Second, 500 * time.Millisecond, 0,
+			ArgonParams{Time: 1, MemoryKiB: calibrateMinMemory, Threads: defaultArgonThreads}},
+		// Très rapide : le nombre de passes plafonne à maxArgonTime.
+		{"serveur", time.Microsecond, 10 * time.Second, 1 << 20,
+			ArgonParams{Time: maxArgonTime, MemoryKiB: 1 << 20, Threads: defaultArgonThreads}},
+	}
+	for _, c := range cas {
+		cal, err := calibrate(c.target, c.maxMem, machineFictive(c.parMio))
+		if err != nil {
+			t.Fatalf("%s : %v", c.nom, err)
+		}
+		if cal.Params != c.attendu {
+			t.Errorf("%s : %+v, attendu %+v", c.nom, cal.Params, c.attendu)
+		}
+		if err := cal.Params.Validate(); err != nil {
+			t.Errorf("%s : paramètres hors bornes : %v", c.nom, err)
+		}
+	}
+
+	for _, c := range []struct {
+		target time.Duration
+		maxMem uint32
+	}{
+		{0, 0},
+		{time.Hour, 0},
+		{time.Second, maxArgonMemory + 1},
+		{time.Second, calibrateMinMemory - 1},
+	} {
+		if _, err := calibrate(c.target, c.maxMem, machineFictive(time.Millisecond)); err == nil {
+			t.Errorf("calibration acceptée : visé %v, plafond %d KiB", c.target, c.maxMem)
+		}
+	}
+}
+
+// TestCalibrationReelle : une vraie mesure, bornée pour rester rapide.
+func TestCalibrationReelle(t *testing.T) {
+	cal, err := CalibrateKDF(50*time.Millisecond, 32<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Params.MemoryKiB > 32<<10 || cal.Estimated <= 0 {
+		t.Errorf("calibration inattendue : %+v", cal)
+	}
+}
+
+func TestOptionsArgon(t *testing.T) {
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", []byte("réglé à la main"))
+
+	enc := filepath.Join(dir, "out.chto")
+	params := ArgonParams{Time: 2, MemoryKiB: 8 << 10, Threads: 1}
+	if err := Encrypt(in, enc, []byte("pw"), Options{KDF: KDFMaximum, Argon: &params}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Inspect(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(d.KDF, "m=8MiB t=2 p=1") {
+		t.Errorf("les paramètres explicites n'ont pas remplacé le profil : %q", d.KDF)
+	}
+	if err := Verify(enc, []byte("pw"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for nom, opts := range map[string]Options{
+		"hors bornes":   {Argon: &ArgonParams{Time: 0, MemoryKiB: 8 << 10, Threads: 1}},
+		"autre KDF":     {KDFAlgo: KDFScrypt, Argon: &params},
+		"trop de cœurs": {Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: maxArgonThreads + 1}},
+	} {
+		out := filepath.Join(dir, "refus.chto")
+		if err := Encrypt(in, out, []byte("pw"), opts); err == nil {
+			t.Errorf("%s : chiffrement accepté", nom)
+		}
+		if _, err := os.Stat(out); err == nil {
+			t.Errorf("%s : une sortie a été écrite malgré le refus", nom)
+		}
+	}
+	assertPasDeTemporaire(t, dir)
+}
@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	"context"
+	"io"
+)
+
+// Annulation.
+//
+// Les variantes …Context acceptent un contexte, vérifié entre deux morceaux
+// de copie et entre deux entrées d'archive. Une annulation passe par le même
+// chemin que n'importe quelle erreur : les defer de atomicFile et atomicDir
+// suppriment le temporaire, et la destination n'est pas touchée.
+//
+// La dérivation de clé, elle, ne s'interrompt pas : Argon2 ne prend pas de
+// contexte. Le contexte est revu juste après, avant le premier octet écrit.
+
+// ErrCanceled signale une opération interrompue par son contexte. L'erreur
+// rendue enveloppe aussi ctx.Err() : errors.Is y reconnaît ErrCanceled comme
+// context.Canceled ou context.DeadlineExceeded.
+var ErrCanceled = errorf("canceled", "opération annulée")
+
+// canceled rend nil tant que ctx est actif, l'erreur d'annulation sinon.
+func canceled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return errorf("canceled.context", "%w: %w", ErrCanceled, err)
+	}
+	return nil
+}
+
+// ctxReader vérifie le contexte avant chaque lecture. io.Copy lit par morceaux
+// de 32 Kio : une annulation est donc vue au morceau suivant.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(b []byte) (int, error) {
+	if err := canceled(c.ctx); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+// withContext branche ctx sur r. Un contexte qui ne peut pas être annulé
+// (context.Background) laisse r tel quel.
+func withContext(ctx context.Context, r io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return r
+	}
+	return &ctxReader{ctx: ctx, r: r}
+}
@@ -0,0 +1,175 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// annuleAuPremierMorceau rend des options dont la progression annule ctx dès
+// le premier morceau copié : l'opération doit s'arrêter au suivant.
+func annuleAuPremierMorceau(opts Options) (context.Context, Options) {
+	ctx, cancel := context.WithCancel(context.Background())
+	opts.Progress = func(done, total int64) { cancel() }
+	return ctx, opts
+}
+
+func assertAnnule(t *testing.T, err error, cause error) {
+	t.Helper()
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, cause) {
+		t.Fatalf("erreur %v, annulation (%v) attendue", err, cause)
+	}
+}
+
+func TestAnnulationChiffrement(t *testing.T) {
+	rapide := Options{Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}, Comp: CompZstd}
+	pw := []byte("motdepassetest123")
+	content := bytes.Repeat([]byte("un morceau de clair, "), 1<<16)
+
+	t.Run("avant de commencer", func(t *testing.T) {
+		dir := t.TempDir()
+		in := write(t, dir, "clair.txt", content)
+		out := filepath.Join(dir, "chiffre.chto")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assertAnnule(t, EncryptContext(ctx, in, out, pw, rapide), context.Canceled)
+		if _, err := os.Stat(out); !os.IsNotExist(err) {
+			t.Error("sortie créée malgré l'annulation")
+		}
+		assertPasDeTemporaire(t, dir)
+	})
+
+	t.Run("échéance dépassée", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		var buf bytes.Buffer
+		err := EncryptStreamContext(ctx, &buf, bytes.NewReader(content), -1, pw, rapide)
+		assertAnnule(t, err, context.DeadlineExceeded)
+		if buf.Len() != 0 {
+			t.Errorf("%d octets écrits malgré l'échéance", buf.Len())
+		}
+	})
+
+	t.Run("en cours de copie", func(t *testing.T) {
+		dir := t.TempDir()
+		in := write(t, dir, "clair.txt", content)
+		out := filepath.Join(dir, "chiffre.chto")
+		ctx, opts := annuleAuPremierMorceau(rapide)
+		assertAnnule(t, EncryptContext(ctx, in, out, pw, opts), context.Canceled)
+		if _, err := os.Stat(out); !os.IsNotExist(err) {
+			t.Error("sortie partielle laissée sur le chemin final")
+		}
+		assertPasDeTemporaire(t, dir)
+	})
+
+	t.Run("dossier", func(t *testing.T) {
+		dir := t.TempDir()
+		src := filepath.Join(dir, "src")
+		os.Mkdir(src, 0755)
+		for _, n := range []string{"a", "b", "c"} {
+			write(t, src, n, content)
+		}
+		out := filepath.Join(dir, "src.chto")
+		ctx, opts := annuleAuPremierMorceau(rapide)
+		assertAnnule(t, EncryptContext(ctx, src, out, pw, opts), context.Canceled)
+		if _, err := os.Stat(out); !os.IsNotExist(err) {
+			t.Error("sortie partielle laissée sur le chemin final")
+		}
+		assertPasDeTemporaire(t, dir)
+	})
+}
+
+func TestAnnulationDechiffrement(t *testing.T) {
+	rapide := Options{Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}}
+	pw := []byte("motdepassetest123")
+	content := bytes.Repeat([]byte("un morceau de clair, "), 1<<16)
+
+	dir := t.TempDir()
+	fichier := filepath.Join(dir, "fichier.chto")
+	if err := Encrypt(write(t, dir, "clair.txt", content), fichier, pw, rapide); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "src")
+	os.Mkdir(src, 0755)
+	for _, n := range []string{"a", "b", "c"} {
+		write(t, src, n, content)
+	}
+	dossier := filepath.Join(dir, "dossier.chto")
+	if err := Encrypt(src, dossier, pw, rapide); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chto := range []string{fichier, dossier} {
+		t.Run(filepath.Base(chto), func(t *testing.T) {
+			out := filepath.Join(dir, "sortie")
+			ctx, opts := annuleAuPremierMorceau(Options{})
+			assertAnnule(t, DecryptContext(ctx, chto, out, pw, opts), context.Canceled)
+			if _, err := os.Stat(out); !os.IsNotExist(err) {
+				t.Error("clair partiel laissé sur le chemin final")
+			}
+			assertPasDeTemporaire(t, dir)
+
+			ctx, opts = annuleAuPremierMorceau(Options{})
+			assertAnnule(t, VerifyContext(ctx, chto, pw, opts), context.Canceled)
+
+			ctx, opts = annuleAuPremierMorceau(Options{})
+			var buf bytes.Buffer
+			err := DecryptStreamContext(ctx, &buf, bytes.NewReader(mustRead(t, chto)), pw, opts)
+			assertAnnule(t, err, context.Canceled)
+			if buf.Len() >= len(content) {
+				t.Error("le flux a été déchiffré jusqu'au bout malgré l'annulation")
+			}
+		})
+	}
+
+	// Un contexte jamais annulé ne change rien au résultat.
+	out := filepath.Join(dir, "complet.txt")
+	if err := DecryptContext(context.Background(), fichier, out, pw, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyStreamContext(context.Background(), bytes.NewReader(mustRead(t, fichier)), pw, Options{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestAnnulationParcoursArchive : un tar sans contenu de fichier ne passe
+// presque pas par les copies, c'est le parcours lui-même qui doit s'arrêter.
+func TestAnnulationParcoursArchive(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	for i := range 50 {
+		os.MkdirAll(filepath.Join(src, "d", string(rune('a'+i%26)), string(rune('a'+i/26))), 0755)
+	}
+	plan, err := scanDirectory(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tarBrut bytes.Buffer
+	if err := writeArchive(context.Background(), &tarBrut, plan, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assertAnnule(t, writeArchive(ctx, &bytes.Buffer{}, plan, nil), context.Canceled)
+	assertAnnule(t, checkArchive(ctx, bytes.NewReader(tarBrut.Bytes()), nil), context.Canceled)
+	dst := filepath.Join(dir, "dst")
+	os.Mkdir(dst, 0700)
+	assertAnnule(t, extractArchive(ctx, bytes.NewReader(tarBrut.Bytes()), dst, nil), context.Canceled)
+	if entries, _ := os.ReadDir(dst); len(entries) != 0 {
+		t.Errorf("%d entrées extraites malgré l'annulation", len(entries))
+	}
+}
@@ -3,23 +3,22 @@ package pkg
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/rand"
-	"errors"
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/klauspost/compress/zstd"
-	"github.com/minio/sio"
 )
 
 // Options regroupe les réglages d'une opération. Elle remplace la série de
 // booléens positionnels de la v1, où -chacha et -parano pouvaient se
 // contredire sans que personne ne le signale.
 type Options struct {
-	// Algo vaut AlgoAES, AlgoChaCha ou AlgoCascade. Zéro équivaut à AlgoAES.
+	// Algo vaut AlgoAES, AlgoChaCha, AlgoCascade, AlgoAEGIS ou l'identifiant
+	// d'une suite enregistrée (voir RegisterSuite). Zéro équivaut à AlgoAES.
 	// Ignoré au déchiffrement : l'algorithme est lu dans l'en-tête du fichier.
 	Algo byte
 
@@ -38,6 +37,37 @@ type Options struct {
 	// l'en-tête du fichier.
 	KDF KDFProfile
 
+	// KDFAlgo choisit la fonction de dérivation : KDFArgon2id (défaut, aussi
+	// pour zéro), KDFScrypt ou KDFPBKDF2. Le profil KDF en règle le coût.
+	// Ignoré au déchiffrement.
+	KDFAlgo byte
+
+	// Argon, si non nil, remplace les paramètres Argon2id du profil : résultat
+	// d'une calibration (CalibrateKDF) ou réglage à la main. Il est refusé
+	// avec une autre KDF que Argon2id. Ignoré au déchiffrement.
+	Argon *ArgonParams
+
+	// Key, si non nil, remplace le mot de passe par une clé symétrique de
+	// SymmetricKeySize octets (voir keyfile.go) : pas de dérivation coûteuse,
+	// seulement HKDF. Le mot de passe passé en argument est alors ignoré, et
+	// KDF, KDFAlgo et Argon sont refusés. Au déchiffrement, elle est exigée
+	// pour un fichier chiffré par clé et refusée pour un fichier chiffré par
+	// mot de passe.
+	Key []byte
+
+	// RecoveryCode, au chiffrement, permet d'ouvrir aussi le fichier avec ce
+	// code de secours (GenerateRecoveryCode), indépendamment du mot de passe.
+	// Refusé avec Key. Au déchiffrement, il remplace le mot de passe, qui est
+	// alors ignoré. Voir recovery.go.
+	RecoveryCode []byte
+
+	// MaxKDFMemory, en KiB, refuse au déchiffrement les fichiers dont la
+	// dérivation exigerait davantage, avant toute allocation. Zéro : seul le
+	// plafond du format (2 Gio) s'applique. Dans tous les cas, un fichier qui
+	// exige plus que la mémoire disponible est refusé (voir memory.go).
+	// Ignoré au chiffrement.
+	MaxKDFMemory uint32
+
 	// Metadata décide si le nom d'origine et la date de modification sont
 	// conservés dans le chiffré. Sans effet sur un dossier, dont la charge utile
 	// est un tar qui les porte déjà. Ignoré au déchiffrement.
@@ -48,15 +78,69 @@ type Options struct {
 	// nul signifie « taille inconnue » — c'est le cas d'une entrée lue sur un
 	// flux.
 	Progress func(done, total int64)
+
+	// Events, si non nil, reçoit en plus l'étape en cours (inventaire,
+	// dérivation, chiffrement…) et l'entrée d'archive qui passe. Il est appelé
+	// dans la goroutine de l'opération, à chaque bloc : c'est à lui d'espacer
+	// ce qu'il affiche. Voir progress.go.
+	Events func(ProgressEvent)
+
+	// kdfFrom, si non nil, impose la dérivation et ses paramètres de cet
+	// en-tête : c'est ainsi qu'EditSession rechiffre à l'identique.
+	kdfFrom *header
+}
+
+// secretFor choisit ce qui sera dérivé pour h : la clé des options pour un
+// fichier chiffré par clé, le mot de passe sinon. Les confusions sont
+// signalées comme telles, plutôt que par une erreur d'authentification.
+func (o Options) secretFor(password []byte, h *header) ([]byte, error) {
+	byKey := h.kdfID() == KDFKey
+	switch {
+	case byKey && o.Key == nil:
+		return nil, errorf("secret.needs_key", "ce fichier est chiffré par clé symétrique : il faut la clé, pas un mot de passe")
+	case !byKey && o.Key != nil:
+		return nil, errorf("secret.needs_password", "ce fichier est protégé par mot de passe, pas par clé symétrique")
+	case byKey:
+		return o.Key, nil
+	}
+	return password, nil
+}
+
+// keysFor dérive le matériel de chiffrement de h au déchiffrement. Un fichier
+// à code de secours passe par sa clé de fichier, ouverte par le code ou par le
+// mot de passe ; les autres par secretFor.
+func (o Options) keysFor(password []byte, h *header) (*keySet, error) {
+	if h.Recovery == nil {
+		if o.RecoveryCode != nil {
+			return nil, errorf("secret.no_recovery", "ce fichier n'a pas de code de secours")
+		}
+		secret, err := o.secretFor(password, h)
+		if err != nil {
+			return nil, err
+		}
+		return deriveKeys(secret, h)
+	}
+	if o.Key != nil {
+		return nil, errorf("secret.needs_password", "ce fichier est protégé par mot de passe, pas par clé symétrique")
+	}
+	fileKey, err := h.openRecovery(password, o.RecoveryCode)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(fileKey)
+	return expandKeys(fileKey, h)
 }
 
 // validate attrape les combinaisons impossibles avant d'écrire quoi que ce soit.
 func (o Options) validate() error {
+	if o.RecoveryCode != nil && o.Key != nil {
+		return errorf("secret.recovery_with_key", "un code de secours double un mot de passe : il n'a pas de sens avec une clé symétrique")
+	}
 	if err := validateCompWrite(o.Comp); err != nil {
 		return err
 	}
 	if o.Pad && o.Comp != CompNone {
-		return errors.New("le remplissage et la compression s'excluent : la taille d'un fichier compressé dépend de la compressibilité du contenu, que le remplissage ne masque pas")
+		return errorf("options.pad_with_comp", "le remplissage et la compression s'excluent : la taille d'un fichier compressé dépend de la compressibilité du contenu, que le remplissage ne masque pas")
 	}
 	return nil
 }
@@ -121,14 +205,14 @@ func newAtomicFile(dest string) (*atomicFile, error) {
 	dir := filepath.Dir(dest)
 	f, err := os.CreateTemp(dir, ".chto-tmp-*")
 	if err != nil {
-		return nil, fmt.Errorf("création du fichier temporaire: %w", err)
+		return nil, errorf("io.temp_create", "création du fichier temporaire: %w", err)
 	}
 	// CreateTemp crée déjà en 0600 ; on le verrouille explicitement pour ne pas
 	// dépendre de l'umask, et parce que la sortie déchiffrée est un secret.
 	if err := f.Chmod(0600); err != nil {
 		f.Close()
 		os.Remove(f.Name())
-		return nil, fmt.Errorf("permissions du fichier temporaire: %w", err)
+		return nil, errorf("io.temp_chmod", "permissions du fichier temporaire: %w", err)
 	}
 	trackTempFile(f.Name(), f)
 	return &atomicFile{f: f, dest: dest}, nil
@@ -138,13 +222,13 @@ func newAtomicFile(dest string) (*atomicFile, error) {
 // destination contient un fichier complet ou n'a pas été touchée du tout.
 func (a *atomicFile) commit() error {
 	if err := a.f.Sync(); err != nil {
-		return fmt.Errorf("synchronisation sur disque: %w", err)
+		return errorf("io.sync", "synchronisation sur disque: %w", err)
 	}
 	if err := a.f.Close(); err != nil {
-		return fmt.Errorf("fermeture du fichier temporaire: %w", err)
+		return errorf("io.temp_close", "fermeture du fichier temporaire: %w", err)
 	}
 	if err := os.Rename(a.f.Name(), a.dest); err != nil {
-		return fmt.Errorf("renommage vers %s: %w", a.dest, err)
+		return errorf("io.rename", "renommage vers %s: %w", a.dest, err)
 	}
 	a.committed = true
 	untrackTemp(a.f.Name())
@@ -186,15 +270,15 @@ func newAtomicDir(dest string) (*atomicDir, error) {
 	// Un rename de dossier échoue si la destination existe déjà (et n'est pas
 	// un dossier vide) : autant le dire tout de suite, et clairement.
 	if _, err := os.Lstat(dest); err == nil {
-		return nil, fmt.Errorf("%s existe déjà : déplace-le ou renomme-le avant d'extraire", dest)
+		return nil, errorf("extract.exists", "%s existe déjà : il faut le déplacer ou le renommer avant d'extraire", dest)
 	}
 	p, err := os.MkdirTemp(filepath.Dir(dest), ".chto-tmp-*")
 	if err != nil {
-		return nil, fmt.Errorf("création du dossier temporaire: %w", err)
+		return nil, errorf("io.tempdir_create", "création du dossier temporaire: %w", err)
 	}
 	if err := os.Chmod(p, 0700); err != nil {
 		os.RemoveAll(p)
-		return nil, fmt.Errorf("permissions du dossier temporaire: %w", err)
+		return nil, errorf("io.tempdir_chmod", "permissions du dossier temporaire: %w", err)
 	}
 	trackTemp(p)
 	return &atomicDir{path: p, dest: dest}, nil
@@ -202,7 +286,7 @@ func newAtomicDir(dest string) (*atomicDir, error) {
 
 func (a *atomicDir) commit() error {
 	if err := os.Rename(a.path, a.dest); err != nil {
-		return fmt.Errorf("renommage vers %s: %w", a.dest, err)
+		return errorf("io.rename", "renommage vers %s: %w", a.dest, err)
 	}
 	a.committed = true
 	untrackTemp(a.path)
@@ -242,100 +326,36 @@ func (p *progressReader) Read(b []byte) (int, error) {
 	return n, err
 }
 
-// --- Flux de chiffrement -----------------------------------------------
-
-// Note sur sio.AES_GCM : la constante s'appelait AES_256_GCM jusqu'à la
-// v0.4.3, elle a été renommée en v0.5.0 parce que la suite accepte aussi des
-// clés de 128 bits. Ici les clés font toujours 32 octets, c'est donc bien de
-// l'AES-256-GCM. La valeur écrite dans le format n'a pas changé (0), les
-// fichiers restent interchangeables entre les deux versions de la librairie.
-
-// cascadeWriteCloser ferme les deux couches du mode cascade dans l'ordre et
-// remonte la première erreur rencontrée. Les deux Close() sont toujours
-// tentés, même si le premier échoue.
-type cascadeWriteCloser struct {
-	inner io.WriteCloser
-	outer io.WriteCloser
+// countingReader compte le clair qui le traverse, pour DecryptResult.Bytes.
+type countingReader struct {
+	r io.Reader
+	n int64
 }
 
-func (c *cascadeWriteCloser) Write(p []byte) (int, error) { return c.inner.Write(p) }
-
-func (c *cascadeWriteCloser) Close() error {
-	errInner := c.inner.Close()
-	errOuter := c.outer.Close()
-	if errInner != nil {
-		return errInner
-	}
-	return errOuter
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
 }
 
-func initCipherWriter(dst io.Writer, algo byte, keys *keySet) (io.WriteCloser, error) {
-	switch algo {
-	case AlgoCascade:
-		outerWriter, err := sio.EncryptWriter(dst, sio.Config{
-			Key:          keys.Outer,
-			CipherSuites: []byte{sio.CHACHA20_POLY1305},
-		})
-		if err != nil {
-			return nil, fmt.Errorf("création du flux externe: %w", err)
-		}
-		innerWriter, err := sio.EncryptWriter(writerOnly{outerWriter}, sio.Config{
-			Key:          keys.Inner,
-			CipherSuites: []byte{sio.AES_GCM},
-		})
-		if err != nil {
-			outerWriter.Close()
-			return nil, fmt.Errorf("création du flux interne: %w", err)
-		}
-		return &cascadeWriteCloser{inner: innerWriter, outer: outerWriter}, nil
-
-	case AlgoChaCha:
-		return sio.EncryptWriter(dst, sio.Config{
-			Key:          keys.Key,
-			CipherSuites: []byte{sio.CHACHA20_POLY1305},
-		})
-
-	case AlgoAES:
-		return sio.EncryptWriter(dst, sio.Config{
-			Key:          keys.Key,
-			CipherSuites: []byte{sio.AES_GCM},
-		})
+// --- Flux de chiffrement -----------------------------------------------
 
-	default:
-		return nil, fmt.Errorf("algorithme inconnu : %d", algo)
+// initCipherWriter et initCipherReader montent la suite désignée par algo.
+// Les algorithmes eux-mêmes vivent dans le registre (voir suite.go).
+func initCipherWriter(dst io.Writer, algo byte, keys *keySet) (io.WriteCloser, error) {
+	s, ok := lookupSuite(algo)
+	if !ok {
+		return nil, errorf("algo.unknown", "algorithme inconnu : %d", algo)
 	}
+	return s.NewWriter(dst, keys.parts(len(s.KeySizes())))
 }
 
 func initCipherReader(src io.Reader, algo byte, keys *keySet) (io.Reader, error) {
-	switch algo {
-	case AlgoCascade:
-		outerReader, err := sio.DecryptReader(src, sio.Config{
-			Key:          keys.Outer,
-			CipherSuites: []byte{sio.CHACHA20_POLY1305},
-		})
-		if err != nil {
-			return nil, fmt.Errorf("init du déchiffrement externe: %w", err)
-		}
-		return sio.DecryptReader(outerReader, sio.Config{
-			Key:          keys.Inner,
-			CipherSuites: []byte{sio.AES_GCM},
-		})
-
-	case AlgoChaCha:
-		return sio.DecryptReader(src, sio.Config{
-			Key:          keys.Key,
-			CipherSuites: []byte{sio.CHACHA20_POLY1305},
-		})
-
-	case AlgoAES:
-		return sio.DecryptReader(src, sio.Config{
-			Key:          keys.Key,
-			CipherSuites: []byte{sio.AES_GCM},
-		})
-
-	default:
-		return nil, fmt.Errorf("algorithme inconnu : %d", algo)
+	s, ok := lookupSuite(algo)
+	if !ok {
+		return nil, errorf("algo.unknown", "algorithme inconnu : %d", algo)
 	}
+	return s.NewReader(src, keys.parts(len(s.KeySizes())))
 }
 
 // --- Couche de compression ---------------------------------------------
@@ -357,7 +377,7 @@ func initCompressWriter(dst io.Writer, comp byte) (io.WriteCloser, error) {
 		// chiffrement sous lui, c'est nous qui ordonnons les Close().
 		w, err := zstd.NewWriter(writerOnly{dst}, zstd.WithEncoderLevel(zstdLevel))
 		if err != nil {
-			return nil, fmt.Errorf("création du flux zstd: %w", err)
+			return nil, errorf("comp.zstd", "création du flux zstd: %w", err)
 		}
 		return w, nil
 	default:
@@ -375,7 +395,7 @@ func initCompressReader(src io.Reader, comp byte) (io.Reader, func(), error) {
 	case CompGzip:
 		r, err := gzip.NewReader(src)
 		if err != nil {
-			return nil, nil, fmt.Errorf("création du flux gzip: %w", err)
+			return nil, nil, errorf("comp.gzip", "création du flux gzip: %w", err)
 		}
 		return r, func() { r.Close() }, nil
 	case CompZstd:
@@ -383,7 +403,7 @@ func initCompressReader(src io.Reader, comp byte) (io.Reader, func(), error) {
 		// concurrence par défaut de zstd ne ferait que consommer de la mémoire.
 		d, err := zstd.NewReader(src, zstd.WithDecoderConcurrency(1))
 		if err != nil {
-			return nil, nil, fmt.Errorf("création du flux zstd: %w", err)
+			return nil, nil, errorf("comp.zstd", "création du flux zstd: %w", err)
 		}
 		return d.IOReadCloser(), func() { d.Close() }, nil
 	default:
@@ -403,6 +423,10 @@ type source struct {
 	// meta est non nil quand le nom d'origine et la date doivent être conservés.
 	// Toujours nil pour un dossier : le tar les porte déjà.
 	meta *FileMetadata
+	// tar signale que r porte déjà un flux tar : c'est le cas d'une archive
+	// relue puis rechiffrée par Upgrade, qui n'a plus de dossier à parcourir
+	// mais doit garder FlagArchive.
+	tar bool
 }
 
 // payloadSize renvoie la taille exacte de la charge utile qui sera chiffrée, et
@@ -426,26 +450,34 @@ func (s source) payloadSize() (int64, bool, error) {
 // du chiffrement et le drapeau FlagArchive est posé dans l'en-tête. Le fichier
 // produit est toujours au format v3.
 func Encrypt(inputPath, outputPath string, password []byte, opts Options) error {
+	return EncryptContext(context.Background(), inputPath, outputPath, password, opts)
+}
+
+// EncryptContext est Encrypt, interrompu dès que ctx est annulé. La sortie
+// n'est alors jamais créée et l'erreur satisfait errors.Is(err, ErrCanceled).
+func EncryptContext(ctx context.Context, inputPath, outputPath string, password []byte, opts Options) error {
 	info, err := os.Stat(inputPath)
 	if err != nil {
-		return fmt.Errorf("lecture: %w", err)
+		return errorf("io.read", "lecture: %w", err)
 	}
 
+	t := opts.track()
 	var src source
 	// Le scan du dossier a lieu avant la création de la sortie : une
 	// arborescence refusée n'y laisse donc aucun fichier partiel.
 	if info.IsDir() {
-		plan, err := scanDirectory(inputPath)
+		t.enter(PhaseScan, 0)
+		plan, err := scanDirectory(inputPath, t)
 		if err != nil {
 			return err
 		}
 		src = source{plan: plan, size: plan.total}
 	} else if !info.Mode().IsRegular() {
-		return fmt.Errorf("%s n'est ni un fichier régulier ni un dossier", inputPath)
+		return errorf("input.not_regular", "%s n'est ni un fichier régulier ni un dossier", inputPath)
 	} else {
 		inFile, err := os.Open(inputPath)
 		if err != nil {
-			return fmt.Errorf("lecture: %w", err)
+			return errorf("io.read", "lecture: %w", err)
 		}
 		defer inFile.Close()
 		src = source{r: inFile, size: info.Size()}
@@ -460,9 +492,10 @@ func Encrypt(inputPath, outputPath string, password []byte, opts Options) error
 	}
 	defer out.cleanup()
 
-	if err := encrypt(out.f, src, password, opts); err != nil {
+	if err := encrypt(ctx, out.f, src, password, opts, t); err != nil {
 		return err
 	}
+	t.enter(PhaseFsync, 0)
 	return out.commit()
 }
 
@@ -474,22 +507,24 @@ func Encrypt(inputPath, outputPath string, password []byte, opts Options) error
 // arrière. C'est à l'appelant de ne pas diriger un flux vers un fichier qu'il
 // tient à conserver.
 func EncryptStream(dst io.Writer, src io.Reader, size int64, password []byte, opts Options) error {
-	return encrypt(dst, source{r: src, size: size}, password, opts)
+	return EncryptStreamContext(context.Background(), dst, src, size, password, opts)
 }
 
-// encrypt monte la chaîne d'écriture (remplissage → compression → chiffrement)
-// et l'exécute. Tous les chemins de chiffrement passent par ici.
-func encrypt(dst io.Writer, src source, password []byte, opts Options) error {
+// EncryptStreamContext est EncryptStream, interrompu dès que ctx est annulé.
+// Ce qui a déjà été écrit dans dst y reste : un flux annulé est un flux
+// tronqué, que le déchiffrement refusera.
+func EncryptStreamContext(ctx context.Context, dst io.Writer, src io.Reader, size int64, password []byte, opts Options) error {
+	return encrypt(ctx, dst, source{r: src, size: size}, password, opts, opts.track())
+}
+
+// encrypt chiffre src vers dst. Tous les chemins de chiffrement par copie
+// passent par ici ; la chaîne d'écriture elle-même est montée par
+// openEncrypted, que partage NewEncryptWriter. t suit l'opération, nil si
+// personne n'écoute.
+func encrypt(ctx context.Context, dst io.Writer, src source, password []byte, opts Options, t *tracker) error {
 	if err := opts.validate(); err != nil {
 		return err
 	}
-	algo := opts.Algo
-	if algo == 0 {
-		algo = AlgoAES
-	}
-	if err := validateAlgo(algo); err != nil {
-		return err
-	}
 
 	// Le bloc de métadonnées est sérialisé avant tout le reste : sa taille entre
 	// dans le calcul du remplissage, sinon la longueur du nom d'origine
@@ -513,109 +548,216 @@ func encrypt(dst io.Writer, src source, password []byte, opts Options) error {
 			return err
 		}
 		if !known {
-			return errors.New("le remplissage exige une taille d'entrée connue : impossible sur un flux")
+			return errorf("pad.needs_size_stream", "le remplissage exige une taille d'entrée connue : impossible sur un flux")
 		}
 		padding = paddingFor(payload + int64(len(metaBlock)))
 	}
 
-	salt := make([]byte, saltSize)
-	if _, err := rand.Read(salt); err != nil {
-		return fmt.Errorf("génération du sel: %w", err)
-	}
-
-	profile, err := ParseKDFProfile(string(opts.KDF))
+	w, err := openEncrypted(ctx, dst, password, opts, payloadLayout{
+		archive:   src.plan != nil || src.tar,
+		padding:   padding,
+		metaBlock: metaBlock,
+	}, t)
 	if err != nil {
 		return err
 	}
 
+	total := src.size
+	if total < 0 {
+		total = 0
+	}
+	t.enter(PhaseEncrypt, total)
+	if src.plan != nil {
+		if err := writeArchive(ctx, w, src.plan, t); err != nil {
+			w.abort()
+			return err
+		}
+	} else {
+		if _, err := io.Copy(w, withProgress(withContext(ctx, src.r), total, t)); err != nil {
+			w.abort()
+			return errorf("crypto.encrypt", "chiffrement: %w", err)
+		}
+	}
+	return w.Close()
+}
+
+// payloadLayout décrit ce qui précède le contenu dans la charge utile, et
+// qu'il faut donc connaître avant d'écrire l'en-tête.
+type payloadLayout struct {
+	archive   bool
+	padding   int64 // ignoré sans opts.Pad
+	metaBlock []byte
+}
+
+// openEncrypted écrit l'en-tête dans dst et monte la chaîne d'écriture
+// (remplissage → compression → chiffrement). Le remplissage et les
+// métadonnées sont déjà écrits au retour : ce qui suit est le contenu. opts a
+// été validé par l'appelant.
+func openEncrypted(ctx context.Context, dst io.Writer, password []byte, opts Options, layout payloadLayout, t *tracker) (*encryptWriter, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	algo := opts.Algo
+	if algo == 0 {
+		algo = AlgoAES
+	}
+	if err := validateAlgo(algo); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errorf("crypto.salt", "génération du sel: %w", err)
+	}
+
 	h := &header{
 		Version: currentVersion,
 		Algo:    algo,
-		Argon:   profile.argonParams(),
 		Comp:    opts.Comp,
 		Salt:    salt,
 	}
-	if src.plan != nil {
+	if err := opts.applyKDF(h); err != nil {
+		return nil, err
+	}
+	if layout.archive {
 		h.Flags |= FlagArchive
 	}
 	if opts.Pad {
 		h.Flags |= FlagPadded
 	}
-	if metaBlock != nil {
+	if layout.metaBlock != nil {
 		h.Flags |= FlagMetadata
 	}
+	// Le même contrôle qu'au déchiffrement : un profil trop lourd pour cette
+	// machine doit être refusé, pas tué par le noyau en cours de route.
+	if err := CheckKDFMemory(h.kdfMemoryKiB(), 0); err != nil {
+		return nil, err
+	}
+	t.enter(PhaseKDF, 0)
+
+	// La clé de fichier est scellée avant d'écrire l'en-tête, qui porte les
+	// deux emplacements.
+	var fileKey []byte
+	if opts.RecoveryCode != nil {
+		k, err := h.sealRecovery(password, opts.RecoveryCode)
+		if err != nil {
+			return nil, err
+		}
+		fileKey = k
+		defer wipe(fileKey)
+	}
+	// sealRecovery dérive déjà une clé : une annulation pendant ce temps ne
+	// doit pas laisser partir un en-tête seul.
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
 	if _, err := dst.Write(h.marshal()); err != nil {
-		return fmt.Errorf("écriture du header: %w", err)
+		return nil, errorf("header.write", "écriture du header: %w", err)
 	}
 
-	keys, err := deriveKeys(password, h)
+	var keys *keySet
+	var err error
+	if fileKey != nil {
+		keys, err = expandKeys(fileKey, h)
+	} else {
+		var secret []byte
+		if secret, err = opts.secretFor(password, h); err == nil {
+			keys, err = deriveKeys(secret, h)
+		}
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := canceled(ctx); err != nil {
+		keys.wipe()
+		return nil, err
 	}
-	defer keys.wipe()
 
 	cipherWriter, err := initCipherWriter(writerOnly{dst}, algo, keys)
 	if err != nil {
-		return err
+		keys.wipe()
+		return nil, err
 	}
+	w := &encryptWriter{cipher: cipherWriter, payload: cipherWriter, keys: keys}
 
 	compWriter, err := initCompressWriter(cipherWriter, opts.Comp)
 	if err != nil {
-		cipherWriter.Close()
-		return err
+		w.abort()
+		return nil, err
 	}
-	var payloadDst io.Writer = cipherWriter
 	if compWriter != nil {
-		payloadDst = compWriter
+		w.comp, w.payload = compWriter, compWriter
 	}
 
 	if opts.Pad {
-		if err := writePadding(payloadDst, padding); err != nil {
-			cipherWriter.Close()
-			return err
+		if err := writePadding(w.payload, layout.padding); err != nil {
+			w.abort()
+			return nil, err
 		}
 	}
 
 	// Après le remplissage, avant le contenu : la lecture suit le même ordre.
-	if metaBlock != nil {
-		if _, err := payloadDst.Write(metaBlock); err != nil {
-			cipherWriter.Close()
-			return fmt.Errorf("écriture des métadonnées: %w", err)
+	if layout.metaBlock != nil {
+		if _, err := w.payload.Write(layout.metaBlock); err != nil {
+			w.abort()
+			return nil, errorf("metadata.write", "écriture des métadonnées: %w", err)
 		}
 	}
+	return w, nil
+}
 
-	if src.plan != nil {
-		if err := writeArchive(payloadDst, src.plan, opts.Progress); err != nil {
-			cipherWriter.Close()
-			return err
-		}
-	} else {
-		total := src.size
-		if total < 0 {
-			total = 0
-		}
-		if _, err := io.Copy(payloadDst, withProgress(src.r, total, opts.Progress)); err != nil {
-			cipherWriter.Close()
-			return fmt.Errorf("chiffrement: %w", err)
-		}
+// encryptWriter est le bout de la chaîne d'écriture : ce qu'on y écrit est
+// compressé, chiffré, puis scellé par Close.
+type encryptWriter struct {
+	cipher  io.WriteCloser
+	comp    io.WriteCloser // nil sans compression
+	payload io.Writer      // comp, ou cipher sans compression
+	keys    *keySet
+	closed  bool
+}
+
+var errWriterClosed = errorf("stream.write_after_close", "écriture après la fermeture du chiffrement")
+
+func (w *encryptWriter) Write(b []byte) (int, error) {
+	if w.closed {
+		return 0, errWriterClosed
 	}
+	return w.payload.Write(b)
+}
 
-	// Les Close() ne sont pas différés : c'est le Close() de sio qui scelle et
-	// écrit le dernier bloc. Ignorer son erreur — ce que faisait la v1 —
-	// revient à annoncer « Opération réussie » sur un fichier tronqué dès que
-	// le disque est plein.
-	if compWriter != nil {
-		if err := compWriter.Close(); err != nil {
-			cipherWriter.Close()
-			return fmt.Errorf("finalisation de la compression: %w", err)
+// Close scelle le flux. Les Close() ne sont pas différés : c'est le Close() de
+// sio qui scelle et écrit le dernier bloc. Ignorer son erreur — ce que faisait
+// la v1 — revient à annoncer « Opération réussie » sur un fichier tronqué dès
+// que le disque est plein.
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.keys.wipe()
+	if w.comp != nil {
+		if err := w.comp.Close(); err != nil {
+			w.cipher.Close()
+			return errorf("comp.finish", "finalisation de la compression: %w", err)
 		}
 	}
-	if err := cipherWriter.Close(); err != nil {
-		return fmt.Errorf("finalisation du chiffrement: %w", err)
+	if err := w.cipher.Close(); err != nil {
+		return errorf("crypto.finish", "finalisation du chiffrement: %w", err)
 	}
 	return nil
 }
 
+// abort referme la chaîne après un échec et efface les clés. Le flux écrit
+// jusque-là n'est pas utilisable, et n'a pas à l'être.
+func (w *encryptWriter) abort() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	w.cipher.Close()
+	w.keys.wipe()
+}
+
 // Decrypt déchiffre inputPath vers outputPath. L'algorithme, la compression, le
 // remplissage, la nature du contenu (fichier ou dossier) et les paramètres
 // Argon2 sont lus dans l'en-tête ; seul opts.Progress est pris en compte ici.
@@ -627,6 +769,13 @@ func Decrypt(inputPath, outputPath string, password []byte, opts Options) error
 	return err
 }
 
+// DecryptContext est Decrypt, interrompu dès que ctx est annulé. Le fichier
+// ou le dossier temporaire est supprimé et outputPath n'est pas créé.
+func DecryptContext(ctx context.Context, inputPath, outputPath string, password []byte, opts Options) error {
+	_, err := DecryptToContext(ctx, inputPath, outputPath, password, opts)
+	return err
+}
+
 // DecryptResult décrit ce qu'a produit un déchiffrement.
 type DecryptResult struct {
 	// Metadata est non nil quand le fichier portait un nom d'origine et une
@@ -635,11 +784,19 @@ type DecryptResult struct {
 	Metadata *FileMetadata
 	// Archive vaut true quand la sortie est une arborescence, pas un fichier.
 	Archive bool
+	// Bytes compte le clair produit : le contenu du fichier, ou le flux tar
+	// d'une archive, remplissage et métadonnées exclus.
+	Bytes int64
 }
 
 // DecryptTo est Decrypt, en rendant compte de ce qui a été trouvé dans le
 // fichier. Les appelants qui veulent signaler le nom d'origine passent par là.
 func DecryptTo(inputPath, outputPath string, password []byte, opts Options) (DecryptResult, error) {
+	return DecryptToContext(context.Background(), inputPath, outputPath, password, opts)
+}
+
+// DecryptToContext est DecryptTo, interrompu dès que ctx est annulé.
+func DecryptToContext(ctx context.Context, inputPath, outputPath string, password []byte, opts Options) (DecryptResult, error) {
 	var res DecryptResult
 
 	inFile, size, err := openInput(inputPath)
@@ -650,7 +807,8 @@ func DecryptTo(inputPath, outputPath string, password []byte, opts Options) (Dec
 
 	// Le flux est monté avant que la sortie n'existe : un en-tête invalide ou
 	// un mauvais mot de passe échoue donc sans rien créer.
-	src, h, closeSrc, err := openDecrypted(inFile, size, password, opts)
+	t := opts.track()
+	src, h, closeSrc, err := openDecrypted(ctx, inFile, size, password, opts, t)
 	if err != nil {
 		return res, err
 	}
@@ -660,15 +818,18 @@ func DecryptTo(inputPath, outputPath string, password []byte, opts Options) (Dec
 	res.Archive = h.archive()
 
 	if h.archive() {
+		t.rename(PhaseExtract)
 		out, err := newAtomicDir(outputPath)
 		if err != nil {
 			return res, err
 		}
 		defer out.cleanup()
 
-		if err := extractArchive(src, out.path); err != nil {
+		counted := &countingReader{r: src}
+		if err := extractArchive(ctx, counted, out.path, t); err != nil {
 			return res, err
 		}
+		res.Bytes = counted.n
 		return res, out.commit()
 	}
 
@@ -678,10 +839,13 @@ func DecryptTo(inputPath, outputPath string, password []byte, opts Options) (Dec
 	}
 	defer out.cleanup()
 
-	if _, err := io.Copy(out.f, src); err != nil {
-		return res, fmt.Errorf("déchiffrement: %w", err)
+	n, err := io.Copy(out.f, src)
+	if err != nil {
+		return res, errorf("crypto.decrypt", "déchiffrement: %w", err)
 	}
+	res.Bytes = n
 
+	t.enter(PhaseFsync, 0)
 	if err := out.commit(); err != nil {
 		return res, err
 	}
@@ -703,14 +867,19 @@ func DecryptTo(inputPath, outputPath string, password []byte, opts Options) (Dec
 // l'eau, donc avant que l'authentification de la fin du fichier soit connue.
 // Acceptable vers un tube, jamais vers un fichier qu'on tient à conserver.
 func DecryptStream(dst io.Writer, src io.Reader, password []byte, opts Options) error {
-	r, _, closeSrc, err := openDecrypted(src, 0, password, opts)
+	return DecryptStreamContext(context.Background(), dst, src, password, opts)
+}
+
+// DecryptStreamContext est DecryptStream, interrompu dès que ctx est annulé.
+func DecryptStreamContext(ctx context.Context, dst io.Writer, src io.Reader, password []byte, opts Options) error {
+	r, _, closeSrc, err := openDecrypted(ctx, src, 0, password, opts, opts.track())
 	if err != nil {
 		return err
 	}
 	defer closeSrc()
 
 	if _, err := io.Copy(dst, r); err != nil {
-		return fmt.Errorf("déchiffrement: %w", err)
+		return errorf("crypto.decrypt", "déchiffrement: %w", err)
 	}
 	return nil
 }
@@ -719,41 +888,76 @@ func DecryptStream(dst io.Writer, src io.Reader, password []byte, opts Options)
 // sur le disque : tout part vers io.Discard. Utile pour vérifier une
 // sauvegarde sans avoir la place — ou l'envie — de l'extraire.
 func Verify(inputPath string, password []byte, opts Options) error {
+	return VerifyContext(context.Background(), inputPath, password, opts)
+}
+
+// VerifyContext est Verify, interrompu dès que ctx est annulé.
+func VerifyContext(ctx context.Context, inputPath string, password []byte, opts Options) error {
 	inFile, size, err := openInput(inputPath)
 	if err != nil {
 		return err
 	}
 	defer inFile.Close()
-	return verify(inFile, size, password, opts)
+	_, err = verify(ctx, inFile, size, password, opts)
+	return err
+}
+
+// VerifyReport est Verify, en rendant compte de ce qui a été contrôlé : nature
+// du contenu, métadonnées, taille du clair. Rien n'est écrit sur le disque.
+func VerifyReport(inputPath string, password []byte, opts Options) (DecryptResult, error) {
+	inFile, size, err := openInput(inputPath)
+	if err != nil {
+		return DecryptResult{}, err
+	}
+	defer inFile.Close()
+	return verify(context.Background(), inFile, size, password, opts)
 }
 
 // VerifyStream est Verify sur un flux, pour contrôler une sauvegarde qui arrive
 // par un tube sans jamais la poser sur le disque.
 func VerifyStream(src io.Reader, password []byte, opts Options) error {
-	return verify(src, 0, password, opts)
+	return VerifyStreamContext(context.Background(), src, password, opts)
+}
+
+// VerifyStreamContext est VerifyStream, interrompu dès que ctx est annulé.
+func VerifyStreamContext(ctx context.Context, src io.Reader, password []byte, opts Options) error {
+	_, err := verify(ctx, src, 0, password, opts)
+	return err
+}
+
+// VerifyStreamReport est VerifyReport sur un flux.
+func VerifyStreamReport(src io.Reader, password []byte, opts Options) (DecryptResult, error) {
+	return verify(context.Background(), src, 0, password, opts)
 }
 
-func verify(in io.Reader, size int64, password []byte, opts Options) error {
-	src, h, closeSrc, err := openDecrypted(in, size, password, opts)
+func verify(ctx context.Context, in io.Reader, size int64, password []byte, opts Options) (DecryptResult, error) {
+	var res DecryptResult
+	t := opts.track()
+	src, h, closeSrc, err := openDecrypted(ctx, in, size, password, opts, t)
 	if err != nil {
-		return err
+		return res, err
 	}
 	defer closeSrc()
+	res.Metadata = h.Meta
+	res.Archive = h.archive()
 
 	// Pour une archive, on déroule le tar plutôt que de jeter les octets en
 	// vrac : ça contrôle aussi que l'extraction serait acceptée, donc qu'une
 	// sauvegarde de dossier est réellement restaurable.
+	counted := &countingReader{r: src}
 	if h.archive() {
-		if err := checkArchive(src); err != nil {
-			return fmt.Errorf("vérification: %w", err)
+		if err := checkArchive(ctx, counted, t); err != nil {
+			return res, errorf("crypto.verify", "vérification: %w", err)
 		}
-		return nil
+		res.Bytes = counted.n
+		return res, nil
 	}
 
-	if _, err := io.Copy(io.Discard, src); err != nil {
-		return fmt.Errorf("vérification: %w", err)
+	if _, err := io.Copy(io.Discard, counted); err != nil {
+		return res, errorf("crypto.verify", "vérification: %w", err)
 	}
-	return nil
+	res.Bytes = counted.n
+	return res, nil
 }
 
 // openInput ouvre un .chto et renvoie sa taille, pour que la progression ait un
@@ -761,12 +965,12 @@ func verify(in io.Reader, size int64, password []byte, opts Options) error {
 func openInput(path string) (*os.File, int64, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, 0, fmt.Errorf("lecture: %w", err)
+		return nil, 0, errorf("io.read", "lecture: %w", err)
 	}
 	info, err := f.Stat()
 	if err != nil {
 		f.Close()
-		return nil, 0, fmt.Errorf("taille du fichier d'entrée: %w", err)
+		return nil, 0, errorf("input.size", "taille du fichier d'entrée: %w", err)
 	}
 	return f, info.Size(), nil
 }
@@ -775,7 +979,11 @@ func openInput(path string) (*os.File, int64, error) {
 // saut du remplissage) et renvoie de quoi la refermer. Partagé par tous les
 // chemins de lecture pour qu'ils ne puissent pas diverger. Un total nul
 // signifie que la taille d'entrée est inconnue.
-func openDecrypted(in io.Reader, total int64, password []byte, opts Options) (io.Reader, *header, func(), error) {
+//
+// ctx est branché sur l'entrée chiffrée : toutes les copies en aval lisent
+// par là, et voient donc l'annulation au morceau suivant. t suit la
+// dérivation puis la lecture du chiffré.
+func openDecrypted(ctx context.Context, in io.Reader, total int64, password []byte, opts Options, t *tracker) (io.Reader, *header, func(), error) {
 	var closers []func()
 	closeAll := func() {
 		for i := len(closers) - 1; i >= 0; i-- {
@@ -810,16 +1018,25 @@ func openDecrypted(in io.Reader, total int64, password []byte, opts Options) (io
 	if h.archive() {
 		var premier [1]byte
 		if _, err := io.ReadFull(in, premier[:]); err != nil {
-			return fail(fmt.Errorf("%w : la charge utile est absente", errTruncated))
+			return fail(errorf("truncated.no_payload", "%w : la charge utile est absente", ErrTruncated))
 		}
 		in = io.MultiReader(bytes.NewReader(premier[:]), in)
 	}
 
-	keys, err := deriveKeys(password, h)
+	// Avant la dérivation : c'est elle qui alloue, et un OOM ne laisse
+	// aucune chance d'expliquer quoi que ce soit.
+	if err := CheckKDFMemory(h.kdfMemoryKiB(), opts.MaxKDFMemory); err != nil {
+		return fail(err)
+	}
+	t.enter(PhaseKDF, 0)
+	keys, err := opts.keysFor(password, h)
 	if err != nil {
 		return fail(err)
 	}
 	closers = append(closers, keys.wipe)
+	if err := canceled(ctx); err != nil {
+		return fail(err)
+	}
 
 	// L'en-tête a déjà été consommé : le compteur ne verra que ce qui reste,
 	// il faut donc l'ôter du total pour que la progression atteigne 100 %.
@@ -827,10 +1044,15 @@ func openDecrypted(in io.Reader, total int64, password []byte, opts Options) (io
 	if total <= 0 {
 		restant = 0
 	}
-	src, err := initCipherReader(withProgress(in, restant, opts.Progress), h.Algo, keys)
+	t.enter(PhaseDecrypt, restant)
+	chiffre := &eofReader{r: withProgress(withContext(ctx, in), restant, t)}
+	src, err := initCipherReader(chiffre, h.Algo, keys)
 	if err != nil {
 		return fail(err)
 	}
+	// Avant la décompression, qui pourrait envelopper les erreurs de sio
+	// au point de les rendre méconnaissables.
+	src = authReader{r: src, in: chiffre}
 
 	src, releaseComp, err := initCompressReader(src, h.Comp)
 	if err != nil {
@@ -860,24 +1082,47 @@ func openDecrypted(in io.Reader, total int64, password []byte, opts Options) (io
 	return src, h, closeAll, nil
 }
 
-// Details décrit un .chto tel que son en-tête l'annonce.
+// Details décrit un .chto tel que son en-tête l'annonce. Les étiquettes JSON
+// sont celles de info -json.
 type Details struct {
-	Version    byte
-	Algo       string
-	KDF        string
-	Compressed bool
-	// Comp nomme l'algorithme de compression ("aucune", "gzip", "zstd").
-	Comp string
+	Version byte `json:"version"`
+	// Algo nomme l'algorithme pour l'interface, hors du JSON comme KDF.
+	Algo string `json:"-"`
+	// AlgoID identifie l'algorithme : "aes-256-gcm", "chacha20-poly1305",
+	// "cascade", "aegis-256", ou "private-N" pour une suite privée.
+	AlgoID string `json:"algo"`
+	// KDF décrit la dérivation pour l'interface, paramètres compris. C'est un
+	// libellé en français, hors du JSON : KDFID et KDFParams en sont la forme
+	// stable.
+	KDF string `json:"-"`
+	// KDFID identifie la dérivation : "argon2id", "scrypt", "pbkdf2-sha256",
+	// ou "key" pour un fichier chiffré par clé symétrique.
+	KDFID     string    `json:"kdf"`
+	KDFParams KDFParams `json:"kdf_params"`
+	// KeyBased vaut true quand le fichier est chiffré par clé symétrique et
+	// non par mot de passe.
+	KeyBased bool `json:"key_based"`
+	// KDFMemoryKiB est la mémoire qu'exigera la dérivation, à comparer avec
+	// CheckKDFMemory avant de demander le mot de passe.
+	KDFMemoryKiB uint64 `json:"kdf_memory_kib"`
+	Compressed   bool   `json:"compressed"`
+	// Comp nomme la compression pour l'interface ("aucune", "zstd"…), hors du
+	// JSON comme KDF.
+	Comp string `json:"-"`
+	// CompID identifie la compression : "none", "gzip" ou "zstd".
+	CompID string `json:"comp"`
 	// Archive vaut true quand le fichier contient un dossier : le
 	// déchiffrement produira une arborescence, pas un fichier.
-	Archive bool
+	Archive bool `json:"archive"`
 	// Padded vaut true quand la taille réelle du clair est masquée par du
 	// remplissage.
-	Padded bool
+	Padded bool `json:"padded"`
 	// Metadata vaut true quand le fichier porte le nom d'origine et la date.
 	// Le drapeau est dans l'en-tête, donc lisible sans mot de passe ; leur
 	// contenu, lui, est à l'intérieur du chiffrement.
-	Metadata bool
+	Metadata bool `json:"metadata"`
+	// Recovery vaut true quand un code de secours ouvre aussi le fichier.
+	Recovery bool `json:"recovery"`
 }
 
 // Inspect lit l'en-tête d'un .chto sans le déchiffrer, pour que l'interface
@@ -886,7 +1131,7 @@ type Details struct {
 func Inspect(path string) (Details, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return Details{}, fmt.Errorf("lecture: %w", err)
+		return Details{}, errorf("io.read", "lecture: %w", err)
 	}
 	defer f.Close()
 
@@ -894,16 +1139,27 @@ func Inspect(path string) (Details, error) {
 	if err != nil {
 		return Details{}, err
 	}
+	return detailsOf(h), nil
+}
+
+func detailsOf(h *header) Details {
 	return Details{
-		Version:    h.Version,
-		Algo:       AlgoName(h.Algo),
-		KDF:        "argon2id  " + h.Argon.String(),
-		Compressed: h.compressed(),
-		Comp:       CompName(h.Comp),
-		Archive:    h.archive(),
-		Padded:     h.padded(),
-		Metadata:   h.hasMetadata(),
-	}, nil
+		Version:      h.Version,
+		Algo:         AlgoName(h.Algo),
+		AlgoID:       algoIdent(h.Algo),
+		KDF:          h.kdfLabel(),
+		KDFID:        kdfIdent(h.kdfID()),
+		KDFParams:    h.kdfParams(),
+		KeyBased:     h.kdfID() == KDFKey,
+		KDFMemoryKiB: h.kdfMemoryKiB(),
+		Compressed:   h.compressed(),
+		Comp:         CompName(h.Comp),
+		CompID:       compIdent(h.Comp),
+		Archive:      h.archive(),
+		Padded:       h.padded(),
+		Metadata:     h.hasMetadata(),
+		Recovery:     h.hasRecovery(),
+	}
 }
 
 // DefaultKDFLabel décrit les paramètres Argon2 utilisés pour les nouveaux
@@ -912,9 +1168,9 @@ func DefaultKDFLabel() string {
 	return "argon2id  " + defaultArgonParams().String()
 }
 
-func withProgress(r io.Reader, total int64, fn func(done, total int64)) io.Reader {
-	if fn == nil {
+func withProgress(r io.Reader, total int64, t *tracker) io.Reader {
+	if t == nil {
 		return r
 	}
-	return &progressReader{r: r, total: total, fn: fn}
+	return &progressReader{r: r, total: total, fn: t.advance}
 }
@@ -486,7 +486,7 @@ func TestCompatibiliteV1(t *testing.T) {
 	}
 }
 
-func TestEncryptEcritDuV3AvecArgonRenforce(t *testing.T) {
+func TestEncryptEcritLeFormatCourantAvecArgonRenforce(t *testing.T) {
 	dir := t.TempDir()
 	in := write(t, dir, "clair.txt", []byte("x"))
 	enc := filepath.Join(dir, "out.chto")
@@ -497,10 +497,10 @@ func TestEncryptEcritDuV3AvecArgonRenforce(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if d.Version != versionV3 {
-		t.Errorf("version écrite %d, attendu %d", d.Version, versionV3)
+	if d.Version != currentVersion {
+		t.Errorf("version écrite %d, attendu %d", d.Version, currentVersion)
 	}
-	if !strings.Contains(d.KDF, "m=256MiB") {
+	if !strings.Contains(d.KDF, "argon2id") || !strings.Contains(d.KDF, "m=256MiB") {
 		t.Errorf("paramètres Argon2 inattendus dans le header: %s", d.KDF)
 	}
 }
@@ -512,13 +512,15 @@ func TestEncryptEcritDuV3AvecArgonRenforce(t *testing.T) {
 // mérite un bump de version.
 func TestProtocolSafety_Tripwire(t *testing.T) {
 	const (
-		expectedVersion  = 3
+		expectedVersion  = 4
 		expectedHeaderV1 = 27 // 8+1+1+1+16
 		expectedHeaderV2 = 36 // 8+1+1+1+4+4+1+16
 		expectedHeaderV3 = 37 // 8+1+1+1+4+4+1+1+16
+		expectedHeaderV4 = 38 // 8+1+1+1+1+4+4+1+1+16
 		expectedMagic    = "CHFRMT03"
-		// FlagArchive (bit1, dossiers), FlagPadded (bit2, taille masquée) et
-		// FlagMetadata (bit3, nom et date d'origine).
+		// FlagArchive (bit1, dossiers), FlagPadded (bit2, taille masquée),
+		// FlagMetadata (bit3, nom et date d'origine) et FlagRecovery (bit4,
+		// code de secours, suivi d'un bloc de recoveryBlockSize octets).
 		//
 		// Définir un bit réservé n'appelle pas de bump de version : la disposition
 		// de l'en-tête v3 ne change pas, et un binaire antérieur *refuse* un bit
@@ -526,7 +528,8 @@ func TestProtocolSafety_Tripwire(t *testing.T) {
 		// exactement ce pour quoi knownFlags existe. Un bump ne serait dû que si
 		// la structure de l'en-tête bougeait — taille, ordre ou sens d'un champ
 		// existant.
-		expectedKnownFlags = FlagCompressed | FlagArchive | FlagPadded | FlagMetadata
+		expectedKnownFlags    = FlagCompressed | FlagArchive | FlagPadded | FlagMetadata | FlagRecovery
+		expectedRecoveryBlock = 96 // 2 × (32 + 16)
 	)
 
 	if currentVersion < expectedVersion {
@@ -546,10 +549,18 @@ func TestProtocolSafety_Tripwire(t *testing.T) {
 		t.Logf("⚠️  la taille du header v3 a changé (avant: %d, maintenant: %d)", expectedHeaderV3, headerSizeV3)
 		structureChanged = true
 	}
+	if headerSizeV4 != expectedHeaderV4 {
+		t.Logf("⚠️  la taille du header v4 a changé (avant: %d, maintenant: %d)", expectedHeaderV4, headerSizeV4)
+		structureChanged = true
+	}
 	if magicNumber != expectedMagic {
 		t.Logf("⚠️  le magic number a changé (avant: %s, maintenant: %s)", expectedMagic, magicNumber)
 		structureChanged = true
 	}
+	if recoveryBlockSize != expectedRecoveryBlock {
+		t.Logf("⚠️  la taille du bloc de code de secours a changé (avant: %d, maintenant: %d)", expectedRecoveryBlock, recoveryBlockSize)
+		structureChanged = true
+	}
 	if knownFlags != expectedKnownFlags {
 		t.Logf("⚠️  la liste des drapeaux connus a changé (avant: %d, maintenant: %d)", expectedKnownFlags, knownFlags)
 		structureChanged = true
@@ -569,6 +580,13 @@ func TestProtocolSafety_Tripwire(t *testing.T) {
 	if versionV2 != 2 {
 		t.Error("l'identifiant de la version 2 ne doit pas changer")
 	}
+	if versionV3 != 3 {
+		t.Error("l'identifiant de la version 3 ne doit pas changer")
+	}
+	// Les identifiants de KDF sont inscrits dans les fichiers v4.
+	if KDFArgon2id != 1 || KDFScrypt != 2 || KDFPBKDF2 != 3 || KDFKey != 4 {
+		t.Error("les identifiants de KDF ne doivent pas changer")
+	}
 	for _, ref := range []string{"v1_aes.chto", "v2_aes.chto"} {
 		if _, err := os.Stat(filepath.Join("testdata", ref)); err != nil {
 			t.Errorf("%s a disparu de testdata/ : la compatibilité n'est plus testée", ref)
@@ -616,6 +634,39 @@ func TestVerify(t *testing.T) {
 	}
 }
 
+// TestVerifyReport : le compte rendu de Verify dit ce que DecryptTo aurait
+// produit, sans rien écrire.
+func TestVerifyReport(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("données à compter. "), 300)
+	in := write(t, dir, "compte.txt", content)
+	enc := filepath.Join(dir, "compte.chto")
+	opts := Options{Comp: CompZstd, Metadata: MetadataMinimal, Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}}
+	if err := Encrypt(in, enc, []byte("pw"), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := VerifyReport(enc, []byte("pw"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Bytes != int64(len(content)) || res.Archive || res.Metadata == nil || res.Metadata.Name != "compte.txt" {
+		t.Errorf("compte rendu %+v", res)
+	}
+	flux, err := VerifyStreamReport(bytes.NewReader(mustRead(t, enc)), []byte("pw"), Options{})
+	if err != nil || flux.Bytes != res.Bytes {
+		t.Errorf("sur un flux : %+v, %v", flux, err)
+	}
+
+	dec, err := DecryptTo(enc, filepath.Join(dir, "relu.txt"), []byte("pw"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Bytes != res.Bytes {
+		t.Errorf("DecryptTo compte %d octets, VerifyReport %d", dec.Bytes, res.Bytes)
+	}
+}
+
 func TestVerifyFichierV1(t *testing.T) {
 	if err := Verify(filepath.Join("testdata", "v1_cascade.chto"), []byte("reference-v1-password"), Options{}); err != nil {
 		t.Errorf("un fichier v1 intact a été rejeté: %v", err)
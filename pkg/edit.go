@@ -0,0 +1,277 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Modification en place.
+//
+// Un éditeur ne sait pas lire un flux chiffré : il lui faut un vrai fichier.
+// Le clair passe donc par le disque, mais le moins possible et le moins
+// longtemps possible :
+//
+//   - dans un répertoire privé (0700), au nom imprévisible, le fichier en 0600 ;
+//   - sur un système de fichiers en mémoire quand il en existe un
+//     ($XDG_RUNTIME_DIR, /dev/shm) : rien n'atteint alors un disque, pas même
+//     un bloc que l'effacement aurait oublié ;
+//   - inscrit au registre des temporaires, pour que CleanupTemporaries le
+//     supprime aussi sur un signal.
+//
+// La session se fait en deux temps, OpenEdit puis Save, parce que l'éditeur
+// tourne entre les deux et qu'il appartient à l'appelant : c'est lui qui sait
+// quel programme lancer, et sur quel terminal.
+//
+// Le rechiffrement reprend les paramètres de l'en-tête d'origine — suite,
+// dérivation et ses paramètres, compression, remplissage, métadonnées —, avec
+// un sel neuf. Il passe par atomicFile : une erreur en cours de route laisse
+// l'ancien fichier intact.
+
+// EditSession est un fichier chiffré ouvert pour modification.
+type EditSession struct {
+	path  string // le .chto
+	dir   string // répertoire privé du clair
+	plain string
+	// memory dit si le clair est sur un système de fichiers en mémoire.
+	memory bool
+
+	h        *header
+	sum      [sha256.Size]byte
+	password *SecureBuffer
+	key      *SecureBuffer
+	opts     Options
+
+	// Taille et date du .chto à l'ouverture : s'il a changé entre-temps,
+	// l'écraser perdrait la modification de quelqu'un d'autre.
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+
+	closeOnce sync.Once
+}
+
+// OpenEdit déchiffre path dans un fichier temporaire privé, dont Path donne le
+// chemin. Le mot de passe ou opts.Key passent dans un tampon protégé, gardé
+// jusqu'à Close pour le rechiffrement ; une fois la session ouverte, la copie
+// de l'appelant est effacée.
+//
+// Les dossiers sont refusés, comme les fichiers à code de secours : le
+// rechiffrement ne saurait pas reconstituer le second accès sans le code, et
+// le perdre en silence serait pire. Les fichiers antérieurs à la v3 doivent
+// d'abord passer par Upgrade.
+//
+// opts.MaxKDFMemory, opts.Progress et opts.Events s'appliquent comme au
+// déchiffrement ; le reste est ignoré, les paramètres étant ceux du fichier.
+func OpenEdit(path string, password []byte, opts Options) (*EditSession, error) {
+	if opts.RecoveryCode != nil {
+		return nil, errorf("edit.recovery", "un fichier à code de secours ne peut pas être modifié en place : il faut le déchiffrer puis le rechiffrer")
+	}
+	in, size, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	st, err := in.Stat()
+	if err != nil {
+		return nil, errorf("io.read", "lecture: %w", err)
+	}
+
+	// L'en-tête est d'abord lu seul : un refus ne doit coûter ni dérivation ni
+	// fichier temporaire.
+	h, err := readHeader(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := detailsOf(h).Editable(); err != nil {
+		return nil, err
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return nil, errorf("upgrade.reread_header", "relecture de l'en-tête: %w", err)
+	}
+
+	s := &EditSession{
+		path:    path,
+		opts:    Options{Progress: opts.Progress, Events: opts.Events},
+		size:    st.Size(),
+		modTime: st.ModTime(),
+		mode:    st.Mode().Perm(),
+	}
+	if err := s.createDir(); err != nil {
+		return nil, err
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			s.Close()
+		}
+	}()
+
+	t := opts.track()
+	src, h, closeSrc, err := openDecrypted(context.Background(), in, size, password, Options{Key: opts.Key, MaxKDFMemory: opts.MaxKDFMemory}, t)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSrc()
+	s.h = h
+	s.plain = filepath.Join(s.dir, plainName(path, h))
+
+	f, err := os.OpenFile(s.plain, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, errorf("io.create_path", "création de %s: %w", s.plain, err)
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hash), src)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, errorf("crypto.decrypt", "déchiffrement: %w", err)
+	}
+	hash.Sum(s.sum[:0])
+
+	if opts.Key != nil {
+		s.key, err = SecureCopy(opts.Key)
+	} else {
+		s.password, err = SecureCopy(password)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok = true
+	return s, nil
+}
+
+// Editable dit si le fichier décrit peut passer par OpenEdit, pour refuser
+// avant de demander le moindre secret.
+func (d Details) Editable() error {
+	switch {
+	case d.Archive:
+		return errorf("edit.archive", "c'est un dossier chiffré : seul un fichier peut être modifié en place")
+	case d.Recovery:
+		return errorf("edit.recovery", "un fichier à code de secours ne peut pas être modifié en place : il faut le déchiffrer puis le rechiffrer")
+	case d.Version < versionV3:
+		return errorf("edit.old_format", "ancien format v%d : il faut d'abord le mettre à niveau", d.Version)
+	}
+	return nil
+}
+
+// createDir crée le répertoire privé, en mémoire si possible.
+func (s *EditSession) createDir() error {
+	if m := memoryDir(); m != "" {
+		if d, err := os.MkdirTemp(m, "chto-edit-*"); err == nil {
+			s.dir, s.memory = d, true
+		}
+	}
+	if s.dir == "" {
+		d, err := os.MkdirTemp("", "chto-edit-*")
+		if err != nil {
+			return errorf("io.tempdir_create", "création du dossier temporaire: %w", err)
+		}
+		s.dir = d
+	}
+	trackTemp(s.dir)
+	return nil
+}
+
+// plainName nomme le clair pour l'éditeur, qui en déduit souvent la
+// coloration : le nom d'origine s'il a été conservé, sinon celui du chiffré
+// sans son extension.
+func plainName(path string, h *header) string {
+	if h.Meta != nil && h.Meta.Name != "" {
+		return h.Meta.Name
+	}
+	base := filepath.Base(path)
+	if name := strings.TrimSuffix(base, filepath.Ext(base)); name != "" {
+		return name
+	}
+	return "clair"
+}
+
+// Path est le chemin du clair, à ouvrir dans l'éditeur.
+func (s *EditSession) Path() string { return s.plain }
+
+// MemoryBacked dit si le clair est sur un système de fichiers en mémoire. À
+// défaut, il est dans le répertoire temporaire du système, et peut atteindre
+// un disque.
+func (s *EditSession) MemoryBacked() bool { return s.memory }
+
+// Save rechiffre le clair à la place du fichier d'origine. Il renvoie false,
+// sans rien écrire, quand le contenu n'a pas changé : un fichier seulement
+// ouvert puis refermé garde son chiffré, et sa date.
+func (s *EditSession) Save() (bool, error) {
+	f, err := os.Open(s.plain)
+	if err != nil {
+		return false, errorf("io.read", "lecture: %w", err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return false, errorf("input.size", "taille du fichier d'entrée: %w", err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return false, errorf("io.read", "lecture: %w", err)
+	}
+	if bytes.Equal(hash.Sum(nil), s.sum[:]) {
+		return false, nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false, errorf("io.read", "lecture: %w", err)
+	}
+
+	if cur, err := os.Stat(s.path); err != nil {
+		return false, errorf("io.read", "lecture: %w", err)
+	} else if cur.Size() != s.size || !cur.ModTime().Equal(s.modTime) {
+		return false, errorf("edit.changed_on_disk", "%s a changé depuis son ouverture : modification abandonnée pour ne pas l'écraser", s.path)
+	}
+
+	var meta *FileMetadata
+	if s.h.Meta != nil {
+		meta = &FileMetadata{Name: s.h.Meta.Name, ModTime: st.ModTime().UTC().Truncate(metaTimeGrain)}
+	}
+	opts := s.opts
+	opts.Algo, opts.Comp, opts.Pad, opts.Key = s.h.Algo, s.h.Comp, s.h.padded(), s.key.Bytes()
+	opts.kdfFrom = s.h
+
+	t := opts.track()
+	out, err := newAtomicFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	defer out.cleanup()
+	err = encrypt(context.Background(), out.f, source{r: f, size: st.Size(), meta: meta}, s.password.Bytes(), opts, t)
+	if err != nil {
+		return false, err
+	}
+	if err := out.f.Chmod(s.mode); err != nil {
+		return false, errorf("io.chmod_path", "permissions de %s: %w", s.path, err)
+	}
+	t.enter(PhaseFsync, 0)
+	if err := out.commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Close supprime le clair et son répertoire, et efface le secret conservé. Il
+// peut être appelé plusieurs fois.
+func (s *EditSession) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.password.Destroy()
+		s.key.Destroy()
+		if e := os.RemoveAll(s.dir); e != nil {
+			err = errorf("io.remove_path", "suppression de %s: %w", s.dir, e)
+			return
+		}
+		untrackTemp(s.dir)
+	})
+	return err
+}
@@ -0,0 +1,18 @@
+package pkg
+
+import "os"
+
+// memoryDir renvoie un répertoire sur un système de fichiers en mémoire :
+// celui de la session ($XDG_RUNTIME_DIR, un tmpfs propre à l'utilisateur),
+// sinon /dev/shm. Vide si aucun n'existe.
+func memoryDir() string {
+	for _, d := range []string{os.Getenv("XDG_RUNTIME_DIR"), "/dev/shm"} {
+		if d == "" {
+			continue
+		}
+		if st, err := os.Stat(d); err == nil && st.IsDir() {
+			return d
+		}
+	}
+	return ""
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package pkg
+
+// Hors Linux, aucun système de fichiers en mémoire n'est garanti : le clair va
+// dans le répertoire temporaire du système.
+func memoryDir() string { return "" }
@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// enTete relit l'en-tête d'un fichier chiffré.
+func enTete(t *testing.T, p string) *header {
+	t.Helper()
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h, err := readHeader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestEdit(t *testing.T) {
+	pw := []byte("mot de passe")
+	rapide := ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}
+	cases := []struct {
+		name string
+		opts Options
+	}{
+		{"chacha zstd", Options{Algo: AlgoChaCha, Comp: CompZstd, Argon: &rapide}},
+		{"cascade pad métadonnées", Options{Algo: AlgoCascade, Pad: true, Metadata: MetadataMinimal, Argon: &rapide}},
+		{"scrypt", Options{KDFAlgo: KDFScrypt}},
+		{"clé", Options{Key: bytes.Repeat([]byte{7}, SymmetricKeySize)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			in := write(t, dir, "notes.txt", []byte("première version\n"))
+			enc := filepath.Join(dir, "notes.txt.chto")
+			if err := Encrypt(in, enc, pw, c.opts); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(enc, 0o640); err != nil {
+				t.Fatal(err)
+			}
+			avant := enTete(t, enc)
+
+			// OpenEdit garde le secret dans un tampon protégé et efface
+			// celui qu'on lui passe.
+			secret, cle := bytes.Clone(pw), bytes.Clone(c.opts.Key)
+			s, err := OpenEdit(enc, secret, Options{Key: cle})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			if cle != nil {
+				secret = cle
+			}
+			if bytes.ContainsFunc(secret, func(r rune) bool { return r != 0 }) {
+				t.Errorf("secret de l'appelant non effacé : %q", secret)
+			}
+			if filepath.Base(s.Path()) != "notes.txt" {
+				t.Errorf("clair nommé %s", s.Path())
+			}
+			if st, err := os.Stat(s.Path()); err != nil || st.Mode().Perm() != 0o600 {
+				t.Errorf("clair: %v, %v", st, err)
+			}
+			if err := os.WriteFile(s.Path(), []byte("seconde version\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			changed, err := s.Save()
+			if err != nil || !changed {
+				t.Fatalf("Save: %v, %v", changed, err)
+			}
+
+			apres := enTete(t, enc)
+			if apres.Algo != avant.Algo || apres.Comp != avant.Comp || apres.Flags != avant.Flags ||
+				apres.kdfParamsField() != avant.kdfParamsField() || apres.kdfID() != avant.kdfID() {
+				t.Errorf("en-tête réécrit: %+v, avant %+v", apres, avant)
+			}
+			if bytes.Equal(apres.Salt, avant.Salt) {
+				t.Error("sel réutilisé")
+			}
+			if st, err := os.Stat(enc); err != nil || st.Mode().Perm() != 0o640 {
+				t.Errorf("permissions du chiffré: %v, %v", st, err)
+			}
+
+			out := filepath.Join(dir, "relu")
+			if err := Decrypt(enc, out, pw, Options{Key: c.opts.Key}); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(out); string(got) != "seconde version\n" {
+				t.Errorf("contenu relu %q", got)
+			}
+
+			tmp := filepath.Dir(s.Path())
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+				t.Errorf("clair laissé derrière: %v", err)
+			}
+			assertPasDeTemporaire(t, dir)
+		})
+	}
+}
+
+func TestEditSansChangement(t *testing.T) {
+	dir := t.TempDir()
+	pw := []byte("pw")
+	enc := filepath.Join(dir, "a.chto")
+	if err := Encrypt(write(t, dir, "a", []byte("contenu")), enc, pw, Options{Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	avant, _ := os.ReadFile(enc)
+
+	s, err := OpenEdit(enc, pw, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// Réécrire le même contenu, comme le fait un éditeur à la sauvegarde.
+	if err := os.WriteFile(s.Path(), []byte("contenu"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := s.Save(); err != nil || changed {
+		t.Fatalf("Save: %v, %v", changed, err)
+	}
+	if apres, _ := os.ReadFile(enc); !bytes.Equal(apres, avant) {
+		t.Error("fichier réécrit sans changement")
+	}
+}
+
+func TestEditRefus(t *testing.T) {
+	dir := t.TempDir()
+	pw := []byte("pw")
+	rapide := &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}
+
+	dossier := filepath.Join(dir, "d")
+	os.Mkdir(dossier, 0o755)
+	write(t, dossier, "f", []byte("x"))
+	archive := filepath.Join(dir, "d.chto")
+	if err := Encrypt(dossier, archive, pw, Options{Argon: rapide}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenEdit(archive, pw, Options{}); err == nil {
+		t.Error("dossier accepté")
+	}
+
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secours := filepath.Join(dir, "s.chto")
+	if err := Encrypt(write(t, dir, "s", []byte("x")), secours, pw, Options{Argon: rapide, RecoveryCode: code}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenEdit(secours, pw, Options{}); err == nil {
+		t.Error("fichier à code de secours accepté")
+	}
+
+	if _, err := OpenEdit(copieTestdata(t, "v2_chacha.chto"), []byte("reference-v2-password"), Options{}); err == nil {
+		t.Error("ancien format accepté")
+	}
+
+	// Un mauvais mot de passe ne laisse rien derrière lui.
+	enc := filepath.Join(dir, "a.chto")
+	if err := Encrypt(write(t, dir, "a", []byte("x")), enc, pw, Options{Argon: rapide}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenEdit(enc, []byte("faux"), Options{}); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("mauvais mot de passe: %v", err)
+	}
+	pendingMu.Lock()
+	n := len(pending)
+	pendingMu.Unlock()
+	if n != 0 {
+		t.Errorf("%d temporaires encore inscrits", n)
+	}
+}
+
+// TestEditNettoyage : sur un signal, CleanupTemporaries emporte le clair.
+func TestEditNettoyage(t *testing.T) {
+	dir := t.TempDir()
+	pw := []byte("pw")
+	enc := filepath.Join(dir, "a.chto")
+	if err := Encrypt(write(t, dir, "a", []byte("secret")), enc, pw, Options{Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenEdit(enc, pw, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	CleanupTemporaries()
+
+	if _, err := os.Stat(s.Path()); !os.IsNotExist(err) {
+		t.Errorf("clair encore présent: %v", err)
+	}
+}
+
+// TestEditFichierModifieEntreTemps : le chiffré a changé pendant l'édition, il
+// n'est pas écrasé.
+func TestEditFichierModifieEntreTemps(t *testing.T) {
+	dir := t.TempDir()
+	pw := []byte("pw")
+	rapide := Options{Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}}
+	enc := filepath.Join(dir, "a.chto")
+	if err := Encrypt(write(t, dir, "a", []byte("v1")), enc, pw, rapide); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenEdit(enc, bytes.Clone(pw), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := Encrypt(write(t, dir, "b", []byte("v2 d'ailleurs")), enc, pw, rapide); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(s.Path(), []byte("v3"), 0o600)
+	if _, err := s.Save(); err == nil {
+		t.Error("modification concurrente écrasée")
+	}
+}
@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/minio/sio"
+)
+
+// Erreurs reconnaissables.
+//
+// Les messages restent en clair et en contexte ; ces valeurs permettent en
+// plus de les trier avec errors.Is, sans lire le texte. Une erreur peut en
+// envelopper une autre : un flux coupé au milieu d'un paquet est à la fois
+// ErrTruncated et l'erreur de lecture d'origine.
+var (
+	// ErrBadMagic : l'entrée n'est pas un .chto.
+	ErrBadMagic = errorf("format.bad_magic", "format inconnu : ce fichier n'a pas été produit par chiffremento")
+
+	// ErrUnsupportedVersion : un .chto d'une version que ce binaire ne lit pas.
+	ErrUnsupportedVersion = errorf("format.unsupported_version", "version de format non supportée")
+
+	// ErrTruncated : le fichier s'arrête avant sa fin annoncée, dans l'en-tête
+	// comme dans la charge utile.
+	ErrTruncated = errorf("format.truncated", "fichier tronqué")
+
+	// ErrAuthentication : le contenu ne s'authentifie pas. Un chiffrement
+	// authentifié ne distingue pas un mauvais secret d'un fichier modifié, sauf
+	// quand le format le permet (emplacement du code de secours, engagement
+	// de clé d'AEGIS-256) : le message le précise alors.
+	ErrAuthentication = errorf("auth.failed", "échec d'authentification")
+
+	// ErrUnsafeArchivePath : une archive désigne un chemin hors de la
+	// destination, ou trop profond.
+	ErrUnsafeArchivePath = errorf("archive.unsafe_path", "chemin refusé dans l'archive")
+
+	// ErrArchiveRefused : une archive contient ce que l'extraction refuse
+	// (type d'entrée non supporté, trop d'entrées).
+	ErrArchiveRefused = errorf("archive.refused", "archive refusée")
+)
+
+// Error est une erreur de la bibliothèque. Code l'identifie de façon stable :
+// une interface la traduit d'après lui, sans dépendre du texte, qui est en
+// français par défaut. Args sont les valeurs qui complètent le message, dans
+// l'ordre de ses verbes ; une erreur passée par %w reste accessible à
+// errors.Is et errors.As.
+type Error struct {
+	Code   string
+	Args   []any
+	format string
+}
+
+func errorf(code, format string, args ...any) error {
+	return &Error{Code: code, Args: args, format: format}
+}
+
+func (e *Error) Error() string {
+	format := e.format
+	if t := translator.Load(); t != nil {
+		if f, ok := (*t)(e.Code); ok {
+			format = f
+		}
+	}
+	return fmt.Errorf(format, e.Args...).Error()
+}
+
+// Unwrap rend les erreurs enveloppées par %w dans le message d'origine.
+func (e *Error) Unwrap() []error {
+	switch w := fmt.Errorf(e.format, e.Args...).(type) {
+	case interface{ Unwrap() error }:
+		return []error{w.Unwrap()}
+	case interface{ Unwrap() []error }:
+		return w.Unwrap()
+	}
+	return nil
+}
+
+var translator atomic.Pointer[func(code string) (string, bool)]
+
+// SetTranslator installe la traduction des messages : t rend, pour un code, le
+// format traduit, avec les mêmes verbes que l'original dans le même ordre.
+// Un code que t ne connaît pas garde son message français. nil rétablit le
+// français partout.
+func SetTranslator(t func(code string) (format string, ok bool)) {
+	if t == nil {
+		translator.Store(nil)
+		return
+	}
+	translator.Store(&t)
+}
+
+var errTruncatedHeader = errorf("truncated.header", "%w : header incomplet", ErrTruncated)
+
+// authReader range les erreurs de minio/sio parmi les nôtres. sio n'exporte
+// que leur type, pas leurs valeurs, et un paquet coupé au milieu y devient une
+// « taille de paquet invalide » : c'est donc in, l'entrée chiffrée, qui dit
+// si le flux s'est arrêté en route. Une étiquette fausse est toujours un échec
+// d'authentification — le dernier paquet, même intact, atteint la fin de
+// l'entrée ; toute autre erreur de sio après cette fin est une troncature.
+type authReader struct {
+	r  io.Reader
+	in *eofReader
+}
+
+const sioTagMismatch = "sio: authentication failed"
+
+func (a authReader) Read(b []byte) (int, error) {
+	n, err := a.r.Read(b)
+	var se sio.Error
+	if err != nil && errors.As(err, &se) {
+		if a.in.eof && se.Error() != sioTagMismatch {
+			return n, errorf("truncated.last_packet", "%w : le dernier paquet manque (%w)", ErrTruncated, err)
+		}
+		return n, errorf("auth.wrong_secret", "%w : mauvais mot de passe, mauvaise clé ou fichier modifié (%w)", ErrAuthentication, err)
+	}
+	return n, err
+}
+
+// eofReader retient que r a atteint sa fin.
+type eofReader struct {
+	r   io.Reader
+	eof bool
+}
+
+func (e *eofReader) Read(b []byte) (int, error) {
+	n, err := e.r.Read(b)
+	if err == io.EOF {
+		e.eof = true
+	}
+	return n, err
+}
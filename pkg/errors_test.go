@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestErreursReconnaissables : chaque famille d'échec se reconnaît par
+// errors.Is, quel que soit l'algorithme qui l'a produite.
+func TestErreursReconnaissables(t *testing.T) {
+	rapide := &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}
+	pw := []byte("motdepassetest123")
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", bytes.Repeat([]byte("a"), 200000))
+
+	for _, algo := range []byte{AlgoAES, AlgoChaCha, AlgoCascade, AlgoAEGIS} {
+		t.Run(AlgoName(algo), func(t *testing.T) {
+			enc := filepath.Join(t.TempDir(), "ref.chto")
+			if err := Encrypt(in, enc, pw, Options{Algo: algo, Argon: rapide}); err != nil {
+				t.Fatal(err)
+			}
+			raw := mustRead(t, enc)
+
+			cases := map[string]struct {
+				data []byte
+				pw   []byte
+				want error
+			}{
+				"mauvais mot de passe": {raw, []byte("faux"), ErrAuthentication},
+				"octet modifié":        {flipByte(raw, len(raw)-20), pw, ErrAuthentication},
+				"tronqué au milieu":    {raw[:len(raw)/2], pw, ErrTruncated},
+				"tronqué à la fin":     {raw[:len(raw)-1], pw, ErrTruncated},
+				"en-tête incomplet":    {raw[:10], pw, ErrTruncated},
+				"pas un .chto":         {bytes.Repeat([]byte("X"), 64), pw, ErrBadMagic},
+				"version future":       {flipVersion(raw), pw, ErrUnsupportedVersion},
+			}
+			for name, c := range cases {
+				err := VerifyStream(bytes.NewReader(c.data), c.pw, Options{})
+				if !errors.Is(err, c.want) {
+					t.Errorf("%s : %v, attendu %v", name, err, c.want)
+				}
+			}
+		})
+	}
+
+	t.Run("code de secours", func(t *testing.T) {
+		code, err := GenerateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc := filepath.Join(t.TempDir(), "secours.chto")
+		if err := Encrypt(in, enc, pw, Options{RecoveryCode: code, Argon: rapide}); err != nil {
+			t.Fatal(err)
+		}
+		if err := Verify(enc, []byte("faux"), Options{}); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("mauvais mot de passe : %v", err)
+		}
+	})
+}
+
+func TestErreursArchive(t *testing.T) {
+	for name, c := range map[string]struct {
+		hdr  tar.Header
+		want error
+	}{
+		"évasion":      {tar.Header{Name: "../dehors", Typeflag: tar.TypeReg, Mode: 0644}, ErrUnsafeArchivePath},
+		"absolu":       {tar.Header{Name: "/etc/passwd", Typeflag: tar.TypeReg, Mode: 0644}, ErrUnsafeArchivePath},
+		"lien":         {tar.Header{Name: "lien", Typeflag: tar.TypeSymlink, Linkname: "/etc"}, ErrArchiveRefused},
+		"périphérique": {tar.Header{Name: "dev", Typeflag: tar.TypeChar}, ErrArchiveRefused},
+	} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		if err := tw.WriteHeader(&c.hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Close()
+		if err := checkArchive(context.Background(), &buf, nil); !errors.Is(err, c.want) {
+			t.Errorf("%s : %v, attendu %v", name, err, c.want)
+		}
+	}
+}
+
+func flipByte(raw []byte, i int) []byte {
+	b := bytes.Clone(raw)
+	b[i] ^= 1
+	return b
+}
+
+func flipVersion(raw []byte) []byte {
+	b := bytes.Clone(raw)
+	b[magicSize] = currentVersion + 1
+	return b
+}
+
+// TestErreursTraduites : le code d'une erreur survit à la traduction, et la
+// traduction ne coupe pas la chaîne des erreurs enveloppées.
+func TestErreursTraduites(t *testing.T) {
+	err := VerifyStream(bytes.NewReader([]byte("CHTO")), []byte("pw"), Options{})
+	var e *Error
+	if !errors.As(err, &e) || e.Code != "truncated.header" {
+		t.Fatalf("code absent : %#v", err)
+	}
+	francais := e.Error()
+
+	SetTranslator(func(code string) (string, bool) {
+		if code == "truncated.header" {
+			return "%w: incomplete header", true
+		}
+		return "", false
+	})
+	t.Cleanup(func() { SetTranslator(nil) })
+	if got := e.Error(); got != "fichier tronqué: incomplete header" {
+		t.Errorf("traduit : %q", got)
+	}
+	if !errors.Is(err, ErrTruncated) {
+		t.Error("ErrTruncated perdu")
+	}
+	SetTranslator(nil)
+	if e.Error() != francais {
+		t.Errorf("français non rétabli : %q", e.Error())
+	}
+}
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -294,12 +295,30 @@ func TestBenchmark(t *testing.T) {
 	if len(rep.KDF) != len(AllKDFProfiles()) {
 		t.Fatalf("%d profils mesurés, attendu %d", len(rep.KDF), len(AllKDFProfiles()))
 	}
+	if len(rep.KDFAlt) != 2*len(AllKDFProfiles()) {
+		t.Errorf("%d mesures scrypt et PBKDF2, attendu %d", len(rep.KDFAlt), 2*len(AllKDFProfiles()))
+	}
+	for _, m := range rep.KDFAlt {
+		if m.KDF == KDFArgon2id || m.Duration <= 0 {
+			t.Errorf("mesure alternative inattendue : %+v", m)
+		}
+	}
 	for _, m := range rep.KDF {
 		if m.Duration <= 0 {
 			t.Errorf("profil %q : durée %v", m.Profile, m.Duration)
 		}
 		t.Logf("%-10s %-24s %5d Mio  %v", m.Profile, m.Label, m.MemoryMiB, m.Duration.Round(time.Millisecond))
 	}
+	// Le JSON nomme KDF et suites par les identifiants de Details.
+	j, err := json.Marshal(rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, attendu := range []string{`"kdf":"argon2id"`, `"kdf":"pbkdf2-sha256"`, `"algo":"cascade"`, `"algo":"aegis-256"`} {
+		if !bytes.Contains(j, []byte(attendu)) {
+			t.Errorf("rapport JSON sans %s", attendu)
+		}
+	}
 
 	// La recommandation doit être un profil réel, et jamais le plus lourd quand
 	// aucun ne tient sous le plafond — c'était le bug de la première version.
@@ -331,8 +350,10 @@ func TestBenchmark(t *testing.T) {
 	}
 	t.Logf("conseillé : %s", rep.Advisory)
 
-	if len(rep.AEAD) != 3 {
-		t.Fatalf("%d algorithmes mesurés, attendu 3", len(rep.AEAD))
+	// Toutes les suites enregistrées sont mesurées, y compris celles qu'un
+	// autre test du paquet a pu ajouter.
+	if len(rep.AEAD) != len(Suites()) {
+		t.Fatalf("%d algorithmes mesurés, attendu %d", len(rep.AEAD), len(Suites()))
 	}
 	for _, m := range rep.AEAD {
 		if m.Err != nil {
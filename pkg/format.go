@@ -1,7 +1,6 @@
 package pkg
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -10,17 +9,22 @@ import (
 // Format de fichier .chto
 //
 //	magic       8   "CHFRMT03"
-//	version     1   1 et 2 (anciens), 3 (courant)
-//	flags       1   bit0 = compressé (v1/v2), bit1 = archive tar, bit2 = rempli
-//	algoID      1   1=AES-GCM, 2=ChaCha20-Poly1305, 3=Cascade
-//	--- v2 et v3 --------------------------------------------
-//	argonTime   4   uint32 big-endian
-//	argonMemory 4   uint32 big-endian, en KiB
-//	argonPar    1   uint8
-//	--- v3 uniquement ---------------------------------------
+//	version     1   1, 2 et 3 (anciens), 4 (courant)
+//	flags       1   bit0 = compressé (v1/v2), bit1 = archive tar, bit2 = rempli,
+//	                bit3 = métadonnées, bit4 = code de secours
+//	algoID      1   1=AES-GCM, 2=ChaCha20-Poly1305, 3=Cascade, 4=AEGIS-256, 0x80+ = privé
+//	--- v4 --------------------------------------------------
+//	kdfID       1   1=argon2id, 2=scrypt, 3=pbkdf2-sha256 (voir kdfalgo.go)
+//	--- v2 et suivantes -------------------------------------
+//	argonTime   4   uint32 big-endian     ┐ paramètres Argon2id, ou ceux
+//	argonMemory 4   uint32 big-endian, KiB├ de la KDF annoncée par kdfID
+//	argonPar    1   uint8                 ┘
+//	--- v3 et suivantes -------------------------------------
 //	compAlgo    1   0=aucune, 1=gzip (lecture seule), 2=zstd
 //	---------------------------------------------------------
 //	salt       16
+//	--- v4, si bit4 ----------------------------------------
+//	recovery   96   clé de fichier scellée deux fois (voir recovery.go)
 //
 // Le magic est resté identique d'une version à l'autre : c'est l'octet de
 // version qui aiguille la lecture. Changer le magic aurait fait échouer les
@@ -30,6 +34,10 @@ import (
 // ne pouvait pas distinguer gzip de zstd, et empiler un bit par algorithme
 // rendait possibles des états contradictoires. En v1 et v2, bit0 signifiait
 // gzip — c'est le seul sens qu'il ait jamais eu, donc la relecture est directe.
+//
+// La v4 ajoute l'identifiant de KDF. Les neuf octets de paramètres gardent
+// leur place et leur taille ; c'est kdfID qui dit comment les lire. Un fichier
+// v3 n'a rien à gagner à être réécrit : il est en Argon2id, comme le défaut.
 const (
 	magicNumber = "CHFRMT03"
 	magicSize   = len(magicNumber)
@@ -37,6 +45,7 @@ const (
 	versionSize  = 1
 	flagsSize    = 1
 	algoIDSize   = 1
+	kdfIDSize    = 1
 	compAlgoSize = 1
 	saltSize     = 16
 
@@ -45,13 +54,19 @@ const (
 	headerSizeV1 = magicSize + versionSize + flagsSize + algoIDSize + saltSize // 27
 	headerSizeV2 = headerSizeV1 + argonParamsSize                              // 36
 	headerSizeV3 = headerSizeV2 + compAlgoSize                                 // 37
+	headerSizeV4 = headerSizeV3 + kdfIDSize                                    // 38
 
 	versionV1      = byte(1)
 	versionV2      = byte(2)
 	versionV3      = byte(3)
-	currentVersion = versionV3
+	versionV4      = byte(4)
+	currentVersion = versionV4
 )
 
+// FormatVersion est la version de format écrite par Encrypt et visée par
+// Upgrade. L'interface l'affiche plutôt que de la recopier.
+const FormatVersion = currentVersion
+
 // Drapeaux du header. Tout bit non listé dans knownFlags est refusé à la
 // lecture : ça garde la place libre pour de futures options sans qu'un vieux
 // binaire n'interprète un fichier récent de travers.
@@ -69,7 +84,10 @@ const (
 	// FlagMetadata : un bloc de métadonnées (nom d'origine, date) précède le
 	// contenu, après le remplissage éventuel. Voir metadata.go.
 	FlagMetadata = byte(1 << 3)
-	knownFlags   = FlagCompressed | FlagArchive | FlagPadded | FlagMetadata
+	// FlagRecovery : le fichier s'ouvre aussi avec un code de secours. Le bloc
+	// qui le permet suit l'en-tête ; voir recovery.go.
+	FlagRecovery = byte(1 << 4)
+	knownFlags   = FlagCompressed | FlagArchive | FlagPadded | FlagMetadata | FlagRecovery
 )
 
 // Algorithmes de compression, tels qu'inscrits dans le champ compAlgo de la v3.
@@ -99,13 +117,27 @@ func CompName(c byte) string {
 	}
 }
 
+// compIdent rend l'identifiant stable d'une compression, pour le JSON.
+func compIdent(c byte) string {
+	switch c {
+	case CompNone:
+		return "none"
+	case CompGzip:
+		return "gzip"
+	case CompZstd:
+		return "zstd"
+	default:
+		return "unknown"
+	}
+}
+
 // validateComp accepte tout ce qui est lisible, gzip compris.
 func validateComp(c byte) error {
 	switch c {
 	case CompNone, CompGzip, CompZstd:
 		return nil
 	default:
-		return fmt.Errorf("algorithme de compression inconnu : %d", c)
+		return errorf("comp.unknown", "algorithme de compression inconnu : %d", c)
 	}
 }
 
@@ -115,17 +147,19 @@ func validateCompWrite(c byte) error {
 	case CompNone, CompZstd:
 		return nil
 	case CompGzip:
-		return errors.New("gzip n'est plus produit : zstd le remplace, environ huit fois plus rapide à taille comparable (les anciens fichiers gzip restent déchiffrables)")
+		return errorf("comp.gzip_write", "gzip n'est plus produit : zstd le remplace, environ huit fois plus rapide à taille comparable (les anciens fichiers gzip restent déchiffrables)")
 	default:
-		return fmt.Errorf("algorithme de compression inconnu : %d", c)
+		return errorf("comp.unknown", "algorithme de compression inconnu : %d", c)
 	}
 }
 
-// Identifiants d'algorithme.
+// Identifiants des suites fournies par le paquet. Les suites privées d'une
+// application commencent à AlgoPrivateMin (voir suite.go).
 const (
 	AlgoAES     = byte(1)
 	AlgoChaCha  = byte(2)
 	AlgoCascade = byte(3)
+	AlgoAEGIS   = byte(4)
 )
 
 // Paramètres Argon2id des nouveaux fichiers. ~144 ms par dérivation sur une
@@ -154,11 +188,6 @@ const (
 	maxArgonThreads = uint8(16)
 )
 
-var (
-	errBadMagic  = errors.New("format inconnu : ce fichier n'a pas été produit par chiffremento")
-	errTruncated = errors.New("fichier tronqué : header incomplet")
-)
-
 type argonParams struct {
 	Time    uint32
 	Memory  uint32 // KiB
@@ -178,11 +207,11 @@ func legacyArgonParams() argonParams {
 func (p argonParams) validate() error {
 	switch {
 	case p.Time == 0 || p.Time > maxArgonTime:
-		return fmt.Errorf("paramètre Argon2 hors bornes : time=%d (attendu 1..%d)", p.Time, maxArgonTime)
+		return errorf("argon.time", "paramètre Argon2 hors bornes : time=%d (attendu 1..%d)", p.Time, maxArgonTime)
 	case p.Memory == 0 || p.Memory > maxArgonMemory:
-		return fmt.Errorf("paramètre Argon2 hors bornes : memory=%d KiB (attendu 1..%d)", p.Memory, maxArgonMemory)
+		return errorf("argon.memory", "paramètre Argon2 hors bornes : memory=%d KiB (attendu 1..%d)", p.Memory, maxArgonMemory)
 	case p.Threads == 0 || p.Threads > maxArgonThreads:
-		return fmt.Errorf("paramètre Argon2 hors bornes : parallelism=%d (attendu 1..%d)", p.Threads, maxArgonThreads)
+		return errorf("argon.parallelism", "paramètre Argon2 hors bornes : parallelism=%d (attendu 1..%d)", p.Threads, maxArgonThreads)
 	}
 	return nil
 }
@@ -202,7 +231,13 @@ type header struct {
 	Version byte
 	Flags   byte
 	Algo    byte
-	Argon   argonParams
+	// KDF est lu dans l'en-tête à partir de la v4, et vaut KDFArgon2id avant.
+	// Seuls les paramètres correspondants parmi Argon, Scrypt et PBKDF2 sont
+	// renseignés.
+	KDF    byte
+	Argon  argonParams
+	Scrypt scryptParams
+	PBKDF2 pbkdf2Params
 	// Comp est l'algorithme de compression. En v1 et v2 il est déduit de
 	// FlagCompressed, en v3 il est lu dans le champ compAlgo.
 	Comp byte
@@ -212,50 +247,66 @@ type header struct {
 	// de l'intérieur du chiffrement, donc après authentification — contrairement
 	// au reste de cette structure, qui est lisible sans mot de passe.
 	Meta *FileMetadata
+	// Recovery porte la clé de fichier scellée quand FlagRecovery est posé.
+	Recovery *recoverySlots
 }
 
 func (h *header) compressed() bool  { return h.Comp != CompNone }
 func (h *header) archive() bool     { return h.Flags&FlagArchive != 0 }
 func (h *header) padded() bool      { return h.Flags&FlagPadded != 0 }
 func (h *header) hasMetadata() bool { return h.Flags&FlagMetadata != 0 }
+func (h *header) hasRecovery() bool { return h.Flags&FlagRecovery != 0 }
 
 // AlgoName rend un identifiant d'algorithme lisible pour l'interface.
 func AlgoName(algo byte) string {
+	if s, ok := lookupSuite(algo); ok {
+		return s.Name()
+	}
+	return "inconnu"
+}
+
+// algoIdent rend l'identifiant stable d'un algorithme, pour le JSON : le nom
+// des suites du paquet, sauf la cascade, dont le nom est un libellé.
+func algoIdent(algo byte) string {
 	switch algo {
-	case AlgoAES:
-		return "aes-256-gcm"
-	case AlgoChaCha:
-		return "chacha20-poly1305"
 	case AlgoCascade:
-		return "cascade chacha20 + aes-256-gcm"
-	default:
-		return "inconnu"
+		return "cascade"
+	case AlgoAES, AlgoChaCha, AlgoAEGIS:
+		return AlgoName(algo)
+	}
+	if _, ok := lookupSuite(algo); ok && algo >= AlgoPrivateMin {
+		return fmt.Sprintf("private-%d", algo)
 	}
+	return "unknown"
 }
 
 func validateAlgo(algo byte) error {
-	switch algo {
-	case AlgoAES, AlgoChaCha, AlgoCascade:
-		return nil
-	default:
-		return fmt.Errorf("algorithme inconnu dans le header : %d", algo)
+	if _, ok := lookupSuite(algo); !ok {
+		return errorf("header.unknown_algo", "algorithme inconnu dans le header : %d", algo)
 	}
+	return nil
 }
 
 // marshal sérialise l'en-tête et mémorise le résultat dans h.Raw.
 func (h *header) marshal() []byte {
-	buf := make([]byte, 0, headerSizeV3)
+	buf := make([]byte, 0, headerSizeV4+recoveryBlockSize)
 	buf = append(buf, magicNumber...)
 	buf = append(buf, h.Version, h.Flags, h.Algo)
+	if h.Version >= versionV4 {
+		buf = append(buf, h.KDF)
+	}
 	if h.Version >= versionV2 {
-		buf = binary.BigEndian.AppendUint32(buf, h.Argon.Time)
-		buf = binary.BigEndian.AppendUint32(buf, h.Argon.Memory)
-		buf = append(buf, h.Argon.Threads)
+		params := h.kdfParamsField()
+		buf = append(buf, params[:]...)
 	}
 	if h.Version >= versionV3 {
 		buf = append(buf, h.Comp)
 	}
 	buf = append(buf, h.Salt...)
+	if h.Recovery != nil {
+		buf = append(buf, h.Recovery.ByPassword...)
+		buf = append(buf, h.Recovery.ByCode...)
+	}
 	h.Raw = buf
 	return buf
 }
@@ -273,7 +324,7 @@ func readHeader(r io.Reader) (*header, error) {
 	}
 
 	if string(prefix[:magicSize]) != magicNumber {
-		return nil, errBadMagic
+		return nil, ErrBadMagic
 	}
 
 	h := &header{
@@ -290,9 +341,11 @@ func readHeader(r io.Reader) (*header, error) {
 		remaining = argonParamsSize + saltSize
 	case versionV3:
 		remaining = argonParamsSize + compAlgoSize + saltSize
+	case versionV4:
+		remaining = kdfIDSize + argonParamsSize + compAlgoSize + saltSize
 	default:
-		return nil, fmt.Errorf("version de format non supportée : %d (ce binaire lit les versions %d à %d)",
-			h.Version, versionV1, versionV3)
+		return nil, errorf("format.version_range", "%w : %d (ce binaire lit les versions %d à %d)",
+			ErrUnsupportedVersion, h.Version, versionV1, currentVersion)
 	}
 
 	rest := make([]byte, remaining)
@@ -300,25 +353,39 @@ func readHeader(r io.Reader) (*header, error) {
 		return nil, err
 	}
 
+	// Avant la v4, pas d'identifiant : la KDF est Argon2id.
+	fields := rest
+	h.KDF = KDFArgon2id
+	if h.Version >= versionV4 {
+		h.KDF = fields[0]
+		fields = fields[kdfIDSize:]
+	}
 	if h.Version == versionV1 {
 		h.Argon = legacyArgonParams()
-	} else {
-		h.Argon = argonParams{
-			Time:    binary.BigEndian.Uint32(rest[0:4]),
-			Memory:  binary.BigEndian.Uint32(rest[4:8]),
-			Threads: rest[8],
-		}
+	} else if err := h.parseKDFParams(fields[:argonParamsSize]); err != nil {
+		return nil, err
 	}
 
 	// Avant la v3, la compression était un unique bit et signifiait gzip.
 	if h.Version >= versionV3 {
-		h.Comp = rest[argonParamsSize]
+		h.Comp = fields[argonParamsSize]
 	} else if h.Flags&FlagCompressed != 0 {
 		h.Comp = CompGzip
 	}
 	h.Salt = rest[remaining-saltSize:]
 	h.Raw = append(prefix, rest...)
 
+	// Le bloc du code de secours n'est lu qu'en v4 : sur une version antérieure,
+	// finalize refusera le drapeau.
+	if h.Version >= versionV4 && h.hasRecovery() {
+		block := make([]byte, recoveryBlockSize)
+		if err := readFull(r, block); err != nil {
+			return nil, err
+		}
+		h.Recovery = &recoverySlots{ByPassword: block[:wrappedKeySize], ByCode: block[wrappedKeySize:]}
+		h.Raw = append(h.Raw, block...)
+	}
+
 	if err := h.finalize(); err != nil {
 		return nil, err
 	}
@@ -328,9 +395,9 @@ func readHeader(r io.Reader) (*header, error) {
 func readFull(r io.Reader, buf []byte) error {
 	if _, err := io.ReadFull(r, buf); err != nil {
 		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
-			return errTruncated
+			return errTruncatedHeader
 		}
-		return fmt.Errorf("lecture du header: %w", err)
+		return errorf("header.read", "lecture du header: %w", err)
 	}
 	return nil
 }
@@ -341,7 +408,7 @@ func (h *header) finalize() error {
 		return err
 	}
 	if h.Flags&^knownFlags != 0 {
-		return fmt.Errorf("drapeaux inconnus dans le header : 0x%02x", h.Flags&^knownFlags)
+		return errorf("header.unknown_flags", "drapeaux inconnus dans le header : 0x%02x", h.Flags&^knownFlags)
 	}
 	if err := validateComp(h.Comp); err != nil {
 		return err
@@ -350,13 +417,18 @@ func (h *header) finalize() error {
 	// les deux est contradictoire : soit il a été bricolé, soit il vient d'un
 	// producteur bogué. Dans les deux cas on refuse plutôt que de choisir.
 	if h.Version >= versionV3 && h.Flags&FlagCompressed != 0 {
-		return errors.New("header incohérent : drapeau de compression v1/v2 sur un fichier v3")
+		return errorf("header.legacy_comp_flag", "header incohérent : drapeau de compression v1/v2 sur un fichier v3")
 	}
 	// Le remplissage n'existe pas avant la v3 : un vieux fichier qui l'annonce
 	// serait relu de travers, ses premiers octets pris pour un en-tête de
 	// remplissage.
 	if h.Version < versionV3 && h.padded() {
-		return fmt.Errorf("header incohérent : drapeau de remplissage sur un fichier v%d", h.Version)
+		return errorf("header.pad_flag", "header incohérent : drapeau de remplissage sur un fichier v%d", h.Version)
+	}
+	// Le code de secours double un mot de passe ; une clé symétrique se
+	// sauvegarde telle quelle, sans second chemin.
+	if h.hasRecovery() && (h.Version < versionV4 || h.KDF == KDFKey) {
+		return errorf("header.recovery_flag", "header incohérent : code de secours sur un fichier qui ne peut pas en porter")
 	}
-	return h.Argon.validate()
+	return h.validateKDF()
 }
@@ -33,6 +33,19 @@ func FuzzReadHeader(f *testing.F) {
 	// Un en-tête v3 valide, construit ici pour ne pas dépendre d'un fichier.
 	h := &header{Version: versionV3, Algo: AlgoAES, Argon: defaultArgonParams(), Comp: CompZstd, Salt: make([]byte, saltSize)}
 	f.Add(h.marshal())
+	// Et un v4 par KDF : les neuf octets de paramètres y changent de sens.
+	for _, kdf := range []byte{KDFArgon2id, KDFScrypt, KDFPBKDF2} {
+		h := &header{Version: versionV4, Algo: AlgoAES, Comp: CompZstd, Salt: make([]byte, saltSize)}
+		KDFStandard.applyTo(h, kdf)
+		f.Add(h.marshal())
+	}
+	f.Add((&header{Version: versionV4, Algo: AlgoAES, KDF: KDFKey, Salt: make([]byte, saltSize)}).marshal())
+	// Un code de secours allonge l'en-tête de son bloc.
+	rh := &header{Version: versionV4, Algo: AlgoAES, Salt: make([]byte, saltSize)}
+	KDFStandard.applyTo(rh, KDFArgon2id)
+	rh.Flags = FlagRecovery
+	rh.Recovery = &recoverySlots{ByPassword: make([]byte, wrappedKeySize), ByCode: make([]byte, wrappedKeySize)}
+	f.Add(rh.marshal())
 	f.Add([]byte(magicNumber))
 	f.Add([]byte{})
 
@@ -44,7 +57,7 @@ func FuzzReadHeader(f *testing.F) {
 
 		// Tout en-tête accepté doit être intégralement cohérent : c'est ce que
 		// le reste du code suppose sans jamais le revérifier.
-		if h.Version != versionV1 && h.Version != versionV2 && h.Version != versionV3 {
+		if h.Version < versionV1 || h.Version > versionV4 {
 			t.Fatalf("version acceptée hors des versions connues : %d", h.Version)
 		}
 		if err := validateAlgo(h.Algo); err != nil {
@@ -53,8 +66,8 @@ func FuzzReadHeader(f *testing.F) {
 		if err := validateComp(h.Comp); err != nil {
 			t.Fatalf("compression acceptée alors qu'elle est invalide : %v", err)
 		}
-		if err := h.Argon.validate(); err != nil {
-			t.Fatalf("paramètres Argon2 acceptés hors bornes : %v", err)
+		if err := h.validateKDF(); err != nil {
+			t.Fatalf("paramètres de KDF acceptés hors bornes : %v", err)
 		}
 		if h.Flags&^knownFlags != 0 {
 			t.Fatalf("drapeau inconnu accepté : 0x%02x", h.Flags)
@@ -62,6 +75,9 @@ func FuzzReadHeader(f *testing.F) {
 		if h.padded() && h.Version < versionV3 {
 			t.Fatal("remplissage accepté sur un format qui ne le connaît pas")
 		}
+		if h.hasRecovery() != (h.Recovery != nil) || (h.Recovery != nil && (h.Version < versionV4 || h.KDF == KDFKey)) {
+			t.Fatal("bloc de code de secours incohérent avec l'en-tête")
+		}
 		if len(h.Salt) != saltSize {
 			t.Fatalf("sel de %d octets accepté, attendu %d", len(h.Salt), saltSize)
 		}
@@ -1,7 +1,5 @@
 package pkg
 
-import "fmt"
-
 // Profils de dérivation de clé.
 //
 // Les paramètres Argon2 sont inscrits dans l'en-tête depuis le format v2, donc
@@ -43,6 +41,115 @@ func (p KDFProfile) argonParams() argonParams {
 	}
 }
 
+// scryptParams : les mêmes trois crans pour scrypt, à r=8 et p=1 comme le
+// recommande son auteur. Ils commencent bien plus bas qu'Argon2id — c'est la
+// raison d'être de scrypt ici : tenir sur un appareil contraint.
+func (p KDFProfile) scryptParams() scryptParams {
+	switch p {
+	case KDFFort:
+		return scryptParams{LogN: 17, R: 8, P: 1} // 128 Mio
+	case KDFMaximum:
+		return scryptParams{LogN: 19, R: 8, P: 1} // 512 Mio
+	default:
+		return scryptParams{LogN: 15, R: 8, P: 1} // 32 Mio
+	}
+}
+
+// pbkdf2Params : le défaut suit la recommandation OWASP pour PBKDF2-HMAC-SHA256
+// (600 000 itérations), les profils suivants la doublent puis la quadruplent.
+// Sans mémoire, c'est le seul levier.
+func (p KDFProfile) pbkdf2Params() pbkdf2Params {
+	switch p {
+	case KDFFort:
+		return pbkdf2Params{Iterations: 1_200_000}
+	case KDFMaximum:
+		return pbkdf2Params{Iterations: 2_400_000}
+	default:
+		return pbkdf2Params{Iterations: 600_000}
+	}
+}
+
+// applyTo inscrit dans l'en-tête la KDF choisie et les paramètres du profil.
+func (p KDFProfile) applyTo(h *header, kdf byte) {
+	h.KDF = kdf
+	switch kdf {
+	case KDFScrypt:
+		h.Scrypt = p.scryptParams()
+	case KDFPBKDF2:
+		h.PBKDF2 = p.pbkdf2Params()
+	default:
+		h.KDF = KDFArgon2id
+		h.Argon = p.argonParams()
+	}
+}
+
+// ArgonParams fixe les paramètres Argon2id hors des profils. La mémoire est en
+// KiB, comme dans l'en-tête.
+type ArgonParams struct {
+	Time      uint32
+	MemoryKiB uint32
+	Threads   uint8
+}
+
+func (p ArgonParams) internal() argonParams {
+	return argonParams{Time: p.Time, Memory: p.MemoryKiB, Threads: p.Threads}
+}
+
+// Validate applique les bornes de lecture : des paramètres qui les dépassent
+// produiraient un fichier que son propre déchiffrement refuserait.
+func (p ArgonParams) Validate() error { return p.internal().validate() }
+
+// String décrit les paramètres comme KDFLabel décrit un profil.
+func (p ArgonParams) String() string { return "argon2id  " + p.internal().String() }
+
+// ArgonParams renvoie les paramètres Argon2id du profil, point de départ d'un
+// réglage à la main.
+func (p KDFProfile) ArgonParams() ArgonParams {
+	a := p.argonParams()
+	return ArgonParams{Time: a.Time, MemoryKiB: a.Memory, Threads: a.Threads}
+}
+
+// applyKDF inscrit dans l'en-tête la dérivation demandée par les options :
+// le profil appliqué à la KDF choisie, des paramètres Argon2id explicites, ou
+// ceux d'un en-tête existant (kdfFrom).
+func (o Options) applyKDF(h *header) error {
+	if o.kdfFrom != nil {
+		h.KDF = o.kdfFrom.kdfID()
+		h.Argon, h.Scrypt, h.PBKDF2 = o.kdfFrom.Argon, o.kdfFrom.Scrypt, o.kdfFrom.PBKDF2
+		return nil
+	}
+	if o.Key != nil {
+		if o.KDF != "" || o.KDFAlgo != 0 || o.Argon != nil {
+			return errorf("kdf.with_key", "une clé symétrique remplace la dérivation : profil, KDF et paramètres Argon2id sont sans objet")
+		}
+		h.KDF = KDFKey
+		return nil
+	}
+	profile, err := ParseKDFProfile(string(o.KDF))
+	if err != nil {
+		return err
+	}
+	kdf := o.KDFAlgo
+	if kdf == 0 {
+		kdf = KDFArgon2id
+	}
+	if KDFAlgoName(kdf) == "inconnue" {
+		return errorf("kdf.unknown_id", "KDF inconnue : %d", kdf)
+	}
+	profile.applyTo(h, kdf)
+	if o.Argon == nil {
+		return nil
+	}
+	if kdf != KDFArgon2id {
+		return errorf("kdf.argon_params", "des paramètres Argon2id ne s'appliquent pas à %s", KDFAlgoName(kdf))
+	}
+	if err := o.Argon.Validate(); err != nil {
+		return err
+	}
+	h.Argon = o.Argon.internal()
+	return nil
+}
+
 // AllKDFProfiles liste les profils dans l'ordre croissant de coût.
 func AllKDFProfiles() []KDFProfile {
 	return []KDFProfile{KDFStandard, KDFFort, KDFMaximum}
@@ -57,15 +164,28 @@ func ParseKDFProfile(s string) (KDFProfile, error) {
 	case KDFMaximum:
 		return KDFMaximum, nil
 	default:
-		return "", fmt.Errorf("profil KDF inconnu %q (attendu standard, fort ou maximum)", s)
+		return "", errorf("kdf.unknown_profile", "profil KDF inconnu %q (attendu standard, fort ou maximum)", s)
 	}
 }
 
-// KDFLabel décrit un profil pour l'interface, paramètres réels compris.
-func (p KDFProfile) KDFLabel() string {
-	return "argon2id  " + p.argonParams().String()
+// KDFLabel décrit un profil Argon2id pour l'interface, paramètres réels
+// compris.
+func (p KDFProfile) KDFLabel() string { return p.KDFLabelFor(KDFArgon2id) }
+
+// KDFLabelFor décrit un profil appliqué à une KDF donnée.
+func (p KDFProfile) KDFLabelFor(kdf byte) string {
+	var h header
+	p.applyTo(&h, kdf)
+	return h.kdfLabel()
 }
 
-// MemoryMiB est la mémoire exigée par le profil, en Mio — à afficher pour que
-// l'utilisateur sache ce qu'il faudra pour déchiffrer.
-func (p KDFProfile) MemoryMiB() uint32 { return p.argonParams().Memory / 1024 }
+// MemoryMiB est la mémoire exigée par le profil Argon2id, en Mio — à afficher
+// pour que l'utilisateur sache ce qu'il faudra pour déchiffrer.
+func (p KDFProfile) MemoryMiB() uint32 { return p.MemoryMiBFor(KDFArgon2id) }
+
+// MemoryMiBFor est la mémoire exigée par le profil appliqué à une KDF donnée.
+func (p KDFProfile) MemoryMiBFor(kdf byte) uint32 {
+	var h header
+	p.applyTo(&h, kdf)
+	return uint32(h.kdfMemoryKiB() / 1024)
+}
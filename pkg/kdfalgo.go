@@ -0,0 +1,302 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Algorithmes de dérivation de clé.
+//
+// Argon2id reste le défaut et le seul à recommander sans réserve. Les deux
+// autres répondent à des contraintes, pas à des préférences :
+//
+//   - scrypt, pour les appareils qui n'ont pas 256 Mio à consacrer à la
+//     dérivation : à mémoire égale il résiste moins bien qu'Argon2id aux
+//     attaques par compromis temps-mémoire, mais ses profils descendent à
+//     32 Mio ;
+//   - PBKDF2-HMAC-SHA256, pour les environnements qui exigent des primitives
+//     approuvées FIPS. Il n'utilise pas de mémoire : un GPU le parallélise sans
+//     frein, et seul le nombre d'itérations le ralentit. C'est la dérivation
+//     la plus faible des trois, à réserver à ce cas.
+//
+// Les trois partagent le champ de paramètres de l'en-tête (neuf octets), que
+// l'identifiant de KDF interprète. Avant la v4 il n'y avait pas d'identifiant :
+// ces fichiers sont en Argon2id.
+//
+// KDFKey n'est pas une dérivation de mot de passe : le secret est déjà une clé
+// aléatoire de 256 bits (voir keyfile.go), et seule l'étape HKDF demeure. Il
+// n'a pas de paramètres, ses neuf octets sont nuls.
+const (
+	KDFArgon2id = byte(1)
+	KDFScrypt   = byte(2)
+	KDFPBKDF2   = byte(3)
+	KDFKey      = byte(4)
+)
+
+// Bornes de scrypt et de PBKDF2, équivalentes à celles d'Argon2 : la mémoire
+// plafonne à maxArgonMemory, et le temps de calcul maximal reste de l'ordre
+// de celui d'un Argon2 aux bornes.
+const (
+	maxScryptLogN = uint8(24)
+	maxScryptR    = uint32(32)
+	maxScryptP    = uint8(16)
+
+	maxPBKDF2Iterations = uint32(20_000_000)
+)
+
+// scryptParams : N = 2^LogN, facteur de bloc R, parallélisme P.
+//
+// Dans l'en-tête : LogN sur les quatre premiers octets, R sur les quatre
+// suivants, P sur le dernier — la place de time, memory et threads.
+type scryptParams struct {
+	LogN uint8
+	R    uint32
+	P    uint8
+}
+
+// memoryKiB est la mémoire exigée par une dérivation : 128·N·r octets.
+func (p scryptParams) memoryKiB() uint64 {
+	return uint64(128) * uint64(p.R) << p.LogN / 1024
+}
+
+func (p scryptParams) validate() error {
+	switch {
+	case p.LogN == 0 || p.LogN > maxScryptLogN:
+		return errorf("scrypt.n", "paramètre scrypt hors bornes : N=2^%d (attendu 2^1..2^%d)", p.LogN, maxScryptLogN)
+	case p.R == 0 || p.R > maxScryptR:
+		return errorf("scrypt.r", "paramètre scrypt hors bornes : r=%d (attendu 1..%d)", p.R, maxScryptR)
+	case p.P == 0 || p.P > maxScryptP:
+		return errorf("scrypt.p", "paramètre scrypt hors bornes : p=%d (attendu 1..%d)", p.P, maxScryptP)
+	case p.memoryKiB() > uint64(maxArgonMemory):
+		return errorf("scrypt.memory", "paramètres scrypt hors bornes : %d Mio de mémoire (maximum %d)",
+			p.memoryKiB()/1024, maxArgonMemory/1024)
+	}
+	return nil
+}
+
+func (p scryptParams) String() string {
+	return fmt.Sprintf("N=2^%d r=%d p=%d (%dMiB)", p.LogN, p.R, p.P, p.memoryKiB()/1024)
+}
+
+// pbkdf2Params : nombre d'itérations de HMAC-SHA256. Dans l'en-tête, les cinq
+// octets qui suivent doivent être nuls.
+type pbkdf2Params struct {
+	Iterations uint32
+}
+
+func (p pbkdf2Params) validate() error {
+	if p.Iterations == 0 || p.Iterations > maxPBKDF2Iterations {
+		return errorf("pbkdf2.iterations", "paramètre PBKDF2 hors bornes : %d itérations (attendu 1..%d)",
+			p.Iterations, maxPBKDF2Iterations)
+	}
+	return nil
+}
+
+func (p pbkdf2Params) String() string {
+	return fmt.Sprintf("%d itérations", p.Iterations)
+}
+
+// KDFAlgoName rend un identifiant de KDF lisible pour l'interface.
+func KDFAlgoName(kdf byte) string {
+	switch kdf {
+	case KDFArgon2id:
+		return "argon2id"
+	case KDFScrypt:
+		return "scrypt"
+	case KDFPBKDF2:
+		return "pbkdf2-sha256"
+	case KDFKey:
+		return "clé symétrique"
+	default:
+		return "inconnue"
+	}
+}
+
+// kdfIdent rend l'identifiant stable d'une KDF, pour le JSON. Il reprend
+// KDFAlgoName, sauf pour la clé symétrique, dont le nom est un libellé.
+func kdfIdent(kdf byte) string {
+	switch kdf {
+	case KDFArgon2id, KDFScrypt, KDFPBKDF2:
+		return KDFAlgoName(kdf)
+	case KDFKey:
+		return "key"
+	default:
+		return "unknown"
+	}
+}
+
+// KDFParams sont les paramètres de dérivation d'un fichier, tels que lus dans
+// l'en-tête. Seuls ceux de sa KDF sont renseignés, aucun pour un fichier
+// chiffré par clé.
+type KDFParams struct {
+	// Argon2id.
+	Time      uint32 `json:"time,omitempty"`
+	MemoryKiB uint32 `json:"memory_kib,omitempty"`
+	Threads   uint8  `json:"threads,omitempty"`
+	// scrypt, avec N = 2^LogN.
+	LogN uint8  `json:"log_n,omitempty"`
+	R    uint32 `json:"r,omitempty"`
+	P    uint8  `json:"p,omitempty"`
+	// PBKDF2-SHA256.
+	Iterations uint32 `json:"iterations,omitempty"`
+}
+
+func (h *header) kdfParams() KDFParams {
+	switch h.kdfID() {
+	case KDFScrypt:
+		return KDFParams{LogN: h.Scrypt.LogN, R: h.Scrypt.R, P: h.Scrypt.P}
+	case KDFPBKDF2:
+		return KDFParams{Iterations: h.PBKDF2.Iterations}
+	case KDFKey:
+		return KDFParams{}
+	default:
+		return KDFParams{Time: h.Argon.Time, MemoryKiB: h.Argon.Memory, Threads: h.Argon.Threads}
+	}
+}
+
+// ParseKDFAlgo lit la valeur de -kdf-algo. La chaîne vide vaut argon2id.
+func ParseKDFAlgo(s string) (byte, error) {
+	switch s {
+	case "", "argon2id":
+		return KDFArgon2id, nil
+	case "scrypt":
+		return KDFScrypt, nil
+	case "pbkdf2", "pbkdf2-sha256":
+		return KDFPBKDF2, nil
+	default:
+		return 0, errorf("kdf.unknown_name", "KDF inconnue %q (attendu argon2id, scrypt ou pbkdf2)", s)
+	}
+}
+
+// kdfID renvoie la KDF de l'en-tête. Avant la v4, un identifiant absent vaut
+// Argon2id — la seule KDF que ces versions connaissent.
+func (h *header) kdfID() byte {
+	if h.KDF == 0 && h.Version < versionV4 {
+		return KDFArgon2id
+	}
+	return h.KDF
+}
+
+// kdfParamsField sérialise les paramètres de la KDF de l'en-tête dans les neuf
+// octets qui leur sont réservés.
+func (h *header) kdfParamsField() [argonParamsSize]byte {
+	var b [argonParamsSize]byte
+	switch h.kdfID() {
+	case KDFScrypt:
+		b[3] = h.Scrypt.LogN
+		binary.BigEndian.PutUint32(b[4:8], h.Scrypt.R)
+		b[8] = h.Scrypt.P
+	case KDFPBKDF2:
+		binary.BigEndian.PutUint32(b[0:4], h.PBKDF2.Iterations)
+	default:
+		binary.BigEndian.PutUint32(b[0:4], h.Argon.Time)
+		binary.BigEndian.PutUint32(b[4:8], h.Argon.Memory)
+		b[8] = h.Argon.Threads
+	}
+	return b
+}
+
+// parseKDFParams interprète les neuf octets de paramètres selon h.KDF.
+func (h *header) parseKDFParams(b []byte) error {
+	switch h.KDF {
+	case KDFArgon2id:
+		h.Argon = argonParams{Time: binary.BigEndian.Uint32(b[0:4]), Memory: binary.BigEndian.Uint32(b[4:8]), Threads: b[8]}
+	case KDFScrypt:
+		// LogN tient sur un octet : les trois premiers doivent être nuls.
+		if b[0]|b[1]|b[2] != 0 {
+			return errorf("scrypt.n_header", "paramètre scrypt hors bornes : N=2^%d", binary.BigEndian.Uint32(b[0:4]))
+		}
+		h.Scrypt = scryptParams{LogN: b[3], R: binary.BigEndian.Uint32(b[4:8]), P: b[8]}
+	case KDFPBKDF2:
+		if b[4]|b[5]|b[6]|b[7]|b[8] != 0 {
+			return errorf("pbkdf2.reserved", "paramètres PBKDF2 incohérents : octets réservés non nuls")
+		}
+		h.PBKDF2 = pbkdf2Params{Iterations: binary.BigEndian.Uint32(b[0:4])}
+	case KDFKey:
+		if !bytes.Equal(b, make([]byte, len(b))) {
+			return errorf("header.kdf_with_key", "en-tête incohérent : paramètres de KDF sur un fichier chiffré par clé")
+		}
+	default:
+		return errorf("header.unknown_kdf", "KDF inconnue dans le header : %d", h.KDF)
+	}
+	return nil
+}
+
+// validateKDF applique les bornes de la KDF annoncée.
+func (h *header) validateKDF() error {
+	switch h.kdfID() {
+	case KDFArgon2id:
+		return h.Argon.validate()
+	case KDFScrypt:
+		return h.Scrypt.validate()
+	case KDFPBKDF2:
+		return h.PBKDF2.validate()
+	case KDFKey:
+		return nil
+	default:
+		return errorf("header.unknown_kdf", "KDF inconnue dans le header : %d", h.KDF)
+	}
+}
+
+// kdfMemoryKiB est la mémoire qu'exigera la dérivation.
+func (h *header) kdfMemoryKiB() uint64 {
+	switch h.kdfID() {
+	case KDFScrypt:
+		return h.Scrypt.memoryKiB()
+	case KDFPBKDF2, KDFKey:
+		return 0
+	default:
+		return uint64(h.Argon.Memory)
+	}
+}
+
+// kdfLabel décrit la dérivation d'un en-tête pour l'interface.
+func (h *header) kdfLabel() string {
+	switch h.kdfID() {
+	case KDFScrypt:
+		return "scrypt  " + h.Scrypt.String()
+	case KDFPBKDF2:
+		return "pbkdf2-sha256  " + h.PBKDF2.String()
+	case KDFKey:
+		return "clé symétrique  sans dérivation de mot de passe"
+	default:
+		return "argon2id  " + h.Argon.String()
+	}
+}
+
+// deriveMaster applique la KDF de l'en-tête au mot de passe. Les bornes sont
+// revérifiées ici, au plus près de l'allocation.
+func deriveMaster(password []byte, h *header) ([]byte, error) {
+	if len(h.Salt) != saltSize {
+		return nil, errorf("kdf.salt_size", "sel invalide : %d octets (attendu %d)", len(h.Salt), saltSize)
+	}
+	if err := h.validateKDF(); err != nil {
+		return nil, err
+	}
+	switch h.kdfID() {
+	case KDFKey:
+		// password est ici la clé elle-même. Pas de coût à payer : elle est
+		// déjà uniforme. HKDF-Extract y mêle le sel, l'info HKDF y liera
+		// ensuite l'en-tête, comme pour les autres KDF.
+		if len(password) != SymmetricKeySize {
+			return nil, errorf("key.size", "clé symétrique de %d octets (attendu %d)", len(password), SymmetricKeySize)
+		}
+		return hkdf.Extract(sha256.New, password, h.Salt)
+	case KDFScrypt:
+		p := h.Scrypt
+		return scrypt.Key(password, h.Salt, 1<<p.LogN, int(p.R), int(p.P), int(argonKeyLen))
+	case KDFPBKDF2:
+		// crypto/pbkdf2 est le module approuvé FIPS de la bibliothèque
+		// standard. Il prend le mot de passe en string : cette copie-là ne peut
+		// pas être effacée, elle disparaît avec le ramasse-miettes.
+		return pbkdf2.Key(sha256.New, string(password), h.Salt, int(h.PBKDF2.Iterations), int(argonKeyLen))
+	default:
+		return deriveKey(password, h.Salt, h.Argon)
+	}
+}
@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKDFAlternativesAllerRetour(t *testing.T) {
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", []byte("dérivé autrement"))
+	for _, c := range []struct {
+		kdf   byte
+		label string
+	}{
+		{KDFScrypt, "scrypt  N=2^15 r=8 p=1"},
+		{KDFPBKDF2, "pbkdf2-sha256  600000 itérations"},
+	} {
+		t.Run(KDFAlgoName(c.kdf), func(t *testing.T) {
+			enc := filepath.Join(dir, KDFAlgoName(c.kdf)+".chto")
+			if err := Encrypt(in, enc, []byte("pw"), Options{KDFAlgo: c.kdf}); err != nil {
+				t.Fatal(err)
+			}
+			d, err := Inspect(enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Version != versionV4 || !strings.HasPrefix(d.KDF, c.label) {
+				t.Errorf("en-tête inattendu : v%d, kdf %q", d.Version, d.KDF)
+			}
+
+			out := filepath.Join(dir, KDFAlgoName(c.kdf)+".txt")
+			if err := Decrypt(enc, out, []byte("pw"), Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(out); string(got) != "dérivé autrement" {
+				t.Errorf("contenu %q", got)
+			}
+			if err := Verify(enc, []byte("faux"), Options{}); err == nil {
+				t.Error("mauvais mot de passe accepté")
+			}
+		})
+	}
+}
+
+// TestKDFIdentifiantLieALaCle : l'identifiant de KDF est dans l'en-tête, donc
+// dans l'info HKDF. Le remplacer — ici par argon2id, dont les paramètres se
+// lisent aussi dans ces neuf octets — ne doit pas donner un fichier lisible.
+func TestKDFIdentifiantLieALaCle(t *testing.T) {
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", []byte("x"))
+	enc := filepath.Join(dir, "out.chto")
+	if err := Encrypt(in, enc, []byte("pw"), Options{KDFAlgo: KDFScrypt}); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(enc)
+	raw[prefixSize] = KDFArgon2id
+	path := write(t, dir, "falsifie.chto", raw)
+	if err := Verify(path, []byte("pw"), Options{}); err == nil {
+		t.Error("un identifiant de KDF modifié a été accepté")
+	}
+}
+
+func TestKDFBornes(t *testing.T) {
+	refus := map[string]*header{
+		"scrypt N nul":        {KDF: KDFScrypt, Scrypt: scryptParams{LogN: 0, R: 8, P: 1}},
+		"scrypt N trop grand": {KDF: KDFScrypt, Scrypt: scryptParams{LogN: maxScryptLogN + 1, R: 1, P: 1}},
+		"scrypt r nul":        {KDF: KDFScrypt, Scrypt: scryptParams{LogN: 15, R: 0, P: 1}},
+		"scrypt p trop grand": {KDF: KDFScrypt, Scrypt: scryptParams{LogN: 15, R: 8, P: maxScryptP + 1}},
+		// 128 · 2^24 · 32 octets = 64 Gio.
+		"scrypt mémoire": {KDF: KDFScrypt, Scrypt: scryptParams{LogN: 24, R: 32, P: 1}},
+		"pbkdf2 nul":     {KDF: KDFPBKDF2, PBKDF2: pbkdf2Params{Iterations: 0}},
+		"pbkdf2 énorme":  {KDF: KDFPBKDF2, PBKDF2: pbkdf2Params{Iterations: maxPBKDF2Iterations + 1}},
+		"kdf inconnue":   {KDF: 9, Argon: defaultArgonParams()},
+	}
+	for name, h := range refus {
+		h.Version, h.Algo, h.Salt = versionV4, AlgoAES, make([]byte, saltSize)
+		if _, err := readHeader(bytes.NewReader(h.marshal())); err == nil {
+			t.Errorf("%s : en-tête accepté", name)
+		}
+	}
+
+	// Les octets réservés de PBKDF2 doivent rester nuls.
+	h := &header{Version: versionV4, Algo: AlgoAES, Salt: make([]byte, saltSize)}
+	KDFStandard.applyTo(h, KDFPBKDF2)
+	raw := h.marshal()
+	raw[prefixSize+kdfIDSize+argonParamsSize-1] = 1
+	if _, err := readHeader(bytes.NewReader(raw)); err == nil {
+		t.Error("octets réservés de PBKDF2 non nuls acceptés")
+	}
+
+	// Tous les profils doivent passer leurs propres bornes.
+	for _, kdf := range []byte{KDFArgon2id, KDFScrypt, KDFPBKDF2} {
+		for _, p := range AllKDFProfiles() {
+			h := &header{Version: versionV4, Algo: AlgoAES, Salt: make([]byte, saltSize)}
+			p.applyTo(h, kdf)
+			if _, err := readHeader(bytes.NewReader(h.marshal())); err != nil {
+				t.Errorf("%s %s refusé : %v", KDFAlgoName(kdf), p, err)
+			}
+		}
+	}
+}
+
+// TestV3ResteEnArgon2id : un en-tête v3 n'a pas d'identifiant de KDF, et se
+// relit en Argon2id sans rien perdre.
+func TestV3ResteEnArgon2id(t *testing.T) {
+	h := &header{Version: versionV3, Algo: AlgoChaCha, Argon: KDFFort.argonParams(), Comp: CompZstd, Salt: make([]byte, saltSize)}
+	raw := h.marshal()
+	if len(raw) != headerSizeV3 {
+		t.Fatalf("en-tête v3 de %d octets, attendu %d", len(raw), headerSizeV3)
+	}
+	got, err := readHeader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.KDF != KDFArgon2id || got.Argon != KDFFort.argonParams() {
+		t.Errorf("relu en kdf %d, %+v", got.KDF, got.Argon)
+	}
+}
@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"os"
+)
+
+// Fichiers de clé symétrique.
+//
+// Entre deux services, le secret n'a pas à être un mot de passe : une clé
+// aléatoire de 256 bits n'a rien à craindre d'une attaque par dictionnaire, et
+// Argon2 n'y ajouterait qu'un délai et 256 Mio de mémoire à chaque appel. Avec
+// Options.Key, la dérivation coûteuse est sautée ; l'étape HKDF reste, et lie
+// l'en-tête à la clé exactement comme avec un mot de passe.
+//
+// Le fichier de clé est une ligne de texte, copiable sans risque d'altération
+// par un éditeur ou un gestionnaire de secrets :
+//
+//	chto-key-1:<base64url(clé ‖ somme)>
+//
+// La somme est les quatre premiers octets de SHA-256(étiquette ‖ clé). Elle ne
+// protège rien contre un attaquant ; elle attrape la clé tronquée par un
+// copier-coller, qui sans elle donnerait une erreur d'authentification
+// indistinguable d'un fichier falsifié.
+
+// SymmetricKeySize est la taille d'une clé symétrique, en octets.
+const SymmetricKeySize = 32
+
+const (
+	keyFilePrefix   = "chto-key-1:"
+	keyChecksumSize = 4
+	keyChecksumInfo = "chiffremento-key-checksum"
+)
+
+var errKeyChecksum = errorf("keyfile.checksum", "fichier de clé corrompu : la somme de contrôle ne correspond pas (clé tronquée ou modifiée)")
+
+// GenerateKey tire une clé symétrique.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, SymmetricKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errorf("keyfile.generate", "génération de la clé: %w", err)
+	}
+	return key, nil
+}
+
+func keyChecksum(key []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(keyChecksumInfo))
+	h.Write(key)
+	return h.Sum(nil)[:keyChecksumSize]
+}
+
+// EncodeKey produit le contenu d'un fichier de clé, fin de ligne comprise.
+func EncodeKey(key []byte) ([]byte, error) {
+	if len(key) != SymmetricKeySize {
+		return nil, errorf("key.size", "clé symétrique de %d octets (attendu %d)", len(key), SymmetricKeySize)
+	}
+	raw := append(bytes.Clone(key), keyChecksum(key)...)
+	defer wipe(raw)
+	enc := base64.RawURLEncoding
+	out := make([]byte, 0, len(keyFilePrefix)+enc.EncodedLen(len(raw))+1)
+	out = append(out, keyFilePrefix...)
+	out = enc.AppendEncode(out, raw)
+	return append(out, '\n'), nil
+}
+
+// ParseKey relit le contenu d'un fichier de clé. Les blancs autour de la ligne
+// sont tolérés, rien d'autre.
+func ParseKey(data []byte) ([]byte, error) {
+	line, ok := bytes.CutPrefix(bytes.TrimSpace(data), []byte(keyFilePrefix))
+	if !ok {
+		return nil, errorf("keyfile.not_a_key", "ce n'est pas un fichier de clé chiffremento (attendu une ligne %q…)", keyFilePrefix)
+	}
+	enc := base64.RawURLEncoding
+	if enc.DecodedLen(len(line)) != SymmetricKeySize+keyChecksumSize {
+		return nil, errorf("keyfile.length", "fichier de clé de longueur invalide : %w", errKeyChecksum)
+	}
+	raw := make([]byte, SymmetricKeySize+keyChecksumSize)
+	defer wipe(raw)
+	if _, err := enc.Decode(raw, line); err != nil {
+		return nil, errorf("keyfile.unreadable", "fichier de clé illisible : %w", err)
+	}
+	key, sum := raw[:SymmetricKeySize], raw[SymmetricKeySize:]
+	if subtle.ConstantTimeCompare(sum, keyChecksum(key)) != 1 {
+		return nil, errKeyChecksum
+	}
+	return bytes.Clone(key), nil
+}
+
+// ReadKeyFile lit et vérifie un fichier de clé.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errorf("keyfile.read", "lecture de la clé: %w", err)
+	}
+	defer wipe(data)
+	key, err := ParseKey(data)
+	if err != nil {
+		return nil, errorf("keyfile.invalid", "%s : %w", path, err)
+	}
+	return key, nil
+}
+
+// WriteKeyFile écrit une clé dans un fichier neuf, lisible par son seul
+// propriétaire. Un fichier existant n'est jamais écrasé : perdre une clé, c'est
+// perdre tout ce qu'elle chiffre.
+func WriteKeyFile(path string, key []byte) error {
+	data, err := EncodeKey(key)
+	if err != nil {
+		return err
+	}
+	defer wipe(data)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return errorf("keyfile.exists", "%s existe déjà : une clé n'est jamais écrasée", path)
+		}
+		return errorf("keyfile.create", "création du fichier de clé: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return errorf("keyfile.write", "écriture de la clé: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return errorf("keyfile.write", "écriture de la clé: %w", err)
+	}
+	return nil
+}
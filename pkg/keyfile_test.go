@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFichierDeCleAllerRetour(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(keyFilePrefix)) || !bytes.HasSuffix(data, []byte("\n")) {
+		t.Errorf("format inattendu : %q", data)
+	}
+	// Les blancs autour de la ligne sont tolérés (éditeur, copier-coller).
+	for _, variante := range [][]byte{data, bytes.TrimSpace(data), append([]byte("  "), append(data, "\r\n"...)...)} {
+		got, err := ParseKey(variante)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Errorf("%q : %v", variante, err)
+		}
+	}
+
+	ligne := bytes.TrimSpace(data)
+	modifiee := bytes.Clone(ligne)
+	i := len(keyFilePrefix) + 5
+	if modifiee[i] == 'A' {
+		modifiee[i] = 'B'
+	} else {
+		modifiee[i] = 'A'
+	}
+	for nom, refus := range map[string][]byte{
+		"caractère modifié": modifiee,
+		"tronquée":          ligne[:len(ligne)-3],
+		"sans préfixe":      ligne[len(keyFilePrefix):],
+		"autre version":     append([]byte("chto-key-2:"), ligne[len(keyFilePrefix):]...),
+		"vide":              nil,
+	} {
+		if _, err := ParseKey(refus); err == nil {
+			t.Errorf("%s : clé acceptée", nom)
+		}
+	}
+	if _, err := EncodeKey(key[:16]); err == nil {
+		t.Error("clé de 128 bits encodée")
+	}
+}
+
+func TestWriteKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service.key")
+	key, _ := GenerateKey()
+	if err := WriteKeyFile(path, key); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0600) {
+		t.Errorf("fichier de clé : %v, %v", info.Mode(), err)
+	}
+	got, err := ReadKeyFile(path)
+	if err != nil || !bytes.Equal(got, key) {
+		t.Fatalf("relecture : %v", err)
+	}
+	// Jamais d'écrasement : l'ancienne clé chiffre peut-être encore des fichiers.
+	autre, _ := GenerateKey()
+	if err := WriteKeyFile(path, autre); err == nil {
+		t.Error("une clé existante a été écrasée")
+	}
+	if got, _ := ReadKeyFile(path); !bytes.Equal(got, key) {
+		t.Error("la clé d'origine a changé")
+	}
+}
+
+func TestChiffrementParCle(t *testing.T) {
+	dir := t.TempDir()
+	contenu := []byte("de service à service")
+	in := write(t, dir, "clair.txt", contenu)
+	key, _ := GenerateKey()
+
+	for _, algo := range []byte{AlgoAES, AlgoCascade, AlgoAEGIS} {
+		enc := filepath.Join(dir, AlgoName(algo)+".chto")
+		if err := Encrypt(in, enc, nil, Options{Algo: algo, Key: key}); err != nil {
+			t.Fatal(err)
+		}
+		d, err := Inspect(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !d.KeyBased || d.KDFMemoryKiB != 0 || !strings.HasPrefix(d.KDF, "clé symétrique") {
+			t.Errorf("%s : détails inattendus %+v", AlgoName(algo), d)
+		}
+		out := filepath.Join(dir, AlgoName(algo)+".txt")
+		if err := Decrypt(enc, out, nil, Options{Key: key}); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := os.ReadFile(out); !bytes.Equal(got, contenu) {
+			t.Errorf("%s : contenu %q", AlgoName(algo), got)
+		}
+	}
+
+	enc := filepath.Join(dir, AlgoName(AlgoAES)+".chto")
+	autre, _ := GenerateKey()
+	if err := Verify(enc, nil, Options{Key: autre}); err == nil {
+		t.Error("une autre clé a été acceptée")
+	}
+	// Un mot de passe ne doit pas ouvrir un fichier chiffré par clé, même s'il
+	// en a les octets : la confusion est signalée comme telle.
+	if err := Verify(enc, key, Options{}); err == nil || !strings.Contains(err.Error(), "clé symétrique") {
+		t.Errorf("mot de passe sur un fichier chiffré par clé : %v", err)
+	}
+
+	// L'identifiant de KDF est lié à la clé par l'en-tête.
+	raw, _ := os.ReadFile(enc)
+	raw[prefixSize] = KDFPBKDF2
+	if err := Verify(write(t, dir, "falsifie.chto", raw), nil, Options{Key: key}); err == nil {
+		t.Error("un en-tête modifié a été accepté")
+	}
+
+	// Dans l'autre sens : une clé sur un fichier à mot de passe.
+	pwFile := filepath.Join(dir, "pw.chto")
+	if err := Encrypt(in, pwFile, []byte("pw"), Options{KDF: KDFStandard}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(pwFile, nil, Options{Key: key}); err == nil {
+		t.Error("une clé a ouvert un fichier à mot de passe")
+	}
+
+	for nom, opts := range map[string]Options{
+		"avec un profil":  {Key: key, KDF: KDFFort},
+		"avec une KDF":    {Key: key, KDFAlgo: KDFScrypt},
+		"clé trop courte": {Key: key[:16]},
+	} {
+		if err := Encrypt(in, filepath.Join(dir, "refus.chto"), nil, opts); err == nil {
+			t.Errorf("%s : chiffrement accepté", nom)
+		}
+	}
+}
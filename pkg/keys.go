@@ -3,7 +3,6 @@ package pkg
 import (
 	"crypto/hkdf"
 	"crypto/sha256"
-	"fmt"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -18,18 +17,48 @@ const (
 )
 
 // keySet regroupe le matériel de chiffrement d'un fichier. Selon l'algorithme,
-// soit Key est renseignée, soit la paire Inner/Outer.
+// soit Key est renseignée, soit la paire Inner/Outer. Les clés vivent dans des
+// tampons protégés (voir secure.go), rangés par hold.
 type keySet struct {
 	Key   []byte
 	Inner []byte
 	Outer []byte
+
+	bufs []*SecureBuffer
+}
+
+// hold range b dans un tampon protégé rattaché au keySet, efface b, et rend la
+// copie protégée.
+func (k *keySet) hold(b []byte) ([]byte, error) {
+	sb, err := SecureCopy(b)
+	if err != nil {
+		k.wipe()
+		return nil, err
+	}
+	k.bufs = append(k.bufs, sb)
+	return sb.Bytes(), nil
 }
 
-// wipe efface les clés de la mémoire dès qu'elles ne servent plus.
+// parts présente les clés dans l'ordre attendu par une suite qui en demande
+// n : la clé simple, ou la clé interne puis la clé externe. C'est la suite qui
+// décide, pas les champs renseignés — un keySet complet sert aux deux.
+func (k *keySet) parts(n int) [][]byte {
+	if n == 2 {
+		return [][]byte{k.Inner, k.Outer}
+	}
+	return [][]byte{k.Key}
+}
+
+// wipe efface les clés de la mémoire dès qu'elles ne servent plus, et rend
+// leurs tampons protégés. Un second appel est sans effet.
 func (k *keySet) wipe() {
 	wipe(k.Key)
 	wipe(k.Inner)
 	wipe(k.Outer)
+	for _, b := range k.bufs {
+		b.Destroy()
+	}
+	k.Key, k.Inner, k.Outer, k.bufs = nil, nil, nil, nil
 }
 
 func wipe(b []byte) {
@@ -43,7 +72,7 @@ func wipe(b []byte) {
 // renvoyer — la clé était alors irreproductible et le fichier perdu.
 func deriveKey(password, salt []byte, p argonParams) ([]byte, error) {
 	if len(salt) != saltSize {
-		return nil, fmt.Errorf("sel invalide : %d octets (attendu %d)", len(salt), saltSize)
+		return nil, errorf("kdf.salt_size", "sel invalide : %d octets (attendu %d)", len(salt), saltSize)
 	}
 	if err := p.validate(); err != nil {
 		return nil, err
@@ -60,8 +89,8 @@ func deriveKeys(password []byte, h *header) (*keySet, error) {
 	return deriveKeysV2(password, h)
 }
 
-// deriveKeysV2 : un seul Argon2id, puis HKDF-Expand pour obtenir les
-// sous-clés.
+// deriveKeysV2 : une seule dérivation coûteuse (Argon2id, ou la KDF annoncée
+// en v4), puis HKDF-Expand pour obtenir les sous-clés.
 //
 // L'ordre compte. La v1 faisait l'inverse en mode cascade (HKDF gratuit sur le
 // mot de passe, puis deux Argon2) : l'utilisateur payait deux dérivations
@@ -74,25 +103,51 @@ func deriveKeys(password []byte, h *header) (*keySet, error) {
 // déchiffrement échoue sur l'authentification AEAD. L'en-tête est ainsi lié à
 // la clé sans avoir besoin d'un champ d'authentification supplémentaire.
 func deriveKeysV2(password []byte, h *header) (*keySet, error) {
-	master, err := deriveKey(password, h.Salt, h.Argon)
+	if _, ok := lookupSuite(h.Algo); !ok {
+		return nil, errorf("algo.unknown", "algorithme inconnu : %d", h.Algo)
+	}
+	master, err := deriveMaster(password, h)
 	if err != nil {
 		return nil, err
 	}
 	defer wipe(master)
+	return expandKeys(master, h)
+}
 
-	if h.Algo == AlgoCascade {
-		out, err := hkdf.Expand(sha256.New, master, infoCascadeV2+string(h.Raw), 64)
+// expandKeys tire les sous-clés de la clé maîtresse : celle que donne la KDF,
+// ou la clé de fichier d'un fichier à code de secours (voir recovery.go).
+func expandKeys(master []byte, h *header) (*keySet, error) {
+	suite, ok := lookupSuite(h.Algo)
+	if !ok {
+		return nil, errorf("algo.unknown", "algorithme inconnu : %d", h.Algo)
+	}
+
+	// Une suite à deux clés reprend l'étiquette de la cascade, une suite à clé
+	// simple celle d'AES et ChaCha : pour les suites fournies, les octets
+	// dérivés sont donc exactement ceux d'avant le registre.
+	sizes := suite.KeySizes()
+	if len(sizes) == 2 {
+		out, err := hkdf.Expand(sha256.New, master, infoCascadeV2+string(h.Raw), sizes[0]+sizes[1])
 		if err != nil {
-			return nil, fmt.Errorf("dérivation des sous-clés: %w", err)
+			return nil, errorf("keys.subkeys", "dérivation des sous-clés: %w", err)
+		}
+		ks := &keySet{}
+		if out, err = ks.hold(out); err != nil {
+			return nil, err
 		}
-		return &keySet{Inner: out[:32], Outer: out[32:]}, nil
+		ks.Inner, ks.Outer = out[:sizes[0]], out[sizes[0]:]
+		return ks, nil
 	}
 
-	key, err := hkdf.Expand(sha256.New, master, infoKeyV2+string(h.Raw), 32)
+	key, err := hkdf.Expand(sha256.New, master, infoKeyV2+string(h.Raw), sizes[0])
 	if err != nil {
-		return nil, fmt.Errorf("dérivation de la clé: %w", err)
+		return nil, errorf("keys.derive", "dérivation de la clé: %w", err)
+	}
+	ks := &keySet{}
+	if ks.Key, err = ks.hold(key); err != nil {
+		return nil, err
 	}
-	return &keySet{Key: key}, nil
+	return ks, nil
 }
 
 // deriveKeysV1 reproduit à l'identique la dérivation des fichiers .chto
@@ -104,24 +159,36 @@ func deriveKeysV1(password []byte, h *header) (*keySet, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &keySet{Key: key}, nil
+		ks := &keySet{}
+		if ks.Key, err = ks.hold(key); err != nil {
+			return nil, err
+		}
+		return ks, nil
 	}
 
 	// v1 : HKDF sur le mot de passe brut, puis un Argon2 par sous-mot-de-passe.
 	sub, err := hkdf.Key(sha256.New, password, nil, infoCascadeV1, 64)
 	if err != nil {
-		return nil, fmt.Errorf("dérivation des sous-mots-de-passe (v1): %w", err)
+		return nil, errorf("keys.v1_subpasswords", "dérivation des sous-mots-de-passe (v1): %w", err)
 	}
 	defer wipe(sub)
 
 	inner, err := deriveKey(sub[:32], h.Salt, h.Argon)
 	if err != nil {
-		return nil, fmt.Errorf("clé interne (v1): %w", err)
+		return nil, errorf("keys.v1_inner", "clé interne (v1): %w", err)
 	}
 	outer, err := deriveKey(sub[32:], h.Salt, h.Argon)
 	if err != nil {
 		wipe(inner)
-		return nil, fmt.Errorf("clé externe (v1): %w", err)
+		return nil, errorf("keys.v1_outer", "clé externe (v1): %w", err)
+	}
+	ks := &keySet{}
+	if ks.Inner, err = ks.hold(inner); err != nil {
+		wipe(outer)
+		return nil, err
+	}
+	if ks.Outer, err = ks.hold(outer); err != nil {
+		return nil, err
 	}
-	return &keySet{Inner: inner, Outer: outer}, nil
+	return ks, nil
 }
@@ -0,0 +1,65 @@
+package pkg
+
+// Contrôle de mémoire avant la dérivation.
+//
+// Argon2 alloue d'un bloc la mémoire annoncée par l'en-tête. Sur une petite VM,
+// un fichier scellé en « maximum » ne produit pas d'erreur : le noyau tue le
+// processus, sans un mot. On compare donc, avant de dériver, la mémoire exigée
+// à deux limites :
+//
+//   - un plafond choisi par l'appelant (Options.MaxKDFMemory), plus bas que
+//     maxArgonMemory, pour qu'un service refuse d'emblée les fichiers hostiles
+//     ou démesurés ;
+//   - la mémoire réellement disponible, quand le système sait la dire (limites
+//     de cgroup et /proc/meminfo sous Linux). Ailleurs, ce contrôle-là est
+//     simplement absent.
+
+// KDFMemoryError signale une dérivation refusée faute de mémoire, avant toute
+// allocation.
+type KDFMemoryError struct {
+	// NeedKiB est la mémoire qu'exigerait la dérivation.
+	NeedKiB uint64
+	// LimitKiB est la limite dépassée.
+	LimitKiB uint64
+	// Available vaut true quand la limite est la mémoire disponible sur la
+	// machine, false quand c'est un plafond choisi (MaxKDFMemory).
+	Available bool
+}
+
+func (e *KDFMemoryError) Error() string { return e.coded().Error() }
+
+// Unwrap rend le message sous forme d'*Error : une interface le traduit
+// d'après son code, comme les autres erreurs du paquet.
+func (e *KDFMemoryError) Unwrap() error { return e.coded() }
+
+func (e *KDFMemoryError) coded() error {
+	if e.Available {
+		return errorf("kdf.memory_available", "mémoire insuffisante : la dérivation de ce fichier exige %d Mio, "+
+			"seuls %d Mio sont disponibles ici (il faut le déchiffrer sur une machine mieux dotée)",
+			e.NeedKiB/1024, e.LimitKiB/1024)
+	}
+	return errorf("kdf.memory_cap", "fichier refusé : sa dérivation exige %d Mio, au-delà du plafond de %d Mio",
+		e.NeedKiB/1024, e.LimitKiB/1024)
+}
+
+// availableMemory renvoie la mémoire disponible en KiB, et false si le
+// système ne permet pas de la connaître. Une variable pour que les tests
+// puissent simuler une machine contrainte.
+var availableMemory = availableMemoryKiB
+
+// AvailableMemoryKiB renvoie la mémoire disponible pour ce processus, en KiB,
+// et false si elle est inconnue sur ce système.
+func AvailableMemoryKiB() (uint64, bool) { return availableMemory() }
+
+// CheckKDFMemory vérifie qu'une dérivation de needKiB peut avoir lieu : sous
+// le plafond maxKiB (zéro : pas d'autre plafond que celui du format) et dans
+// la mémoire disponible. L'erreur est un *KDFMemoryError.
+func CheckKDFMemory(needKiB uint64, maxKiB uint32) error {
+	if maxKiB != 0 && needKiB > uint64(maxKiB) {
+		return &KDFMemoryError{NeedKiB: needKiB, LimitKiB: uint64(maxKiB)}
+	}
+	if avail, ok := availableMemory(); ok && needKiB > avail {
+		return &KDFMemoryError{NeedKiB: needKiB, LimitKiB: avail, Available: true}
+	}
+	return nil
+}
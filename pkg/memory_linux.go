@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Sous Linux, la mémoire disponible est la plus petite de :
+//
+//   - MemAvailable dans /proc/meminfo, l'estimation du noyau de ce qu'un
+//     processus peut allouer sans faire swapper la machine ;
+//   - ce qui reste sous la limite de chaque cgroup, du nôtre jusqu'à la
+//     racine — dans un conteneur, c'est elle qui déclenche l'OOM killer, bien
+//     avant que la machine elle-même manque de mémoire.
+//
+// Le cache de pages inactif est compté comme disponible : le noyau le récupère
+// avant de tuer quiconque.
+
+const (
+	procMeminfo = "/proc/meminfo"
+	procCgroup  = "/proc/self/cgroup"
+	cgroupRoot  = "/sys/fs/cgroup"
+)
+
+func availableMemoryKiB() (uint64, bool) {
+	avail, ok := meminfoAvailable(procMeminfo)
+	if c, cok := cgroupAvailable(procCgroup, cgroupRoot); cok && (!ok || c < avail) {
+		avail, ok = c, true
+	}
+	return avail, ok
+}
+
+// meminfoAvailable lit MemAvailable, déjà en KiB.
+func meminfoAvailable(path string) (uint64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), "MemAvailable:"); ok {
+			n, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(v), " kB"), 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// cgroupAvailable renvoie, en KiB, la marge restante sous la plus basse
+// limite mémoire des cgroups du processus. false s'il n'y a aucune limite.
+func cgroupAvailable(procPath, root string) (uint64, bool) {
+	data, err := os.ReadFile(procPath)
+	if err != nil {
+		return 0, false
+	}
+	var best uint64
+	found := false
+	keep := func(b uint64, ok bool) {
+		if ok && (!found || b < best) {
+			best, found = b, true
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// hiérarchie:contrôleurs:chemin
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			// cgroup v2 : une seule hiérarchie, limites dans memory.max.
+			for dir := filepath.Join(root, parts[2]); ; dir = filepath.Dir(dir) {
+				keep(cgroupHeadroom(dir, "memory.max", "memory.current", "inactive_file"))
+				if dir == root || !strings.HasPrefix(dir, root) {
+					break
+				}
+			}
+		case hasController(parts[1], "memory"):
+			// cgroup v1 : hiérarchie mémoire à part.
+			base := filepath.Join(root, "memory")
+			for dir := filepath.Join(base, parts[2]); ; dir = filepath.Dir(dir) {
+				keep(cgroupHeadroom(dir, "memory.limit_in_bytes", "memory.usage_in_bytes", "total_inactive_file"))
+				if dir == base || !strings.HasPrefix(dir, base) {
+					break
+				}
+			}
+		}
+	}
+	return best, found
+}
+
+func hasController(list, name string) bool {
+	for _, c := range strings.Split(list, ",") {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cgroupHeadroom calcule limite − (usage − cache inactif) pour un cgroup, en
+// KiB. false si le cgroup n'a pas de limite ou ne se lit pas.
+func cgroupHeadroom(dir, limitFile, usageFile, inactiveKey string) (uint64, bool) {
+	limit, ok := readCgroupValue(filepath.Join(dir, limitFile))
+	// cgroup v1 exprime « pas de limite » par une valeur géante, arrondie à la
+	// page : tout ce qui dépasse 2^60 en est une.
+	if !ok || limit >= 1<<60 {
+		return 0, false
+	}
+	usage, ok := readCgroupValue(filepath.Join(dir, usageFile))
+	if !ok {
+		return 0, false
+	}
+	if stat, err := os.ReadFile(filepath.Join(dir, "memory.stat")); err == nil {
+		for _, l := range bytes.Split(stat, []byte("\n")) {
+			if v, ok := bytes.CutPrefix(l, []byte(inactiveKey+" ")); ok {
+				if n, err := strconv.ParseUint(string(v), 10, 64); err == nil && n <= usage {
+					usage -= n
+				}
+				break
+			}
+		}
+	}
+	if usage >= limit {
+		return 0, true
+	}
+	return (limit - usage) / 1024, true
+}
+
+// readCgroupValue lit un fichier de cgroup à une valeur. « max » (v2) veut
+// dire sans limite.
+func readCgroupValue(path string) (uint64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	return n, err == nil
+}
+
+// excludeFromDump retire une zone des vidages mémoire : un plantage ne doit
+// pas écrire les secrets d'un SecureBuffer sur le disque.
+func excludeFromDump(b []byte) { unix.Madvise(b, unix.MADV_DONTDUMP) }
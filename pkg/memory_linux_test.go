@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func ecrireCgroup(t *testing.T, dir string, fichiers map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for nom, contenu := range fichiers {
+		if err := os.WriteFile(filepath.Join(dir, nom), []byte(contenu), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMeminfoAvailable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meminfo")
+	ecrireCgroup(t, dir, map[string]string{"meminfo": "MemTotal:       16384000 kB\nMemFree:         1000000 kB\nMemAvailable:    8192000 kB\n"})
+	if got, ok := meminfoAvailable(path); !ok || got != 8192000 {
+		t.Errorf("MemAvailable = %d, %v", got, ok)
+	}
+	if _, ok := meminfoAvailable(filepath.Join(dir, "absent")); ok {
+		t.Error("fichier absent lu comme une mesure")
+	}
+}
+
+func TestCgroupAvailable(t *testing.T) {
+	const mio = 1 << 20
+
+	// v2 : la limite du parent, plus basse, l'emporte sur celle du cgroup.
+	root := t.TempDir()
+	ecrireCgroup(t, filepath.Join(root, "service"), map[string]string{
+		"memory.max": "1073741824\n", "memory.current": "104857600\n",
+	})
+	ecrireCgroup(t, filepath.Join(root, "service", "worker"), map[string]string{
+		"memory.max": "max\n", "memory.current": "52428800\n",
+	})
+	ecrireCgroup(t, filepath.Join(root, "service", "worker", "job"), map[string]string{
+		"memory.max":     "536870912\n",
+		"memory.current": "314572800\n",
+		// Le cache inactif compte comme disponible.
+		"memory.stat": "anon 100\ninactive_file 104857600\nactive_file 5\n",
+	})
+	proc := filepath.Join(root, "cgroup")
+	ecrireCgroup(t, root, map[string]string{"cgroup": "0::/service/worker/job\n"})
+	// job : 512 − (300 − 100) = 312 Mio ; service : 1024 − 100 = 924 Mio.
+	if got, ok := cgroupAvailable(proc, root); !ok || got != 312*mio/1024 {
+		t.Errorf("v2 : %d KiB, %v ; attendu %d", got, ok, 312*mio/1024)
+	}
+
+	// v2 sans aucune limite.
+	libre := t.TempDir()
+	ecrireCgroup(t, libre, map[string]string{"cgroup": "0::/\n", "memory.max": "max\n", "memory.current": "1\n"})
+	if _, ok := cgroupAvailable(filepath.Join(libre, "cgroup"), libre); ok {
+		t.Error("v2 sans limite lu comme limité")
+	}
+
+	// v1 : hiérarchie mémoire séparée, « pas de limite » codé par une valeur
+	// géante.
+	v1 := t.TempDir()
+	ecrireCgroup(t, filepath.Join(v1, "memory"), map[string]string{
+		"memory.limit_in_bytes": "9223372036854771712\n", "memory.usage_in_bytes": "1\n",
+	})
+	ecrireCgroup(t, filepath.Join(v1, "memory", "docker", "abc"), map[string]string{
+		"memory.limit_in_bytes": "268435456\n",
+		"memory.usage_in_bytes": "268435456\n",
+	})
+	ecrireCgroup(t, v1, map[string]string{"cgroup": "12:cpu,cpuacct:/docker/abc\n11:memory:/docker/abc\n"})
+	if got, ok := cgroupAvailable(filepath.Join(v1, "cgroup"), v1); !ok || got != 0 {
+		t.Errorf("v1 saturé : %d KiB, %v ; attendu 0", got, ok)
+	}
+}
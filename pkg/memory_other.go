@@ -0,0 +1,10 @@
+//go:build !linux
+
+package pkg
+
+// Hors Linux, la mémoire disponible n'est pas mesurée : seul le plafond choisi
+// par l'appelant s'applique.
+func availableMemoryKiB() (uint64, bool) { return 0, false }
+
+// excludeFromDump n'a d'équivalent que sous Linux.
+func excludeFromDump([]byte) {}
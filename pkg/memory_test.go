@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// avecMemoire simule une machine qui annonce avail KiB disponibles.
+func avecMemoire(t *testing.T, avail uint64, ok bool) {
+	t.Helper()
+	precedent := availableMemory
+	availableMemory = func() (uint64, bool) { return avail, ok }
+	t.Cleanup(func() { availableMemory = precedent })
+}
+
+func TestCheckKDFMemory(t *testing.T) {
+	avecMemoire(t, 512<<10, true)
+	cas := []struct {
+		need      uint64
+		max       uint32
+		refus     bool
+		available bool
+	}{
+		{256 << 10, 0, false, false},
+		{1 << 20, 0, true, true},
+		{256 << 10, 128 << 10, true, false},
+		{256 << 10, 256 << 10, false, false},
+		// Le plafond choisi passe avant la mémoire disponible.
+		{1 << 20, 256 << 10, true, false},
+	}
+	for _, c := range cas {
+		err := CheckKDFMemory(c.need, c.max)
+		var me *KDFMemoryError
+		if !c.refus {
+			if err != nil {
+				t.Errorf("besoin %d, plafond %d : refusé : %v", c.need, c.max, err)
+			}
+			continue
+		}
+		if !errors.As(err, &me) || me.Available != c.available || me.NeedKiB != c.need {
+			t.Errorf("besoin %d, plafond %d : %v", c.need, c.max, err)
+		}
+	}
+
+	// Mémoire inconnue : seul le plafond compte.
+	avecMemoire(t, 0, false)
+	if err := CheckKDFMemory(2<<20, 0); err != nil {
+		t.Errorf("mémoire inconnue, aucun plafond : %v", err)
+	}
+}
+
+// TestDechiffrementRefuseFauteDeMemoire : le refus arrive avant la dérivation
+// et ne laisse rien sur le disque.
+func TestDechiffrementRefuseFauteDeMemoire(t *testing.T) {
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", []byte("lourd à dériver"))
+	enc := filepath.Join(dir, "out.chto")
+	if err := Encrypt(in, enc, []byte("pw"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Inspect(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.KDFMemoryKiB != uint64(defaultArgonMemory) {
+		t.Errorf("mémoire annoncée %d KiB, attendu %d", d.KDFMemoryKiB, defaultArgonMemory)
+	}
+
+	out := filepath.Join(dir, "relu.txt")
+	var me *KDFMemoryError
+	err = Decrypt(enc, out, []byte("pw"), Options{MaxKDFMemory: 64 << 10})
+	if !errors.As(err, &me) || me.Available {
+		t.Errorf("plafond ignoré : %v", err)
+	}
+
+	avecMemoire(t, 64<<10, true)
+	err = Decrypt(enc, out, []byte("pw"), Options{})
+	if !errors.As(err, &me) || !me.Available {
+		t.Errorf("mémoire disponible ignorée : %v", err)
+	}
+	if err := Encrypt(in, filepath.Join(dir, "autre.chto"), []byte("pw"), Options{}); !errors.As(err, &me) {
+		t.Errorf("chiffrement accepté sans la mémoire nécessaire : %v", err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("une sortie a été écrite malgré le refus")
+	}
+	assertPasDeTemporaire(t, dir)
+}
@@ -2,8 +2,6 @@ package pkg
 
 import (
 	"encoding/binary"
-	"errors"
-	"fmt"
 	"io"
 	"path"
 	"path/filepath"
@@ -42,7 +40,7 @@ func ParseMetadataMode(s string) (MetadataMode, error) {
 	case MetadataMinimal:
 		return MetadataMinimal, nil
 	default:
-		return "", fmt.Errorf("mode de métadonnées inconnu %q (attendu none ou minimal)", s)
+		return "", errorf("metadata.unknown_mode", "mode de métadonnées inconnu %q (attendu none ou minimal)", s)
 	}
 }
 
@@ -83,10 +81,10 @@ func newFileMetadata(localPath string, modTime time.Time) *FileMetadata {
 func marshalMetadata(m *FileMetadata) ([]byte, error) {
 	name := []byte(m.Name)
 	if len(name) == 0 {
-		return nil, errors.New("métadonnées : nom de fichier vide")
+		return nil, errorf("metadata.empty_name", "métadonnées : nom de fichier vide")
 	}
 	if len(name) > maxMetaName {
-		return nil, fmt.Errorf("métadonnées : nom de %d octets, maximum %d", len(name), maxMetaName)
+		return nil, errorf("metadata.name_too_long", "métadonnées : nom de %d octets, maximum %d", len(name), maxMetaName)
 	}
 	buf := make([]byte, 0, metaPrefixSize+len(name))
 	buf = append(buf, metaMagic...)
@@ -104,18 +102,18 @@ func marshalMetadata(m *FileMetadata) ([]byte, error) {
 func readMetadata(r io.Reader) (*FileMetadata, error) {
 	prefix := make([]byte, metaPrefixSize)
 	if _, err := io.ReadFull(r, prefix); err != nil {
-		return nil, fmt.Errorf("lecture des métadonnées: %w", err)
+		return nil, errorf("metadata.read", "lecture des métadonnées: %w", err)
 	}
 	if string(prefix[:len(metaMagic)]) != metaMagic {
-		return nil, errors.New("métadonnées absentes ou corrompues")
+		return nil, errorf("metadata.corrupt", "métadonnées absentes ou corrompues")
 	}
 	nameLen := binary.BigEndian.Uint16(prefix[len(metaMagic) : len(metaMagic)+2])
 	if nameLen == 0 || int(nameLen) > maxMetaName {
-		return nil, fmt.Errorf("métadonnées : longueur de nom invalide (%d)", nameLen)
+		return nil, errorf("metadata.name_length", "métadonnées : longueur de nom invalide (%d)", nameLen)
 	}
 	name := make([]byte, nameLen)
 	if _, err := io.ReadFull(r, name); err != nil {
-		return nil, fmt.Errorf("lecture du nom d'origine: %w", err)
+		return nil, errorf("metadata.read_name", "lecture du nom d'origine: %w", err)
 	}
 
 	clean, err := sanitizeMetaName(string(name))
@@ -137,7 +135,7 @@ func readMetadata(r io.Reader) (*FileMetadata, error) {
 // séparateur nu en guise de nom de fichier, et « . », « .. » ou la chaîne vide
 // passaient au travers.
 func sanitizeMetaName(name string) (string, error) {
-	invalide := errors.New("métadonnées : nom d'origine inutilisable")
+	invalide := errorf("metadata.unusable_name", "métadonnées : nom d'origine inutilisable")
 
 	// Les séparateurs Windows sont normalisés d'abord : path ne les connaît pas,
 	// et un nom forgé peut en contenir quel que soit le système de lecture.
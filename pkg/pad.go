@@ -3,7 +3,6 @@ package pkg
 import (
 	"crypto/rand"
 	"encoding/binary"
-	"fmt"
 	"io"
 	"math"
 	"math/bits"
@@ -94,18 +93,18 @@ func paddingFor(payloadSize int64) int64 {
 // des zéros seraient triviaux à repérer si un jour le clair fuitait par ailleurs.
 func writePadding(w io.Writer, pad int64) error {
 	if pad < 0 || pad > maxPadding {
-		return fmt.Errorf("remplissage hors bornes : %d octets", pad)
+		return errorf("pad.out_of_range", "remplissage hors bornes : %d octets", pad)
 	}
 	var hdr [padHeaderSize]byte
 	binary.BigEndian.PutUint32(hdr[:], uint32(pad))
 	if _, err := w.Write(hdr[:]); err != nil {
-		return fmt.Errorf("écriture de l'en-tête de remplissage: %w", err)
+		return errorf("pad.write_header", "écriture de l'en-tête de remplissage: %w", err)
 	}
 	if pad == 0 {
 		return nil
 	}
 	if _, err := io.CopyN(w, rand.Reader, pad); err != nil {
-		return fmt.Errorf("écriture du remplissage: %w", err)
+		return errorf("pad.write", "écriture du remplissage: %w", err)
 	}
 	return nil
 }
@@ -115,14 +114,14 @@ func writePadding(w io.Writer, pad int64) error {
 func skipPadding(r io.Reader) error {
 	var hdr [padHeaderSize]byte
 	if _, err := io.ReadFull(r, hdr[:]); err != nil {
-		return fmt.Errorf("lecture de l'en-tête de remplissage: %w", err)
+		return errorf("pad.read_header", "lecture de l'en-tête de remplissage: %w", err)
 	}
 	pad := int64(binary.BigEndian.Uint32(hdr[:]))
 	if pad == 0 {
 		return nil
 	}
 	if _, err := io.CopyN(io.Discard, r, pad); err != nil {
-		return fmt.Errorf("lecture du remplissage: %w", err)
+		return errorf("pad.read", "lecture du remplissage: %w", err)
 	}
 	return nil
 }
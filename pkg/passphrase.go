@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Phrases de passe.
+//
+// Le meilleur mot de passe pour un humain est une suite de mots tirés au
+// hasard : facile à retenir et à taper, et dont l'entropie se calcule au lieu
+// de s'estimer. Chaque mot vient d'une liste de 2048, soit onze bits par mot,
+// tiré par crypto/rand sans biais. Six mots donnent 66 bits : hors de portée
+// d'une attaque hors ligne contre Argon2id (voir le modèle de menace).
+//
+// Les listes sont embarquées dans le binaire : un mot par ligne, minuscules
+// sans accent, de trois à huit lettres. Sans accent pour la saisie — un « é »
+// ne se tape pas pareil sur tous les claviers, et un mot de passe qui ne se
+// retape pas est un fichier perdu.
+
+//go:embed wordlists/fr.txt
+var wordlistFR string
+
+//go:embed wordlists/en.txt
+var wordlistEN string
+
+const (
+	// DefaultPassphraseWords est la longueur par défaut d'une phrase de passe.
+	DefaultPassphraseWords = 6
+	// MinPassphraseWords et MaxPassphraseWords bornent sa longueur : en deçà de
+	// quatre mots, mieux vaut ne pas appeler ça une phrase de passe.
+	MinPassphraseWords = 4
+	MaxPassphraseWords = 32
+
+	passphraseSeparator = "-"
+	maxWordLen          = 8
+)
+
+// PassphraseLanguages liste les langues des listes embarquées, la langue par
+// défaut en tête.
+var PassphraseLanguages = []string{"fr", "en"}
+
+var (
+	wordlistsOnce sync.Once
+	wordlists     map[string][]string
+)
+
+func wordlist(lang string) ([]string, error) {
+	wordlistsOnce.Do(func() {
+		wordlists = map[string][]string{
+			"fr": strings.Fields(wordlistFR),
+			"en": strings.Fields(wordlistEN),
+		}
+	})
+	w, ok := wordlists[lang]
+	if !ok {
+		return nil, errorf("passphrase.unknown_list", "liste de mots inconnue : %q (attendu %s)", lang, strings.Join(PassphraseLanguages, " ou "))
+	}
+	return w, nil
+}
+
+// GeneratePassphrase tire une phrase de passe de n mots dans la liste de la
+// langue donnée, séparés par des tirets. Elle est rendue en octets, pour être
+// effacée après usage comme un mot de passe saisi.
+func GeneratePassphrase(n int, lang string) ([]byte, error) {
+	if n < MinPassphraseWords || n > MaxPassphraseWords {
+		return nil, errorf("passphrase.words", "nombre de mots hors bornes : %d (attendu %d..%d)", n, MinPassphraseWords, MaxPassphraseWords)
+	}
+	words, err := wordlist(lang)
+	if err != nil {
+		return nil, err
+	}
+	size := big.NewInt(int64(len(words)))
+	// Assez de place d'emblée : une réallocation laisserait des copies
+	// partielles de la phrase derrière elle.
+	out := make([]byte, 0, n*(maxWordLen+len(passphraseSeparator)))
+	for i := range n {
+		// rand.Int tire uniformément dans [0, size) : pas de biais de modulo.
+		idx, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return nil, errorf("passphrase.draw", "tirage de la phrase de passe: %w", err)
+		}
+		if i > 0 {
+			out = append(out, passphraseSeparator...)
+		}
+		out = append(out, words[idx.Int64()]...)
+	}
+	return out, nil
+}
+
+// PassphraseBits rend l'entropie exacte d'une phrase de n mots tirée par
+// GeneratePassphrase. C'est un compte, pas une estimation : l'attaquant qui
+// connaît la liste et la longueur n'a pas moins de possibilités à essayer.
+func PassphraseBits(n int, lang string) (float64, error) {
+	words, err := wordlist(lang)
+	if err != nil {
+		return 0, err
+	}
+	return float64(n) * math.Log2(float64(len(words))), nil
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestListesDeMots(t *testing.T) {
+	forme := regexp.MustCompile(`^[a-z]{3,8}$`)
+	for _, lang := range PassphraseLanguages {
+		words, err := wordlist(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Exactement 2048 : onze bits par mot, sans arrondi.
+		if len(words) != 2048 {
+			t.Errorf("%s : %d mots", lang, len(words))
+		}
+		if !slices.IsSorted(words) || len(slices.Compact(slices.Clone(words))) != len(words) {
+			t.Errorf("%s : liste non triée ou avec des doublons", lang)
+		}
+		for _, w := range words {
+			if !forme.MatchString(w) {
+				t.Errorf("%s : mot %q hors format", lang, w)
+			}
+		}
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	words, _ := wordlist("fr")
+	vues := map[string]bool{}
+	for range 20 {
+		p, err := GeneratePassphrase(DefaultPassphraseWords, "fr")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := strings.Split(string(p), passphraseSeparator)
+		if len(parts) != DefaultPassphraseWords {
+			t.Fatalf("%q : %d mots", p, len(parts))
+		}
+		for _, w := range parts {
+			if _, ok := slices.BinarySearch(words, w); !ok {
+				t.Errorf("%q n'est pas dans la liste", w)
+			}
+		}
+		vues[string(p)] = true
+	}
+	if len(vues) < 20 {
+		t.Error("deux tirages identiques sur vingt : le hasard est en cause")
+	}
+
+	if bits, _ := PassphraseBits(6, "en"); bits != 66 {
+		t.Errorf("six mots : %v bits, attendu 66", bits)
+	}
+	for nom, c := range map[string]struct {
+		n    int
+		lang string
+	}{
+		"trop court":      {MinPassphraseWords - 1, "fr"},
+		"trop long":       {MaxPassphraseWords + 1, "fr"},
+		"langue inconnue": {6, "de"},
+	} {
+		if _, err := GeneratePassphrase(c.n, c.lang); err == nil {
+			t.Errorf("%s : accepté", nom)
+		}
+	}
+}
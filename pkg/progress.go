@@ -0,0 +1,108 @@
+package pkg
+
+// Suivi d'une opération.
+//
+// Options.Progress ne dit que « tant d'octets sur tant » : assez pour une
+// barre, pas pour un programme qui suit un dossier de 500 Go et veut savoir si
+// l'on inventorie encore, si l'on dérive la clé ou quel fichier passe.
+// Options.Events le dit, sans que les chemins de chiffrement aient à le
+// savoir : ils ne connaissent qu'un tracker, nil quand personne n'écoute.
+
+// Phase nomme une étape d'une opération. Les valeurs sont stables : elles
+// sortent telles quelles de -progress ndjson.
+type Phase string
+
+const (
+	PhaseScan    Phase = "scan"    // inventaire d'un dossier, avant de chiffrer
+	PhaseKDF     Phase = "kdf"     // dérivation de clé
+	PhaseEncrypt Phase = "encrypt" // chiffrement du contenu
+	PhaseDecrypt Phase = "decrypt" // déchiffrement, ou vérification, du contenu
+	PhaseExtract Phase = "extract" // déroulement d'une archive dans son dossier
+	PhaseFsync   Phase = "fsync"   // mise sur disque, avant le renommage
+)
+
+// ProgressEvent décrit où en est une opération.
+type ProgressEvent struct {
+	Phase Phase
+	// Done et Total comptent les octets comme Progress : le clair lu au
+	// chiffrement, le chiffré lu au déchiffrement. Total nul : inconnu, ou
+	// sans objet (kdf, fsync). Pendant scan, Done est le volume trouvé
+	// jusque-là.
+	Done, Total int64
+	// Entry est l'entrée d'archive en cours, relative au dossier, avec des
+	// séparateurs '/'. Vide hors archive.
+	Entry string
+}
+
+// tracker relaie l'avancement vers Options.Progress et Options.Events. Ses
+// méthodes acceptent un tracker nil, qui ne fait rien.
+type tracker struct {
+	progress func(done, total int64)
+	events   func(ProgressEvent)
+	ev       ProgressEvent
+}
+
+// track renvoie nil quand personne n'écoute : les lecteurs de progression ne
+// sont alors même pas montés.
+func (o Options) track() *tracker {
+	if o.Progress == nil && o.Events == nil {
+		return nil
+	}
+	return &tracker{progress: o.Progress, events: o.Events}
+}
+
+// enter ouvre une étape, compteurs à zéro.
+func (t *tracker) enter(p Phase, total int64) {
+	if t == nil {
+		return
+	}
+	t.ev = ProgressEvent{Phase: p, Total: total}
+	t.emit()
+}
+
+// rename change le nom de l'étape en cours sans toucher aux compteurs.
+func (t *tracker) rename(p Phase) {
+	if t == nil {
+		return
+	}
+	t.ev.Phase = p
+	t.emit()
+}
+
+// entry annonce l'entrée d'archive qui commence.
+func (t *tracker) entry(rel string) {
+	if t == nil {
+		return
+	}
+	t.ev.Entry = rel
+	t.emit()
+}
+
+// scanned annonce ce que l'inventaire d'un dossier a trouvé jusque-là. Rien
+// vers Progress : ce n'est pas encore du travail fait.
+func (t *tracker) scanned(found int64, rel string) {
+	if t == nil {
+		return
+	}
+	t.ev.Done, t.ev.Entry = found, rel
+	t.emit()
+}
+
+// advance est le seul relais vers Progress : un changement d'étape n'y
+// apparaît pas, une barre ne reculerait que pour rien.
+func (t *tracker) advance(done, total int64) {
+	if t == nil {
+		return
+	}
+	if t.progress != nil {
+		t.progress(done, total)
+	}
+	t.ev.Done, t.ev.Total = done, total
+	t.emit()
+}
+
+func (t *tracker) emit() {
+	if t.events != nil {
+		t.events(t.ev)
+	}
+}
@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// journal retient les événements reçus, et les étapes dans leur ordre
+// d'apparition.
+type journal struct {
+	events []ProgressEvent
+	phases []Phase
+}
+
+func (j *journal) record(e ProgressEvent) {
+	j.events = append(j.events, e)
+	if n := len(j.phases); n == 0 || j.phases[n-1] != e.Phase {
+		j.phases = append(j.phases, e.Phase)
+	}
+}
+
+func (j *journal) entries(p Phase) []string {
+	var out []string
+	for _, e := range j.events {
+		if e.Phase == p && e.Entry != "" && !slices.Contains(out, e.Entry) {
+			out = append(out, e.Entry)
+		}
+	}
+	return out
+}
+
+// last renvoie le dernier événement de l'étape p.
+func (j *journal) last(p Phase) ProgressEvent {
+	var ev ProgressEvent
+	for _, e := range j.events {
+		if e.Phase == p {
+			ev = e
+		}
+	}
+	return ev
+}
+
+func TestEvenementsDossier(t *testing.T) {
+	pw := []byte("motdepassetest123")
+	src := arbre(t)
+	total, err := InputSize(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := filepath.Join(t.TempDir(), "arbre.chto")
+
+	var chiffre journal
+	var barre []int64
+	opts := Options{
+		Argon:    &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1},
+		Events:   chiffre.record,
+		Progress: func(done, _ int64) { barre = append(barre, done) },
+	}
+	if err := Encrypt(src, enc, pw, opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Phase{PhaseScan, PhaseKDF, PhaseEncrypt, PhaseFsync}; !slices.Equal(chiffre.phases, want) {
+		t.Errorf("étapes du chiffrement %v, attendu %v", chiffre.phases, want)
+	}
+	if scan := chiffre.last(PhaseScan); scan.Done != total {
+		t.Errorf("inventaire : %d octets trouvés, attendu %d", scan.Done, total)
+	}
+	if fin := chiffre.last(PhaseEncrypt); fin.Done != total || fin.Total != total {
+		t.Errorf("fin du chiffrement : %d/%d, attendu %d", fin.Done, fin.Total, total)
+	}
+	entrees := chiffre.entries(PhaseEncrypt)
+	if !slices.Contains(entrees, "sous/profond/d é.txt") || !slices.Equal(entrees, chiffre.entries(PhaseScan)) {
+		t.Errorf("entrées chiffrées %v, inventoriées %v", entrees, chiffre.entries(PhaseScan))
+	}
+	// Progress ne voit que le travail fait : ni l'inventaire, ni les
+	// changements d'étape ne le font reculer.
+	if !slices.IsSorted(barre) || len(barre) == 0 || barre[len(barre)-1] != total {
+		t.Errorf("progression %v", barre)
+	}
+
+	var dechiffre journal
+	if _, err := DecryptTo(enc, filepath.Join(t.TempDir(), "relu"), pw, Options{Events: dechiffre.record}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Phase{PhaseKDF, PhaseDecrypt, PhaseExtract}; !slices.Equal(dechiffre.phases, want) {
+		t.Errorf("étapes du déchiffrement %v, attendu %v", dechiffre.phases, want)
+	}
+	if !slices.Equal(dechiffre.entries(PhaseExtract), entrees) {
+		t.Errorf("entrées extraites %v, attendu %v", dechiffre.entries(PhaseExtract), entrees)
+	}
+	if fin := dechiffre.last(PhaseExtract); fin.Total == 0 || fin.Done != fin.Total {
+		t.Errorf("fin de l'extraction : %d/%d", fin.Done, fin.Total)
+	}
+}
+
+func TestEvenementsFichier(t *testing.T) {
+	pw := []byte("motdepassetest123")
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", make([]byte, 300<<10))
+	enc := filepath.Join(dir, "clair.chto")
+	if err := Encrypt(in, enc, pw, Options{Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var j journal
+	if _, err := DecryptTo(enc, filepath.Join(dir, "relu.txt"), pw, Options{Events: j.record}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Phase{PhaseKDF, PhaseDecrypt, PhaseFsync}; !slices.Equal(j.phases, want) {
+		t.Errorf("étapes %v, attendu %v", j.phases, want)
+	}
+	if e := j.entries(PhaseDecrypt); e != nil {
+		t.Errorf("entrées annoncées hors archive : %v", e)
+	}
+}
@@ -0,0 +1,287 @@
+package pkg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// Code de secours.
+//
+// Un mot de passe oublié, et l'archive est perdue. À la demande
+// (Options.RecoveryCode), le fichier s'ouvre aussi avec un code de secours à
+// imprimer : 128 bits tirés au hasard, indépendants du mot de passe.
+//
+// Les deux secrets doivent ouvrir le même contenu : celui-ci est donc chiffré
+// par une clé de fichier aléatoire, scellée deux fois dans l'en-tête — sous la
+// clé dérivée du mot de passe, et sous celle du code. Le bloc suit l'en-tête
+// v4 quand FlagRecovery est posé :
+//
+//	parMotDePasse 48   AES-256-GCM(clé de fichier), nonce nul
+//	parCode       48   idem, sous la clé du code
+//
+// Chaque clé d'emballage ne sert qu'une fois (le sel de l'en-tête est neuf à
+// chaque fichier), d'où le nonce nul. Le bloc fait partie de l'en-tête, donc
+// de l'info HKDF : le modifier rend le fichier illisible, comme tout autre
+// octet de l'en-tête.
+//
+// Le code a toute son entropie : pas de dérivation coûteuse, HKDF-Extract
+// avec le sel de l'en-tête suffit, comme pour une clé symétrique.
+//
+// À l'écrit, le code est une suite de mots : seize pour les seize octets, et
+// deux de plus pour une somme de contrôle qui attrape une faute de recopie.
+// Les quatre premières lettres de chaque mot suffisent à le reconnaître. Sous
+// forme compacte (celle du QR code), c'est « chto-rc-1: » suivi des mêmes
+// octets en base32.
+
+const (
+	// RecoveryCodeSize est la taille d'un code de secours, en octets.
+	RecoveryCodeSize = 16
+
+	recoveryChecksumSize = 2
+	recoveryWords        = RecoveryCodeSize + recoveryChecksumSize
+	recoveryChecksumInfo = "chiffremento-recovery-checksum"
+	recoveryWrapInfo     = "chiffremento-recovery-wrap-"
+	recoveryCompact      = "chto-rc-1:"
+
+	wrappedKeySize    = SymmetricKeySize + 16 // clé + étiquette GCM
+	recoveryBlockSize = 2 * wrappedKeySize
+)
+
+// recoveryWordList compte 256 mots : un octet par mot. Pas d'accent, pour la
+// saisie, et pas deux mots qui partagent leurs quatre premières lettres.
+var recoveryWordList = [256]string{
+	"abeille", "abricot", "acier", "adresse", "agneau", "aigle", "album", "amande",
+	"ampoule", "ancre", "ange", "animal", "anneau", "antenne", "arbre", "arche",
+	"argent", "armoire", "artiste", "asperge", "atelier", "atome", "auberge", "avion",
+	"avocat", "badge", "bagage", "balcon", "baleine", "ballon", "bambou", "banane",
+	"banc", "bandeau", "banjo", "barque", "bassin", "bateau", "bazar", "beignet",
+	"berger", "betail", "beurre", "biche", "bijou", "biscuit", "bison", "blason",
+	"bocal", "bougie", "bouton", "branche", "brique", "brosse", "buffle", "bureau",
+	"cabane", "cactus", "cadeau", "cahier", "caillou", "calcul", "camion", "canard",
+	"canne", "canon", "caramel", "carotte", "casque", "castor", "cerise", "chameau",
+	"chapeau", "chariot", "chateau", "chemin", "cheval", "chiffre", "cigale", "citron",
+	"clairon", "clavier", "cloche", "clown", "cobra", "cocotte", "colis", "colombe",
+	"comete", "compas", "concert", "condor", "corbeau", "cornet", "costume", "coton",
+	"crabe", "crayon", "cygne", "dauphin", "degre", "desert", "diamant", "dindon",
+	"domino", "dragon", "drapeau", "dune", "eclair", "ecole", "effort", "eglise",
+	"elan", "email", "enclume", "encre", "energie", "enigme", "epice", "etoile",
+	"fanfare", "farine", "faucon", "fenetre", "fermier", "festin", "feuille", "ficelle",
+	"figue", "flamant", "fleuve", "flute", "foret", "fourmi", "fraise", "fromage",
+	"fusee", "galet", "gazelle", "girafe", "glacier", "gomme", "gorille", "goudron",
+	"grenier", "grotte", "hamac", "harpe", "hibou", "homard", "hublot", "igloo",
+	"image", "indigo", "iris", "jaguar", "jardin", "jasmin", "jeton", "jungle",
+	"kayak", "koala", "lagune", "lampe", "lapin", "legume", "lezard", "lilas",
+	"limace", "lion", "loutre", "lune", "lutin", "luxe", "maison", "manege",
+	"masque", "melon", "miel", "miroir", "moulin", "mouton", "muguet", "navire",
+	"neige", "nuage", "oasis", "ocean", "oignon", "olive", "ombre", "orange",
+	"ortie", "otarie", "ours", "outil", "panda", "pastel", "patin", "perle",
+	"phare", "piano", "pigeon", "pirate", "plume", "pomme", "pont", "poulpe",
+	"puzzle", "quartz", "quille", "racine", "radis", "raisin", "rameau", "renard",
+	"requin", "rivage", "robot", "rocher", "ruban", "ruche", "sable", "sabot",
+	"safran", "salade", "sapin", "saumon", "savon", "sirop", "soleil", "souris",
+	"statue", "sucre", "tapis", "taupe", "tigre", "tomate", "torche", "tortue",
+	"toupie", "trefle", "tresor", "tulipe", "tunnel", "turban", "vague", "valise",
+	"velo", "verger", "violon", "volcan", "wagon", "yaourt", "zebre", "zeste",
+}
+
+// recoverySlots est le bloc qui suit l'en-tête quand FlagRecovery est posé.
+type recoverySlots struct {
+	ByPassword []byte
+	ByCode     []byte
+}
+
+// GenerateRecoveryCode tire un code de secours.
+func GenerateRecoveryCode() ([]byte, error) {
+	code := make([]byte, RecoveryCodeSize)
+	if _, err := rand.Read(code); err != nil {
+		return nil, errorf("recovery.generate", "génération du code de secours: %w", err)
+	}
+	return code, nil
+}
+
+func recoveryChecksum(code []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(recoveryChecksumInfo))
+	h.Write(code)
+	return h.Sum(nil)[:recoveryChecksumSize]
+}
+
+// FormatRecoveryCode écrit le code pour le papier : dix-huit mots numérotés,
+// six par ligne.
+func FormatRecoveryCode(code []byte) (string, error) {
+	if len(code) != RecoveryCodeSize {
+		return "", errorf("recovery.size", "code de secours de %d octets (attendu %d)", len(code), RecoveryCodeSize)
+	}
+	var b strings.Builder
+	for i, v := range append(append([]byte(nil), code...), recoveryChecksum(code)...) {
+		switch {
+		case i == 0:
+		case i%6 == 0:
+			b.WriteByte('\n')
+		default:
+			b.WriteString("  ")
+		}
+		w := fmt.Sprintf("%2d. %s", i+1, recoveryWordList[v])
+		if i%6 != 5 {
+			w = fmt.Sprintf("%-11s", w)
+		}
+		b.WriteString(w)
+	}
+	return b.String(), nil
+}
+
+// CompactRecoveryCode rend la forme compacte du code, celle du QR code.
+func CompactRecoveryCode(code []byte) (string, error) {
+	if len(code) != RecoveryCodeSize {
+		return "", errorf("recovery.size", "code de secours de %d octets (attendu %d)", len(code), RecoveryCodeSize)
+	}
+	raw := append(append([]byte(nil), code...), recoveryChecksum(code)...)
+	return recoveryCompact + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw), nil
+}
+
+// ParseRecoveryCode relit un code de secours, sous forme de mots ou compacte.
+// Les numéros, la casse et la ponctuation sont ignorés ; un mot se reconnaît à
+// ses quatre premières lettres.
+func ParseRecoveryCode(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	var raw []byte
+	if rest, ok := strings.CutPrefix(strings.ToLower(s), recoveryCompact); ok {
+		b, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(rest))
+		if err != nil || len(b) != recoveryWords {
+			return nil, errorf("recovery.compact", "code de secours compact illisible")
+		}
+		raw = b
+	} else {
+		fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return r < 'a' || r > 'z'
+		})
+		if len(fields) != recoveryWords {
+			return nil, errorf("recovery.word_count", "code de secours de %d mots (attendu %d)", len(fields), recoveryWords)
+		}
+		for i, f := range fields {
+			v, ok := recoveryWordIndex(f)
+			if !ok {
+				return nil, errorf("recovery.unknown_word", "mot %d inconnu : %q", i+1, f)
+			}
+			raw = append(raw, v)
+		}
+	}
+	code, sum := raw[:RecoveryCodeSize], raw[RecoveryCodeSize:]
+	if string(sum) != string(recoveryChecksum(code)) {
+		return nil, errorf("recovery.checksum", "code de secours mal recopié : la somme de contrôle ne correspond pas")
+	}
+	return code, nil
+}
+
+func recoveryWordIndex(w string) (byte, bool) {
+	if len(w) < 4 {
+		return 0, false
+	}
+	for i, cand := range recoveryWordList {
+		if cand[:4] == w[:4] {
+			return byte(i), true
+		}
+	}
+	return 0, false
+}
+
+// wrapAEAD dérive la clé qui scelle un emplacement, à partir de la clé maîtresse
+// du secret correspondant.
+func wrapAEAD(kek []byte, slot string) (cipher.AEAD, error) {
+	k, err := hkdf.Expand(sha256.New, kek, recoveryWrapInfo+slot, 32)
+	if err != nil {
+		return nil, errorf("recovery.wrap_key", "clé d'emballage: %w", err)
+	}
+	defer wipe(k)
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func codeKEK(code []byte, h *header) ([]byte, error) {
+	if len(code) != RecoveryCodeSize {
+		return nil, errorf("recovery.size", "code de secours de %d octets (attendu %d)", len(code), RecoveryCodeSize)
+	}
+	return hkdf.Extract(sha256.New, code, h.Salt)
+}
+
+func seal(kek []byte, slot string, fileKey []byte) ([]byte, error) {
+	aead, err := wrapAEAD(kek, slot)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, make([]byte, aead.NonceSize()), fileKey, nil), nil
+}
+
+// sealRecovery tire la clé de fichier, la scelle sous le mot de passe et sous
+// le code, et inscrit le bloc dans l'en-tête. Elle rend la clé de fichier, qui
+// tient lieu de clé maîtresse pour la suite de la dérivation.
+func (h *header) sealRecovery(password, code []byte) ([]byte, error) {
+	if h.KDF == KDFKey {
+		return nil, errorf("secret.recovery_with_key", "un code de secours double un mot de passe : il n'a pas de sens avec une clé symétrique")
+	}
+	ckek, err := codeKEK(code, h)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(ckek)
+	pkek, err := deriveMaster(password, h)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(pkek)
+
+	fileKey := make([]byte, SymmetricKeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, errorf("recovery.file_key", "génération de la clé de fichier: %w", err)
+	}
+	byPassword, err := seal(pkek, "password", fileKey)
+	if err != nil {
+		wipe(fileKey)
+		return nil, err
+	}
+	byCode, err := seal(ckek, "code", fileKey)
+	if err != nil {
+		wipe(fileKey)
+		return nil, err
+	}
+	h.Flags |= FlagRecovery
+	h.Recovery = &recoverySlots{ByPassword: byPassword, ByCode: byCode}
+	return fileKey, nil
+}
+
+// openRecovery rend la clé de fichier, avec le code s'il est donné, avec le
+// mot de passe sinon. Un mauvais secret est signalé comme tel : l'étiquette
+// GCM de l'emplacement le distingue d'un contenu abîmé.
+func (h *header) openRecovery(password, code []byte) ([]byte, error) {
+	var kek []byte
+	var err error
+	slot, sealed, wrong := "password", h.Recovery.ByPassword, errorf("auth.wrong_password", "%w : mot de passe incorrect", ErrAuthentication)
+	if code != nil {
+		slot, sealed, wrong = "code", h.Recovery.ByCode, errorf("auth.wrong_recovery_code", "%w : code de secours incorrect", ErrAuthentication)
+		kek, err = codeKEK(code, h)
+	} else {
+		kek, err = deriveMaster(password, h)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(kek)
+	aead, err := wrapAEAD(kek, slot)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), sealed, nil)
+	if err != nil {
+		return nil, wrong
+	}
+	return fileKey, nil
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListeDeMotsDeSecours(t *testing.T) {
+	vus := map[string]bool{}
+	for i, w := range recoveryWordList {
+		if len(w) < 4 || strings.Trim(w, "abcdefghijklmnopqrstuvwxyz") != "" {
+			t.Errorf("mot %d %q : quatre lettres minuscules au moins, sans accent", i, w)
+			continue
+		}
+		if vus[w[:4]] {
+			t.Errorf("préfixe %q en double", w[:4])
+		}
+		vus[w[:4]] = true
+	}
+}
+
+func TestLectureCodeDeSecours(t *testing.T) {
+	// Un code fixe : la somme de contrôle n'a que seize bits, et un code tiré
+	// au hasard laisserait passer la faute de recopie une fois sur 65 536.
+	code := []byte("seize octets fix")
+	mots, err := FormatRecoveryCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(mots, "\n"); n != 2 {
+		t.Errorf("%d lignes, attendu 3 :\n%s", n+1, mots)
+	}
+	compact, _ := CompactRecoveryCode(code)
+
+	// Numéros, casse, ponctuation et fin des mots ne comptent pas.
+	var prefixes []string
+	for _, f := range strings.Fields(mots) {
+		if !strings.HasSuffix(f, ".") {
+			prefixes = append(prefixes, strings.ToUpper(f[:4]))
+		}
+	}
+	for nom, s := range map[string]string{
+		"tel qu'imprimé":       mots,
+		"sur une ligne":        strings.Join(strings.Fields(mots), " "),
+		"préfixes":             strings.Join(prefixes, "-"),
+		"compact":              compact,
+		"compact en minuscule": "  " + strings.ToLower(compact) + "\n",
+	} {
+		got, err := ParseRecoveryCode(s)
+		if err != nil || !bytes.Equal(got, code) {
+			t.Errorf("%s : %v", nom, err)
+		}
+	}
+
+	// Une faute de recopie : un mot remplacé par son voisin dans la liste.
+	champs := strings.Fields(strings.Join(strings.Fields(mots), " "))
+	for i, f := range champs {
+		if f == "1." {
+			w := champs[i+1]
+			for j, cand := range recoveryWordList {
+				if cand == w {
+					champs[i+1] = recoveryWordList[(j+1)%256]
+				}
+			}
+		}
+	}
+	for nom, s := range map[string]string{
+		"mot changé":      strings.Join(champs, " "),
+		"mot manquant":    strings.Join(prefixes[1:], " "),
+		"mot inconnu":     "zzzz " + strings.Join(prefixes[1:], " "),
+		"compact tronqué": compact[:len(compact)-2],
+		"vide":            "",
+	} {
+		if _, err := ParseRecoveryCode(s); err == nil {
+			t.Errorf("%s : code accepté", nom)
+		}
+	}
+}
+
+func TestCodeDeSecours(t *testing.T) {
+	dir := t.TempDir()
+	contenu := []byte("archive de famille, mot de passe oublié")
+	in := write(t, dir, "clair.txt", contenu)
+	password := []byte("mot de passe principal")
+	rapide := &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}
+	code, _ := GenerateRecoveryCode()
+
+	for _, algo := range []byte{AlgoAES, AlgoCascade} {
+		enc := filepath.Join(dir, AlgoName(algo)+".chto")
+		if err := Encrypt(in, enc, password, Options{Algo: algo, Argon: rapide, RecoveryCode: code}); err != nil {
+			t.Fatal(err)
+		}
+		if d, err := Inspect(enc); err != nil || !d.Recovery {
+			t.Fatalf("%s : code de secours absent de l'inspection (%+v, %v)", AlgoName(algo), d, err)
+		}
+		// Chacun des deux secrets ouvre seul le même contenu.
+		for nom, o := range map[string]struct {
+			password []byte
+			opts     Options
+		}{
+			"mot de passe":                   {password, Options{}},
+			"code":                           {nil, Options{RecoveryCode: code}},
+			"code, mot de passe faux ignoré": {[]byte("faux"), Options{RecoveryCode: code}},
+		} {
+			out := filepath.Join(dir, AlgoName(algo)+".txt")
+			os.Remove(out)
+			if err := Decrypt(enc, out, o.password, o.opts); err != nil {
+				t.Fatalf("%s, %s : %v", AlgoName(algo), nom, err)
+			}
+			if got, _ := os.ReadFile(out); !bytes.Equal(got, contenu) {
+				t.Errorf("%s, %s : contenu %q", AlgoName(algo), nom, got)
+			}
+		}
+	}
+
+	enc := filepath.Join(dir, AlgoName(AlgoAES)+".chto")
+	autre, _ := GenerateRecoveryCode()
+	if err := Verify(enc, nil, Options{RecoveryCode: autre}); err == nil || !strings.Contains(err.Error(), "code de secours incorrect") {
+		t.Errorf("mauvais code : %v", err)
+	}
+	if err := Verify(enc, []byte("faux"), Options{}); err == nil || !strings.Contains(err.Error(), "mot de passe incorrect") {
+		t.Errorf("mauvais mot de passe : %v", err)
+	}
+
+	// Le bloc fait partie de l'en-tête : y toucher, c'est perdre le fichier,
+	// même avec le secret de l'autre emplacement.
+	raw, _ := os.ReadFile(enc)
+	raw[headerSizeV4+wrappedKeySize+3] ^= 1
+	falsifie := write(t, dir, "falsifie.chto", raw)
+	if err := Verify(falsifie, nil, Options{RecoveryCode: code}); err == nil {
+		t.Error("emplacement modifié accepté")
+	}
+	if err := Verify(falsifie, password, Options{}); err == nil {
+		t.Error("en-tête modifié accepté par le mot de passe")
+	}
+
+	// Sans code de secours, le fichier le dit.
+	sans := filepath.Join(dir, "sans.chto")
+	if err := Encrypt(in, sans, password, Options{Argon: rapide}); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := Inspect(sans); d.Recovery {
+		t.Error("code de secours annoncé sans en avoir")
+	}
+	if err := Verify(sans, nil, Options{RecoveryCode: code}); err == nil || !strings.Contains(err.Error(), "pas de code de secours") {
+		t.Errorf("code sur un fichier qui n'en a pas : %v", err)
+	}
+
+	key, _ := GenerateKey()
+	if err := Encrypt(in, filepath.Join(dir, "refus.chto"), nil, Options{Key: key, RecoveryCode: code}); err == nil {
+		t.Error("code de secours accepté avec une clé symétrique")
+	}
+	if err := Encrypt(in, filepath.Join(dir, "refus.chto"), password, Options{Argon: rapide, RecoveryCode: code[:8]}); err == nil {
+		t.Error("code de secours trop court accepté")
+	}
+}
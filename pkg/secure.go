@@ -0,0 +1,86 @@
+package pkg
+
+// Mémoire protégée pour les secrets.
+//
+// wipe efface un tampon, mais ne dit rien de ce qu'il a traversé avant : une
+// page du tas peut partir dans le swap ou dans un vidage mémoire, et une
+// string ne s'efface pas du tout. SecureBuffer range un secret hors du tas Go :
+//
+//   - dans une zone à part (mmap, VirtualAlloc), que le ramasse-miettes ne
+//     connaît pas et ne recopie donc jamais ;
+//   - verrouillée en mémoire (mlock, VirtualLock) : jamais écrite dans le
+//     swap, et sous Linux exclue des vidages mémoire ;
+//   - encadrée de deux pages de garde sans aucun droit. Le secret est calé
+//     contre la page haute : un débordement d'un seul octet, en lecture comme
+//     en écriture, arrête le processus au lieu de lire le voisin ;
+//   - effacée puis rendue au système par Destroy.
+//
+// Le verrouillage peut être refusé (RLIMIT_MEMLOCK, 64 Kio sur certains
+// systèmes) : le tampon reste utilisable, hors tas et gardé, et Locked le dit.
+//
+// Ce qui sort du tampon n'est plus protégé. Les implémentations d'AES et de
+// ChaCha20 recopient la clé dans leur propre état, Argon2 et scrypt hachent le
+// mot de passe dans des états intermédiaires, PBKDF2 le prend en string : ces
+// copies-là sont courtes et locales, mais hors de portée.
+
+var errSecureSize = errorf("secure.negative_size", "taille de tampon protégé négative")
+
+// SecureBuffer est un tampon de taille fixe hors du tas Go. Après Destroy, la
+// zone est rendue au système : toute tranche obtenue par Bytes devient
+// inutilisable, et y accéder arrête le processus.
+type SecureBuffer struct {
+	region []byte // zone entière, pages de garde comprises
+	data   []byte
+	locked bool
+}
+
+// NewSecureBuffer alloue un tampon protégé de size octets, à zéro.
+func NewSecureBuffer(size int) (*SecureBuffer, error) {
+	if size < 0 {
+		return nil, errSecureSize
+	}
+	region, data, locked, err := secureAlloc(size)
+	if err != nil {
+		return nil, err
+	}
+	return &SecureBuffer{region: region, data: data[:size:size], locked: locked}, nil
+}
+
+// SecureCopy range src dans un tampon protégé, puis efface src.
+func SecureCopy(src []byte) (*SecureBuffer, error) {
+	b, err := NewSecureBuffer(len(src))
+	if err != nil {
+		wipe(src)
+		return nil, err
+	}
+	copy(b.data, src)
+	wipe(src)
+	return b, nil
+}
+
+// Bytes rend le contenu. Sa capacité est sa longueur : un append recopierait
+// le secret sur le tas au lieu de déborder sur la page de garde. nil sur un
+// tampon nil ou détruit.
+func (b *SecureBuffer) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	return b.data
+}
+
+// Len rend la taille du contenu.
+func (b *SecureBuffer) Len() int { return len(b.Bytes()) }
+
+// Locked indique si le tampon est verrouillé en mémoire.
+func (b *SecureBuffer) Locked() bool { return b != nil && b.locked }
+
+// Destroy efface le tampon et le rend au système. Sans effet sur un tampon nil
+// ou déjà détruit.
+func (b *SecureBuffer) Destroy() {
+	if b == nil || b.region == nil {
+		return
+	}
+	wipe(b.data)
+	secureFree(b.region, b.locked)
+	b.region, b.data, b.locked = nil, nil, false
+}
@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package pkg
+
+// Ailleurs, ni verrouillage ni pages de garde : le tampon vit sur le tas, et
+// seul l'effacement de Destroy reste.
+func secureAlloc(size int) (region, data []byte, locked bool, err error) {
+	region = make([]byte, max(1, size))
+	return region, region[:size], false, nil
+}
+
+func secureFree([]byte, bool) {}
@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/debug"
+	"testing"
+	"unsafe"
+)
+
+func TestTamponProtege(t *testing.T) {
+	src := []byte("clé de fichier, trente-deux o. ")
+	want := bytes.Clone(src)
+	b, err := SecureCopy(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("contenu %q", b.Bytes())
+	}
+	if !bytes.Equal(src, make([]byte, len(src))) {
+		t.Error("la source n'a pas été effacée")
+	}
+	if cap(b.Bytes()) != b.Len() {
+		t.Errorf("capacité %d pour %d octets : un append déborderait", cap(b.Bytes()), b.Len())
+	}
+	t.Logf("verrouillé en mémoire : %v", b.Locked())
+
+	b.Destroy()
+	b.Destroy()
+	if b.Bytes() != nil || b.Len() != 0 || b.Locked() {
+		t.Error("tampon encore lisible après Destroy")
+	}
+	var vide *SecureBuffer
+	vide.Destroy()
+	if vide.Bytes() != nil {
+		t.Error("tampon nil non vide")
+	}
+	if _, err := NewSecureBuffer(-1); err == nil {
+		t.Error("taille négative acceptée")
+	}
+	z, err := NewSecureBuffer(0)
+	if err != nil || z.Len() != 0 {
+		t.Errorf("tampon vide : %v", err)
+	}
+	z.Destroy()
+}
+
+// TestPageDeGarde lit l'octet qui suit le secret : il tombe sur la page de
+// garde, et SetPanicOnFault change l'arrêt du processus en panique récupérable.
+func TestPageDeGarde(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "wasip1", "plan9":
+		t.Skip("pas de pages de garde sur ce système")
+	}
+	b, err := NewSecureBuffer(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Destroy()
+	apres := unsafe.Add(unsafe.Pointer(unsafe.SliceData(b.Bytes())), b.Len())
+
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	faute := func() (ok bool) {
+		defer func() { ok = recover() != nil }()
+		_ = *(*byte)(apres)
+		return false
+	}()
+	if !faute {
+		t.Error("lecture au-delà du tampon permise")
+	}
+}
+
+func TestClesProtegees(t *testing.T) {
+	h := &header{Version: versionV4, Algo: AlgoCascade, Raw: []byte("en-tête")}
+	ks, err := expandKeys(bytes.Repeat([]byte{7}, 32), h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ks.bufs) != 1 || len(ks.Inner) == 0 || len(ks.Outer) == 0 {
+		t.Fatalf("clés hors tampon protégé : %+v", ks)
+	}
+	ks.wipe()
+	ks.wipe()
+	if ks.Inner != nil || ks.Outer != nil || ks.bufs != nil {
+		t.Error("clés encore référencées après wipe")
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package pkg
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// secureAlloc réserve pages de garde et pages de données d'un seul mmap, puis
+// retire tout droit aux deux pages extrêmes. data est calé contre la page de
+// garde haute.
+func secureAlloc(size int) (region, data []byte, locked bool, err error) {
+	page := os.Getpagesize()
+	inner := max(1, (size+page-1)/page) * page
+	region, err = unix.Mmap(-1, 0, inner+2*page, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, nil, false, errorf("secure.alloc", "allocation d'un tampon protégé: %w", err)
+	}
+	if err := unix.Mprotect(region[:page], unix.PROT_NONE); err == nil {
+		err = unix.Mprotect(region[page+inner:], unix.PROT_NONE)
+	}
+	if err != nil {
+		unix.Munmap(region)
+		return nil, nil, false, errorf("secure.guard_pages", "pages de garde: %w", err)
+	}
+	mid := region[page : page+inner]
+	locked = unix.Mlock(mid) == nil
+	excludeFromDump(mid)
+	return region, mid[inner-size:], locked, nil
+}
+
+func secureFree(region []byte, locked bool) {
+	page := os.Getpagesize()
+	mid := region[page : len(region)-page]
+	if locked {
+		unix.Munlock(mid)
+	}
+	unix.Munmap(region)
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package pkg
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// secureAlloc : même disposition que sous Unix, avec VirtualAlloc,
+// VirtualProtect et VirtualLock.
+func secureAlloc(size int) (region, data []byte, locked bool, err error) {
+	page := os.Getpagesize()
+	inner := max(1, (size+page-1)/page) * page
+	total := inner + 2*page
+	addr, err := windows.VirtualAlloc(0, uintptr(total), windows.MEM_RESERVE|windows.MEM_COMMIT, windows.PAGE_READWRITE)
+	if err != nil {
+		return nil, nil, false, errorf("secure.alloc", "allocation d'un tampon protégé: %w", err)
+	}
+	var old uint32
+	if err = windows.VirtualProtect(addr, uintptr(page), windows.PAGE_NOACCESS, &old); err == nil {
+		err = windows.VirtualProtect(addr+uintptr(page+inner), uintptr(page), windows.PAGE_NOACCESS, &old)
+	}
+	if err != nil {
+		windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+		return nil, nil, false, errorf("secure.guard_pages", "pages de garde: %w", err)
+	}
+	// La zone n'appartient pas au tas Go : la réinterpréter en pointeur ne
+	// trompe pas le ramasse-miettes.
+	region = unsafe.Slice(*(**byte)(unsafe.Pointer(&addr)), total)
+	locked = windows.VirtualLock(addr+uintptr(page), uintptr(inner)) == nil
+	return region, region[page+inner-size : page+inner], locked, nil
+}
+
+func secureFree(region []byte, locked bool) {
+	page := os.Getpagesize()
+	addr := uintptr(unsafe.Pointer(unsafe.SliceData(region)))
+	if locked {
+		windows.VirtualUnlock(addr+uintptr(page), uintptr(len(region)-2*page))
+	}
+	windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+}
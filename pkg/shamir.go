@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// Partage de secret de Shamir.
+//
+// Pour qu'aucune personne seule ne puisse déchiffrer, mais que K d'entre N le
+// puissent ensemble, le fichier est chiffré par une clé symétrique tirée au
+// hasard (Options.Key, donc le même chemin keySet que -key-file, pour tous les
+// algorithmes), et cette clé est découpée en N parts. Chaque octet de la clé
+// est le terme constant d'un polynôme aléatoire de degré K−1 sur GF(2⁸) ; la
+// part i porte l'évaluation de ces polynômes en x = i. K parts donnent la clé
+// par interpolation de Lagrange en 0 ; K−1 n'en disent strictement rien.
+//
+// Une part est une ligne de texte, imprimable et recopiable :
+//
+//	chto-share-1:<base64url(ensemble ‖ K ‖ x ‖ y ‖ somme)>
+//
+// L'ensemble identifie le découpage, pour refuser de mélanger les parts de
+// deux fichiers : ce sont les huit premiers octets de SHA-256(étiquette ‖
+// clé), qui permettent aussi de vérifier la clé reconstituée avant de
+// l'essayer. La somme (quatre octets, comme pour les fichiers de clé) attrape
+// une part mal recopiée.
+
+const (
+	sharePrefix      = "chto-share-1:"
+	shareSetSize     = 8
+	shareSetInfo     = "chiffremento-share-set"
+	shareSumInfo     = "chiffremento-share-checksum"
+	shareBodySize    = shareSetSize + 2 + SymmetricKeySize
+	shareEncodedSize = shareBodySize + keyChecksumSize
+
+	// MaxShares est le nombre maximal de parts : x parcourt GF(2⁸) privé de 0.
+	MaxShares = 255
+)
+
+// Share est une part de clé relue.
+type Share struct {
+	Set       [shareSetSize]byte
+	Threshold int
+	Index     int
+	y         []byte
+}
+
+func shareSet(key []byte) (set [shareSetSize]byte) {
+	h := sha256.New()
+	h.Write([]byte(shareSetInfo))
+	h.Write(key)
+	copy(set[:], h.Sum(nil))
+	return set
+}
+
+func shareChecksum(body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(shareSumInfo))
+	h.Write(body)
+	return h.Sum(nil)[:keyChecksumSize]
+}
+
+// SplitKey découpe une clé symétrique en n parts, dont k suffisent à la
+// reconstituer. Chaque part est rendue encodée, sans fin de ligne.
+func SplitKey(key []byte, n, k int) ([][]byte, error) {
+	if len(key) != SymmetricKeySize {
+		return nil, errorf("key.size", "clé symétrique de %d octets (attendu %d)", len(key), SymmetricKeySize)
+	}
+	switch {
+	case n < 2 || n > MaxShares:
+		return nil, errorf("shamir.shares", "nombre de parts hors bornes : %d (attendu 2..%d)", n, MaxShares)
+	case k < 2 || k > n:
+		return nil, errorf("shamir.threshold", "seuil hors bornes : %d (attendu 2..%d)", k, n)
+	}
+
+	// coeffs[j] contient les coefficients de degré 1..k−1 du polynôme de
+	// l'octet j de la clé.
+	coeffs := make([]byte, SymmetricKeySize*(k-1))
+	defer wipe(coeffs)
+	if _, err := rand.Read(coeffs); err != nil {
+		return nil, errorf("shamir.draw", "tirage des parts: %w", err)
+	}
+
+	set := shareSet(key)
+	enc := base64.RawURLEncoding
+	shares := make([][]byte, n)
+	raw := make([]byte, shareEncodedSize)
+	defer wipe(raw)
+	for i := 1; i <= n; i++ {
+		x := byte(i)
+		copy(raw, set[:])
+		raw[shareSetSize] = byte(k)
+		raw[shareSetSize+1] = x
+		y := raw[shareSetSize+2 : shareBodySize]
+		for j := range key {
+			// Horner, du coefficient de plus haut degré au terme constant.
+			var acc byte
+			c := coeffs[j*(k-1) : (j+1)*(k-1)]
+			for d := len(c) - 1; d >= 0; d-- {
+				acc = gfMul(acc, x) ^ c[d]
+			}
+			y[j] = gfMul(acc, x) ^ key[j]
+		}
+		copy(raw[shareBodySize:], shareChecksum(raw[:shareBodySize]))
+		shares[i-1] = append([]byte(sharePrefix), enc.AppendEncode(nil, raw)...)
+	}
+	return shares, nil
+}
+
+// ParseShare relit une part. Les lignes vides et celles qui commencent par #
+// sont ignorées : un fichier de part peut porter ses explications.
+func ParseShare(data []byte) (Share, error) {
+	var s Share
+	var line []byte
+	for _, l := range bytes.Split(data, []byte("\n")) {
+		l = bytes.TrimSpace(l)
+		if len(l) == 0 || l[0] == '#' {
+			continue
+		}
+		if line != nil {
+			return s, errorf("shamir.one_per_file", "une seule part par fichier")
+		}
+		line = l
+	}
+	body, ok := bytes.CutPrefix(line, []byte(sharePrefix))
+	if !ok {
+		return s, errorf("shamir.not_a_share", "ce n'est pas une part chiffremento (attendu une ligne %q…)", sharePrefix)
+	}
+	enc := base64.RawURLEncoding
+	if enc.DecodedLen(len(body)) != shareEncodedSize {
+		return s, errorf("shamir.length", "part de longueur invalide : mal recopiée ?")
+	}
+	raw := make([]byte, shareEncodedSize)
+	defer wipe(raw)
+	if _, err := enc.Decode(raw, body); err != nil {
+		return s, errorf("shamir.unreadable", "part illisible : %w", err)
+	}
+	if subtle.ConstantTimeCompare(raw[shareBodySize:], shareChecksum(raw[:shareBodySize])) != 1 {
+		return s, errorf("shamir.checksum", "part corrompue : la somme de contrôle ne correspond pas (part mal recopiée ?)")
+	}
+	copy(s.Set[:], raw)
+	s.Threshold = int(raw[shareSetSize])
+	s.Index = int(raw[shareSetSize+1])
+	if s.Threshold < 2 || s.Index == 0 {
+		return s, errorf("shamir.inconsistent", "part incohérente : seuil ou indice invalide")
+	}
+	s.y = bytes.Clone(raw[shareSetSize+2 : shareBodySize])
+	return s, nil
+}
+
+// Wipe efface le contenu secret de la part.
+func (s *Share) Wipe() { wipe(s.y) }
+
+// CombineShares reconstitue la clé à partir d'au moins Threshold parts du même
+// ensemble. Au-delà du seuil, les parts en trop sont ignorées.
+func CombineShares(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errorf("shamir.none", "aucune part fournie")
+	}
+	first := shares[0]
+	seen := map[int]bool{}
+	var use []Share
+	for _, s := range shares {
+		if s.Set != first.Set || s.Threshold != first.Threshold {
+			return nil, errorf("shamir.mixed", "ces parts ne proviennent pas du même fichier")
+		}
+		if seen[s.Index] {
+			return nil, errorf("shamir.duplicate", "la part %d est fournie deux fois", s.Index)
+		}
+		seen[s.Index] = true
+		if len(use) < first.Threshold {
+			use = append(use, s)
+		}
+	}
+	if len(use) < first.Threshold {
+		return nil, errorf("shamir.not_enough", "%d part(s) sur les %d nécessaires", len(use), first.Threshold)
+	}
+
+	// Lagrange en 0 : clé = Σ yᵢ · Π_{j≠i} xⱼ / (xⱼ − xᵢ). En caractéristique 2,
+	// la soustraction est un XOR.
+	key := make([]byte, SymmetricKeySize)
+	for i, si := range use {
+		num, den := byte(1), byte(1)
+		xi := byte(si.Index)
+		for j, sj := range use {
+			if i == j {
+				continue
+			}
+			xj := byte(sj.Index)
+			num = gfMul(num, xj)
+			den = gfMul(den, xj^xi)
+		}
+		l := gfMul(num, gfInv(den))
+		for b := range key {
+			key[b] ^= gfMul(si.y[b], l)
+		}
+	}
+	if set := shareSet(key); subtle.ConstantTimeCompare(set[:], first.Set[:]) != 1 {
+		wipe(key)
+		return nil, errorf("shamir.wrong", "les parts ne reconstituent pas la clé : l'une d'elles est fausse")
+	}
+	return key, nil
+}
+
+// gfMul multiplie dans GF(2⁸) modulo x⁸ + x⁴ + x³ + x + 1, sans branche ni
+// table dépendant des opérandes : le temps ne dit rien des octets de la clé.
+func gfMul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		p ^= a & -(b & 1)
+		hi := -(a >> 7)
+		a = a<<1 ^ 0x1b&hi
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv rend l'inverse de a (non nul) : a²⁵⁴, puisque a²⁵⁵ = 1.
+func gfInv(a byte) byte {
+	r := byte(1)
+	for range 254 {
+		r = gfMul(r, a)
+	}
+	return r
+}
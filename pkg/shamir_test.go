@@ -0,0 +1,163 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gfMulLent est la multiplication d'école, pour contrôler gfMul.
+func gfMulLent(a, b byte) byte {
+	var p byte
+	for b != 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+func TestCorpsGF256(t *testing.T) {
+	for a := range 256 {
+		for b := range 256 {
+			if got, want := gfMul(byte(a), byte(b)), gfMulLent(byte(a), byte(b)); got != want {
+				t.Fatalf("%d·%d = %d, attendu %d", a, b, got, want)
+			}
+		}
+		if a != 0 && gfMul(byte(a), gfInv(byte(a))) != 1 {
+			t.Fatalf("inverse de %d faux", a)
+		}
+	}
+}
+
+func parts(t *testing.T, key []byte, n, k int) []Share {
+	t.Helper()
+	encoded, err := SplitKey(key, n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]Share, n)
+	for i, e := range encoded {
+		if !bytes.HasPrefix(e, []byte(sharePrefix)) {
+			t.Fatalf("part %d mal préfixée : %q", i+1, e)
+		}
+		if out[i], err = ParseShare(append([]byte("# commentaire\n\n"), e...)); err != nil {
+			t.Fatalf("part %d : %v", i+1, err)
+		}
+	}
+	return out
+}
+
+func TestPartageDeShamir(t *testing.T) {
+	key, _ := GenerateKey()
+	all := parts(t, key, 5, 3)
+
+	// Tous les sous-ensembles de trois parts, dans tous les ordres de départ.
+	for i := range all {
+		for j := i + 1; j < len(all); j++ {
+			for k := j + 1; k < len(all); k++ {
+				for _, lot := range [][]Share{{all[i], all[j], all[k]}, {all[k], all[i], all[j]}} {
+					got, err := CombineShares(lot)
+					if err != nil || !bytes.Equal(got, key) {
+						t.Fatalf("parts %d,%d,%d : %v", i+1, j+1, k+1, err)
+					}
+				}
+			}
+		}
+	}
+	if got, err := CombineShares(all); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("cinq parts : %v", err)
+	}
+
+	if _, err := CombineShares(all[:2]); err == nil || !strings.Contains(err.Error(), "2 part(s) sur les 3") {
+		t.Errorf("sous le seuil : %v", err)
+	}
+	if _, err := CombineShares([]Share{all[0], all[0], all[1]}); err == nil || !strings.Contains(err.Error(), "deux fois") {
+		t.Errorf("part en double : %v", err)
+	}
+	autre, _ := GenerateKey()
+	etrangere := parts(t, autre, 5, 3)
+	if _, err := CombineShares([]Share{all[0], all[1], etrangere[2]}); err == nil || !strings.Contains(err.Error(), "même fichier") {
+		t.Errorf("parts mélangées : %v", err)
+	}
+
+	// Une part falsifiée avec une somme recalculée passe la lecture, mais pas
+	// le contrôle de la clé reconstituée.
+	fausse := all[2]
+	fausse.y = bytes.Clone(fausse.y)
+	fausse.y[0] ^= 1
+	if _, err := CombineShares([]Share{all[0], all[1], fausse}); err == nil || !strings.Contains(err.Error(), "fausse") {
+		t.Errorf("part falsifiée : %v", err)
+	}
+
+	for nom, nk := range map[string][2]int{
+		"seuil 1":       {5, 1},
+		"seuil > parts": {3, 4},
+		"une part":      {1, 1},
+		"trop de parts": {256, 3},
+	} {
+		if _, err := SplitKey(key, nk[0], nk[1]); err == nil {
+			t.Errorf("%s : découpage accepté", nom)
+		}
+	}
+}
+
+func TestLecturePartRefus(t *testing.T) {
+	key, _ := GenerateKey()
+	encoded, _ := SplitKey(key, 2, 2)
+	part := encoded[0]
+
+	enc := base64.RawURLEncoding
+	raw, _ := enc.DecodeString(string(part[len(sharePrefix):]))
+	raw[shareSetSize+2] ^= 1
+	alteree := append([]byte(sharePrefix), enc.EncodeToString(raw)...)
+
+	for nom, data := range map[string][]byte{
+		"vide":         nil,
+		"autre format": []byte("chto-key-1:abc"),
+		"tronquée":     part[:len(part)-3],
+		"altérée":      alteree,
+		"deux parts":   append(append(bytes.Clone(part), '\n'), encoded[1]...),
+	} {
+		if _, err := ParseShare(data); err == nil {
+			t.Errorf("%s : part acceptée", nom)
+		}
+	}
+}
+
+// TestChiffrementParParts passe par la clé reconstituée pour chaque suite :
+// le partage ne touche qu'au secret, pas au chiffrement.
+func TestChiffrementParParts(t *testing.T) {
+	dir := t.TempDir()
+	contenu := []byte("coffre de reprise d'activité")
+	in := write(t, dir, "clair.txt", contenu)
+	key, _ := GenerateKey()
+	all := parts(t, key, 5, 3)
+
+	for _, algo := range []byte{AlgoAES, AlgoChaCha, AlgoCascade, AlgoAEGIS} {
+		enc := filepath.Join(dir, AlgoName(algo)+".chto")
+		if err := Encrypt(in, enc, nil, Options{Algo: algo, Key: key}); err != nil {
+			t.Fatal(err)
+		}
+		reconstituee, err := CombineShares([]Share{all[4], all[1], all[3]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(dir, AlgoName(algo)+".txt")
+		if err := Decrypt(enc, out, nil, Options{Key: reconstituee}); err != nil {
+			t.Fatalf("%s : %v", AlgoName(algo), err)
+		}
+		if got, _ := os.ReadFile(out); !bytes.Equal(got, contenu) {
+			t.Errorf("%s : contenu %q", AlgoName(algo), got)
+		}
+	}
+}
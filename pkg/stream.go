@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Chiffrement et déchiffrement en writer et reader.
+//
+// EncryptStream et DecryptStream copient d'un bout à l'autre : pour les
+// brancher sur un programme qui produit ses données au fil de l'eau, il
+// fallait un io.Pipe et une goroutine. NewEncryptWriter et NewDecryptReader
+// rendent directement les deux bouts de la chaîne, montés par openEncrypted et
+// openDecrypted comme pour les copies : les deux chemins ne peuvent pas
+// diverger.
+
+// NewEncryptWriter écrit l'en-tête dans dst et rend un writer qui chiffre ce
+// qu'on y écrit. Close scelle le dernier bloc et efface les clés : sans lui,
+// le fichier est tronqué et sera refusé au déchiffrement. Close ne ferme pas
+// dst.
+//
+// La taille du clair n'est pas connue d'avance : opts.Pad est refusé, et
+// opts.Metadata, opts.Progress et opts.Events sont sans effet. La dérivation de clé a lieu
+// ici, avant le retour.
+func NewEncryptWriter(dst io.Writer, password []byte, opts Options) (io.WriteCloser, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Pad {
+		return nil, errorf("pad.needs_size_writer", "le remplissage exige une taille d'entrée connue : impossible sur un writer")
+	}
+	return openEncrypted(context.Background(), dst, password, opts, payloadLayout{}, nil)
+}
+
+// NewDecryptReader lit l'en-tête de src, dérive les clés à partir du mot de
+// passe et rend un reader du clair, avec l'en-tête lu.
+//
+// Comme DecryptStream, le clair sort au fil de l'eau : chaque bloc est
+// authentifié avant d'être rendu, mais une troncature n'est signalée qu'à la
+// fin, par une erreur de Read. Il faut lire jusqu'à io.EOF avant de se fier au
+// contenu. Pour un dossier, le reader rend le flux tar. Close efface les clés
+// et ne ferme pas src.
+func NewDecryptReader(src io.Reader, password []byte) (io.ReadCloser, *Header, error) {
+	return NewDecryptReaderWithOptions(src, password, Options{})
+}
+
+// NewDecryptReaderWithOptions est NewDecryptReader avec des options, qui jouent
+// le même rôle que pour DecryptStream (Key, RecoveryCode, MaxKDFMemory,
+// Progress, Events).
+func NewDecryptReaderWithOptions(src io.Reader, password []byte, opts Options) (io.ReadCloser, *Header, error) {
+	r, h, closeSrc, err := openDecrypted(context.Background(), src, 0, password, opts, opts.track())
+	if err != nil {
+		return nil, nil, err
+	}
+	return &decryptReader{r: r, close: closeSrc}, &Header{h: h}, nil
+}
+
+type decryptReader struct {
+	r     io.Reader
+	close func()
+}
+
+func (d *decryptReader) Read(b []byte) (int, error) {
+	if d.close == nil {
+		return 0, errReaderClosed
+	}
+	return d.r.Read(b)
+}
+
+var errReaderClosed = errorf("stream.read_after_close", "lecture après la fermeture du déchiffrement")
+
+func (d *decryptReader) Close() error {
+	if d.close != nil {
+		d.close()
+		d.close = nil
+	}
+	return nil
+}
+
+// Header est l'en-tête d'un .chto tel que lu par NewDecryptReader, en lecture
+// seule.
+type Header struct {
+	h *header
+}
+
+// Version rend la version du format.
+func (h *Header) Version() byte { return h.h.Version }
+
+// Algo rend l'identifiant de l'algorithme (AlgoAES, AlgoChaCha…).
+func (h *Header) Algo() byte { return h.h.Algo }
+
+// Comp rend l'identifiant de la compression (CompNone, CompGzip…).
+func (h *Header) Comp() byte { return h.h.Comp }
+
+// Archive indique que le clair est un flux tar.
+func (h *Header) Archive() bool { return h.h.archive() }
+
+// Metadata rend une copie du nom d'origine et de la date, ou nil si le fichier
+// n'en porte pas. Lues à l'intérieur du chiffrement, donc authentifiées.
+func (h *Header) Metadata() *FileMetadata {
+	if h.h.Meta == nil {
+		return nil
+	}
+	m := *h.h.Meta
+	return &m
+}
+
+// Raw rend une copie des octets de l'en-tête, tels qu'authentifiés.
+func (h *Header) Raw() []byte { return bytes.Clone(h.h.Raw) }
+
+// Details résume l'en-tête comme Inspect.
+func (h *Header) Details() Details { return detailsOf(h.h) }
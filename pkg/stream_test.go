@@ -0,0 +1,139 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriterReader(t *testing.T) {
+	pw := []byte("motdepassetest123")
+	content := bytes.Repeat([]byte("ligne de journal, écrite au fil de l'eau\n"), 5000)
+
+	for _, opts := range []Options{
+		{Algo: AlgoAES},
+		{Algo: AlgoChaCha, Comp: CompZstd},
+		{Algo: AlgoCascade, Comp: CompZstd},
+		{Algo: AlgoAEGIS},
+	} {
+		opts.Argon = &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}
+		t.Run(AlgoName(opts.Algo), func(t *testing.T) {
+			var chto bytes.Buffer
+			w, err := NewEncryptWriter(&chto, pw, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Des écritures de toutes tailles, comme un programme qui journalise.
+			for rest := content; len(rest) > 0; {
+				n := min(len(rest), 1+len(rest)%7919)
+				if _, err := w.Write(rest[:n]); err != nil {
+					t.Fatal(err)
+				}
+				rest = rest[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Errorf("second Close : %v", err)
+			}
+			if _, err := w.Write([]byte("x")); err == nil {
+				t.Error("écriture acceptée après Close")
+			}
+
+			// Le writer produit un .chto ordinaire…
+			var clair bytes.Buffer
+			if err := DecryptStream(&clair, bytes.NewReader(chto.Bytes()), pw, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(clair.Bytes(), content) {
+				t.Fatal("le contenu relu par DecryptStream diffère")
+			}
+
+			// …que le reader relit.
+			r, h, err := NewDecryptReader(bytes.NewReader(chto.Bytes()), pw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Close()
+			if !bytes.Equal(got, content) {
+				t.Fatal("le contenu relu par NewDecryptReader diffère")
+			}
+			if h.Version() != currentVersion || h.Algo() != opts.Algo || h.Comp() != opts.Comp || h.Archive() || h.Metadata() != nil {
+				t.Errorf("en-tête inattendu : %+v", h.Details())
+			}
+			raw := h.Raw()
+			raw[0] ^= 0xff
+			if bytes.Equal(raw, h.Raw()) {
+				t.Error("Raw rend l'en-tête lui-même, pas une copie")
+			}
+			if _, err := r.Read(make([]byte, 1)); err == nil {
+				t.Error("lecture acceptée après Close")
+			}
+		})
+	}
+}
+
+func TestWriterNonFermeEstTronque(t *testing.T) {
+	pw := []byte("motdepassetest123")
+	opts := Options{Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}}
+	var chto bytes.Buffer
+	w, err := NewEncryptWriter(&chto, pw, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("x"), 200<<10)); err != nil {
+		t.Fatal(err)
+	}
+	// Pas de Close : le dernier bloc n'est jamais scellé.
+	r, _, err := NewDecryptReader(&chto, pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := io.Copy(io.Discard, r); err == nil {
+		t.Error("flux jamais scellé accepté")
+	}
+
+	if _, err := NewEncryptWriter(io.Discard, pw, Options{Pad: true}); err == nil {
+		t.Error("remplissage accepté sans taille connue")
+	}
+}
+
+func TestReaderMetadonnees(t *testing.T) {
+	pw := []byte("motdepassetest123")
+	opts := Options{Argon: &ArgonParams{Time: 1, MemoryKiB: 8 << 10, Threads: 1}, Metadata: MetadataMinimal}
+	dir := t.TempDir()
+	in := write(t, dir, "rapport.txt", []byte("contenu"))
+	enc := filepath.Join(dir, "rapport.chto")
+	if err := Encrypt(in, enc, pw, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	r, h, err := NewDecryptReader(bytes.NewReader(mustRead(t, enc)), pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if m := h.Metadata(); m == nil || m.Name != "rapport.txt" {
+		t.Errorf("métadonnées %+v", m)
+	}
+	if got, _ := io.ReadAll(r); string(got) != "contenu" {
+		t.Errorf("contenu %q : les métadonnées n'ont pas été retirées du flux", got)
+	}
+
+	if _, _, err := NewDecryptReader(bytes.NewReader(mustRead(t, enc)), []byte("faux")); err == nil {
+		t.Error("mauvais mot de passe accepté")
+	}
+	// Les options passent par NewDecryptReaderWithOptions : ici le plafond
+	// mémoire, sous les 8 Mio du fichier.
+	if _, _, err := NewDecryptReaderWithOptions(bytes.NewReader(mustRead(t, enc)), pw, Options{MaxKDFMemory: 4 << 10}); !errors.As(err, new(*KDFMemoryError)) {
+		t.Errorf("plafond mémoire ignoré : %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package pkg
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/minio/sio"
+)
+
+// Registre des suites de chiffrement.
+//
+// Ajouter un algorithme obligeait à retoucher quatre switch en même temps —
+// écriture, lecture, validation et nom — et l'oubli d'un seul donnait un
+// fichier qu'on savait produire sans savoir le relire. Chaque algorithme est
+// désormais une suite enregistrée ici, et tout le reste du paquet passe par le
+// registre.
+//
+// Les identifiants sont un octet de l'en-tête, donc une ressource partagée
+// entre tous les producteurs de .chto. Ils sont coupés en deux plages : sous
+// AlgoPrivateMin, les suites du paquet lui-même ; à partir de AlgoPrivateMin,
+// celles qu'une application embarquant chiffremento enregistre pour son propre
+// usage. Une suite privée ne peut donc jamais entrer en collision avec une
+// suite ajoutée plus tard ici.
+//
+// Un fichier scellé avec une suite privée n'est lisible que par un programme
+// qui l'a enregistrée : pour tous les autres, l'identifiant est inconnu et
+// l'en-tête est refusé, comme n'importe quel algorithme qu'ils ne connaissent
+// pas.
+
+// AlgoPrivateMin est le premier identifiant de la plage réservée aux suites
+// enregistrées par une application.
+const AlgoPrivateMin = byte(0x80)
+
+// CipherSuite est un algorithme de chiffrement de flux authentifié.
+//
+// Le flux produit doit détecter à lui seul la troncature, la réorganisation et
+// la falsification de ses morceaux : le format ne les vérifie nulle part
+// ailleurs. C'est ce que DARE garantit pour les suites fournies, et le découpage
+// d'aegis_stream.go pour AEGIS-256.
+type CipherSuite interface {
+	// ID est l'octet inscrit dans l'en-tête.
+	ID() byte
+	// Name est le nom affiché à l'utilisateur.
+	Name() string
+	// KeySizes donne la taille de chaque clé, en octets : une seule pour un
+	// AEAD simple, deux pour une cascade (clé interne puis clé externe).
+	KeySizes() []int
+	// NewWriter chiffre vers dst. Le Close du writer renvoyé scelle le flux,
+	// sans fermer dst.
+	NewWriter(dst io.Writer, keys [][]byte) (io.WriteCloser, error)
+	// NewReader déchiffre src. Une erreur de lecture doit être renvoyée dès
+	// qu'un morceau ne s'authentifie pas, et à la fin si le flux est tronqué.
+	NewReader(src io.Reader, keys [][]byte) (io.Reader, error)
+}
+
+var (
+	suitesMu sync.RWMutex
+	suites   = map[byte]CipherSuite{}
+)
+
+// RegisterSuite enregistre une suite privée. Son identifiant doit être dans
+// la plage AlgoPrivateMin..0xFF et ne pas être déjà pris.
+//
+// À appeler à l'initialisation du programme, avant tout chiffrement : une
+// suite enregistrée en cours de route ne change rien aux opérations déjà
+// lancées, mais rendrait le comportement dépendant de l'ordre d'exécution.
+func RegisterSuite(s CipherSuite) error {
+	if s.ID() < AlgoPrivateMin {
+		return errorf("suite.reserved_id", "identifiant de suite %d réservé : les suites privées commencent à %d",
+			s.ID(), AlgoPrivateMin)
+	}
+	return registerSuite(s)
+}
+
+// registerSuite est RegisterSuite sans la restriction de plage, pour les
+// suites du paquet.
+func registerSuite(s CipherSuite) error {
+	if s.ID() == 0 {
+		return errorf("suite.zero", "l'identifiant de suite 0 est réservé : il signifie « défaut » dans Options")
+	}
+	if s.Name() == "" {
+		return errorf("suite.unnamed", "suite %d sans nom", s.ID())
+	}
+	// Une ou deux clés : c'est ce que deriveKeysV2 sait produire, une clé
+	// simple ou une paire en cascade.
+	sizes := s.KeySizes()
+	if len(sizes) == 0 || len(sizes) > 2 {
+		return errorf("suite.key_count", "suite %s : %d clés demandées (attendu 1 ou 2)", s.Name(), len(sizes))
+	}
+	for _, n := range sizes {
+		if n < 16 || n > 64 {
+			return errorf("suite.key_size", "suite %s : clé de %d octets (attendu 16 à 64)", s.Name(), n)
+		}
+	}
+
+	suitesMu.Lock()
+	defer suitesMu.Unlock()
+	if prev, ok := suites[s.ID()]; ok {
+		return errorf("suite.taken", "identifiant de suite %d déjà pris par %s", s.ID(), prev.Name())
+	}
+	suites[s.ID()] = s
+	return nil
+}
+
+// lookupSuite renvoie la suite enregistrée sous id.
+func lookupSuite(id byte) (CipherSuite, bool) {
+	suitesMu.RLock()
+	defer suitesMu.RUnlock()
+	s, ok := suites[id]
+	return s, ok
+}
+
+// Suites liste les suites enregistrées, par identifiant croissant.
+func Suites() []CipherSuite {
+	suitesMu.RLock()
+	defer suitesMu.RUnlock()
+	out := make([]CipherSuite, 0, len(suites))
+	for _, s := range suites {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID() < out[j].ID() })
+	return out
+}
+
+// --- Suites fournies ---------------------------------------------------
+
+func init() {
+	for _, s := range []CipherSuite{
+		dareSuite{id: AlgoAES, name: "aes-256-gcm", cipher: sio.AES_GCM},
+		dareSuite{id: AlgoChaCha, name: "chacha20-poly1305", cipher: sio.CHACHA20_POLY1305},
+		cascadeSuite{},
+		aegisSuite{},
+	} {
+		if err := registerSuite(s); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Note sur sio.AES_GCM : la constante s'appelait AES_256_GCM jusqu'à la
+// v0.4.3, elle a été renommée en v0.5.0 parce que la suite accepte aussi des
+// clés de 128 bits. Ici les clés font toujours 32 octets, c'est donc bien de
+// l'AES-256-GCM. La valeur écrite dans le format n'a pas changé (0), les
+// fichiers restent interchangeables entre les deux versions de la librairie.
+
+// cascadeWriteCloser ferme les deux couches du mode cascade dans l'ordre et
+// remonte la première erreur rencontrée. Les deux Close() sont toujours
+// tentés, même si le premier échoue.
+type cascadeWriteCloser struct {
+	inner io.WriteCloser
+	outer io.WriteCloser
+}
+
+func (c *cascadeWriteCloser) Write(p []byte) (int, error) { return c.inner.Write(p) }
+
+func (c *cascadeWriteCloser) Close() error {
+	errInner := c.inner.Close()
+	errOuter := c.outer.Close()
+	if errInner != nil {
+		return errInner
+	}
+	return errOuter
+}
+
+// dareSuite est un AEAD simple en flux DARE, via minio/sio.
+type dareSuite struct {
+	id     byte
+	name   string
+	cipher byte
+}
+
+func (s dareSuite) ID() byte        { return s.id }
+func (s dareSuite) Name() string    { return s.name }
+func (s dareSuite) KeySizes() []int { return []int{32} }
+
+func (s dareSuite) NewWriter(dst io.Writer, keys [][]byte) (io.WriteCloser, error) {
+	return sio.EncryptWriter(dst, sio.Config{Key: keys[0], CipherSuites: []byte{s.cipher}})
+}
+
+func (s dareSuite) NewReader(src io.Reader, keys [][]byte) (io.Reader, error) {
+	return sio.DecryptReader(src, sio.Config{Key: keys[0], CipherSuites: []byte{s.cipher}})
+}
+
+// cascadeSuite est le mode parano : AES-GCM à l'intérieur, ChaCha20 à
+// l'extérieur, chacun sous sa propre clé.
+type cascadeSuite struct{}
+
+func (cascadeSuite) ID() byte        { return AlgoCascade }
+func (cascadeSuite) Name() string    { return "cascade chacha20 + aes-256-gcm" }
+func (cascadeSuite) KeySizes() []int { return []int{32, 32} }
+
+func (cascadeSuite) NewWriter(dst io.Writer, keys [][]byte) (io.WriteCloser, error) {
+	outerWriter, err := sio.EncryptWriter(dst, sio.Config{
+		Key:          keys[1],
+		CipherSuites: []byte{sio.CHACHA20_POLY1305},
+	})
+	if err != nil {
+		return nil, errorf("cascade.outer", "création du flux externe: %w", err)
+	}
+	innerWriter, err := sio.EncryptWriter(writerOnly{outerWriter}, sio.Config{
+		Key:          keys[0],
+		CipherSuites: []byte{sio.AES_GCM},
+	})
+	if err != nil {
+		outerWriter.Close()
+		return nil, errorf("cascade.inner", "création du flux interne: %w", err)
+	}
+	return &cascadeWriteCloser{inner: innerWriter, outer: outerWriter}, nil
+}
+
+func (cascadeSuite) NewReader(src io.Reader, keys [][]byte) (io.Reader, error) {
+	outerReader, err := sio.DecryptReader(src, sio.Config{
+		Key:          keys[1],
+		CipherSuites: []byte{sio.CHACHA20_POLY1305},
+	})
+	if err != nil {
+		return nil, errorf("cascade.outer_decrypt", "init du déchiffrement externe: %w", err)
+	}
+	return sio.DecryptReader(outerReader, sio.Config{
+		Key:          keys[0],
+		CipherSuites: []byte{sio.AES_GCM},
+	})
+}
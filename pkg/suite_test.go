@@ -0,0 +1,115 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/sio"
+)
+
+// suitePrivee est une suite d'application fictive : ChaCha20 en DARE, sous un
+// identifiant de la plage privée. Elle ne vaut que par son enregistrement.
+type suitePrivee struct{ dareSuite }
+
+const algoTestPrive = byte(0xF0)
+
+var enregistreSuitePrivee = sync.OnceValue(func() error {
+	return RegisterSuite(suitePrivee{dareSuite{
+		id: algoTestPrive, name: "suite privée de test", cipher: sio.CHACHA20_POLY1305,
+	}})
+})
+
+func TestSuitesFourniesInchangees(t *testing.T) {
+	for algo, nom := range map[byte]string{
+		AlgoAES:     "aes-256-gcm",
+		AlgoChaCha:  "chacha20-poly1305",
+		AlgoCascade: "cascade chacha20 + aes-256-gcm",
+		AlgoAEGIS:   "aegis-256",
+	} {
+		if got := AlgoName(algo); got != nom {
+			t.Errorf("AlgoName(%d) = %q, attendu %q", algo, got, nom)
+		}
+		if err := validateAlgo(algo); err != nil {
+			t.Errorf("algorithme %d refusé: %v", algo, err)
+		}
+	}
+	if AlgoName(0x7f) != "inconnu" || validateAlgo(0x7f) == nil {
+		t.Error("un identifiant non enregistré est accepté")
+	}
+}
+
+func TestRegisterSuiteRefus(t *testing.T) {
+	cases := map[string]CipherSuite{
+		"plage réservée": dareSuite{id: 0x10, name: "x", cipher: sio.AES_GCM},
+		"déjà pris":      dareSuite{id: AlgoAES, name: "x", cipher: sio.AES_GCM},
+		"sans nom":       dareSuite{id: 0xF1, cipher: sio.AES_GCM},
+		"trois clés":     suiteTroisCles{},
+	}
+	for nom, s := range cases {
+		if err := RegisterSuite(s); err == nil {
+			t.Errorf("%s : enregistrement accepté", nom)
+		}
+	}
+	if err := enregistreSuitePrivee(); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterSuite(suitePrivee{dareSuite{id: algoTestPrive, name: "doublon"}}); err == nil {
+		t.Error("un identifiant privé a été enregistré deux fois")
+	}
+}
+
+type suiteTroisCles struct{ dareSuite }
+
+func (suiteTroisCles) ID() byte        { return 0xF2 }
+func (suiteTroisCles) Name() string    { return "trois clés" }
+func (suiteTroisCles) KeySizes() []int { return []int{32, 32, 32} }
+
+// TestSuitePriveeAllerRetour : une suite enregistrée par une application se
+// chiffre, s'inspecte et se relit comme les autres, sans rien toucher ailleurs.
+func TestSuitePriveeAllerRetour(t *testing.T) {
+	if err := enregistreSuitePrivee(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	contenu := []byte("chiffré par une suite enregistrée de l'extérieur")
+	in := write(t, dir, "clair.txt", contenu)
+	enc := filepath.Join(dir, "clair.chto")
+	if err := Encrypt(in, enc, []byte("pw"), Options{Algo: algoTestPrive}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Inspect(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Algo != "suite privée de test" || d.AlgoID != fmt.Sprintf("private-%d", algoTestPrive) {
+		t.Errorf("algorithme annoncé %q (%s)", d.Algo, d.AlgoID)
+	}
+	out := filepath.Join(dir, "relu.txt")
+	if err := Decrypt(enc, out, []byte("pw"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != string(contenu) {
+		t.Errorf("contenu relu %q", got)
+	}
+
+	found := false
+	for _, s := range Suites() {
+		found = found || s.ID() == algoTestPrive
+	}
+	if !found {
+		t.Error("Suites() ne liste pas la suite enregistrée")
+	}
+}
+
+func TestEncryptAlgoNonEnregistre(t *testing.T) {
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", []byte("x"))
+	err := Encrypt(in, filepath.Join(dir, "x.chto"), []byte("pw"), Options{Algo: 0xEE})
+	if err == nil || !strings.Contains(err.Error(), "inconnu") {
+		t.Errorf("erreur attendue sur un algorithme non enregistré, obtenu %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+)
+
+// Mise à niveau des anciens fichiers.
+//
+// Un .chto v1 reste déchiffrable, mais il garde les faiblesses de sa
+// génération : Argon2 à 32 Mio et, en mode cascade, la dérivation de
+// deriveKeysV1 qui faisait payer deux Argon2 à l'utilisateur pour un seul à
+// l'attaquant. Les relire ne les corrige pas ; il faut les réécrire.
+//
+// Upgrade déchiffre et rechiffre dans le même flux : le clair ne touche
+// jamais le disque, seul le nouveau chiffré est écrit, dans un temporaire qui
+// remplace l'original d'un seul rename. Une coupure en cours de route laisse
+// donc l'ancien fichier intact.
+
+// UpgradeResult décrit ce qu'a fait une mise à niveau.
+type UpgradeResult struct {
+	// From est la version de format lue dans l'en-tête d'origine.
+	From byte
+	// Upgraded vaut false quand le fichier était déjà à jour (voir
+	// Details.Outdated) : il n'a alors pas été touché, et le mot de passe n'a
+	// même pas été essayé.
+	Upgraded bool
+	// CompFrom et CompTo sont la compression avant et après.
+	CompFrom byte
+	CompTo   byte
+}
+
+// Upgrade réécrit un .chto v1 ou v2 au format courant, en place, ainsi qu'un
+// v3 compressé en gzip par une version intermédiaire. Les autres fichiers v3
+// et suivants sont laissés tels quels. Un lien symbolique est suivi : sa cible
+// est réécrite, et le lien reste un lien.
+//
+// L'algorithme, la nature du contenu (fichier ou dossier) et les métadonnées
+// sont conservés ; la dérivation de clé suit opts.KDF, opts.KDFAlgo et opts.Argon. Pour la
+// compression, opts.Comp ne décide que du sort des charges utiles gzip :
+// CompZstd les recompresse, CompNone les laisse décompressées. Un fichier qui n'était pas
+// compressé ne le devient pas — la compression laisse fuiter la
+// compressibilité du contenu, et personne ne l'avait choisie pour lui.
+//
+// opts.Progress et opts.Events suivent la lecture de l'ancien fichier — la
+// dérivation de la nouvelle clé n'y apparaît pas —, et opts.MaxKDFMemory
+// s'applique à sa dérivation. opts.Algo, opts.Pad, opts.Metadata et opts.Key
+// sont ignorés : les anciens formats ne connaissent que le mot de passe.
+func Upgrade(path string, password []byte, opts Options) (UpgradeResult, error) {
+	var res UpgradeResult
+	if err := validateCompWrite(opts.Comp); err != nil {
+		return res, err
+	}
+	// Le rename final remplacerait le lien par un fichier ordinaire : c'est
+	// la cible qu'il faut réécrire. Un lien cassé échoue à l'ouverture.
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	inFile, size, err := openInput(path)
+	if err != nil {
+		return res, err
+	}
+	defer inFile.Close()
+
+	// L'en-tête est lu une première fois sans mot de passe : un fichier déjà à
+	// jour n'a aucune raison de coûter une dérivation.
+	h, err := readHeader(inFile)
+	if err != nil {
+		return res, err
+	}
+	res.From, res.CompFrom = h.Version, h.Comp
+	if !detailsOf(h).Outdated() {
+		res.CompTo = h.Comp
+		return res, nil
+	}
+	if _, err := inFile.Seek(0, io.SeekStart); err != nil {
+		return res, errorf("upgrade.reread_header", "relecture de l'en-tête: %w", err)
+	}
+	st, err := inFile.Stat()
+	if err != nil {
+		return res, errorf("io.read", "lecture: %w", err)
+	}
+
+	t := opts.track()
+	src, h, closeSrc, err := openDecrypted(context.Background(), inFile, size, password, Options{MaxKDFMemory: opts.MaxKDFMemory}, t)
+	if err != nil {
+		return res, err
+	}
+	// La chaîne de lecture est refermée avant le rename, puis de nouveau en
+	// defer sur les chemins d'échec : OnceFunc évite de libérer deux fois le
+	// décodeur.
+	closeSrc = sync.OnceFunc(closeSrc)
+	defer closeSrc()
+
+	res.CompTo = CompNone
+	if h.compressed() {
+		res.CompTo = opts.Comp
+	}
+
+	out, err := newAtomicFile(path)
+	if err != nil {
+		return res, err
+	}
+	defer out.cleanup()
+
+	err = encrypt(context.Background(), out.f, source{r: src, size: -1, meta: h.Meta, tar: h.archive()}, password, Options{
+		Algo: h.Algo, Comp: res.CompTo, KDF: opts.KDF, KDFAlgo: opts.KDFAlgo, Argon: opts.Argon,
+	}, nil)
+	if err != nil {
+		return res, err
+	}
+
+	// Le chiffré n'est pas un secret : il retrouve les permissions que
+	// l'utilisateur lui avait données, au lieu du 0600 du temporaire.
+	if err := out.f.Chmod(st.Mode().Perm()); err != nil {
+		return res, errorf("io.chmod_path", "permissions de %s: %w", path, err)
+	}
+
+	// L'original est refermé avant le rename : Windows refuse de remplacer un
+	// fichier encore ouvert.
+	closeSrc()
+	inFile.Close()
+	t.enter(PhaseFsync, 0)
+	if err := out.commit(); err != nil {
+		return res, err
+	}
+	res.Upgraded = true
+	return res, nil
+}
+
+// Outdated dit si Upgrade réécrirait le fichier décrit. La v3 compte comme à
+// jour : la v4 n'ajoute que le choix de la KDF, et un fichier v3 est déjà en
+// Argon2id aux paramètres de son choix. Seul gzip, qui n'est plus produit, y
+// justifie une réécriture.
+func (d Details) Outdated() bool {
+	return d.Version < versionV3 || d.CompID == compIdent(CompGzip)
+}
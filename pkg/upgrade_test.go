@@ -0,0 +1,150 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copieTestdata recopie un fichier de référence dans un dossier temporaire :
+// Upgrade réécrit en place, il ne doit jamais toucher testdata.
+func copieTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return write(t, t.TempDir(), name, data)
+}
+
+func TestUpgradeAnciensFormats(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		attendu  string
+		comp     byte
+		wantComp byte
+	}{
+		{"v1_aes.chto", "reference-v1-password", "Fichier de reference v1 chiffre en AES-256-GCM.\n", CompNone, CompNone},
+		{"v1_cascade.chto", "reference-v1-password", "Fichier de reference v1 chiffre en mode cascade (parano).\n", CompNone, CompNone},
+		{"v1_aes_gzip.chto", "reference-v1-password", "Fichier de reference v1 compresse puis chiffre en AES-256-GCM.\n", CompZstd, CompZstd},
+		{"v2_aes_gzip.chto", "reference-v2-password", "Fichier de reference v2 compresse puis chiffre en AES-256-GCM.\n", CompNone, CompNone},
+		// -comp ne compresse pas ce qui ne l'était pas.
+		{"v2_chacha.chto", "reference-v2-password", "Fichier de reference v2 chiffre en ChaCha20-Poly1305.\n", CompZstd, CompNone},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := copieTestdata(t, c.name)
+			avant, err := Inspect(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := Upgrade(p, []byte(c.password), Options{Comp: c.comp})
+			if err != nil {
+				t.Fatalf("mise à niveau: %v", err)
+			}
+			if !res.Upgraded || res.From != avant.Version || res.CompTo != c.wantComp {
+				t.Errorf("résultat inattendu: %+v", res)
+			}
+
+			apres, err := Inspect(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if apres.Version != currentVersion || apres.Algo != avant.Algo || apres.Comp != CompName(c.wantComp) {
+				t.Errorf("en-tête réécrit inattendu: %+v (avant %+v)", apres, avant)
+			}
+			if apres.KDF != DefaultKDFLabel() {
+				t.Errorf("kdf %q, attendu le profil courant %q", apres.KDF, DefaultKDFLabel())
+			}
+
+			out := filepath.Join(t.TempDir(), "clair")
+			if err := Decrypt(p, out, []byte(c.password), Options{}); err != nil {
+				t.Fatalf("relecture après mise à niveau: %v", err)
+			}
+			if got, _ := os.ReadFile(out); string(got) != c.attendu {
+				t.Errorf("contenu %q, attendu %q", got, c.attendu)
+			}
+			assertPasDeTemporaire(t, filepath.Dir(p))
+		})
+	}
+}
+
+func TestUpgradeDossierResteUneArchive(t *testing.T) {
+	const password = "reference-v2-password"
+	p := copieTestdata(t, "v2_dossier_gzip.chto")
+	if _, err := Upgrade(p, []byte(password), Options{Comp: CompZstd}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Inspect(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Archive || d.Comp != "zstd" {
+		t.Fatalf("archive=%v comp=%s après mise à niveau", d.Archive, d.Comp)
+	}
+	dst := filepath.Join(t.TempDir(), "restaure")
+	if err := Decrypt(p, dst, []byte(password), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "sous", "b.txt")); string(got) != "second\n" {
+		t.Errorf("sous/b.txt : %q", got)
+	}
+}
+
+func TestUpgradeMauvaisMotDePasseNeTouchePasLOriginal(t *testing.T) {
+	p := copieTestdata(t, "v1_chacha.chto")
+	avant, _ := os.ReadFile(p)
+	if _, err := Upgrade(p, []byte("pas-le-bon"), Options{}); err == nil {
+		t.Fatal("mise à niveau acceptée avec un mauvais mot de passe")
+	}
+	apres, _ := os.ReadFile(p)
+	if !bytes.Equal(avant, apres) {
+		t.Error("l'original a été modifié par une mise à niveau ratée")
+	}
+	assertPasDeTemporaire(t, filepath.Dir(p))
+}
+
+func TestUpgradeFormatCourantIgnore(t *testing.T) {
+	dir := t.TempDir()
+	in := write(t, dir, "clair.txt", []byte("déjà récent"))
+	enc := filepath.Join(dir, "clair.txt.chto")
+	if err := Encrypt(in, enc, []byte("pw"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	avant, _ := os.ReadFile(enc)
+
+	// Le mot de passe n'est même pas essayé : un faux passe.
+	res, err := Upgrade(enc, []byte("faux"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Upgraded || res.From != currentVersion {
+		t.Errorf("résultat inattendu: %+v", res)
+	}
+	if apres, _ := os.ReadFile(enc); !bytes.Equal(avant, apres) {
+		t.Error("un fichier déjà à jour a été réécrit")
+	}
+}
+
+// TestUpgradeSuitLeLien : un lien reste un lien, c'est sa cible qui est
+// réécrite.
+func TestUpgradeSuitLeLien(t *testing.T) {
+	cible := copieTestdata(t, "v2_aes.chto")
+	lien := filepath.Join(t.TempDir(), "lien.chto")
+	if err := os.Symlink(cible, lien); err != nil {
+		t.Skipf("liens symboliques indisponibles ici: %v", err)
+	}
+	if _, err := Upgrade(lien, []byte("reference-v2-password"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(lien); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("le lien a été remplacé (%v)", err)
+	}
+	if d, err := Inspect(cible); err != nil || d.Version != currentVersion {
+		t.Errorf("cible non réécrite: %+v (%v)", d, err)
+	}
+	assertPasDeTemporaire(t, filepath.Dir(cible))
+}
@@ -3,6 +3,7 @@ package pkg
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"io"
 	"os"
 	"path/filepath"
@@ -91,7 +92,7 @@ func TestCompatibiliteV2Dossier(t *testing.T) {
 	}
 }
 
-func TestEncryptEcritDuV3(t *testing.T) {
+func TestEncryptEcritLeFormatCourant(t *testing.T) {
 	dir := t.TempDir()
 	in := write(t, dir, "clair.txt", []byte("x"))
 	enc := filepath.Join(dir, "out.chto")
@@ -102,8 +103,8 @@ func TestEncryptEcritDuV3(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if d.Version != versionV3 {
-		t.Errorf("version écrite %d, attendu %d", d.Version, versionV3)
+	if d.Version != currentVersion {
+		t.Errorf("version écrite %d, attendu %d", d.Version, currentVersion)
 	}
 	if d.Comp != "zstd" {
 		t.Errorf("compression annoncée %q, attendu zstd", d.Comp)
@@ -115,7 +116,7 @@ func TestEncryptEcritDuV3(t *testing.T) {
 		t.Fatal(err)
 	}
 	if raw[magicSize+versionSize]&FlagCompressed != 0 {
-		t.Error("le drapeau de compression v1/v2 est encore posé sur un fichier v3")
+		t.Error("le drapeau de compression v1/v2 est encore posé sur un fichier récent")
 	}
 }
 
@@ -244,7 +245,7 @@ func TestRemplissageMasqueLaTaille(t *testing.T) {
 // remplissage d'un dossier viserait à côté du palier.
 func TestRemplissageDossierViseLePalier(t *testing.T) {
 	src := arbre(t)
-	plan, err := scanDirectory(src)
+	plan, err := scanDirectory(src, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -255,7 +256,7 @@ func TestRemplissageDossierViseLePalier(t *testing.T) {
 
 	// La taille annoncée doit être exactement celle du tar réellement produit.
 	var buf bytes.Buffer
-	if err := writeArchive(&buf, plan, nil); err != nil {
+	if err := writeArchive(context.Background(), &buf, plan, nil); err != nil {
 		t.Fatal(err)
 	}
 	if int64(buf.Len()) != attendu {
@@ -408,12 +409,12 @@ func TestFluxDossierSortTelQuel(t *testing.T) {
 		t.Fatal(err)
 	}
 	// Ce qui sort doit être un tar lisible, et rien d'autre.
-	if err := checkArchive(bytes.NewReader(tarBrut.Bytes())); err != nil {
+	if err := checkArchive(context.Background(), bytes.NewReader(tarBrut.Bytes()), nil); err != nil {
 		t.Fatalf("la sortie n'est pas un tar exploitable: %v", err)
 	}
 
 	dst := t.TempDir()
-	if err := extractArchive(bytes.NewReader(tarBrut.Bytes()), dst); err != nil {
+	if err := extractArchive(context.Background(), bytes.NewReader(tarBrut.Bytes()), dst, nil); err != nil {
 		t.Fatal(err)
 	}
 	compareArbres(t, src, dst)
@@ -459,7 +460,7 @@ func TestCorpsFalsifie(t *testing.T) {
 			}
 
 			// Un octet au milieu du corps, bien après l'en-tête.
-			milieu := headerSizeV3 + (len(raw)-headerSizeV3)/2
+			milieu := headerSizeV4 + (len(raw)-headerSizeV4)/2
 			raw[milieu] ^= 0x01
 
 			sous := t.TempDir()
@@ -493,7 +494,7 @@ func TestFichierTronque(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	for _, garde := range []int{headerSizeV3 + 1, len(raw) / 2, len(raw) - 1} {
+	for _, garde := range []int{headerSizeV4 + 1, len(raw) / 2, len(raw) - 1} {
 		sous := t.TempDir()
 		path := write(t, sous, "tronque.chto", raw[:garde])
 		out := filepath.Join(sous, "out")
@@ -510,7 +511,7 @@ func TestFichierTronque(t *testing.T) {
 	// clair vide. On documente donc le comportement au lieu de prétendre le
 	// détecter.
 	sous := t.TempDir()
-	path := write(t, sous, "entete_seul.chto", raw[:headerSizeV3])
+	path := write(t, sous, "entete_seul.chto", raw[:headerSizeV4])
 	out := filepath.Join(sous, "out")
 	if err := Decrypt(path, out, []byte("pw"), Options{}); err != nil {
 		t.Fatalf("un fichier réduit à son en-tête devrait se lire comme un clair vide: %v", err)
@@ -535,7 +536,7 @@ func TestArchiveTronqueeALEntete(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	path := write(t, dir, "tronque.chto", raw[:headerSizeV3])
+	path := write(t, dir, "tronque.chto", raw[:headerSizeV4])
 	dst := filepath.Join(dir, "restaure")
 	err = Decrypt(path, dst, []byte("pw"), Options{})
 	if err == nil {
@@ -745,4 +746,13 @@ func TestGzipResteLisibleEnV3(t *testing.T) {
 	if !bytes.Equal(clair, got) {
 		t.Error("le contenu relu diffère de l'original")
 	}
+
+	// upgrade le réécrit quand même, bien qu'il soit déjà en v3.
+	res, err := Upgrade(path, password, Options{Comp: CompZstd})
+	if err != nil || !res.Upgraded || res.CompTo != CompZstd {
+		t.Fatalf("mise à niveau d'un v3 gzip: %+v (%v)", res, err)
+	}
+	if d, _ := Inspect(path); d.Comp != CompName(CompZstd) {
+		t.Errorf("compression %q après mise à niveau", d.Comp)
+	}
 }
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/trustelem/zxcvbn"
+)
+
+// Politique de mots de passe.
+//
+// L'indicateur de force ne fait que conseiller. Sur un poste partagé, l'équipe
+// de sécurité veut pouvoir refuser : une politique fixe un score zxcvbn
+// minimal (0 à 4), une entropie estimée minimale, une longueur minimale et une
+// liste de mots interdits — le nom de l'entreprise, celui du produit.
+//
+// Elle vient de trois endroits, qui s'additionnent :
+//
+//   - le fichier système (/etc/chiffremento/policy.conf, ou
+//     %ProgramData%\chiffremento\policy.conf sous Windows), posé par
+//     l'administrateur ;
+//   - le fichier désigné par -policy ou $CHTO_POLICY ;
+//   - les options -min-score, -min-entropy, -min-length et -banned-words.
+//
+// La règle la plus stricte l'emporte et les listes de mots s'ajoutent : une
+// option ne desserre jamais ce que le fichier système impose.
+//
+// La politique ne s'applique qu'au choix d'un mot de passe, en enc, agent add
+// et au chiffrement de l'interface guidée. Le déchiffrement n'est jamais
+// bloqué : un fichier chiffré avant la politique doit rester lisible, et un
+// fichier de politique illisible n'est même pas ouvert.
+//
+// Format du fichier, une règle par ligne :
+//
+//	# commentaire
+//	min-score    = 3
+//	min-entropy  = 50
+//	min-length   = 12
+//	banned       = acme
+//	banned-words = interdits.txt
+//
+// banned se répète ; banned-words désigne un fichier d'un mot par ligne,
+// relatif au fichier de politique.
+
+// policyEnv désigne un fichier de politique en plus du fichier système.
+const policyEnv = "CHTO_POLICY"
+
+// maxZxcvbnScore est le score le plus élevé que rend zxcvbn.
+const maxZxcvbnScore = 4
+
+// systemPolicyFile est le fichier posé par l'administrateur. C'est une
+// variable pour que les tests puissent le déplacer.
+var systemPolicyFile = func() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "chiffremento", "policy.conf")
+	}
+	return "/etc/chiffremento/policy.conf"
+}()
+
+type passwordPolicy struct {
+	minScore   int
+	minEntropy float64
+	minLength  int
+	banned     []string // en minuscules
+}
+
+// policy est la politique en vigueur, chargée avant la saisie d'un nouveau
+// mot de passe. Sans politique, tout mot de passe non vide passe.
+var policy passwordPolicy
+
+// tighten ajoute les règles de o à p, en gardant la plus stricte.
+func (p *passwordPolicy) tighten(o passwordPolicy) {
+	p.minScore = max(p.minScore, o.minScore)
+	p.minEntropy = max(p.minEntropy, o.minEntropy)
+	p.minLength = max(p.minLength, o.minLength)
+	p.banned = append(p.banned, o.banned...)
+}
+
+// check rend la première règle que s vient enfreindre, dite en clair.
+func (p passwordPolicy) check(s string) error {
+	if n := utf8.RuneCountInString(s); n < p.minLength {
+		return errorf("politique de mots de passe : au moins %d caractères (%d ici)", p.minLength, n)
+	}
+	// ToLower recopie la saisie : seulement s'il y a des mots à chercher.
+	if len(p.banned) > 0 {
+		lower := strings.ToLower(s)
+		for _, w := range p.banned {
+			if strings.Contains(lower, w) {
+				return errorf("politique de mots de passe : « %s » est interdit dans un mot de passe", w)
+			}
+		}
+	}
+	if p.minScore == 0 && p.minEntropy == 0 {
+		return nil
+	}
+	// Les mots interdits servent aussi d'indices à zxcvbn : une variante en
+	// l33t speak ou à l'envers baisse le score au lieu de passer inaperçue.
+	r := zxcvbn.PasswordStrength(s, p.banned)
+	if r.Score < p.minScore {
+		return errorf("politique de mots de passe : score zxcvbn d'au moins %d sur %d (%d ici)", p.minScore, maxZxcvbnScore, r.Score)
+	}
+	if bits := math.Log2(max(r.Guesses, 1)); bits < p.minEntropy {
+		return errorf("politique de mots de passe : au moins %.0f bits d'entropie estimée (~%.0f ici)", p.minEntropy, bits)
+	}
+	return nil
+}
+
+// describe résume la politique en une ligne, vide sans règle.
+func (p passwordPolicy) describe() string {
+	var parts []string
+	if p.minLength > 0 {
+		parts = append(parts, fmt.Sprintf(tr("%d caractères"), p.minLength))
+	}
+	if p.minScore > 0 {
+		parts = append(parts, fmt.Sprintf(tr("score %d/%d"), p.minScore, maxZxcvbnScore))
+	}
+	if p.minEntropy > 0 {
+		parts = append(parts, fmt.Sprintf(tr("%.0f bits"), p.minEntropy))
+	}
+	if len(p.banned) > 0 {
+		parts = append(parts, fmt.Sprintf(tr("%d mots interdits"), len(p.banned)))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(tr("au moins %s"), strings.Join(parts, ", "))
+}
+
+// validateNewPassword valide un mot de passe tapé au moment de le choisir :
+// non vide, conforme à la politique et absent de la liste de fuites.
+func validateNewPassword(s string) error {
+	if err := validatePassword(s); err != nil {
+		return err
+	}
+	if err := policy.check(s); err != nil {
+		return err
+	}
+	found, err := breached(secretBytes(s))
+	if err != nil {
+		return err
+	}
+	if found {
+		return errBreached
+	}
+	return nil
+}
+
+// checkSourcedPassword contrôle un nouveau mot de passe lu à une source
+// (-passfile…, entrée standard) : la politique s'applique pareil, la liste de
+// fuites ne fait que le signaler.
+func checkSourcedPassword(password []byte) error {
+	if err := policy.check(secretString(password)); err != nil {
+		return err
+	}
+	return warnIfBreached(password)
+}
+
+type policyFlags struct {
+	file, banned string
+	minScore     int
+	minEntropy   float64
+	minLength    int
+	set          map[string]bool
+}
+
+func registerPolicyFlags() *policyFlags {
+	f := &policyFlags{}
+	flag.StringVar(&f.file, "policy", os.Getenv(policyEnv), fmt.Sprintf(tr("fichier de politique de mots de passe, en plus de %s (enc et agent add ; défaut : $%s)"), systemPolicyFile, policyEnv))
+	flag.IntVar(&f.minScore, "min-score", 0, tr("refuser un nouveau mot de passe sous ce score zxcvbn, de 0 à 4"))
+	flag.Float64Var(&f.minEntropy, "min-entropy", 0, tr("refuser un nouveau mot de passe sous cette entropie estimée, en bits"))
+	flag.IntVar(&f.minLength, "min-length", 0, tr("refuser un nouveau mot de passe plus court que ce nombre de caractères"))
+	flag.StringVar(&f.banned, "banned-words", "", tr("refuser un nouveau mot de passe qui contient un mot de ce fichier (un par ligne)"))
+	return f
+}
+
+// any indique si une option de politique a été donnée.
+func (f *policyFlags) any() bool {
+	for _, n := range []string{"policy", "min-score", "min-entropy", "min-length", "banned-words"} {
+		if f.set[n] {
+			return true
+		}
+	}
+	return false
+}
+
+// load assemble la politique : fichier système, fichier choisi, options.
+func (f *policyFlags) load() (passwordPolicy, error) {
+	p, err := loadPolicyFiles(f.file)
+	if err != nil {
+		return p, err
+	}
+	o := passwordPolicy{minScore: f.minScore, minEntropy: f.minEntropy, minLength: f.minLength}
+	if err := o.validate(); err != nil {
+		return p, err
+	}
+	if f.banned != "" {
+		if o.banned, err = loadBannedWords(f.banned); err != nil {
+			return p, err
+		}
+	}
+	p.tighten(o)
+	return p, nil
+}
+
+// loadPolicyFiles lit le fichier système s'il existe, puis file s'il est
+// donné. Un fichier désigné mais absent est une erreur ; le fichier système,
+// lui, est facultatif.
+func loadPolicyFiles(file string) (passwordPolicy, error) {
+	var p passwordPolicy
+	if sys, err := parsePolicyFile(systemPolicyFile); err == nil {
+		p.tighten(sys)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return p, err
+	}
+	if file != "" {
+		o, err := parsePolicyFile(file)
+		if err != nil {
+			return p, err
+		}
+		p.tighten(o)
+	}
+	return p, nil
+}
+
+func (p passwordPolicy) validate() error {
+	if p.minScore < 0 || p.minScore > maxZxcvbnScore {
+		return errorf("score zxcvbn minimal hors bornes : %d (attendu 0..%d)", p.minScore, maxZxcvbnScore)
+	}
+	if p.minEntropy < 0 || math.IsNaN(p.minEntropy) {
+		return errorf("entropie minimale invalide : %v", p.minEntropy)
+	}
+	if p.minLength < 0 {
+		return errorf("longueur minimale invalide : %d", p.minLength)
+	}
+	return nil
+}
+
+func parsePolicyFile(path string) (passwordPolicy, error) {
+	var p passwordPolicy
+	f, err := os.Open(path)
+	if err != nil {
+		return p, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(l, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return p, errorf("%s, ligne %d : « clé = valeur » attendu", path, line)
+		}
+		switch key {
+		case "min-score":
+			p.minScore, err = strconv.Atoi(value)
+		case "min-entropy":
+			p.minEntropy, err = strconv.ParseFloat(value, 64)
+		case "min-length":
+			p.minLength, err = strconv.Atoi(value)
+		case "banned":
+			p.banned = append(p.banned, strings.ToLower(value))
+		case "banned-words":
+			if !filepath.IsAbs(value) {
+				value = filepath.Join(filepath.Dir(path), value)
+			}
+			var words []string
+			words, err = loadBannedWords(value)
+			p.banned = append(p.banned, words...)
+		default:
+			// Une faute de frappe dans une politique de sécurité ne doit pas
+			// passer pour une règle appliquée.
+			return p, errorf("%s, ligne %d : règle inconnue %q", path, line, key)
+		}
+		if err != nil {
+			return p, errorf("%s, ligne %d : %w", path, line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return p, errorf("lecture de %s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return p, fmt.Errorf("%s : %w", path, err)
+	}
+	return p, nil
+}
+
+// loadBannedWords lit une liste de mots interdits, un par ligne, en ignorant
+// les lignes vides et les commentaires.
+func loadBannedWords(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, "#") {
+			words = append(words, strings.ToLower(l))
+		}
+	}
+	return words, nil
+}
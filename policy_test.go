@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+// avecPolitique installe une politique pour la durée du test, et déplace le
+// fichier système pour que celui de la machine n'interfère pas.
+func avecPolitique(t *testing.T, p passwordPolicy) {
+	t.Helper()
+	precedent, fichier := policy, systemPolicyFile
+	policy = p
+	systemPolicyFile = filepath.Join(t.TempDir(), "policy.conf")
+	t.Cleanup(func() { policy, systemPolicyFile = precedent, fichier })
+}
+
+func TestPolitiqueRegles(t *testing.T) {
+	p := passwordPolicy{minScore: 3, minEntropy: 40, minLength: 12, banned: []string{"acme"}}
+	for s, regle := range map[string]string{
+		"court":                          "au moins 12 caractères (5 ici)",
+		"ACME-entrepot-lyon-1987":        "« acme » est interdit",
+		"azertyuiop123":                  "score zxcvbn",
+		"volcan-guitare-ecume-barque-47": "",
+	} {
+		err := p.check(s)
+		switch {
+		case regle == "" && err != nil:
+			t.Errorf("%q refusé : %v", s, err)
+		case regle != "" && (err == nil || !strings.Contains(err.Error(), regle)):
+			t.Errorf("%q : %v, attendu %q", s, err, regle)
+		}
+	}
+	if err := (passwordPolicy{minEntropy: 200}).check("volcan-guitare-ecume-barque-47"); err == nil || !strings.Contains(err.Error(), "bits d'entropie") {
+		t.Errorf("entropie : %v", err)
+	}
+	if err := (passwordPolicy{}).check("a"); err != nil {
+		t.Errorf("sans politique : %v", err)
+	}
+}
+
+func TestPolitiqueFichiers(t *testing.T) {
+	avecPolitique(t, passwordPolicy{})
+	dir := t.TempDir()
+	ecrire(t, filepath.Join(dir, "interdits.txt"), []byte("# noms maison\nChiffremento\n\nlyon\n"))
+	ecrire(t, systemPolicyFile, []byte("# posé par l'administrateur\nmin-score = 2\nmin-length=10\nbanned = Acme\nbanned-words = "+filepath.Join(dir, "interdits.txt")+"\n"))
+	perso := ecrire(t, filepath.Join(dir, "perso.conf"), []byte("min-length = 8\nmin-entropy = 45\nbanned-words = interdits.txt\n"))
+
+	// Les options ne desserrent pas le fichier système.
+	f := &policyFlags{file: perso, minScore: 1, minLength: 14}
+	p, err := f.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.minScore != 2 || p.minLength != 14 || p.minEntropy != 45 {
+		t.Errorf("politique assemblée %+v", p)
+	}
+	if err := p.check("motdepasse-de-lyon-tres-long"); err == nil || !strings.Contains(err.Error(), "« lyon »") {
+		t.Errorf("mot interdit par fichier relatif : %v", err)
+	}
+	if d := p.describe(); !strings.Contains(d, "14 caractères") || !strings.Contains(d, "score 2/4") {
+		t.Errorf("résumé %q", d)
+	}
+
+	for nom, contenu := range map[string]string{
+		"règle inconnue": "min-lenght = 12\n",
+		"sans valeur":    "min-score =\n",
+		"score":          "min-score = 5\n",
+		"nombre":         "min-length = douze\n",
+		"liste absente":  "banned-words = absente.txt\n",
+	} {
+		if _, err := parsePolicyFile(ecrire(t, filepath.Join(dir, "faux.conf"), []byte(contenu))); err == nil {
+			t.Errorf("%s : fichier accepté", nom)
+		}
+	}
+	if _, err := (&policyFlags{file: filepath.Join(dir, "absent.conf")}).load(); err == nil {
+		t.Error("fichier de politique désigné mais absent accepté")
+	}
+	os.Remove(systemPolicyFile)
+	if p, err := (&policyFlags{}).load(); err != nil || p.describe() != "" {
+		t.Errorf("sans fichier système : %+v, %v", p, err)
+	}
+}
+
+func TestPolitiqueChiffrement(t *testing.T) {
+	avecPolitique(t, passwordPolicy{minLength: 20})
+	dir := t.TempDir()
+	in := ecrire(t, filepath.Join(dir, "clair.txt"), []byte("x"))
+	rapide := &pkg.ArgonParams{Time: 1, MemoryKiB: 1024, Threads: 1}
+
+	// Lu sur l'entrée standard, il est refusé comme au clavier.
+	avecMotDePasse(t, "trop-court")
+	if err := doEncrypt(in, "", pkg.Options{Argon: rapide}); err == nil || !strings.Contains(err.Error(), "au moins 20 caractères") {
+		t.Fatalf("mot de passe trop court : %v", err)
+	}
+
+	// Le déchiffrement n'est jamais bloqué.
+	policy = passwordPolicy{}
+	avecMotDePasse(t, "trop-court")
+	if err := doEncrypt(in, "", pkg.Options{Argon: rapide}); err != nil {
+		t.Fatal(err)
+	}
+	policy = passwordPolicy{minLength: 20}
+	avecMotDePasse(t, "trop-court")
+	if err := doVerify(in+extension, pkg.Options{}); err != nil {
+		t.Errorf("déchiffrement bloqué par la politique : %v", err)
+	}
+}
@@ -158,13 +158,13 @@ func (m *progressModel) View() string {
 	const sizeCol = 9
 	nameWidth := innerWidth - labelWidth - sizeCol
 
-	b.WriteString(styleLabel.Render("entrée") + styleText.Render(pad(base(m.info.In), nameWidth)) +
+	b.WriteString(styleLabel.Render(tr("entrée")) + styleText.Render(pad(base(m.info.In), nameWidth)) +
 		styleDim.Render(rightAlign(humanSize(m.info.Size), sizeCol)) + "\n")
-	b.WriteString(styleLabel.Render("sortie") + styleText.Render(pad(base(m.info.Out), nameWidth+sizeCol)) + "\n\n")
+	b.WriteString(styleLabel.Render(tr("sortie")) + styleText.Render(pad(base(m.info.Out), nameWidth+sizeCol)) + "\n\n")
 
 	b.WriteString(styleLabel.Render("aead") + styleAccent.Render(m.info.AEAD) + "\n")
 	b.WriteString(styleLabel.Render("kdf") + styleAccent.Render(m.info.KDF) + "\n")
-	b.WriteString(styleLabel.Render("sel") + styleDim.Render(m.info.Salt) + "\n\n")
+	b.WriteString(styleLabel.Render(tr("sel")) + styleDim.Render(m.info.Salt) + "\n\n")
 
 	b.WriteString(m.scramble(ratio) + "\n")
 	b.WriteString(m.bar(ratio, done))
@@ -225,14 +225,14 @@ func (m *progressModel) bar(ratio float64, done int64) string {
 func humanSize(n int64) string {
 	const unit = 1024
 	if n < unit {
-		return fmt.Sprintf("%d o", n)
+		return fmt.Sprintf(tr("%d o"), n)
 	}
 	div, exp := int64(unit), 0
 	for v := n / unit; v >= unit; v /= unit {
 		div *= unit
 		exp++
 	}
-	return fmt.Sprintf("%.1f %co", float64(n)/float64(div), "kMGT"[exp])
+	return fmt.Sprintf(tr("%.1f %co"), float64(n)/float64(div), "kMGT"[exp])
 }
 
 // base raccourcit un chemin à son nom de fichier : un chemin absolu long
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"chiffremento-cli/pkg"
+)
+
+// Événements de progression (-progress).
+//
+// L'écran animé n'existe que dans l'interface guidée. En ligne de commande,
+// un chiffrement de plusieurs centaines de gigaoctets ne disait rien jusqu'à
+// la fin, et une interface graphique qui enveloppe l'outil n'avait rien à
+// suivre. -progress traduit pkg.Options.Events en lignes périodiques, sur la
+// sortie d'erreur ou sur un descripteur hérité (-progress-fd 3).
+
+// progressInterval espace les lignes d'une même étape. Un changement d'étape
+// est écrit tout de suite, précédé du dernier état de l'étape qui se termine.
+const progressInterval = 500 * time.Millisecond
+
+type progressFlags struct {
+	format string
+	fd     int
+}
+
+func registerProgressFlags() *progressFlags {
+	f := &progressFlags{}
+	flag.StringVar(&f.format, "progress", "", tr("suivre l'opération par des événements périodiques : ndjson (une ligne JSON par événement) ou plain (une ligne lisible) ; enc, dec, verify et upgrade"))
+	flag.IntVar(&f.fd, "progress-fd", 2, tr("descripteur hérité qui reçoit les événements de -progress (défaut : 2, la sortie d'erreur)"))
+	return f
+}
+
+// open valide les drapeaux et renvoie le journal, nil sans -progress.
+// stdoutTaken : la sortie standard porte déjà des données ou le JSON.
+func (f *progressFlags) open(mode string, set map[string]bool, stdoutTaken bool) (*progressLog, error) {
+	if f.format == "" {
+		if set["progress-fd"] {
+			fmt.Fprintln(diag, styleDim.Render(tr("note : -progress-fd n'a d'effet qu'avec -progress, il est ignoré")))
+		}
+		return nil, nil
+	}
+	if f.format != "ndjson" && f.format != "plain" {
+		return nil, errorf("-progress %q inconnu (attendu ndjson ou plain)", f.format)
+	}
+	switch mode {
+	case "enc", "dec", "verify", "upgrade":
+	default:
+		fmt.Fprintln(diag, styleDim.Render(tr("note : -progress n'a d'effet qu'en modes enc, dec, verify et upgrade, il est ignoré")))
+		return nil, nil
+	}
+
+	var w io.Writer
+	switch {
+	case f.fd == 0:
+		return nil, errorf("-progress-fd 0 est l'entrée standard : il faut un descripteur ouvert en écriture")
+	case f.fd == 1 && stdoutTaken:
+		return nil, errorf("-progress-fd 1 : la sortie standard porte déjà les données ou le JSON")
+	case f.fd == 1:
+		w = os.Stdout
+	case f.fd == 2:
+		w = os.Stderr
+	default:
+		file := os.NewFile(uintptr(f.fd), "descripteur "+strconv.Itoa(f.fd))
+		if file == nil {
+			return nil, errorf("-progress-fd : descripteur %d invalide", f.fd)
+		}
+		if _, err := file.Stat(); err != nil {
+			return nil, errorf("-progress-fd : descripteur %d invalide (%w)", f.fd, err)
+		}
+		w = file
+	}
+	return &progressLog{w: w, ndjson: f.format == "ndjson"}, nil
+}
+
+// progressOut est le journal de la commande en cours, nil sans -progress.
+// Chaque commande le vide (flush) dès que l'opération rend la main, avant
+// d'annoncer son résultat : un lecteur doit voir le dernier événement avant
+// la ligne « ✓ » ou l'objet -json, pas après.
+var progressOut *progressLog
+
+// progressLog écrit les événements, une ligne chacun. Il est appelé dans la
+// goroutine de l'opération : rien à synchroniser.
+type progressLog struct {
+	w      io.Writer
+	ndjson bool
+
+	begin      time.Time // premier événement : origine de elapsed_ms
+	phaseStart time.Time // début de l'étape, pour le débit
+	lastWrite  time.Time
+	phase      pkg.Phase
+	written    pkg.ProgressEvent  // dernier état écrit
+	pending    *pkg.ProgressEvent // dernier état pas encore écrit
+	now        func() time.Time   // remplacé par les tests
+}
+
+// progressLine est le schéma d'une ligne ndjson. Comme celui de -json, il ne
+// perd ni ne renomme de champ sans que le README le dise.
+type progressLine struct {
+	Phase       pkg.Phase `json:"phase"`
+	Done        int64     `json:"done"`
+	Total       int64     `json:"total"` // 0 : inconnu ou sans objet
+	Entry       string    `json:"entry,omitempty"`
+	BytesPerSec int64     `json:"bytes_per_sec"`
+	ElapsedMs   int64     `json:"elapsed_ms"`
+}
+
+// attach branche le journal sur opts. Sans -progress, opts reste tel quel.
+func (l *progressLog) attach(opts *pkg.Options) {
+	if l != nil {
+		opts.Events = l.event
+	}
+}
+
+func (l *progressLog) event(e pkg.ProgressEvent) {
+	now := l.clock()
+	if l.begin.IsZero() {
+		l.begin = now
+	}
+	if e.Phase != l.phase {
+		l.flush()
+		l.phase, l.phaseStart = e.Phase, now
+		l.write(e, now)
+		return
+	}
+	// Les 100 % passent tout de suite : sinon ils n'arriveraient qu'après le
+	// message de fin de l'opération.
+	complete := e.Total > 0 && e.Done >= e.Total && l.written.Done < e.Total
+	if now.Sub(l.lastWrite) < progressInterval && !complete {
+		l.pending = &e
+		return
+	}
+	l.write(e, now)
+}
+
+// flush écrit le dernier état retenu : sans lui, la dernière ligne d'une
+// étape ne montrerait pas ses 100 %.
+func (l *progressLog) flush() {
+	if l == nil || l.pending == nil {
+		return
+	}
+	l.write(*l.pending, l.clock())
+}
+
+func (l *progressLog) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+// write ignore les erreurs d'écriture : un lecteur qui ferme son bout du
+// descripteur ne doit pas interrompre le chiffrement.
+func (l *progressLog) write(e pkg.ProgressEvent, now time.Time) {
+	l.pending, l.lastWrite, l.written = nil, now, e
+	line := progressLine{
+		Phase:     e.Phase,
+		Done:      e.Done,
+		Total:     e.Total,
+		Entry:     e.Entry,
+		ElapsedMs: now.Sub(l.begin).Milliseconds(),
+	}
+	// Pas de débit pendant l'inventaire : Done y compte ce qui a été trouvé,
+	// pas ce qui a été lu.
+	if d := now.Sub(l.phaseStart); d > 0 && e.Phase != pkg.PhaseScan {
+		line.BytesPerSec = int64(float64(e.Done) / d.Seconds())
+	}
+	if l.ndjson {
+		raw, _ := json.Marshal(line)
+		l.w.Write(append(raw, '\n'))
+		return
+	}
+	fmt.Fprintln(l.w, plainProgress(line))
+}
+
+// phaseLabels nomme les phases, traduites à l'affichage.
+var phaseLabels = map[pkg.Phase]string{
+	pkg.PhaseScan:    "inventaire",
+	pkg.PhaseKDF:     "dérivation",
+	pkg.PhaseEncrypt: "chiffrement",
+	pkg.PhaseDecrypt: "déchiffrement",
+	pkg.PhaseExtract: "extraction",
+	pkg.PhaseFsync:   "écriture",
+}
+
+// plainProgress met une ligne en forme pour un journal lu par un humain : pas
+// de couleur ni de retour chariot, elle finit souvent dans un fichier.
+func plainProgress(l progressLine) string {
+	label := string(l.Phase)
+	if name, ok := phaseLabels[l.Phase]; ok {
+		label = tr(name)
+	}
+	s := fmt.Sprintf("%-13s", label)
+	switch {
+	case l.Phase == pkg.PhaseScan:
+		s += " " + fmt.Sprintf(tr("%s trouvés"), humanSize(l.Done))
+	case l.Total > 0:
+		s += fmt.Sprintf(" %3d %%  %s / %s", l.Done*100/l.Total, humanSize(l.Done), humanSize(l.Total))
+	case l.Done > 0:
+		s += " " + humanSize(l.Done)
+	}
+	if l.BytesPerSec > 0 {
+		s += "  " + humanSize(l.BytesPerSec) + "/s"
+	}
+	if l.Entry != "" {
+		s += "  " + l.Entry
+	}
+	return strings.TrimRight(s, " ")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"chiffremento-cli/pkg"
+)
+
+// lignesNDJSON relit le journal écrit en ndjson.
+func lignesNDJSON(t *testing.T, buf *bytes.Buffer) []progressLine {
+	t.Helper()
+	var out []progressLine
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var p progressLine
+		if err := json.Unmarshal([]byte(l), &p); err != nil {
+			t.Fatalf("ligne qui n'est pas du JSON : %q (%v)", l, err)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func TestProgressEspacement(t *testing.T) {
+	var buf bytes.Buffer
+	horloge := time.Unix(0, 0)
+	l := &progressLog{w: &buf, ndjson: true, now: func() time.Time { return horloge }}
+
+	l.event(pkg.ProgressEvent{Phase: pkg.PhaseKDF})
+	horloge = horloge.Add(time.Second)
+	l.event(pkg.ProgressEvent{Phase: pkg.PhaseEncrypt, Total: 1000})
+	// Trois blocs en moins d'un intervalle : un seul est retenu…
+	for _, done := range []int64{100, 200, 300} {
+		horloge = horloge.Add(progressInterval / 10)
+		l.event(pkg.ProgressEvent{Phase: pkg.PhaseEncrypt, Done: done, Total: 1000, Entry: "a.txt"})
+	}
+	// …le suivant, passé l'intervalle, est écrit.
+	horloge = horloge.Add(progressInterval)
+	l.event(pkg.ProgressEvent{Phase: pkg.PhaseEncrypt, Done: 900, Total: 1000, Entry: "b.txt"})
+	horloge = horloge.Add(progressInterval / 10)
+	l.event(pkg.ProgressEvent{Phase: pkg.PhaseEncrypt, Done: 1000, Total: 1000, Entry: "b.txt"})
+	// Le changement d'étape écrit d'abord les 100 % restés en attente.
+	l.event(pkg.ProgressEvent{Phase: pkg.PhaseFsync})
+	l.flush()
+
+	got := lignesNDJSON(t, &buf)
+	var resume []string
+	for _, p := range got {
+		resume = append(resume, string(p.Phase)+":"+strconv.FormatInt(p.Done, 10))
+	}
+	want := "kdf:0 encrypt:0 encrypt:900 encrypt:1000 fsync:0"
+	if strings.Join(resume, " ") != want {
+		t.Fatalf("lignes %v, attendu %s", resume, want)
+	}
+	fin := got[3]
+	if fin.Entry != "b.txt" || fin.Total != 1000 || fin.ElapsedMs != 1700 {
+		t.Errorf("dernière ligne de chiffrement : %+v", fin)
+	}
+	if fin.BytesPerSec <= 0 {
+		t.Errorf("débit absent : %+v", fin)
+	}
+}
+
+func TestProgressPlain(t *testing.T) {
+	for _, c := range []struct {
+		line progressLine
+		want string
+	}{
+		{progressLine{Phase: pkg.PhaseScan, Done: 2048, Entry: "photos/a.jpg"}, "inventaire    2.0 ko trouvés  photos/a.jpg"},
+		{progressLine{Phase: pkg.PhaseEncrypt, Done: 512, Total: 2048, BytesPerSec: 1024}, "chiffrement    25 %  512 o / 2.0 ko  1.0 ko/s"},
+		{progressLine{Phase: pkg.PhaseKDF}, "dérivation"},
+	} {
+		if got := plainProgress(c.line); got != c.want {
+			t.Errorf("%+v :\n  %q\nattendu\n  %q", c.line, got, c.want)
+		}
+	}
+}
+
+// TestProgressChiffrement suit un vrai chiffrement de dossier de bout en bout.
+func TestProgressChiffrement(t *testing.T) {
+	racine := arbreCLI(t)
+	var buf bytes.Buffer
+	l := &progressLog{w: &buf, ndjson: true}
+	opts := pkg.Options{}
+	l.attach(&opts)
+	avecMotDePasse(t, motDePasseTest)
+	if err := doEncrypt(racine, filepath.Join(t.TempDir(), "source.chto"), opts); err != nil {
+		t.Fatal(err)
+	}
+	l.flush()
+
+	var phases []string
+	entrees := map[string]bool{}
+	for _, p := range lignesNDJSON(t, &buf) {
+		if n := len(phases); n == 0 || phases[n-1] != string(p.Phase) {
+			phases = append(phases, string(p.Phase))
+		}
+		entrees[p.Entry] = true
+	}
+	if strings.Join(phases, " ") != "scan kdf encrypt fsync" {
+		t.Errorf("étapes %v", phases)
+	}
+	if !entrees["sous/b.bin"] {
+		t.Errorf("aucune entrée d'archive annoncée : %v", entrees)
+	}
+}
+
+// TestProgressAvantResultat : le dernier événement est écrit avant la ligne
+// de résultat, pas par un defer après elle.
+func TestProgressAvantResultat(t *testing.T) {
+	dir := t.TempDir()
+	in := ecrire(t, filepath.Join(dir, "flux.bin"), bytes.Repeat([]byte("flux "), 1<<20))
+	avecMotDePasse(t, motDePasseTest)
+	if err := doEncrypt(in, "", pkg.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	// Sur un flux, le total est inconnu, et une horloge figée retient tous
+	// les événements d'une même étape : le dernier attend encore quand
+	// l'opération se termine.
+	fige := time.Now()
+	precedent, source := progressOut, passwordFrom
+	progressOut = &progressLog{w: &buf, ndjson: true, now: func() time.Time { return fige }}
+	diag = &buf
+	t.Setenv("CHTO_TEST_PW", motDePasseTest)
+	passwordFrom = passwordSource{env: "CHTO_TEST_PW", fd: -1}
+	t.Cleanup(func() { progressOut, diag, passwordFrom = precedent, os.Stderr, source })
+
+	avecEntree(t, in+extension)
+	opts := pkg.Options{}
+	progressOut.attach(&opts)
+	if err := doVerify("-", opts); err != nil {
+		t.Fatal(err)
+	}
+	if progressOut.pending != nil {
+		t.Error("un événement attend encore après le résultat")
+	}
+	lignes := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	fin := lignes[len(lignes)-1]
+	if !strings.Contains(fin, "✓") {
+		t.Errorf("la dernière ligne n'est pas le résultat : %q", fin)
+	}
+	// Horloge figée : seul le premier événement, à zéro, passe tout de suite.
+	// Un compte non nul juste avant le résultat est donc l'état final.
+	var dernier progressLine
+	if err := json.Unmarshal([]byte(lignes[len(lignes)-2]), &dernier); err != nil || dernier.Done == 0 {
+		t.Errorf("l'événement final ne précède pas le résultat : %q (%v)", lignes[len(lignes)-2], err)
+	}
+}
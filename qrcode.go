@@ -0,0 +1,355 @@
+package main
+
+import (
+	"strings"
+)
+
+// QR code du code de secours.
+//
+// Un seul usage, donc un seul format : version 3 (29×29 modules), correction
+// M, mode octet. La forme compacte du code (« chto-rc-1: » et 29 caractères
+// base32) tient dans ses 42 octets utiles. Un encodeur général, avec ses
+// quarante versions et ses blocs entrelacés, serait une dépendance de plus
+// pour rien.
+
+const (
+	qrSize      = 29 // version 3
+	qrDataBytes = 44 // correction M : un bloc de 44 octets de données…
+	qrECBytes   = 26 // … suivis de 26 octets de correction
+	qrMaxInput  = qrDataBytes - 2
+)
+
+type qrCode struct {
+	modules [qrSize][qrSize]bool // [y][x], true = sombre
+	isFunc  [qrSize][qrSize]bool
+}
+
+// encodeQR construit le symbole de data, avec le masque de moindre pénalité.
+func encodeQR(data []byte) (*qrCode, error) {
+	if len(data) > qrMaxInput {
+		return nil, errorf("trop long pour un QR code de version 3")
+	}
+	codewords := qrCodewords(data)
+
+	q := &qrCode{}
+	q.drawFunctionPatterns()
+	q.drawCodewords(codewords)
+
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask) // XOR : une seconde application annule la première
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+	return q, nil
+}
+
+// qrCodewords assemble le flux : mode octet (0100), longueur sur 8 bits, les
+// données, le terminateur, le bourrage 0xEC/0x11, puis la correction.
+func qrCodewords(data []byte) []byte {
+	var bits []bool
+	put := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, v>>i&1 == 1)
+		}
+	}
+	put(0b0100, 4)
+	put(len(data), 8)
+	for _, b := range data {
+		put(int(b), 8)
+	}
+	put(0, min(4, qrDataBytes*8-len(bits)))
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	out := make([]byte, 0, qrDataBytes+qrECBytes)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for _, bit := range bits[i : i+8] {
+			b <<= 1
+			if bit {
+				b |= 1
+			}
+		}
+		out = append(out, b)
+	}
+	for pad := byte(0xEC); len(out) < qrDataBytes; pad ^= 0xEC ^ 0x11 {
+		out = append(out, pad)
+	}
+	return append(out, reedSolomon(out, qrECBytes)...)
+}
+
+// qrMul multiplie dans GF(2⁸) modulo x⁸ + x⁴ + x³ + x² + 1, le polynôme des
+// QR codes (différent de celui d'AES, utilisé par Shamir).
+func qrMul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1d
+		}
+		b >>= 1
+	}
+	return p
+}
+
+// reedSolomon rend les n octets de correction de data : le reste de la
+// division par le polynôme générateur Π (x − αⁱ), i < n, avec α = 2.
+func reedSolomon(data []byte, n int) []byte {
+	gen := make([]byte, n)
+	gen[n-1] = 1
+	root := byte(1)
+	for range n {
+		for j := range gen {
+			gen[j] = qrMul(gen[j], root)
+			if j+1 < n {
+				gen[j] ^= gen[j+1]
+			}
+		}
+		root = qrMul(root, 2)
+	}
+	rem := make([]byte, n)
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[n-1] = 0
+		for j := range rem {
+			rem[j] ^= qrMul(gen[j], factor)
+		}
+	}
+	return rem
+}
+
+func (q *qrCode) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.isFunc[y][x] = true
+}
+
+// drawFunctionPatterns place ce qui ne porte pas de données : motifs de
+// repérage et leurs séparateurs, lignes de synchronisation, motif
+// d'alignement, et la place des bits de format.
+func (q *qrCode) drawFunctionPatterns() {
+	for i := range qrSize {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+	for _, c := range [][2]int{{3, 3}, {qrSize - 4, 3}, {3, qrSize - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x < 0 || x >= qrSize || y < 0 || y >= qrSize {
+					continue
+				}
+				d := max(abs(dx), abs(dy))
+				q.set(x, y, d != 2 && d != 4)
+			}
+		}
+	}
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.set(22+dx, 22+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+	q.drawFormatBits(0)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// formatBits rend les 15 bits de format : correction M (00) et masque, suivis
+// de leur code BCH, puis masqués par 0x5412.
+func formatBits(mask int) int {
+	data := mask // bits de correction de M : 00
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawFormatBits écrit les bits de format en double, autour du motif de
+// repérage en haut à gauche, et le long des deux autres.
+func (q *qrCode) drawFormatBits(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	for i := range 8 {
+		q.set(qrSize-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, qrSize-15+i, bit(i))
+	}
+	q.set(8, qrSize-8, true) // module toujours sombre
+}
+
+// qrZigzag parcourt les modules de données dans l'ordre de la norme : par
+// colonnes de deux, de droite à gauche, en montant puis en descendant, la
+// colonne 6 de synchronisation sautée.
+func qrZigzag(isFunc *[qrSize][qrSize]bool, visit func(x, y int)) {
+	for right := qrSize - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range qrSize {
+			for j := range 2 {
+				x, y := right-j, vert
+				if upward {
+					y = qrSize - 1 - vert
+				}
+				if !isFunc[y][x] {
+					visit(x, y)
+				}
+			}
+		}
+	}
+}
+
+func (q *qrCode) drawCodewords(data []byte) {
+	i := 0
+	qrZigzag(&q.isFunc, func(x, y int) {
+		// Les modules restants (7 en version 3) restent clairs.
+		if i < len(data)*8 {
+			q.modules[y][x] = data[i>>3]>>(7-i&7)&1 == 1
+			i++
+		}
+	})
+}
+
+// qrMaskBit dit si le masque inverse le module (x, y).
+func qrMaskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (q *qrCode) applyMask(mask int) {
+	for y := range qrSize {
+		for x := range qrSize {
+			if !q.isFunc[y][x] && qrMaskBit(mask, x, y) {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty applique les quatre règles de la norme : longues suites d'une même
+// couleur, blocs 2×2, faux motifs de repérage, déséquilibre sombre/clair.
+func (q *qrCode) penalty() int {
+	p := 0
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i <= qrSize; i++ {
+			if i < qrSize && get(i) == get(i-1) {
+				run++
+				continue
+			}
+			if run >= 5 {
+				p += run - 2
+			}
+			run = 1
+		}
+		// 1:1:3:1:1 bordé de quatre clairs, d'un côté ou de l'autre.
+		for i := 0; i+11 <= qrSize; i++ {
+			var w [11]bool
+			for k := range w {
+				w[k] = get(i + k)
+			}
+			if w == [11]bool{true, false, true, true, true, false, true} ||
+				w == [11]bool{false, false, false, false, true, false, true, true, true, false, true} {
+				p += 40
+			}
+		}
+	}
+	dark := 0
+	for y := range qrSize {
+		line(func(i int) bool { return q.modules[y][i] })
+		line(func(i int) bool { return q.modules[i][y] })
+		for x := range qrSize {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < qrSize && y+1 < qrSize {
+				c := q.modules[y][x]
+				if q.modules[y][x+1] == c && q.modules[y+1][x] == c && q.modules[y+1][x+1] == c {
+					p += 3
+				}
+			}
+		}
+	}
+	total := qrSize * qrSize
+	p += abs(dark*20-total*10) / total * 10
+	return p
+}
+
+// qrQuietZone est la marge claire exigée autour du symbole, en modules.
+const qrQuietZone = 4
+
+// render dessine le symbole au terminal, deux rangées de modules par ligne
+// grâce aux demi-blocs. Ce sont les modules clairs qui sont tracés : sur un
+// fond sombre, le cas le plus courant, le symbole apparaît dans ses vraies
+// couleurs, marge comprise.
+func (q *qrCode) render() string {
+	light := func(x, y int) bool {
+		x, y = x-qrQuietZone, y-qrQuietZone
+		if x < 0 || x >= qrSize || y < 0 || y >= qrSize {
+			return true
+		}
+		return !q.modules[y][x]
+	}
+	full := qrSize + 2*qrQuietZone
+	var b strings.Builder
+	for y := 0; y < full; y += 2 {
+		for x := range full {
+			top, bottom := light(x, y), y+1 < full && light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
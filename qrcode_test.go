@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Vecteur de la norme, repris par tous les tutoriels : « HELLO WORLD » en
+// version 1-M, seize octets de données et dix de correction.
+func TestReedSolomon(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomon(data, 10); !bytes.Equal(got, want) {
+		t.Errorf("correction %v, attendu %v", got, want)
+	}
+}
+
+func TestBitsDeFormat(t *testing.T) {
+	// Table de la norme pour la correction M.
+	want := []int{
+		0b101010000010010, 0b101000100100101, 0b101111001111100, 0b101101101001011,
+		0b100010111111001, 0b100000011001110, 0b100111110010111, 0b100101010100000,
+	}
+	for mask, w := range want {
+		if got := formatBits(mask); got != w {
+			t.Errorf("masque %d : %015b, attendu %015b", mask, got, w)
+		}
+	}
+}
+
+// TestQRRelu relit le symbole comme le ferait un lecteur : bits de format,
+// démasquage, parcours en zigzag, puis contrôle de la correction et des
+// données.
+func TestQRRelu(t *testing.T) {
+	payload := []byte("chto-rc-1:MSDA5O6UXYXNJ5MGGIPMFF3KVSXRM")
+	q, err := encodeQR(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Les bits de format, lus le long du motif en haut à gauche.
+	var format int
+	for i := 0; i <= 5; i++ {
+		format |= b2i(q.modules[i][8]) << i
+	}
+	format |= b2i(q.modules[7][8])<<6 | b2i(q.modules[8][8])<<7 | b2i(q.modules[8][7])<<8
+	for i := 9; i < 15; i++ {
+		format |= b2i(q.modules[8][14-i]) << i
+	}
+	mask := -1
+	for m := range 8 {
+		if formatBits(m) == format {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("bits de format illisibles : %015b", format)
+	}
+
+	var bits []bool
+	qrZigzag(&q.isFunc, func(x, y int) {
+		bits = append(bits, q.modules[y][x] != qrMaskBit(mask, x, y))
+	})
+	if len(bits) != (qrDataBytes+qrECBytes)*8+7 {
+		t.Fatalf("%d modules de données, attendu %d", len(bits), (qrDataBytes+qrECBytes)*8+7)
+	}
+	words := make([]byte, qrDataBytes+qrECBytes)
+	for i := range words {
+		for _, bit := range bits[i*8 : i*8+8] {
+			words[i] = words[i]<<1 | byte(b2i(bit))
+		}
+	}
+	if !bytes.Equal(reedSolomon(words[:qrDataBytes], qrECBytes), words[qrDataBytes:]) {
+		t.Fatal("la correction relue ne correspond pas aux données")
+	}
+	if words[0]>>4 != 0b0100 {
+		t.Fatalf("mode %04b, attendu octet", words[0]>>4)
+	}
+	n := int(words[0]&0xf)<<4 | int(words[1]>>4)
+	var got []byte
+	for i := range n {
+		got = append(got, words[1+i]<<4|words[2+i]>>4)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("relu %q, attendu %q", got, payload)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(q.render(), "\n"), "\n")
+	if len(lines) != (qrSize+2*qrQuietZone+1)/2 {
+		t.Errorf("%d lignes au rendu", len(lines))
+	}
+	if _, err := encodeQR(make([]byte, qrMaxInput+1)); err == nil {
+		t.Error("charge trop longue acceptée")
+	}
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"chiffremento-cli/pkg"
+)
+
+// Code de secours.
+//
+// -recovery, au chiffrement, tire un code de secours et l'affiche une seule
+// fois, une fois le fichier écrit : dix-huit mots à imprimer ou recopier, et
+// avec -qr leur forme compacte en QR code. Le code ouvre le fichier sans le
+// mot de passe : -recovery-code au déchiffrement.
+//
+// Le code se donne en argument, ou se tape au terminal avec
+// « -recovery-code - » : en argument, il resterait dans l'historique du shell.
+
+// printRecoveryCode affiche le code sur la sortie d'erreur, qui reste au
+// terminal même quand le chiffré part sur la sortie standard.
+func printRecoveryCode(code []byte, qr bool) error {
+	words, err := pkg.FormatRecoveryCode(code)
+	if err != nil {
+		return err
+	}
+	compact, err := pkg.CompactRecoveryCode(code)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n\n", styleAccent.Render(tr("code de secours — à imprimer ou recopier, puis à ranger loin du fichier")))
+	for _, l := range strings.Split(words, "\n") {
+		fmt.Fprintf(os.Stderr, "  %s\n", styleText.Render(l))
+	}
+	fmt.Fprintf(os.Stderr, "\n  %s %s\n", styleDim.Render(tr("forme compacte")), compact)
+	if qr {
+		symbol, err := encodeQR([]byte(compact))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "\n%s", symbol.render())
+	}
+	fmt.Fprintf(os.Stderr, "\n  %s\n", styleDim.Render(tr("il ouvre le fichier sans mot de passe (-recovery-code) ; il ne sera plus affiché")))
+	return nil
+}
+
+// readRecoveryCode relit -recovery-code : le code lui-même, ou « - » pour le
+// taper au terminal.
+func readRecoveryCode(v string) ([]byte, error) {
+	if v != "-" {
+		return pkg.ParseRecoveryCode(v)
+	}
+	tty, err := os.OpenFile(ttyDevice, os.O_RDWR, 0)
+	if err != nil {
+		return nil, errorf("aucun terminal pour saisir le code de secours (%v)", err)
+	}
+	defer tty.Close()
+	if !term.IsTerminal(int(tty.Fd())) {
+		return nil, errorf("aucun terminal pour saisir le code de secours")
+	}
+	fmt.Fprintf(tty, "%s\n", styleDim.Render(tr("saisie des dix-huit mots, ou de la forme compacte (ligne vide pour abandonner)")))
+	for {
+		fmt.Fprintf(tty, "%s ", styleDim.Render(tr("code de secours :")))
+		line, err := term.ReadPassword(int(tty.Fd()))
+		fmt.Fprintln(tty)
+		if err != nil {
+			return nil, errorf("lecture du code de secours: %w", err)
+		}
+		if len(line) == 0 {
+			return nil, errorf("saisie du code de secours abandonnée")
+		}
+		code, err := pkg.ParseRecoveryCode(string(line))
+		zero(line)
+		if err == nil {
+			return code, nil
+		}
+		fmt.Fprintf(tty, "%s\n", styleDim.Render(err.Error()))
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+func TestCodeDeSecoursCLI(t *testing.T) {
+	dir := t.TempDir()
+	contenu := []byte("photos de famille\n")
+	in := ecrire(t, filepath.Join(dir, "photos.txt"), contenu)
+	rapide := &pkg.ArgonParams{Time: 1, MemoryKiB: 1024, Threads: 1}
+	code, _ := pkg.GenerateRecoveryCode()
+
+	avecMotDePasse(t, motDePasseTest)
+	if err := doEncrypt(in, "", pkg.Options{Argon: rapide, RecoveryCode: code}); err != nil {
+		t.Fatal(err)
+	}
+	enc := in + extension
+
+	sortie := captureSortie(t)
+	if err := doInfo(enc); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.ReadFile(sortie); !strings.Contains(string(info), "code de secours (-recovery-code)") {
+		t.Errorf("info ne signale pas le code de secours :\n%s", info)
+	}
+
+	// Le code tel qu'imprimé ouvre le fichier, sans rien demander.
+	mots, _ := pkg.FormatRecoveryCode(code)
+	relu, err := readRecoveryCode(mots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sansTerminal(t)
+	avecEntree(t, os.DevNull)
+	out := filepath.Join(dir, "relu.txt")
+	if err := doDecrypt(enc, out, pkg.Options{RecoveryCode: relu}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, contenu) {
+		t.Errorf("contenu relu %q", got)
+	}
+
+	// Saisie au terminal demandée, mais pas de terminal.
+	if _, err := readRecoveryCode("-"); err == nil || !strings.Contains(err.Error(), "terminal") {
+		t.Errorf("saisie sans terminal : %v", err)
+	}
+
+	// Un fichier sans code de secours est refusé avant toute dérivation.
+	sans := filepath.Join(dir, "sans.chto")
+	avecMotDePasse(t, motDePasseTest)
+	if err := doEncrypt(in, sans, pkg.Options{Argon: rapide}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doVerify(sans, pkg.Options{RecoveryCode: code}); err == nil || !strings.Contains(err.Error(), "pas de code de secours") {
+		t.Errorf("code sur un fichier qui n'en a pas : %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"golang.org/x/term"
+
+	"chiffremento-cli/pkg"
+)
+
+// Saisie dans un tampon protégé.
+//
+// term.ReadPassword et bufio rendent la saisie dans des tranches qui grandissent
+// par append : chaque agrandissement laisse derrière lui une copie partielle
+// du mot de passe sur le tas, hors de portée de zero. Ici, un tampon protégé
+// de taille fixe est alloué d'avance et rempli octet par octet ; seul le
+// résultat, à sa taille exacte, en ressort.
+
+// maxPasswordInput borne une saisie : le tampon est alloué d'avance, et un mot
+// de passe n'a rien à faire au-delà.
+const maxPasswordInput = 4 << 10
+
+var errInputAborted error = sentinel("saisie interrompue")
+
+// readSecretLine lit une ligne de r dans un tampon protégé, sans le saut de
+// ligne. Sur un terminal en mode brut (terminal), l'édition est à sa charge :
+// effacement, Ctrl+U, Ctrl+C, Ctrl+D ; les séquences d'échappement (flèches)
+// sont ignorées. io.EOF si r se termine avant le moindre octet.
+func readSecretLine(r io.Reader, terminal bool) (*pkg.SecureBuffer, error) {
+	buf, err := pkg.NewSecureBuffer(maxPasswordInput)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Destroy()
+	b := buf.Bytes()
+	n := 0
+	// c est sur le tas (Read prend une tranche) : effacé en sortie.
+	c := make([]byte, 1)
+	defer zero(c)
+	escape := 0 // 1 : après ESC, 2 : dans une séquence CSI
+
+	for {
+		if _, err := io.ReadFull(r, c); err != nil {
+			if errors.Is(err, io.EOF) && n > 0 && !terminal {
+				break
+			}
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, errorf("lecture du mot de passe: %w", err)
+		}
+		if c[0] == '\n' || (terminal && c[0] == '\r') {
+			break
+		}
+		if terminal {
+			switch {
+			case escape == 1:
+				escape = 0
+				if c[0] == '[' || c[0] == 'O' {
+					escape = 2
+				}
+				continue
+			case escape == 2:
+				if c[0] >= 0x40 && c[0] <= 0x7e {
+					escape = 0
+				}
+				continue
+			}
+			switch c[0] {
+			case 0x1b:
+				escape = 1
+				continue
+			case 0x03: // Ctrl+C
+				return nil, errInputAborted
+			case 0x04: // Ctrl+D
+				if n == 0 {
+					return nil, io.EOF
+				}
+				continue
+			case 0x15: // Ctrl+U
+				zero(b[:n])
+				n = 0
+				continue
+			case 0x7f, 0x08: // effacement d'un caractère, octets UTF-8 compris
+				for n > 0 {
+					n--
+					cont := b[n]&0xc0 == 0x80
+					b[n] = 0
+					if !cont {
+						break
+					}
+				}
+				continue
+			}
+			if c[0] < 0x20 {
+				continue
+			}
+		}
+		if n == len(b) {
+			return nil, errorf("mot de passe de plus de %d Kio", maxPasswordInput>>10)
+		}
+		b[n] = c[0]
+		n++
+	}
+	if n > 0 && b[n-1] == '\r' {
+		n--
+	}
+	return pkg.SecureCopy(b[:n])
+}
+
+// readTerminalSecret affiche prompt sur out et lit une saisie masquée sur le
+// terminal in, passé en mode brut le temps de la lecture.
+func readTerminalSecret(in *os.File, out io.Writer, prompt string) (*pkg.SecureBuffer, error) {
+	fmt.Fprintf(out, "%s ", styleDim.Render(prompt))
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, errorf("lecture du mot de passe: %w", err)
+	}
+	pw, err := readSecretLine(in, true)
+	term.Restore(int(in.Fd()), state)
+	// Le mode brut ne traduit plus « \n » : retour chariot explicite.
+	fmt.Fprint(out, "\r\n")
+	if errors.Is(err, io.EOF) {
+		return nil, errorf("saisie du mot de passe abandonnée")
+	}
+	return pw, err
+}
+
+// secretString présente un secret en string sans le recopier, pour zxcvbn et
+// la politique qui n'acceptent que ce type. Elle ne doit pas survivre à son
+// tampon : après Destroy, la lire arrête le processus.
+func secretString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// secretBytes est l'inverse, en lecture seule : écrire dans le résultat
+// corromprait la string.
+func secretBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSaisieProtegee(t *testing.T) {
+	for nom, c := range map[string]struct {
+		entree   string
+		terminal bool
+		want     string
+	}{
+		"ligne":              {"secret\nreste", false, "secret"},
+		"fin windows":        {"secret\r\n", false, "secret"},
+		"sans saut de ligne": {"secret", false, "secret"},
+		"contrôles gardés":   {"a\x7fb\n", false, "a\x7fb"},
+		"terminal":           {"secret\r", true, "secret"},
+		"effacement":         {"secrez\x7ft\r", true, "secret"},
+		"effacement utf-8":   {"clé\x7fe\r", true, "cle"},
+		"ctrl+u":             {"faux\x15secret\r", true, "secret"},
+		"flèches ignorées":   {"sec\x1b[Dret\x1bOA\r", true, "secret"},
+		"ctrl+d au milieu":   {"sec\x04ret\n", true, "secret"},
+	} {
+		pw, err := readSecretLine(strings.NewReader(c.entree), c.terminal)
+		if err != nil {
+			t.Errorf("%s : %v", nom, err)
+			continue
+		}
+		if got := string(pw.Bytes()); got != c.want {
+			t.Errorf("%s : %q, attendu %q", nom, got, c.want)
+		}
+		pw.Destroy()
+	}
+
+	if _, err := readSecretLine(strings.NewReader(""), false); !errors.Is(err, io.EOF) {
+		t.Errorf("entrée vide : %v", err)
+	}
+	if _, err := readSecretLine(strings.NewReader("\x04"), true); !errors.Is(err, io.EOF) {
+		t.Errorf("ctrl+d : %v", err)
+	}
+	if _, err := readSecretLine(strings.NewReader("sec\x03"), true); !errors.Is(err, errInputAborted) {
+		t.Errorf("ctrl+c : %v", err)
+	}
+	if _, err := readSecretLine(strings.NewReader(strings.Repeat("x", maxPasswordInput+1)), false); err == nil {
+		t.Error("saisie démesurée acceptée")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+
+	"chiffremento-cli/pkg"
+)
+
+// Parts de Shamir.
+//
+// -shares N -threshold K chiffre avec une clé tirée au hasard et la découpe en
+// N parts, écrites à côté du chiffré (doc.txt.chto.share-1, …) : chacune va à
+// une personne différente, et K d'entre elles réunies déchiffrent. La clé
+// elle-même n'est écrite nulle part.
+//
+// Au déchiffrement, les parts se donnent par -share FICHIER (à répéter), ou se
+// collent une à une au terminal quand il en manque.
+
+// shareFiles est la liste des -share, dans l'ordre de la ligne de commande.
+type shareFiles []string
+
+func (s *shareFiles) String() string { return strings.Join(*s, ",") }
+
+func (s *shareFiles) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// shareBase est le chiffré à côté duquel les parts sont écrites.
+func shareBase(in, out string) (string, error) {
+	if isStream(out) || (out == "" && isStream(in)) {
+		return "", errorf("-shares écrit les parts à côté du chiffré : il faut un -out fichier")
+	}
+	if out != "" {
+		return out, nil
+	}
+	return trimTrailingSeparator(in) + extension, nil
+}
+
+func sharePath(base string, i int) string {
+	return fmt.Sprintf("%s.share-%d", base, i)
+}
+
+// encryptWithShares chiffre avec une clé neuve, découpée en n parts dont k
+// suffisent. Les parts sont écrites avant le chiffré : un chiffré sans ses
+// parts serait perdu. Si le chiffrement échoue, elles sont retirées.
+func encryptWithShares(in, out string, n, k int, opts pkg.Options) error {
+	base, err := shareBase(in, out)
+	if err != nil {
+		return err
+	}
+	key, err := pkg.GenerateKey()
+	if err != nil {
+		return err
+	}
+	defer zero(key)
+	shares, err := pkg.SplitKey(key, n, k)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, s := range shares {
+			zero(s)
+		}
+	}()
+
+	var written []string
+	removeAll := func() {
+		for _, p := range written {
+			os.Remove(p)
+		}
+	}
+	for i, s := range shares {
+		p := sharePath(base, i+1)
+		if err := writeShare(p, s, i+1, n, k, filepath.Base(base)); err != nil {
+			removeAll()
+			return err
+		}
+		written = append(written, p)
+	}
+
+	opts.Key = key
+	if err := doEncrypt(in, out, opts); err != nil {
+		removeAll()
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s %s\n", styleAccent.Render("✓"),
+		styleText.Render(fmt.Sprintf(tr("%d parts écrites (%s.share-1 à -%d), %d suffisent à déchiffrer"), n, base, n, k)))
+	fmt.Fprintln(os.Stderr, styleDim.Render(tr("  chaque part est à remettre à une personne différente, puis à effacer d'ici")))
+	return nil
+}
+
+// writeShare écrit une part dans un fichier neuf, lisible par son seul
+// propriétaire, avec de quoi savoir à quoi elle sert une fois imprimée.
+func writeShare(path string, share []byte, i, n, k int, of string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return errorf("%s existe déjà : une part n'est jamais écrasée", path)
+		}
+		return errorf("création de la part: %w", err)
+	}
+	_, err = fmt.Fprintf(f, tr("# chiffremento — part %d sur %d de %s ; %d parts réunies la déchiffrent.\n"+
+		"# Seule, cette part ne révèle rien. À remettre à une seule personne.\n%s\n"), i, n, of, k, share)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return errorf("écriture de la part: %w", err)
+	}
+	return nil
+}
+
+// loadShares relit les -share et reconstitue la clé, en demandant au terminal
+// les parts qui manquent.
+func loadShares(paths []string) ([]byte, error) {
+	var shares []pkg.Share
+	defer func() {
+		for i := range shares {
+			shares[i].Wipe()
+		}
+	}()
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil {
+			warnIfShared(p, info)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, errorf("lecture de la part: %w", err)
+		}
+		s, err := pkg.ParseShare(data)
+		zero(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", p, err)
+		}
+		shares = append(shares, s)
+	}
+	if len(shares) < shares[0].Threshold {
+		more, err := promptShares(shares)
+		if err != nil {
+			return nil, err
+		}
+		shares = more
+	}
+	return pkg.CombineShares(shares)
+}
+
+var errNoShareTerminal error = sentinel("aucun terminal pour saisir les parts")
+
+// keyFromTerminal reconstitue la clé à partir de parts collées au terminal.
+func keyFromTerminal() ([]byte, error) {
+	shares, err := promptShares(nil)
+	defer func() {
+		for i := range shares {
+			shares[i].Wipe()
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	return pkg.CombineShares(shares)
+}
+
+// promptShares fait coller au terminal les parts qui manquent, en saisie
+// masquée. Une ligne vide abandonne.
+func promptShares(have []pkg.Share) ([]pkg.Share, error) {
+	tty, err := os.OpenFile(ttyDevice, os.O_RDWR, 0)
+	if err != nil {
+		return have, errorf("%w (%v) : les parts se donnent par -share", errNoShareTerminal, err)
+	}
+	defer tty.Close()
+	if !term.IsTerminal(int(tty.Fd())) {
+		return have, errorf("%w : les parts se donnent par -share", errNoShareTerminal)
+	}
+
+	need := 0
+	if len(have) > 0 {
+		need = have[0].Threshold
+		fmt.Fprintf(tty, "%s\n", styleDim.Render(fmt.Sprintf(tr("%d part(s) sur %d : les autres sont à coller une à une"), len(have), need)))
+	} else {
+		fmt.Fprintf(tty, "%s\n", styleDim.Render(tr("ce fichier est chiffré par clé : ses parts sont à coller une à une (ligne vide pour abandonner)")))
+	}
+	for need == 0 || len(have) < need {
+		fmt.Fprintf(tty, "%s ", styleDim.Render(fmt.Sprintf(tr("part %d :"), len(have)+1)))
+		line, err := term.ReadPassword(int(tty.Fd()))
+		fmt.Fprintln(tty)
+		if err != nil {
+			return have, errorf("lecture de la part: %w", err)
+		}
+		if len(line) == 0 {
+			return have, errorf("saisie des parts abandonnée")
+		}
+		s, err := pkg.ParseShare(line)
+		zero(line)
+		if err != nil {
+			fmt.Fprintf(tty, "%s\n", styleDim.Render(err.Error()))
+			continue
+		}
+		have = append(have, s)
+		need = s.Threshold
+	}
+	return have, nil
+}
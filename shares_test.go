@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chiffremento-cli/pkg"
+)
+
+func TestPartsDeShamir(t *testing.T) {
+	dir := t.TempDir()
+	contenu := []byte("coffre de reprise d'activité\n")
+	in := ecrire(t, filepath.Join(dir, "coffre.txt"), contenu)
+	sansTerminal(t)
+
+	// Aucun mot de passe n'est demandé : l'entrée standard est vide.
+	avecEntree(t, os.DevNull)
+	if err := encryptWithShares(in, "", 5, 3, pkg.Options{Algo: pkg.AlgoAEGIS}); err != nil {
+		t.Fatal(err)
+	}
+	enc := in + extension
+	for i := 1; i <= 5; i++ {
+		info, err := os.Stat(sharePath(enc, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("part %d en %04o", i, info.Mode().Perm())
+		}
+	}
+	if d, err := pkg.Inspect(enc); err != nil || !d.KeyBased {
+		t.Errorf("le chiffré n'est pas par clé : %+v, %v", d, err)
+	}
+
+	// Trois parts quelconques ouvrent le fichier.
+	key, err := loadShares([]string{sharePath(enc, 5), sharePath(enc, 2), sharePath(enc, 4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "relu.txt")
+	if err := doDecrypt(enc, out, pkg.Options{Key: key}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, contenu) {
+		t.Errorf("contenu relu %q", got)
+	}
+
+	// Deux ne suffisent pas, et sans terminal il n'y a pas de quoi compléter.
+	if _, err := loadShares([]string{sharePath(enc, 1), sharePath(enc, 3)}); err == nil || !strings.Contains(err.Error(), "-share") {
+		t.Errorf("deux parts : %v", err)
+	}
+	if err := doVerify(enc, pkg.Options{}); err == nil || !strings.Contains(err.Error(), "-share") {
+		t.Errorf("sans part : %v", err)
+	}
+
+	// Les parts existantes ne sont jamais écrasées, et un échec ne laisse rien.
+	if err := encryptWithShares(in, enc, 5, 3, pkg.Options{}); err == nil {
+		t.Error("parts existantes écrasées")
+	}
+	autre := filepath.Join(dir, "autre.chto")
+	if err := encryptWithShares(filepath.Join(dir, "absent.txt"), autre, 3, 2, pkg.Options{}); err == nil {
+		t.Error("chiffrement d'une entrée absente accepté")
+	}
+	if _, err := os.Stat(sharePath(autre, 1)); !os.IsNotExist(err) {
+		t.Errorf("part orpheline après échec : %v", err)
+	}
+	if err := encryptWithShares("-", "-", 3, 2, pkg.Options{}); err == nil {
+		t.Error("parts sans fichier de sortie acceptées")
+	}
+}
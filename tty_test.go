@@ -3,12 +3,15 @@
 package main
 
 import (
+	"io"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/creack/pty"
+
+	"chiffremento-cli/pkg"
 )
 
 // TestReadPasswordDepuisTTY couvre le chemin ouvert par -in - : l'entrée
@@ -45,7 +48,7 @@ func TestReadPasswordDepuisTTY(t *testing.T) {
 	defer faux.Close()
 	os.Stdin = faux
 
-	// term.ReadPassword bascule le terminal en mode brut avant de lire. On écrit
+	// readTerminalSecret bascule le terminal en mode brut avant de lire. On écrit
 	// donc en boucle plutôt qu'une seule fois : une écriture arrivée trop tôt
 	// serait consommée par la discipline de ligne encore active.
 	fini := make(chan struct{})
@@ -65,7 +68,7 @@ func TestReadPasswordDepuisTTY(t *testing.T) {
 	// Garde-fou : sans lui, une régression sur ce chemin bloque la suite
 	// jusqu'au timeout global de dix minutes au lieu d'échouer tout de suite.
 	type resultat struct {
-		pw  []byte
+		pw  *pkg.SecureBuffer
 		err error
 	}
 	res := make(chan resultat, 1)
@@ -79,8 +82,9 @@ func TestReadPasswordDepuisTTY(t *testing.T) {
 		if r.err != nil {
 			t.Fatalf("lecture du mot de passe sur le terminal: %v", r.err)
 		}
-		if string(r.pw) != "motdepassetube" {
-			t.Errorf("mot de passe lu %q, attendu %q", r.pw, "motdepassetube")
+		defer r.pw.Destroy()
+		if string(r.pw.Bytes()) != "motdepassetube" {
+			t.Errorf("mot de passe lu %q, attendu %q", r.pw.Bytes(), "motdepassetube")
 		}
 	case <-time.After(15 * time.Second):
 		t.Fatal("readPassword n'a pas rendu la main : la saisie au terminal est bloquée")
@@ -107,3 +111,47 @@ func filepathJoinInexistant(t *testing.T) string {
 	t.Helper()
 	return t.TempDir() + "/pas-un-terminal"
 }
+
+// TestRecopiePhrase : la phrase générée par la TUI est recopiée au terminal,
+// dans un tampon protégé ; trois recopies fausses abandonnent.
+func TestRecopiePhrase(t *testing.T) {
+	for _, c := range []struct {
+		tape string
+		ok   bool
+	}{
+		{"cheval agrafe pile", true},
+		{"cheval agrafe face", false},
+	} {
+		maitre, esclave, err := pty.Open()
+		if err != nil {
+			t.Skipf("pseudo-terminal indisponible ici: %v", err)
+		}
+		// Même raison que plus haut : écrire en boucle, le mode brut n'est
+		// pas encore actif au premier passage.
+		fini := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-fini:
+					return
+				default:
+				}
+				maitre.WriteString(c.tape + "\n")
+				time.Sleep(20 * time.Millisecond)
+			}
+		}()
+		res := make(chan error, 1)
+		go func() { res <- retypePassphrase(esclave, io.Discard, []byte("cheval agrafe pile")) }()
+		select {
+		case err := <-res:
+			if (err == nil) != c.ok {
+				t.Errorf("%q : %v", c.tape, err)
+			}
+		case <-time.After(15 * time.Second):
+			t.Fatal("retypePassphrase n'a pas rendu la main")
+		}
+		close(fini)
+		esclave.Close()
+		maitre.Close()
+	}
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
@@ -61,20 +61,20 @@ func choixForm(action *string, mode *string) *huh.Form {
 		huh.NewGroup(
 			huh.NewSelect[string]().
 				Key("action").
-				Title("opération").
+				Title(tr("opération")).
 				Options(
-					huh.NewOption("chiffrer un fichier ou un dossier", "enc"),
-					huh.NewOption("déchiffrer un "+extension+"  (fichier ou dossier)", "dec"),
-					huh.NewOption("vérifier un "+extension+"  (sans rien écrire)", "verify"),
+					huh.NewOption(tr("chiffrer un fichier ou un dossier"), "enc"),
+					huh.NewOption(fmt.Sprintf(tr("déchiffrer un %s  (fichier ou dossier)"), extension), "dec"),
+					huh.NewOption(fmt.Sprintf(tr("vérifier un %s  (sans rien écrire)"), extension), "verify"),
 				).
 				Value(action),
 
 			huh.NewSelect[string]().
 				Key("mode").
-				Title("désigner la cible").
+				Title(tr("désigner la cible")).
 				Options(
-					huh.NewOption("saisir un chemin  (ou glisser-déposer)", "saisie"),
-					huh.NewOption("parcourir les fichiers", "parcourir"),
+					huh.NewOption(tr("saisir un chemin  (ou glisser-déposer)"), "saisie"),
+					huh.NewOption(tr("parcourir les fichiers"), "parcourir"),
 				).
 				Value(mode),
 		),
@@ -132,7 +132,7 @@ func filePickerField(action string, path *string) huh.Field {
 		Height(16).
 		Key("picker").
 		Title(cibleTitre(action)).
-		Description("↑↓ se déplacer · → entrer dans un dossier · entrée choisir").
+		Description(tr("↑↓ se déplacer · → entrer dans un dossier · entrée choisir")).
 		CurrentDirectory(".").
 		// Picking : sans lui, le champ affiche « No file selected. » et il faut
 		// appuyer sur une touche pour déplier l'arborescence. On veut la liste
@@ -162,16 +162,16 @@ func filePickerField(action string, path *string) huh.Field {
 // cibleTitre nomme ce qu'on attend selon l'opération.
 func cibleTitre(action string) string {
 	if action == "enc" {
-		return "fichier ou dossier à chiffrer"
+		return tr("fichier ou dossier à chiffrer")
 	}
-	return "fichier " + extension + " à lire"
+	return fmt.Sprintf(tr("fichier %s à lire"), extension)
 }
 
 func ciblePlaceholder(action string) string {
 	if action == "enc" {
-		return "chemin, ou glisser-déposer le fichier ou le dossier"
+		return tr("chemin, ou glisser-déposer le fichier ou le dossier")
 	}
-	return "chemin du fichier " + extension + ", ou glisser-déposer"
+	return fmt.Sprintf(tr("chemin du fichier %s, ou glisser-déposer"), extension)
 }
 
 // validateTarget refuse tout de suite les cas qui échoueraient plus loin :
@@ -180,29 +180,62 @@ func ciblePlaceholder(action string) string {
 func validateTarget(s, action string) error {
 	s = trimTrailingSeparator(strings.TrimSpace(expandHome(s)))
 	if s == "" {
-		return errors.New("indique un fichier ou un dossier")
+		return errorf("un fichier ou un dossier est attendu")
 	}
 	info, err := os.Stat(s)
 	if err != nil {
-		return errors.New("chemin introuvable")
+		return errorf("chemin introuvable")
 	}
 	if info.IsDir() && action != "enc" {
-		return errors.New("c'est un dossier : seul un fichier " + extension + " peut être déchiffré ou vérifié")
+		return errorf("c'est un dossier : seul un fichier %s peut être déchiffré ou vérifié", extension)
 	}
 	if !info.IsDir() && !info.Mode().IsRegular() {
-		return errors.New("ce n'est ni un fichier régulier ni un dossier")
+		return errorf("ce n'est ni un fichier régulier ni un dossier")
 	}
 	if (action == "dec" || action == "verify") && !strings.HasSuffix(s, extension) {
-		return errors.New("ce fichier doit porter l'extension " + extension)
+		return errorf("ce fichier doit porter l'extension %s", extension)
 	}
 	if action == "enc" && strings.HasSuffix(s, extension) {
-		return errors.New("ce fichier est déjà chiffré")
+		return errorf("ce fichier est déjà chiffré")
 	}
 	return nil
 }
 
+// presetForm propose les préréglages du fichier de configuration, s'il en
+// définit, et rend les réglages de départ du chiffrement. C'est un formulaire à
+// part, pour la même raison que dans runTUI : les champs du suivant sont
+// initialisés d'après ce choix, et restent modifiables.
+func presetForm() (tuiSettings, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return tuiSettings{}, err
+	}
+	preset := ""
+	if len(cfg.names) > 0 {
+		options := []huh.Option[string]{huh.NewOption(tr("aucun  (réglages par défaut)"), "")}
+		for _, name := range cfg.names {
+			options = append(options, huh.NewOption(name+"  ("+cfg.summary(name)+")", name))
+		}
+		form := huh.NewForm(huh.NewGroup(
+			huh.NewSelect[string]().
+				Title(tr("préréglage")).
+				Description(fmt.Sprintf(tr("tiré de %s"), cfg.path)).
+				Options(options...).
+				Value(&preset),
+		)).WithTheme(formTheme()).WithShowHelp(true)
+		if err := form.Run(); err != nil {
+			return tuiSettings{}, err
+		}
+	}
+	return cfg.tuiSettings(preset)
+}
+
 func tuiEncrypt(path string) error {
-	algo := pkg.AlgoAES
+	prefs, err := presetForm()
+	if err != nil {
+		return err
+	}
+	algo := prefs.algo
 	// Un dossier est presque toujours un mélange de texte, de code et de
 	// métadonnées répétitives, et le tar ajoute lui-même beaucoup de zéros de
 	// bourrage : la compression y gagne largement plus que sur un fichier
@@ -216,21 +249,37 @@ func tuiEncrypt(path string) error {
 	// relu pour les anciens fichiers. La question se réduit donc à « compresser
 	// ou pas ».
 	compresser := estDossier
-	pad := false
-	kdf := pkg.KDFStandard
+	if prefs.compress != nil {
+		compresser = *prefs.compress
+	}
+	pad := prefs.pad && !compresser
+	kdf := prefs.kdf
 	// Les métadonnées ne concernent qu'un fichier : l'archive tar d'un dossier
 	// porte déjà noms, dates et permissions de chaque entrée.
-	garderMeta := false
-	password, confirm := "", ""
+	garderMeta := prefs.keepMeta && !estDossier
+	// La phrase proposée est tirée d'avance : elle ne sert que si on la
+	// choisit, et la tirer ne coûte rien.
+	generer := false
+	phrase, err := pkg.GeneratePassphrase(pkg.DefaultPassphraseWords, pkg.PassphraseLanguages[0])
+	if err != nil {
+		return err
+	}
+	defer zero(phrase)
+	// La TUI ne prend pas d'option : seuls le fichier système et
+	// $CHTO_POLICY fixent la politique.
+	if policy, err = loadPolicyFiles(os.Getenv(policyEnv)); err != nil {
+		return err
+	}
 
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewSelect[byte]().
-				Title("algorithme").
+				Title(tr("algorithme")).
 				Options(
-					huh.NewOption("aes-256-gcm  (défaut)", pkg.AlgoAES),
+					huh.NewOption(tr("aes-256-gcm  (défaut)"), pkg.AlgoAES),
 					huh.NewOption("chacha20-poly1305", pkg.AlgoChaCha),
-					huh.NewOption("cascade  chacha20 + aes  (parano)", pkg.AlgoCascade),
+					huh.NewOption(tr("cascade  chacha20 + aes  (parano)"), pkg.AlgoCascade),
+					huh.NewOption(tr("aegis-256  (rapide avec AES-NI)"), pkg.AlgoAEGIS),
 				).
 				Value(&algo),
 
@@ -238,20 +287,20 @@ func tuiEncrypt(path string) error {
 			// aussi au déchiffrement : un fichier scellé en « maximum » ici sera
 			// illisible sur une machine qui n'a pas 1 Gio à y consacrer.
 			huh.NewSelect[pkg.KDFProfile]().
-				Title("coût de la dérivation de clé").
+				Title(tr("coût de la dérivation de clé")).
 				DescriptionFunc(func() string { return kdfHint(kdf) }, &kdf).
 				Options(
-					huh.NewOption("standard  (défaut)", pkg.KDFStandard),
-					huh.NewOption("fort", pkg.KDFFort),
-					huh.NewOption("maximum", pkg.KDFMaximum),
+					huh.NewOption(tr("standard  (défaut)"), pkg.KDFStandard),
+					huh.NewOption(tr("fort"), pkg.KDFFort),
+					huh.NewOption(tr("maximum"), pkg.KDFMaximum),
 				).
 				Value(&kdf),
 
 			huh.NewConfirm().
-				Title("compresser avant chiffrement  (zstd)").
+				Title(tr("compresser avant chiffrement  (zstd)")).
 				Description(compressHint(estDossier)).
-				Affirmative("oui").
-				Negative("non").
+				Affirmative(tr("oui")).
+				Negative(tr("non")).
 				Value(&compresser),
 		),
 
@@ -262,64 +311,76 @@ func tuiEncrypt(path string) error {
 		// passe.
 		huh.NewGroup(
 			huh.NewConfirm().
-				Title("masquer la taille réelle").
-				Description("arrondit la taille au palier supérieur (jusqu'à ~12 % de disque en plus)").
-				Affirmative("oui").
-				Negative("non").
+				Title(tr("masquer la taille réelle")).
+				Description(tr("arrondit la taille au palier supérieur (jusqu'à ~12 % de disque en plus)")).
+				Affirmative(tr("oui")).
+				Negative(tr("non")).
 				Value(&pad),
 		).WithHideFunc(func() bool { return compresser }),
 
 		huh.NewGroup(
 			huh.NewConfirm().
-				Title("conserver le nom et la date d'origine").
-				Description("stockés à l'intérieur du chiffré, donc restituables même sous un nom neutre").
-				Affirmative("oui").
-				Negative("non").
+				Title(tr("conserver le nom et la date d'origine")).
+				Description(tr("stockés à l'intérieur du chiffré, donc restituables même sous un nom neutre")).
+				Affirmative(tr("oui")).
+				Negative(tr("non")).
 				Value(&garderMeta),
 		).WithHideFunc(func() bool { return estDossier }),
 
 		huh.NewGroup(
-			huh.NewInput().
-				Title("mot de passe").
-				// La description se recalcule à chaque frappe : l'utilisateur
-				// voit la robustesse de son mot de passe pendant qu'il le tape.
-				DescriptionFunc(func() string { return strengthHint(password) }, &password).
-				EchoMode(huh.EchoModePassword).
-				Value(&password).
-				Validate(validatePassword),
-
-			huh.NewInput().
-				Title("confirmation").
-				Description("une faute de frappe rendrait le fichier définitivement irrécupérable").
-				EchoMode(huh.EchoModePassword).
-				Value(&confirm).
-				Validate(func(s string) error {
-					if s != password {
-						return errors.New("les deux saisies diffèrent")
-					}
-					return nil
-				}),
+			huh.NewSelect[bool]().
+				Title(tr("mot de passe")).
+				Options(
+					huh.NewOption(tr("le choisir moi-même"), false),
+					huh.NewOption(tr("en générer un pour moi  (phrase de passe)"), true),
+				).
+				Value(&generer),
 		),
 	).WithTheme(formTheme()).WithShowHelp(true)
 
 	if err := form.Run(); err != nil {
 		return err
 	}
+	// Le mot de passe est saisi hors du formulaire : les champs de huh le
+	// garderaient dans une string, que rien n'efface.
+	var password *pkg.SecureBuffer
+	if generer {
+		// Une politique plus exigeante que la phrase proposée la refuse aussi.
+		if err := policy.check(secretString(phrase)); err != nil {
+			return err
+		}
+		// La phrase s'affiche sur son propre écran, puis doit être recopiée
+		// de mémoire ou depuis le papier : c'est la preuve qu'elle a été
+		// notée.
+		if err := showPassphrase(os.Stdin, os.Stdout, phrase); err != nil {
+			return err
+		}
+		if err := retypePassphrase(os.Stdin, os.Stdout, phrase); err != nil {
+			return err
+		}
+		password, err = pkg.SecureCopy(phrase)
+	} else {
+		password, err = promptPassword(os.Stdin, os.Stdout, true)
+	}
+	if err != nil {
+		return err
+	}
+	defer password.Destroy()
 
 	out := path + extension
 	// La ligne « sel » du cadre reste sur une seule ligne : les mentions
 	// s'y ajoutent plutôt que de casser la mise en page.
-	salt := "16 o aléatoires · en-tête lié à la clé"
+	salt := tr("16 o aléatoires · en-tête lié à la clé")
 	switch {
 	case estDossier && pad:
-		salt = "16 o aléatoires · dossier tar · taille masquée"
+		salt = tr("16 o aléatoires · dossier tar · taille masquée")
 	case estDossier:
-		salt = "16 o aléatoires · dossier empaqueté en tar"
+		salt = tr("16 o aléatoires · dossier empaqueté en tar")
 	case pad:
-		salt = "16 o aléatoires · taille réelle masquée"
+		salt = tr("16 o aléatoires · taille réelle masquée")
 	}
 	info := jobInfo{
-		Action:  "chiffrement",
+		Action:  tr("chiffrement"),
 		In:      path,
 		Out:     out,
 		AEAD:    pkg.AlgoName(algo),
@@ -328,7 +389,7 @@ func tuiEncrypt(path string) error {
 		Success: out,
 	}
 	return runJob(info, func(p func(int64, int64)) error {
-		return pkg.Encrypt(path, out, []byte(password), pkg.Options{
+		return pkg.Encrypt(path, out, password.Bytes(), pkg.Options{
 			Algo: algo, Comp: compEncodee(compresser), Pad: pad,
 			KDF: kdf, Metadata: metaEncodee(garderMeta), Progress: p,
 		})
@@ -343,47 +404,36 @@ func tuiDecrypt(path string) error {
 		return err
 	}
 
-	details := fmt.Sprintf("format v%d · %s · %s", d.Version, d.Algo, d.KDF)
+	details := fmt.Sprintf(tr("format v%d · %s · %s"), d.Version, d.Algo, d.KDF)
 	if d.Compressed {
-		details += " · compressé"
+		details += " · " + tr("compressé")
 	}
 	if d.Archive {
-		details += "\ncontient un dossier : il sera extrait dans " +
-			filepath.Base(strings.TrimSuffix(path, extension)) + string(os.PathSeparator) +
-			", qui ne doit pas déjà exister"
+		details += fmt.Sprintf(tr("\ncontient un dossier : il sera extrait dans %s, qui ne doit pas déjà exister"),
+			filepath.Base(strings.TrimSuffix(path, extension))+string(os.PathSeparator))
 	}
 	if d.Version < 3 {
-		details += fmt.Sprintf("\nformat v%d, plus ancien que celui produit aujourd'hui : lecture seule, il sera relu tel quel", d.Version)
+		details += fmt.Sprintf(tr("\nformat v%d, plus ancien que celui produit aujourd'hui : lecture seule, il sera relu tel quel"), d.Version)
 	}
 
-	password := ""
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewNote().Title("fichier").Description(details),
-			huh.NewInput().
-				Title("mot de passe").
-				EchoMode(huh.EchoModePassword).
-				Value(&password).
-				Validate(validatePassword),
-		),
-	).WithTheme(formTheme()).WithShowHelp(true)
-
-	if err := form.Run(); err != nil {
+	password, err := tuiUnlockPassword(details)
+	if err != nil {
 		return err
 	}
+	defer password.Destroy()
 
 	out := strings.TrimSuffix(path, extension)
 	info := jobInfo{
-		Action:  "déchiffrement",
+		Action:  tr("déchiffrement"),
 		In:      path,
 		Out:     out,
 		AEAD:    d.Algo,
 		KDF:     d.KDF,
-		Salt:    fmt.Sprintf("format v%d · lu dans l'en-tête", d.Version),
+		Salt:    fmt.Sprintf(tr("format v%d · lu dans l'en-tête"), d.Version),
 		Success: out,
 	}
 	return runJob(info, func(p func(int64, int64)) error {
-		return pkg.Decrypt(path, out, []byte(password), pkg.Options{Progress: p})
+		return pkg.Decrypt(path, out, password.Bytes(), pkg.Options{Progress: p})
 	})
 }
 
@@ -393,45 +443,66 @@ func tuiVerify(path string) error {
 		return err
 	}
 
-	details := fmt.Sprintf("format v%d · %s · %s", d.Version, d.Algo, d.KDF)
+	details := fmt.Sprintf(tr("format v%d · %s · %s"), d.Version, d.Algo, d.KDF)
 	if d.Compressed {
-		details += " · compressé"
+		details += " · " + tr("compressé")
 	}
 	if d.Archive {
-		details += " · dossier"
+		details += " · " + tr("dossier")
 	}
-	details += "\nrien ne sera écrit sur le disque"
-
-	password := ""
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewNote().Title("fichier").Description(details),
-			huh.NewInput().
-				Title("mot de passe").
-				EchoMode(huh.EchoModePassword).
-				Value(&password).
-				Validate(validatePassword),
-		),
-	).WithTheme(formTheme()).WithShowHelp(true)
+	details += "\n" + tr("rien ne sera écrit sur le disque")
 
-	if err := form.Run(); err != nil {
+	password, err := tuiUnlockPassword(details)
+	if err != nil {
 		return err
 	}
+	defer password.Destroy()
 
 	info := jobInfo{
-		Action:  "vérification",
+		Action:  tr("vérification"),
 		In:      path,
-		Out:     "(rien, contrôle seul)",
+		Out:     tr("(rien, contrôle seul)"),
 		AEAD:    d.Algo,
 		KDF:     d.KDF,
-		Salt:    fmt.Sprintf("format v%d · lu dans l'en-tête", d.Version),
+		Salt:    fmt.Sprintf(tr("format v%d · lu dans l'en-tête"), d.Version),
 		Success: verifySucces(d.Archive),
 	}
 	return runJob(info, func(p func(int64, int64)) error {
-		return pkg.Verify(path, []byte(password), pkg.Options{Progress: p})
+		return pkg.Verify(path, password.Bytes(), pkg.Options{Progress: p})
 	})
 }
 
+// tuiUnlockPassword montre les paramètres du fichier, puis demande son mot de
+// passe dans un tampon protégé, comme la ligne de commande.
+func tuiUnlockPassword(details string) (*pkg.SecureBuffer, error) {
+	note := huh.NewNote().Title(tr("fichier")).Description(details)
+	if err := huh.NewForm(huh.NewGroup(note)).WithTheme(formTheme()).WithShowHelp(true).Run(); err != nil {
+		return nil, err
+	}
+	return promptPassword(os.Stdin, os.Stdout, false)
+}
+
+// retypePassphrase fait recopier la phrase générée, qui n'est plus affichée :
+// c'est la preuve qu'elle a été notée. La saisie reste dans un tampon
+// protégé, et la comparaison se fait à temps constant.
+func retypePassphrase(in *os.File, out io.Writer, phrase []byte) error {
+	fmt.Fprintln(out, styleDim.Render(tr("elle n'est plus affichée : sans elle, le fichier est définitivement perdu")))
+	last := errorf("ce n'est pas la phrase affichée")
+	for range maxPromptAttempts {
+		typed, err := readTerminalSecret(in, out, tr("recopie de la phrase de passe :"))
+		if err != nil {
+			return err
+		}
+		same := subtle.ConstantTimeCompare(typed.Bytes(), phrase) == 1
+		typed.Destroy()
+		if same {
+			return nil
+		}
+		fmt.Fprintln(out, styleDim.Render(last.Error()))
+	}
+	return last
+}
+
 // teaOptions est vide en production. Les tests s'en servent pour faire
 // tourner l'écran sans terminal, et donc vérifier sa durée réelle.
 var teaOptions []tea.ProgramOption
@@ -472,7 +543,8 @@ func runJob(info jobInfo, op func(progress func(done, total int64)) error) error
 // ligne de commande : le flag -key de la v1 était visible dans `ps aux` pour
 // tous les utilisateurs de la machine et finissait dans l'historique du shell.
 //
-// Trois chemins, dans cet ordre :
+// Une source explicite (-passfile, -passenv, -passfd, -passcmd : voir
+// passsource.go) passe avant tout. Sinon, trois chemins, dans cet ordre :
 //
 //   - stdinTaken (l'entrée standard porte les données, avec -in -) : le mot de
 //     passe est demandé sur le terminal de contrôle, /dev/tty. Sans ce détour,
@@ -480,111 +552,134 @@ func runJob(info jobInfo, op func(progress func(done, total int64)) error) error
 //     silencieux qui chiffrerait le reste avec un secret involontaire.
 //   - entrée standard qui n'est pas un terminal : on lit une ligne. C'est le
 //     chemin des scripts, et il ne fuite ni dans ps ni dans les arguments :
-//     echo 'motdepasse' | chiffremento -mode enc -in f
+//     echo 'motdepasse' | chiffremento enc -in f
 //   - terminal : saisie masquée.
-func readPassword(confirm bool, stdinTaken bool) ([]byte, error) {
+//
+// Le mot de passe est rendu dans un tampon protégé (voir pkg/secure.go), lu
+// octet par octet sans passer par une string : à détruire par Destroy.
+func readPassword(confirm bool, stdinTaken bool) (*pkg.SecureBuffer, error) {
+	if passwordFrom.set() {
+		raw, err := passwordFrom.read(stdinTaken)
+		if err != nil {
+			zero(raw)
+			return nil, err
+		}
+		pw, err := pkg.SecureCopy(raw)
+		if err != nil {
+			return nil, err
+		}
+		if confirm {
+			if err := checkSourcedPassword(pw.Bytes()); err != nil {
+				pw.Destroy()
+				return nil, err
+			}
+		}
+		return pw, nil
+	}
 	if stdinTaken {
 		return readPasswordFromTTY(confirm)
 	}
 
 	if !term.IsTerminal(int(os.Stdin.Fd())) {
-		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
-		if err != nil && line == "" {
-			return nil, errors.New("aucun mot de passe reçu sur l'entrée standard")
+		pw, err := readSecretLine(os.Stdin, false)
+		if errors.Is(err, io.EOF) {
+			return nil, errorf("aucun mot de passe reçu sur l'entrée standard")
 		}
-		pw := strings.TrimRight(line, "\r\n")
-		if pw == "" {
-			return nil, errors.New("mot de passe vide")
-		}
-		return []byte(pw), nil
-	}
-
-	password := ""
-	field := huh.NewInput().
-		Title("mot de passe").
-		EchoMode(huh.EchoModePassword).
-		Value(&password).
-		Validate(validatePassword).
-		WithTheme(formTheme())
-	if err := field.Run(); err != nil {
-		return nil, err
-	}
-
-	if confirm {
-		second := ""
-		check := huh.NewInput().
-			Title("confirmation").
-			EchoMode(huh.EchoModePassword).
-			Value(&second).
-			Validate(func(s string) error {
-				if s != password {
-					return errors.New("les deux saisies diffèrent")
-				}
-				return nil
-			}).
-			WithTheme(formTheme())
-		if err := check.Run(); err != nil {
+		if err != nil {
 			return nil, err
 		}
+		if pw.Len() == 0 {
+			pw.Destroy()
+			return nil, errorf("mot de passe vide")
+		}
+		if confirm {
+			if err := checkSourcedPassword(pw.Bytes()); err != nil {
+				pw.Destroy()
+				return nil, err
+			}
+		}
+		return pw, nil
 	}
-
-	return []byte(password), nil
+	// En -json, la sortie d'erreur doit rester muette : on passe par le terminal.
+	if jsonOutput {
+		return readPasswordFromTTY(confirm)
+	}
+	return promptPassword(os.Stdin, os.Stderr, confirm)
 }
 
 // readPasswordFromTTY demande le mot de passe au terminal de contrôle, l'entrée
 // standard étant occupée par les données.
-//
-// huh n'est pas utilisable ici : il lit os.Stdin. On passe donc directement par
-// term.ReadPassword sur /dev/tty, ce qui donne la même saisie masquée sans
-// habillage.
-func readPasswordFromTTY(confirm bool) ([]byte, error) {
+func readPasswordFromTTY(confirm bool) (*pkg.SecureBuffer, error) {
 	tty, err := os.OpenFile(ttyDevice, os.O_RDWR, 0)
 	if err != nil {
-		return nil, fmt.Errorf("l'entrée standard porte les données à chiffrer, "+
+		return nil, errorf("l'entrée standard porte les données à chiffrer, "+
 			"le mot de passe doit donc être saisi au terminal — introuvable ici (%w). "+
-			"Utilise -in FICHIER plutôt que -in -", err)
+			"Il faut -in FICHIER plutôt que -in -", err)
 	}
 	defer tty.Close()
 
 	if !term.IsTerminal(int(tty.Fd())) {
-		return nil, errors.New("le terminal de contrôle n'est pas utilisable pour une saisie masquée : " +
-			"utilise -in FICHIER plutôt que -in -")
+		return nil, errorf("le terminal de contrôle n'est pas utilisable pour une saisie masquée : " +
+			"il faut -in FICHIER plutôt que -in -")
 	}
+	return promptPassword(tty, tty, confirm)
+}
 
-	ask := func(prompt string) ([]byte, error) {
-		fmt.Fprintf(tty, "%s ", styleDim.Render(prompt))
-		pw, err := term.ReadPassword(int(tty.Fd()))
-		fmt.Fprintln(tty)
-		if err != nil {
-			return nil, fmt.Errorf("lecture du mot de passe: %w", err)
-		}
-		return pw, nil
-	}
+// maxPromptAttempts borne les saisies refusées avant d'abandonner.
+const maxPromptAttempts = 3
 
-	password, err := ask("mot de passe :")
-	if err != nil {
-		return nil, err
-	}
-	if err := validatePassword(string(password)); err != nil {
-		return nil, err
+// promptPassword demande le mot de passe sur le terminal in, et sa
+// confirmation s'il est nouveau. Une saisie refusée — vide, contraire à la
+// politique, différente de sa confirmation — est redemandée, comme dans un
+// formulaire.
+//
+// huh n'est pas utilisé ici : ses champs gardent la saisie dans une string,
+// qui ne s'efface pas. readTerminalSecret écrit directement dans le tampon
+// protégé.
+func promptPassword(in *os.File, out io.Writer, confirm bool) (*pkg.SecureBuffer, error) {
+	validate := validatePassword
+	if confirm {
+		validate = validateNewPassword
+		if rules := policy.describe(); rules != "" {
+			fmt.Fprintln(out, styleDim.Render(fmt.Sprintf(tr("politique : %s"), rules)))
+		}
 	}
+	var last error
+	for range maxPromptAttempts {
+		pw, err := readTerminalSecret(in, out, tr("mot de passe :"))
+		if err != nil {
+			return nil, err
+		}
+		if last = validate(secretString(pw.Bytes())); last != nil {
+			pw.Destroy()
+			fmt.Fprintln(out, styleDim.Render(last.Error()))
+			continue
+		}
+		if !confirm {
+			return pw, nil
+		}
 
-	if confirm {
-		second, err := ask("confirmation :")
+		fmt.Fprintln(out, styleDim.Render(strengthHint(secretString(pw.Bytes()))))
+		second, err := readTerminalSecret(in, out, tr("confirmation :"))
 		if err != nil {
+			pw.Destroy()
 			return nil, err
 		}
-		defer zero(second)
-		if !bytes.Equal(password, second) {
-			return nil, errors.New("les deux saisies diffèrent")
+		same := subtle.ConstantTimeCompare(pw.Bytes(), second.Bytes()) == 1
+		second.Destroy()
+		if same {
+			return pw, nil
 		}
+		pw.Destroy()
+		last = errorf("les deux saisies diffèrent")
+		fmt.Fprintln(out, styleDim.Render(last.Error()))
 	}
-	return password, nil
+	return nil, last
 }
 
 func validatePassword(s string) error {
 	if s == "" {
-		return errors.New("le mot de passe ne peut pas être vide")
+		return errorf("le mot de passe ne peut pas être vide")
 	}
 	return nil
 }
@@ -617,7 +712,7 @@ const (
 // l33t speak) et additionne le coût de chacun. C'est ce qui fait que
 // « azerty123 » est désormais évalué à une vingtaine de bits au lieu d'une
 // soixantaine. Ça reste un repère pour l'utilisateur, pas une garantie, et rien
-// ne bloque la saisie.
+// ne bloque la saisie, sauf politique explicite (policy.go).
 func passwordEntropy(s string) float64 {
 	if s == "" {
 		return 0
@@ -629,45 +724,69 @@ func passwordEntropy(s string) float64 {
 	return math.Log2(guesses)
 }
 
+// showPassphrase présente une phrase de passe générée avec son entropie, qui
+// se compte au lieu de s'estimer. La phrase est écrite telle quelle depuis son
+// tampon : une note de huh la garderait dans une string, que rien n'efface.
+// Une fois Entrée tapée, l'écran et l'historique du terminal sont effacés.
+func showPassphrase(in io.Reader, out io.Writer, phrase []byte) error {
+	bits, _ := pkg.PassphraseBits(pkg.DefaultPassphraseWords, pkg.PassphraseLanguages[0])
+	fmt.Fprintf(out, "%s\n\n  ", styleAccent.Render(tr("phrase de passe")))
+	out.Write(phrase)
+	fmt.Fprintf(out, "\n\n"+tr("%.0f bits, %d mots tirés au hasard · %s hors ligne")+"\n", bits, pkg.DefaultPassphraseWords, crackTime(bits))
+	fmt.Fprint(out, styleDim.Render(tr("à noter maintenant, puis Entrée : elle ne sera plus affichée")))
+	var b [1]byte
+	for b[0] != '\n' {
+		if _, err := in.Read(b[:]); err != nil {
+			return errInputAborted
+		}
+	}
+	fmt.Fprint(out, clearScreen)
+	return nil
+}
+
+// clearScreen efface l'écran, puis l'historique de défilement, et ramène le
+// curseur en haut.
+const clearScreen = "\x1b[2J\x1b[3J\x1b[H"
+
 // strengthHint traduit l'entropie en une ligne lisible, avec l'ordre de
 // grandeur du temps qu'une attaque hors ligne y passerait.
 func strengthHint(s string) string {
 	if s == "" {
-		return "jamais affiché, jamais visible dans ps ni dans l'historique"
+		return tr("jamais affiché, jamais visible dans ps ni dans l'historique")
 	}
 	bits := passwordEntropy(s)
-	verdict := "solide"
+	verdict := tr("solide")
 	switch {
 	case bits < bitsFaible:
-		verdict = "faible, une phrase de passe serait bien plus sûre"
+		verdict = tr("faible, une phrase de passe serait bien plus sûre")
 	case bits < bitsCorrect:
-		verdict = "correct"
+		verdict = tr("correct")
 	}
-	return fmt.Sprintf("~%.0f bits — %s · %s hors ligne", bits, verdict, crackTime(bits))
+	return fmt.Sprintf(tr("~%.0f bits — %s · %s hors ligne"), bits, verdict, crackTime(bits))
 }
 
 // crackTime donne l'ordre de grandeur, pas une prédiction : seule la puissance
 // de dix compte, et l'hypothèse de débit peut se tromper d'un facteur cent.
 func crackTime(bits float64) string {
 	if bits <= 0 {
-		return "instantané"
+		return tr("instantané")
 	}
 	secondes := math.Pow(2, bits) / offlineGuessRate
 	switch {
 	case secondes < 60:
-		return "cassé en quelques secondes"
+		return tr("cassé en quelques secondes")
 	case secondes < 3600:
-		return "cassé en quelques minutes"
+		return tr("cassé en quelques minutes")
 	case secondes < 86400:
-		return "cassé en quelques heures"
+		return tr("cassé en quelques heures")
 	case secondes < 30*86400:
-		return "cassé en quelques jours"
+		return tr("cassé en quelques jours")
 	case secondes < 365*86400:
-		return "cassé en quelques mois"
+		return tr("cassé en quelques mois")
 	case secondes < 1000*365*86400:
-		return fmt.Sprintf("~%.0f ans", secondes/(365*86400))
+		return fmt.Sprintf(tr("~%.0f ans"), secondes/(365*86400))
 	default:
-		return "des millénaires"
+		return tr("des millénaires")
 	}
 }
 
@@ -677,9 +796,9 @@ func crackTime(bits float64) string {
 // est bien qu'elle serait extractible, pas seulement lisible.
 func verifySucces(archive bool) string {
 	if archive {
-		return "archive intacte, extractible, rien écrit sur le disque"
+		return tr("archive intacte, extractible, rien écrit sur le disque")
 	}
-	return "fichier intact, déchiffrable, rien écrit sur le disque"
+	return tr("fichier intact, déchiffrable, rien écrit sur le disque")
 }
 
 // compEncodee traduit la réponse de l'interface en identifiant de compression.
@@ -692,9 +811,9 @@ func compEncodee(compresser bool) byte {
 
 func compressHint(dossier bool) string {
 	if dossier {
-		return "proposée active sur un dossier · laisse fuiter la compressibilité du contenu"
+		return tr("proposée active sur un dossier · laisse fuiter la compressibilité du contenu")
 	}
-	return "réduit la taille, mais laisse fuiter la compressibilité du contenu"
+	return tr("réduit la taille, mais laisse fuiter la compressibilité du contenu")
 }
 
 func expandHome(p string) string {
@@ -712,7 +831,7 @@ func expandHome(p string) string {
 // kdfHint décrit un profil KDF pour la TUI. La mémoire est annoncée parce
 // qu'elle sera aussi exigée au déchiffrement.
 func kdfHint(p pkg.KDFProfile) string {
-	return fmt.Sprintf("%s · %d Mio de mémoire, exigés aussi au déchiffrement",
+	return fmt.Sprintf(tr("%s · %d Mio de mémoire, exigés aussi au déchiffrement"),
 		p.KDFLabel(), p.MemoryMiB())
 }
 
@@ -139,23 +139,30 @@ func TestStrengthHintVide(t *testing.T) {
 }
 
 func TestChooseAlgo(t *testing.T) {
-	if _, err := chooseAlgo(true, true); err == nil {
+	if _, err := chooseAlgo(true, true, false); err == nil {
 		t.Error("-chacha et -parano combinés devraient être refusés")
 	}
+	if _, err := chooseAlgo(true, false, true); err == nil {
+		t.Error("-chacha et -aegis combinés devraient être refusés")
+	}
+	if _, err := chooseAlgo(false, true, true); err == nil {
+		t.Error("-parano et -aegis combinés devraient être refusés")
+	}
 	for _, c := range []struct {
-		chacha, parano bool
-		want           byte
+		chacha, parano, aegis bool
+		want                  byte
 	}{
-		{false, false, 1}, // aes
-		{true, false, 2},  // chacha
-		{false, true, 3},  // cascade
+		{false, false, false, 1}, // aes
+		{true, false, false, 2},  // chacha
+		{false, true, false, 3},  // cascade
+		{false, false, true, 4},  // aegis
 	} {
-		got, err := chooseAlgo(c.chacha, c.parano)
+		got, err := chooseAlgo(c.chacha, c.parano, c.aegis)
 		if err != nil {
 			t.Fatal(err)
 		}
 		if got != c.want {
-			t.Errorf("chooseAlgo(%v, %v) = %d, attendu %d", c.chacha, c.parano, got, c.want)
+			t.Errorf("chooseAlgo(%v, %v, %v) = %d, attendu %d", c.chacha, c.parano, c.aegis, got, c.want)
 		}
 	}
 }